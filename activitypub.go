@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	activityJSON         = "application/activity+json"
+	activityStreams      = "https://www.w3.org/ns/activitystreams"
+	activityPublic       = activityStreams + "#Public"
+	activityOutboxSize   = 20
+	activityMaxBody      = 1 << 20
+	activitySignatureAge = 12 * time.Hour
+)
+
+// Follower is a Fediverse account following the ActivityPub actor.
+type Follower struct {
+	ActorID   string `gorm:"primaryKey"`
+	Inbox     string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (s *gormStore) SaveFollower(ctx context.Context, follower *Follower) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(follower).Error
+}
+
+func (s *gormStore) DeleteFollower(ctx context.Context, actorID string) error {
+	return s.db.WithContext(ctx).Delete(&Follower{}, "actor_id = ?", actorID).Error
+}
+
+func (s *gormStore) Followers(ctx context.Context) ([]Follower, error) {
+	var followers []Follower
+	err := s.db.WithContext(ctx).Order("created_at").Find(&followers).Error
+	return followers, err
+}
+
+// activityPub is a minimal ActivityPub server with a single actor, so that
+// the feed can be followed from Mastodon and other Fediverse software.
+type activityPub struct {
+	user   string
+	base   string
+	host   string
+	key    *rsa.PrivateKey
+	client HTTPDoer
+}
+
+func newActivityPub(config Config) (*activityPub, error) {
+	if config.ActivityPubUser == "" {
+		return nil, nil
+	}
+	base, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, errors.New("ACTIVITYPUB_USER requires BASE_URL")
+	}
+	key, err := loadActivityPubKey(config.ActivityPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("loading activitypub key: %w", err)
+	}
+	// Inbox requests name the actor to fetch, and actors their inboxes, so
+	// only public servers are contacted.
+	return &activityPub{
+		user:   config.ActivityPubUser,
+		base:   base.String(),
+		host:   base.Host,
+		key:    key,
+		client: newPublicClient(notifyTimeout),
+	}, nil
+}
+
+// loadActivityPubKey loads the actor's signing key, generating and storing
+// one on first use. Remote servers cache the public key, so it must not
+// change between restarts.
+func loadActivityPubKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Generated ActivityPub key in", path)
+		return key, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
+
+func (ap *activityPub) actorID() string { return ap.base + "/ap/actor" }
+
+func (ap *activityPub) noteID(hash string) string { return ap.base + "/ap/notes/" + hash }
+
+func (ap *activityPub) actor() map[string]any {
+	der, _ := x509.MarshalPKIXPublicKey(&ap.key.PublicKey)
+	return map[string]any{
+		"@context":                  []string{activityStreams, "https://w3id.org/security/v1"},
+		"id":                        ap.actorID(),
+		"type":                      "Service",
+		"preferredUsername":         ap.user,
+		"name":                      "Berliner Polizeimeldungen",
+		"summary":                   "<p>Die Pressemitteilungen der Polizei Berlin, automatisch veröffentlicht.</p>",
+		"url":                       ap.base,
+		"inbox":                     ap.base + "/ap/inbox",
+		"outbox":                    ap.base + "/ap/outbox",
+		"followers":                 ap.base + "/ap/followers",
+		"manuallyApprovesFollowers": false,
+		"discoverable":              true,
+		"publicKey": map[string]string{
+			"id":           ap.actorID() + "#main-key",
+			"owner":        ap.actorID(),
+			"publicKeyPem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		},
+	}
+}
+
+// note renders an event as a public Note addressed to the followers.
+func (ap *activityPub) note(event *Event) map[string]any {
+	var content strings.Builder
+	content.WriteString("<p><strong>" + html.EscapeString(event.Title) + "</strong></p>")
+	for _, paragraph := range strings.Split(strings.TrimSpace(event.Description), "\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			content.WriteString("<p>" + html.EscapeString(paragraph) + "</p>")
+		}
+	}
+	if event.Link != "" {
+		link := html.EscapeString(event.Link)
+		content.WriteString(`<p><a href="` + link + `">` + link + `</a></p>`)
+	}
+	var tags []map[string]string
+	if hashtag := nostrHashtag(event.Location); hashtag != "" {
+		content.WriteString("<p>#" + html.EscapeString(hashtag) + "</p>")
+		tags = append(tags, map[string]string{"type": "Hashtag", "name": "#" + hashtag})
+	}
+
+	note := map[string]any{
+		"id":           ap.noteID(event.Hash),
+		"type":         "Note",
+		"attributedTo": ap.actorID(),
+		"published":    time.Unix(event.DateTime, 0).UTC().Format(time.RFC3339),
+		"to":           []string{activityPublic},
+		"cc":           []string{ap.base + "/ap/followers"},
+		"content":      content.String(),
+		"tag":          tags,
+	}
+	if event.Link != "" {
+		note["url"] = event.Link
+	}
+	return note
+}
+
+// create wraps the note of an event in the Create activity announcing it.
+func (ap *activityPub) create(event *Event) map[string]any {
+	note := ap.note(event)
+	return map[string]any{
+		"@context":  activityStreams,
+		"id":        ap.noteID(event.Hash) + "/activity",
+		"type":      "Create",
+		"actor":     ap.actorID(),
+		"published": note["published"],
+		"to":        note["to"],
+		"cc":        note["cc"],
+		"object":    note,
+	}
+}
+
+// update wraps the current note of an event in an Update activity. Its id
+// includes the time, as every update is a new activity.
+func (ap *activityPub) update(event *Event, at time.Time) map[string]any {
+	note := ap.note(event)
+	note["updated"] = at.UTC().Format(time.RFC3339)
+	return map[string]any{
+		"@context": activityStreams,
+		"id":       ap.noteID(event.Hash) + "/update/" + strconv.FormatInt(at.Unix(), 10),
+		"type":     "Update",
+		"actor":    ap.actorID(),
+		"to":       note["to"],
+		"cc":       note["cc"],
+		"object":   note,
+	}
+}
+
+// delete replaces the note of an event with a Tombstone.
+func (ap *activityPub) delete(hash string) map[string]any {
+	return map[string]any{
+		"@context": activityStreams,
+		"id":       ap.noteID(hash) + "/delete",
+		"type":     "Delete",
+		"actor":    ap.actorID(),
+		"to":       []string{activityPublic},
+		"cc":       []string{ap.base + "/ap/followers"},
+		"object":   map[string]any{"id": ap.noteID(hash), "type": "Tombstone"},
+	}
+}
+
+// signRequest adds an HTTP signature (draft-cavage-http-signatures, as used
+// by Mastodon) covering the target, host, date and body digest.
+func (ap *activityPub) signRequest(req *http.Request, body []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		digest := sha256.Sum256(body)
+		req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+		headers = append(headers, "digest")
+	}
+	hashed := sha256.Sum256([]byte(signingString(req.Method, req.URL.RequestURI(), req.URL.Host, req.Header, headers)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ap.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s#main-key",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		ap.actorID(), strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+func signingString(method, target, host string, header http.Header, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		switch name {
+		case "(request-target)":
+			lines = append(lines, "(request-target): "+strings.ToLower(method)+" "+target)
+		case "host":
+			lines = append(lines, "host: "+host)
+		default:
+			lines = append(lines, name+": "+strings.Join(header.Values(name), ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// remoteActor is the part of a remote actor document needed to verify its
+// requests and deliver to it.
+type remoteActor struct {
+	ID        string `json:"id"`
+	Inbox     string `json:"inbox"`
+	Endpoints struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPem string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+}
+
+func (ap *activityPub) fetchActor(ctx context.Context, id string) (*remoteActor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", activityJSON)
+	// Servers in secure mode only answer signed fetches.
+	err = ap.signRequest(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ap.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil, fmt.Errorf("%s responded with %s", id, res.Status)
+	}
+	var actor remoteActor
+	err = json.NewDecoder(io.LimitReader(res.Body, activityMaxBody)).Decode(&actor)
+	if err != nil {
+		return nil, err
+	}
+	if actor.ID != id || actor.Inbox == "" {
+		return nil, fmt.Errorf("invalid actor document at %s", id)
+	}
+	return &actor, nil
+}
+
+// verifyRequest checks the HTTP signature of an inbox request and returns the
+// actor that signed it.
+func (ap *activityPub) verifyRequest(r *http.Request, body []byte) (*remoteActor, error) {
+	params := make(map[string]string)
+	for _, part := range strings.Split(r.Header.Get("Signature"), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	headers := strings.Fields(params["headers"])
+	for _, required := range []string{"(request-target)", "host", "date", "digest"} {
+		if !strings.Contains(" "+params["headers"]+" ", " "+required+" ") {
+			return nil, fmt.Errorf("signature does not cover %s", required)
+		}
+	}
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil || time.Since(date).Abs() > activitySignatureAge {
+		return nil, errors.New("date missing or out of range")
+	}
+	digest := sha256.Sum256(body)
+	if r.Header.Get("Digest") != "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]) {
+		return nil, errors.New("digest mismatch")
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return nil, errors.New("invalid signature encoding")
+	}
+
+	actorID, _, _ := strings.Cut(params["keyId"], "#")
+	actor, err := ap.fetchActor(r.Context(), actorID)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(actor.PublicKey.PublicKeyPem))
+	if block == nil || actor.PublicKey.ID != params["keyId"] {
+		return nil, errors.New("actor has no matching public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	hashed := sha256.Sum256([]byte(signingString(r.Method, r.URL.RequestURI(), r.Host, r.Header, headers)))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+	if err != nil {
+		return nil, errors.New("invalid signature")
+	}
+	return actor, nil
+}
+
+// deliver POSTs a signed activity to an inbox.
+func (ap *activityPub) deliver(ctx context.Context, inbox string, activity any) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", activityJSON)
+	err = ap.signRequest(req, body)
+	if err != nil {
+		return err
+	}
+	res, err := ap.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", inbox, res.Status)
+	}
+	return nil
+}
+
+func writeActivityJSON(w http.ResponseWriter, v any) {
+	if m, ok := v.(map[string]any); ok && m["@context"] == nil {
+		m["@context"] = activityStreams
+	}
+	w.Header().Set("Content-Type", activityJSON)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Println("Error writing activity json:", err)
+	}
+}
+
+// handleWebFinger resolves acct:user@host to the actor, which is how
+// Mastodon finds it when searching for @user@host.
+func (a *App) handleWebFinger(w http.ResponseWriter, r *http.Request) {
+	ap := a.activityPub
+	subject := "acct:" + ap.user + "@" + ap.host
+	if r.URL.Query().Get("resource") != subject {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"subject": subject,
+		"aliases": []string{ap.actorID()},
+		"links": []map[string]string{
+			{"rel": "self", "type": activityJSON, "href": ap.actorID()},
+		},
+	})
+	if err != nil {
+		log.Println("Error writing webfinger:", err)
+	}
+}
+
+func (a *App) handleActor(w http.ResponseWriter, r *http.Request) {
+	writeActivityJSON(w, a.activityPub.actor())
+}
+
+// handleOutbox lists the Create activities of the newest events.
+func (a *App) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	events, err := a.store.Recent(r.Context(), EventFilter{}, activityOutboxSize)
+	if err != nil {
+		log.Println("Error loading events:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	events = a.redactions.events(events)
+	items := make([]map[string]any, 0, len(events))
+	for i := range events {
+		items = append(items, a.activityPub.create(&events[i]))
+	}
+	writeActivityJSON(w, map[string]any{
+		"id":           a.activityPub.base + "/ap/outbox",
+		"type":         "OrderedCollection",
+		"totalItems":   len(items),
+		"orderedItems": items,
+	})
+}
+
+// handleFollowers only reveals the number of followers, not who they are.
+func (a *App) handleFollowers(w http.ResponseWriter, r *http.Request) {
+	followers, err := a.store.Followers(r.Context())
+	if err != nil {
+		log.Println("Error loading followers:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeActivityJSON(w, map[string]any{
+		"id":         a.activityPub.base + "/ap/followers",
+		"type":       "OrderedCollection",
+		"totalItems": len(followers),
+	})
+}
+
+func (a *App) handleNote(w http.ResponseWriter, r *http.Request) {
+	event, err := a.store.FindByHash(r.Context(), r.PathValue("hash"))
+	if err != nil || event.TakenDownAt != nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeActivityJSON(w, a.activityPub.note(a.redactions.event(event)))
+}
+
+type inboxActivity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// handleInbox accepts follows and unfollows. Everything else is acknowledged
+// and ignored.
+func (a *App) handleInbox(w http.ResponseWriter, r *http.Request) {
+	ap := a.activityPub
+	body, err := io.ReadAll(io.LimitReader(r.Body, activityMaxBody))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	var activity inboxActivity
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		http.Error(w, "invalid activity", http.StatusBadRequest)
+		return
+	}
+	actor, err := ap.verifyRequest(r, body)
+	if err != nil {
+		// The details help attackers more than senders, who can't fix them.
+		log.Printf("Rejected ActivityPub activity from %q: %v", activity.Actor, err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if actor.ID != activity.Actor {
+		http.Error(w, "actor does not match signature", http.StatusForbidden)
+		return
+	}
+
+	switch activity.Type {
+	case "Follow":
+		var object string
+		if json.Unmarshal(activity.Object, &object) != nil || object != ap.actorID() {
+			http.Error(w, "can only follow "+ap.actorID(), http.StatusBadRequest)
+			return
+		}
+		inbox := actor.Endpoints.SharedInbox
+		if inbox == "" {
+			inbox = actor.Inbox
+		}
+		err = a.store.SaveFollower(r.Context(), &Follower{ActorID: actor.ID, Inbox: inbox})
+		if err != nil {
+			log.Println("Error saving follower:", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		log.Println("New ActivityPub follower:", actor.ID)
+		id := make([]byte, 8)
+		_, _ = rand.Read(id)
+		accept := map[string]any{
+			"@context": activityStreams,
+			"id":       ap.base + "/ap/accepts/" + hex.EncodeToString(id),
+			"type":     "Accept",
+			"actor":    ap.actorID(),
+			"object":   json.RawMessage(body),
+		}
+		go func() {
+			err := ap.deliver(context.Background(), actor.Inbox, accept)
+			if err != nil {
+				log.Printf("Error accepting follow of %s: %v", actor.ID, err)
+			}
+		}()
+	case "Undo":
+		var undone inboxActivity
+		if json.Unmarshal(activity.Object, &undone) == nil && undone.Type == "Follow" {
+			err = a.store.DeleteFollower(r.Context(), actor.ID)
+			if err != nil {
+				log.Println("Error deleting follower:", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			log.Println("ActivityPub follower left:", actor.ID)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// activityPubNotifier delivers new events to the inboxes of all followers.
+type activityPubNotifier struct {
+	ap    *activityPub
+	store EventStore
+}
+
+func (n *activityPubNotifier) Name() string { return "activitypub" }
+
+// Notify sends one Create per event to every distinct inbox. Retries resend
+// to all inboxes; receivers drop activities they already know by id.
+func (n *activityPubNotifier) Notify(ctx context.Context, notification Notification) error {
+	events := notification.Digest
+	if events == nil {
+		events = []Event{notification.Event}
+	}
+	followers, err := n.store.Followers(ctx)
+	if err != nil {
+		return err
+	}
+	inboxes := make(map[string]bool)
+	var errs []error
+	for _, follower := range followers {
+		if inboxes[follower.Inbox] {
+			continue
+		}
+		inboxes[follower.Inbox] = true
+		for i := range events {
+			err := n.ap.deliver(ctx, follower.Inbox, n.ap.create(&events[i]))
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Correct sends an Update or Delete of the note to every distinct inbox.
+func (n *activityPubNotifier) Correct(ctx context.Context, correction Correction) error {
+	var activity map[string]any
+	if correction.Type == correctionDeleted {
+		activity = n.ap.delete(correction.Event.Hash)
+	} else {
+		activity = n.ap.update(&correction.Event, time.Now())
+	}
+	followers, err := n.store.Followers(ctx)
+	if err != nil {
+		return err
+	}
+	inboxes := make(map[string]bool)
+	var errs []error
+	for _, follower := range followers {
+		if inboxes[follower.Inbox] {
+			continue
+		}
+		inboxes[follower.Inbox] = true
+		err := n.ap.deliver(ctx, follower.Inbox, activity)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestActivityPubServer starts the app's routes with ActivityPub enabled
+// on a server whose address is the base URL.
+func newTestActivityPubServer(t *testing.T, app *App) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(nil)
+	app.config.BaseURL = "http://" + server.Listener.Addr().String()
+	app.config.ActivityPubUser = "polizeiberlin"
+	app.config.ActivityPubKey = filepath.Join(t.TempDir(), "activitypub.pem")
+	var err error
+	app.activityPub, err = newActivityPub(app.config)
+	if err != nil {
+		t.Fatalf("newActivityPub failed: %v", err)
+	}
+	// The remote servers of the tests run on loopback.
+	app.activityPub.client = http.DefaultClient
+	server.Config.Handler = app.routes()
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestActivityPub_Discovery(t *testing.T) {
+	app := newTestApp(t)
+	seedExportEvents(t, app.store)
+	server := newTestActivityPubServer(t, app)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	get := func(path string, v any) *http.Response {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer res.Body.Close()
+		if v != nil {
+			if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+				t.Fatalf("decoding %s failed: %v", path, err)
+			}
+		}
+		return res
+	}
+
+	var finger struct {
+		Subject string
+		Links   []struct{ Rel, Type, Href string }
+	}
+	get("/.well-known/webfinger?resource=acct:polizeiberlin@"+host, &finger)
+	if finger.Subject != "acct:polizeiberlin@"+host || len(finger.Links) != 1 || finger.Links[0].Href != server.URL+"/ap/actor" {
+		t.Fatalf("unexpected webfinger response %+v", finger)
+	}
+	if res := get("/.well-known/webfinger?resource=acct:someone@"+host, nil); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for other accounts, got %d", res.StatusCode)
+	}
+
+	var actor remoteActor
+	if res := get("/ap/actor", &actor); res.Header.Get("Content-Type") != activityJSON {
+		t.Fatalf("unexpected content type %s", res.Header.Get("Content-Type"))
+	}
+	if actor.ID != server.URL+"/ap/actor" || actor.Inbox != server.URL+"/ap/inbox" || !strings.Contains(actor.PublicKey.PublicKeyPem, "PUBLIC KEY") {
+		t.Fatalf("unexpected actor %+v", actor)
+	}
+
+	var outbox struct {
+		TotalItems   int
+		OrderedItems []struct {
+			Type   string
+			Object struct{ ID, Content string }
+		}
+	}
+	get("/ap/outbox", &outbox)
+	if outbox.TotalItems != 3 || outbox.OrderedItems[0].Type != "Create" || outbox.OrderedItems[0].Object.ID != server.URL+"/ap/notes/e3" {
+		t.Fatalf("unexpected outbox %+v", outbox)
+	}
+	if !strings.Contains(outbox.OrderedItems[0].Object.Content, "<strong>Unfall</strong>") {
+		t.Fatalf("unexpected note content %s", outbox.OrderedItems[0].Object.Content)
+	}
+	var note struct{ ID string }
+	get("/ap/notes/e1", &note)
+	if note.ID != server.URL+"/ap/notes/e1" {
+		t.Fatalf("unexpected note %+v", note)
+	}
+}
+
+func TestActivityPub_FollowDeliverUnfollow(t *testing.T) {
+	app := newTestApp(t)
+	server := newTestActivityPubServer(t, app)
+
+	// A remote server with its own actor and an inbox collecting deliveries.
+	deliveries := make(chan map[string]any, 10)
+	remoteServer := httptest.NewUnstartedServer(nil)
+	remoteKey, err := loadActivityPubKey(filepath.Join(t.TempDir(), "remote.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := &activityPub{user: "alice", base: "http://" + remoteServer.Listener.Addr().String(), key: remoteKey, client: http.DefaultClient}
+	remoteServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ap/actor":
+			writeActivityJSON(w, remote.actor())
+		case "/ap/inbox":
+			if r.Header.Get("Signature") == "" {
+				http.Error(w, "unsigned", http.StatusUnauthorized)
+				return
+			}
+			var activity map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&activity)
+			deliveries <- activity
+			w.WriteHeader(http.StatusAccepted)
+		}
+	})
+	remoteServer.Start()
+	defer remoteServer.Close()
+
+	receive := func() map[string]any {
+		select {
+		case activity := <-deliveries:
+			return activity
+		case <-time.After(5 * time.Second):
+			t.Fatal("no delivery")
+			return nil
+		}
+	}
+
+	follow := map[string]any{
+		"@context": activityStreams,
+		"id":       remote.base + "/follows/1",
+		"type":     "Follow",
+		"actor":    remote.actorID(),
+		"object":   server.URL + "/ap/actor",
+	}
+	if err := remote.deliver(context.Background(), server.URL+"/ap/inbox", follow); err != nil {
+		t.Fatalf("follow failed: %v", err)
+	}
+	if accept := receive(); accept["type"] != "Accept" || accept["object"].(map[string]any)["id"] != remote.base+"/follows/1" {
+		t.Fatalf("unexpected accept %v", accept)
+	}
+	followers, err := app.store.Followers(context.Background())
+	if err != nil || len(followers) != 1 || followers[0].Inbox != remote.base+"/ap/inbox" {
+		t.Fatalf("unexpected followers %+v, %v", followers, err)
+	}
+
+	notifier := &activityPubNotifier{ap: app.activityPub, store: app.store}
+	if err := notifier.Notify(context.Background(), Notification{Event: Event{Hash: "new", Title: "Raub", Location: "Mitte"}}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if create := receive(); create["type"] != "Create" || create["object"].(map[string]any)["id"] != server.URL+"/ap/notes/new" {
+		t.Fatalf("unexpected create %v", create)
+	}
+
+	// Requests that don't match their signature are rejected.
+	body, _ := json.Marshal(follow)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/ap/inbox", bytes.NewReader(body))
+	_ = remote.signRequest(req, body)
+	req.Body = io.NopCloser(strings.NewReader(strings.Replace(string(body), "Follow", "Block", 1)))
+	req.ContentLength = -1
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejection, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized || strings.TrimSpace(string(rejection)) != "invalid signature" {
+		t.Fatalf("expected a bare 401 for a tampered body, got %d %q", res.StatusCode, rejection)
+	}
+
+	undo := map[string]any{
+		"@context": activityStreams,
+		"id":       remote.base + "/undos/1",
+		"type":     "Undo",
+		"actor":    remote.actorID(),
+		"object":   follow,
+	}
+	if err := remote.deliver(context.Background(), server.URL+"/ap/inbox", undo); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if followers, _ := app.store.Followers(context.Background()); len(followers) != 0 {
+		t.Fatalf("expected follower to be removed, got %+v", followers)
+	}
+}
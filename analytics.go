@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	exportFormatParquet = "parquet"
+	exportFormatSQLite  = "sqlite"
+)
+
+// parquetEvent and parquetFact are the rows of events.parquet and
+// facts.parquet. Timestamps are Unix milliseconds. Optional columns hold
+// null for zero values, except age, where 0 is a valid value.
+type parquetEvent struct {
+	Hash        string `parquet:"hash"`
+	Title       string `parquet:"title"`
+	Description string `parquet:"description"`
+	District    string `parquet:"district"`
+	Link        string `parquet:"link"`
+	PublishedAt int64  `parquet:"published_at,timestamp"`
+	IncidentAt  int64  `parquet:"incident_at,optional,timestamp"`
+	ParentHash  string `parquet:"parent_hash,optional"`
+}
+
+type parquetFact struct {
+	EventHash string `parquet:"event_hash"`
+	Kind      string `parquet:"kind"`
+	Value     string `parquet:"value"`
+	Age       *int64 `parquet:"age,optional"`
+	Role      string `parquet:"role,optional"`
+}
+
+// parquetOptions compresses the exports with Snappy, which every reader
+// supports, and carries the metadata of meta.
+func parquetOptions(meta apiMeta) []parquet.WriterOption {
+	options := []parquet.WriterOption{parquet.Compression(&parquet.Snappy)}
+	fields := meta.fields()
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		options = append(options, parquet.KeyValueMetadata(key, fields[key]))
+	}
+	return options
+}
+
+// writeParquetExport writes the matching events and their facts, redacted,
+// into events.parquet and facts.parquet in dir. Files are written next to their
+// final name and renamed at the end, so readers never see partial files.
+func writeParquetExport(ctx context.Context, store EventStore, filter EventFilter, redactions redactor, meta apiMeta, dir string) (int, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return 0, err
+	}
+	eventsPath := filepath.Join(dir, "events.parquet")
+	factsPath := filepath.Join(dir, "facts.parquet")
+
+	eventsFile, err := os.Create(eventsPath + ".tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(eventsPath + ".tmp")
+	defer eventsFile.Close()
+	factsFile, err := os.Create(factsPath + ".tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(factsPath + ".tmp")
+	defer factsFile.Close()
+
+	eventsBuffer, factsBuffer := bufio.NewWriter(eventsFile), bufio.NewWriter(factsFile)
+	events := parquet.NewGenericWriter[parquetEvent](eventsBuffer, parquetOptions(meta)...)
+	facts := parquet.NewGenericWriter[parquetFact](factsBuffer, parquetOptions(meta)...)
+
+	rows := 0
+	err = store.EachBatch(ctx, filter, exportBatchSize, func(batch []Event) error {
+		hashes := make([]string, 0, len(batch))
+		eventRows := make([]parquetEvent, 0, len(batch))
+		for _, event := range redactions.events(batch) {
+			hashes = append(hashes, event.Hash)
+			row := parquetEvent{
+				Hash:        event.Hash,
+				Title:       event.Title,
+				Description: event.Description,
+				District:    event.Location,
+				Link:        event.Link,
+				PublishedAt: event.DateTime * 1000,
+				ParentHash:  event.ParentHash,
+			}
+			if event.IncidentTime != nil {
+				row.IncidentAt = *event.IncidentTime * 1000
+			}
+			eventRows = append(eventRows, row)
+		}
+		_, err := events.Write(eventRows)
+		if err != nil {
+			return err
+		}
+		rows += len(eventRows)
+
+		byHash, err := store.Facts(ctx, hashes)
+		if err != nil {
+			return err
+		}
+		var factRows []parquetFact
+		for _, hash := range hashes {
+			for _, fact := range byHash[hash] {
+				row := parquetFact{EventHash: fact.EventHash, Kind: fact.Kind, Value: redactions.text(fact.Value), Role: fact.Role}
+				if fact.Kind == factAge {
+					age := int64(fact.Number)
+					row.Age = &age
+				}
+				factRows = append(factRows, row)
+			}
+		}
+		_, err = facts.Write(factRows)
+		return err
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	for _, step := range []func() error{
+		events.Close, eventsBuffer.Flush, eventsFile.Close,
+		facts.Close, factsBuffer.Flush, factsFile.Close,
+	} {
+		err = step()
+		if err != nil {
+			return rows, err
+		}
+	}
+	err = os.Rename(eventsPath+".tmp", eventsPath)
+	if err != nil {
+		return rows, err
+	}
+	return rows, os.Rename(factsPath+".tmp", factsPath)
+}
+
+// runExport implements the export command, which writes the events straight
+// from the database into a directory, e.g. for analysis with DuckDB or Pandas.
+func runExport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", exportFormatParquet, "parquet (events and facts), sqlite, csv or ndjson")
+	out := flags.String("out", ".", "directory to write the files to")
+	from := flags.String("from", "", "only events published on or after this date (YYYY-MM-DD)")
+	to := flags.String("to", "", "only events published before this date (YYYY-MM-DD)")
+	district := flags.String("district", "", "only events in this district")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	filter, err := parseEventFilter(url.Values{"from": {*from}, "to": {*to}, "district": {*district}})
+	if err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	redactions, err := loadRedactionRules(config.RedactionRulesFile)
+	if err != nil {
+		return err
+	}
+	db, err := openConfiguredDatabase(config)
+	if err != nil {
+		return err
+	}
+	store, err := NewGormStore(db)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var rows int
+	switch *format {
+	case exportFormatParquet:
+		rows, err = writeParquetExport(ctx, store, filter, redactions, newAPIMeta(config), *out)
+	case exportFormatSQLite:
+		err = os.MkdirAll(*out, 0o755)
+		if err == nil {
+			path := filepath.Join(*out, "events.db")
+			_ = os.Remove(path)
+			rows, err = writeSQLiteSnapshot(ctx, store, filter, redactions, newAPIMeta(config), path)
+		}
+	case exportFormatCSV, exportFormatNDJSON:
+		rows, err = writeExportFile(ctx, store, filter, redactions, *format, filepath.Join(*out, "events."+*format))
+	default:
+		return fmt.Errorf("export: unsupported format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Exported %d events as %s to %s in %s", rows, *format, *out, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func writeExportFile(ctx context.Context, store EventStore, filter EventFilter, redactions redactor, format, path string) (int, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := writeEvents(ctx, store, filter, redactions, format, file)
+	return rows, errors.Join(err, file.Close())
+}
+
+// runAnalyticsExports refreshes the Parquet files in AnalyticsExportDir every
+// AnalyticsExportInterval until ctx is cancelled, so that analyses never have
+// to touch the live database.
+func (a *App) runAnalyticsExports(ctx context.Context) {
+	if a.config.AnalyticsExportDir == "" {
+		return
+	}
+	for {
+		start := time.Now()
+		rows, err := writeParquetExport(ctx, a.store, EventFilter{}, a.redactions, newAPIMeta(a.config), a.config.AnalyticsExportDir)
+		if err != nil {
+			log.Println("Error writing analytics export:", err)
+		} else {
+			log.Printf("Wrote analytics export of %d events in %s", rows, time.Since(start).Round(time.Millisecond))
+		}
+		if sleepContext(ctx, a.config.AnalyticsExportInterval) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestWriteParquetExport(t *testing.T) {
+	app := newTestApp(t)
+	seedExportEvents(t, app.store)
+	ctx := context.Background()
+	facts := []EventFact{
+		{EventHash: "e1", Kind: factAge, Value: "17", Number: 17, Role: roleSuspect},
+		{EventHash: "e1", Kind: factWeapon, Value: "messer"},
+	}
+	if err := app.store.ReplaceFacts(ctx, "e1", facts); err != nil {
+		t.Fatalf("ReplaceFacts failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	meta := apiMeta{License: "CC BY 4.0", Source: "https://example.com/", Attribution: defaultAttribution}
+	rows, err := writeParquetExport(ctx, app.store, EventFilter{District: "Mitte"}, nil, meta, dir)
+	if err != nil {
+		t.Fatalf("writeParquetExport failed: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("expected 2 rows, got %d", rows)
+	}
+
+	eventsPath := filepath.Join(dir, "events.parquet")
+	events, err := parquet.ReadFile[parquetEvent](eventsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Hash != "e1" || events[0].District != "Mitte" || events[0].IncidentAt != 0 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if got := parquetMetadata(t, eventsPath); got["license"] != "CC BY 4.0" || got["attribution"] != defaultAttribution {
+		t.Fatalf("unexpected file metadata %v", got)
+	}
+
+	factRows, err := parquet.ReadFile[parquetFact](filepath.Join(dir, "facts.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(factRows) != 2 || factRows[0].Age == nil || *factRows[0].Age != 17 || factRows[0].Role != roleSuspect ||
+		factRows[1].Value != "messer" || factRows[1].Age != nil {
+		t.Fatalf("unexpected fact rows %+v", factRows)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+}
+
+// parquetMetadata returns the key/value metadata of the Parquet file at path.
+func parquetMetadata(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]string{}
+	for _, kv := range file.Metadata().KeyValueMetadata {
+		metadata[kv.Key] = kv.Value
+	}
+	return metadata
+}
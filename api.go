@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+	"gorm.io/gorm"
+)
+
+// apiEvent is the public JSON representation of an Event.
+type apiEvent = model.Event
+
+func toAPIEvent(event *Event) apiEvent {
+	apiEvent := apiEvent{
+		Hash:        event.Hash,
+		Title:       event.Title,
+		Description: event.Description,
+		District:    event.Location,
+		Link:        event.Link,
+		PublishedAt: time.Unix(event.DateTime, 0).UTC(),
+		Edits:       event.Edits,
+		ParentHash:  event.ParentHash,
+		WaybackURL:  event.WaybackURL,
+	}
+	if event.LinkDeadSince != nil {
+		deadSince := event.LinkDeadSince.UTC()
+		apiEvent.LinkDeadSince = &deadSince
+	}
+	if event.IncidentTime != nil {
+		incidentAt := time.Unix(*event.IncidentTime, 0).UTC()
+		apiEvent.IncidentAt = &incidentAt
+	}
+	return apiEvent
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Println("Error writing json response:", err)
+	}
+}
+
+// parseTime accepts either an RFC 3339 timestamp or a plain date, which is
+// interpreted as midnight in Berlin.
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, berlin)
+}
+
+// parseEventFilter reads the from, to and district query parameters.
+func parseEventFilter(query url.Values) (EventFilter, error) {
+	var filter EventFilter
+	var err error
+	if from := query.Get("from"); from != "" {
+		filter.From, err = parseTime(from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		filter.To, err = parseTime(to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	filter.District = query.Get("district")
+	return filter, nil
+}
+
+// eventPatch lists the fields an operator may correct. Omitted fields are left
+// untouched.
+type eventPatch struct {
+	Title       *string `json:"title"`
+	District    *string `json:"district"`
+	Description *string `json:"description"`
+	Note        string  `json:"note"`
+}
+
+// apply changes event according to the patch and returns the resulting edits.
+func (p eventPatch) apply(event *Event, actor string, at time.Time) []EventEdit {
+	var edits []EventEdit
+	set := func(field string, target *string, value *string) {
+		if value == nil {
+			return
+		}
+		v := strings.TrimSpace(*value)
+		if v == *target {
+			return
+		}
+		edits = append(edits, EventEdit{At: at, Actor: actor, Field: field, Old: *target, New: v, Note: p.Note})
+		*target = v
+	}
+	set("title", &event.Title, p.Title)
+	set("district", &event.Location, p.District)
+	set("description", &event.Description, p.Description)
+	event.Edits = append(event.Edits, edits...)
+	if p.Description != nil || p.Title != nil {
+		setIncidentTime(event)
+	}
+	return edits
+}
+
+// handleEvent returns a single event. Feed items link here as their
+// permalink, see FeedBuilder.SetBaseURL.
+func (a *App) handleEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := a.store.FindByHash(r.Context(), r.PathValue("hash"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "event not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if event.TakenDownAt != nil {
+		writeTombstone(w, event)
+		return
+	}
+	apiEvent := toAPIEvent(a.redactions.event(event))
+	if slices.Contains(a.config.Features, featureWebmention) {
+		mentions, err := a.store.Mentions(r.Context(), event.Hash)
+		if err != nil {
+			log.Println("Error loading mentions:", err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		apiEvent.Mentions = toAPIMentions(mentions)
+		w.Header().Set("Link", "<"+a.webmentionEndpoint()+`>; rel="webmention"`)
+	}
+	newAPIMeta(a.config).setHeaders(w)
+	writeJSON(w, http.StatusOK, apiEvent)
+}
+
+func (a *App) handleEventPatch(w http.ResponseWriter, r *http.Request) {
+	var patch eventPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&patch)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if patch.Title != nil && strings.TrimSpace(*patch.Title) == "" {
+		writeJSONError(w, http.StatusBadRequest, "title must not be empty")
+		return
+	}
+
+	event, err := a.store.FindByHash(r.Context(), r.PathValue("hash"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "event not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if event.TakenDownAt != nil {
+		writeTombstone(w, event)
+		return
+	}
+
+	edits := patch.apply(event, actorFromContext(r.Context()), time.Now().UTC())
+	if len(edits) > 0 {
+		err = a.store.Update(r.Context(), event)
+		if err != nil {
+			log.Println("Error updating event:", err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		a.replaceEvent(*event)
+		a.updateFacts(r.Context(), *event)
+		a.audit(r.Context(), auditActionEventEdit, event.Hash, edits)
+		changes := make([]string, 0, len(edits))
+		for _, edit := range edits {
+			changes = append(changes, edit.Field)
+		}
+		a.correct(r.Context(), Correction{Type: correctionUpdated, Event: *event, Changes: changes})
+		log.Printf("Event %s edited by %s (%d fields)", event.Hash, actorFromContext(r.Context()), len(edits))
+	}
+
+	writeJSON(w, http.StatusOK, toAPIEvent(event))
+}
+
+// replaceEvent swaps the feed item of an event for the given, updated
+// version.
+func (a *App) replaceEvent(event Event) {
+	a.feed.Replace(event)
+	a.responses.invalidate()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func patchEvent(t *testing.T, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("building request failed: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PATCH failed: %v", err)
+	}
+	return res
+}
+
+func TestEventPatch_RequiresToken(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res := patchEvent(t, server.URL+"/api/events/abc", "", `{"title":"x"}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", res.StatusCode)
+	}
+
+	res = patchEvent(t, server.URL+"/api/events/abc", "wrong", `{"title":"x"}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", res.StatusCode)
+	}
+}
+
+func TestEventPatch_CorrectsEvent(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	event := Event{Title: "Garbled", Location: "Mittte", Hash: "fixme", DateTime: 1}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	app.feed.Add(event)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res := patchEvent(t, server.URL+"/api/events/fixme", "secret", `{"title":"Fixed","district":"Mitte","note":"typo"}`)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	var got apiEvent
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	if got.Title != "Fixed" || got.District != "Mitte" || len(got.Edits) != 2 {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+	if got.Edits[0].Actor != tokenID("secret") || got.Edits[0].Old != "Garbled" || got.Edits[0].Note != "typo" {
+		t.Fatalf("unexpected edit record: %+v", got.Edits[0])
+	}
+
+	stored, err := app.store.FindByHash(context.Background(), "fixme")
+	if err != nil {
+		t.Fatalf("FindByHash failed: %v", err)
+	}
+	if stored.Title != "Fixed" || len(stored.Edits) != 2 {
+		t.Fatalf("correction not persisted: %+v", stored)
+	}
+	if !strings.Contains(app.feed.RSS(), "Fixed") || strings.Contains(app.feed.RSS(), "Garbled") {
+		t.Fatalf("feed does not reflect correction")
+	}
+}
+
+func TestEventPatch_NotFound(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res := patchEvent(t, server.URL+"/api/events/missing", "secret", `{"title":"x"}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", res.StatusCode)
+	}
+}
+
+func TestEvent_Public(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	event := Event{Title: "Public", Location: "Mitte", Hash: "public", DateTime: 1}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/events/public")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer res.Body.Close()
+	var got apiEvent
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || got.Title != "Public" {
+		t.Fatalf("unexpected response %d: %+v", res.StatusCode, got)
+	}
+
+	res, err = http.Get(server.URL + "/api/events/missing")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+// App bundles everything the scraper and web server share, so that no state
+// has to live in package-level variables.
+type App struct {
+	config Config
+	client HTTPDoer
+	store  EventStore
+	feed   *FeedBuilder
+
+	// seen holds the hashes of recent events for checkDuplicate.
+	seen *hashCache
+	// live passes new events on to streaming clients.
+	live *eventBroadcaster
+
+	scrapeMu sync.Mutex
+	// lifecycleHooks receive the lifecycle events of scrape runs.
+	lifecycleHooks  []lifecycleHook
+	lifecycleClient HTTPDoer
+	scrapeState     scrapeState
+	// detailFetches collapses concurrent fetches of the same detail page,
+	// see fetchDetail.
+	detailFetches singleflight.Group
+
+	exports chan string
+	// snapshots limits concurrent SQLite exports, see handleSQLiteExport.
+	snapshots chan struct{}
+	quality   qualityState
+	linkRot   linkRotState
+
+	// responses caches hot API responses if enabled, else nil.
+	responses *responseCache
+	// shedder limits concurrent expensive requests if enabled, else nil.
+	shedder *loadShedder
+
+	// usage counts requests if reader analytics are enabled, else nil.
+	usage *usageTracker
+
+	// scrapers are the sources scraped besides berlin.de, see Source.
+	scrapers []Source
+
+	notifiers []Notifier
+	// deliveries tracks the notifications sent in the background, and
+	// targetLocks holds a *sync.Mutex per target name, see notifyTarget.
+	deliveries  sync.WaitGroup
+	targetLocks sync.Map
+	summarizer  Summarizer
+	// activityPub serves the Fediverse actor if enabled, else nil.
+	activityPub *activityPub
+	// speaker records the daily podcast if enabled, else nil.
+	speaker Speaker
+	// renderer keeps PDF copies of the detail pages if enabled, else nil.
+	renderer PageRenderer
+	// mentionClient fetches the sources of received Webmentions.
+	mentionClient HTTPDoer
+	// wayback submits new detail pages to the Wayback Machine if enabled,
+	// else nil.
+	wayback *waybackClient
+
+	rulesMu             sync.RWMutex
+	classificationRules []classificationRule
+	// redactions are applied to events when they are rendered, see redactor.
+	redactions redactor
+	// feedVariants are the feeds served under /f/ by name, see feedVariant.
+	feedVariants map[string]*feedVariant
+	// robots is served as /robots.txt, see robotsTxt.
+	robots    []byte
+	districts *districtMap
+	// bundle names the imported config bundle in effect, nil if the rules
+	// come from CLASSIFICATION_RULES or the defaults.
+	bundle *bundleInfo
+}
+
+func NewApp(ctx context.Context, config Config, client HTTPDoer, store EventStore, feed *FeedBuilder) (*App, error) {
+	a := &App{
+		config: config,
+		client: client,
+		store:  store,
+		feed:   feed,
+		live:   newEventBroadcaster(),
+
+		exports:   make(chan string, exportQueueSize),
+		snapshots: make(chan struct{}, maxConcurrentSnapshots),
+
+		notifiers: buildNotifiers(config),
+
+		lifecycleClient: &http.Client{Timeout: notifyTimeout},
+		mentionClient:   newPublicClient(notifyTimeout),
+	}
+	for _, raw := range config.LifecycleWebhookURLs {
+		hook, err := parseLifecycleHook(raw)
+		if err != nil {
+			log.Println("Skipping lifecycle webhook:", err)
+			continue
+		}
+		a.lifecycleHooks = append(a.lifecycleHooks, hook)
+	}
+
+	if slices.Contains(config.Features, featureReaderAnalytics) {
+		a.usage = newUsageTracker()
+	}
+	if config.APICacheTTL > 0 && config.APICacheSize > 0 {
+		a.responses = newResponseCache(config.APICacheSize)
+	}
+	if config.ExpensiveRequestLimit > 0 {
+		a.shedder = newLoadShedder(config.ExpensiveRequestLimit)
+	}
+
+	var err error
+	a.scrapers, err = buildSources(config)
+	if err != nil {
+		return nil, err
+	}
+	a.limitSources()
+	a.summarizer, err = buildSummarizer(config)
+	if err != nil {
+		return nil, err
+	}
+	a.speaker, err = buildSpeaker(config)
+	if err != nil {
+		return nil, err
+	}
+	a.renderer = buildRenderer(config)
+	a.wayback = newWaybackClient(config)
+	a.activityPub, err = newActivityPub(config)
+	if err != nil {
+		return nil, err
+	}
+	if a.activityPub != nil {
+		a.notifiers = append(a.notifiers, &activityPubNotifier{ap: a.activityPub, store: store})
+	}
+	if slices.Contains(config.Features, featureWebmention) {
+		if config.BaseURL == "" {
+			return nil, errors.New("the webmention feature requires BASE_URL")
+		}
+		// Pings go to the sites the events link to, so they share the rate
+		// limit and upstream metrics of the scraper.
+		a.notifiers = append(a.notifiers, &webmentionNotifier{feed: feed, client: &http.Client{Timeout: notifyTimeout, Transport: a.transport()}})
+	}
+	if slices.Contains(config.Features, featureShortLinks) && config.BaseURL == "" {
+		return nil, errors.New("the short_links feature requires BASE_URL")
+	}
+
+	a.classificationRules, err = loadClassificationRules(config.ClassificationRulesFile)
+	if err != nil {
+		return nil, err
+	}
+	a.districts = defaultDistricts
+	a.redactions, err = loadRedactionRules(config.RedactionRulesFile)
+	if err != nil {
+		return nil, err
+	}
+	a.feedVariants, err = loadFeedVariants(config.FeedVariantsFile)
+	if err != nil {
+		return nil, err
+	}
+	a.robots, err = robotsTxt(config)
+	if err != nil {
+		return nil, err
+	}
+	err = a.loadBundle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = a.correctRedactions(ctx)
+	if err != nil {
+		log.Println("Error correcting events after changes to the redaction rules:", err)
+	}
+
+	err = store.Prune(ctx, config.PruneMinEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := store.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feed.SetCopyright(newAPIMeta(config).notice())
+	feed.SetBaseURL(config.BaseURL)
+	feed.SetImage(config.FeedImage, config.FeedFavicon)
+	feed.SetRedactor(a.redactions)
+	for _, image := range []string{config.FeedImage, config.FeedFavicon} {
+		if strings.HasPrefix(image, "/") && config.BaseURL == "" {
+			log.Printf("Ignoring feed image %s, paths need BASE_URL to be set", image)
+		}
+	}
+	feed.Add(slices.DeleteFunc(events, func(e Event) bool { return !e.fromBerlin() })...)
+
+	a.seen, err = loadHashCache(ctx, store, config.DedupCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *App) scrape(ctx context.Context) error {
+	return a.scrapeInto(ctx, &scrapeRun{})
+}
+
+// scrapeInto scrapes the list page after preparing the session, counting
+// what it found in run. It fails with the reason if the list page was
+// rejected, e.g. errMaintenance for a maintenance page.
+func (a *App) scrapeInto(ctx context.Context, run *scrapeRun) error {
+	a.prepareSession(ctx)
+	err := a.newCollector(ctx, run).Visit(a.config.PoliceURL)
+	if run.aborted != nil && (err == nil || errors.Is(err, colly.ErrAbortedAfterHeaders)) {
+		err = run.aborted
+	}
+	return err
+}
+
+// Run schedules the scrape runs while serving the feeds. It returns once ctx
+// is cancelled and the web server has shut down.
+func (a *App) Run(ctx context.Context) error {
+	// TODO maybe initially scrape all the pages
+	go a.schedule(ctx)
+	for _, source := range a.scrapers {
+		go a.scheduleSource(ctx, source)
+	}
+	go a.runExports(ctx)
+	go a.runQuietHours(ctx)
+	go a.runAnalyticsExports(ctx)
+	go a.runUsageFlush(ctx)
+	go a.runPodcast(ctx)
+	go a.runGemini(ctx)
+	go a.runGRPC(ctx)
+	go a.runArchive(ctx)
+	go a.runWayback(ctx)
+	go a.runLinkChecks(ctx)
+
+	listeners, err := listen(a.config.ListenAddresses)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: a.routes()}
+	server.RegisterOnShutdown(a.live.shutdown)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Println("Error shutting down web server:", err)
+		}
+	}()
+
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		log.Println("Listening on", listener.Addr())
+		go func() {
+			errs <- server.Serve(listener)
+		}()
+	}
+	for range listeners {
+		err := <-errs
+		if !errors.Is(err, http.ErrServerClosed) {
+			_ = server.Close()
+			return err
+		}
+	}
+	log.Println("Shutting down...")
+
+	// Give notifications still being sent a moment to go out.
+	delivered := make(chan struct{})
+	go func() {
+		a.deliveries.Wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(10 * time.Second):
+		log.Println("Gave up waiting for notifications still being sent")
+	}
+	return nil
+}
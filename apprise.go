@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// appriseNotifier sends events to a target given as an Apprise URL, like
+// "ntfy://ntfy.sh/berlin" or "tgram://bottoken/chatid", see
+// https://github.com/caronc/apprise/wiki. Common services are spoken to
+// directly; any other URL is passed on to an Apprise API server if one is
+// configured.
+type appriseNotifier struct {
+	name   string
+	client HTTPDoer
+	// request builds the request delivering a message.
+	request func(ctx context.Context, title, body, link string) (*http.Request, error)
+}
+
+// Name identifies the target by its scheme and a hash of its URL, as the URL
+// itself mostly contains secrets.
+func (n *appriseNotifier) Name() string { return n.name }
+
+func (n *appriseNotifier) Notify(ctx context.Context, notification Notification) error {
+	title, body, link := appriseMessage(notification)
+	req, err := n.request(ctx, title, body, link)
+	if err != nil {
+		return err
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", req.URL.Host, res.Status)
+	}
+	return nil
+}
+
+// appriseMessage writes a notification as title and plain text body, with the
+// link (the short link if there is one) also given separately for services
+// that can attach it.
+func appriseMessage(notification Notification) (title, body, link string) {
+	if notification.Digest != nil {
+		return fmt.Sprintf("%d neue Polizeimeldungen", len(notification.Digest)), digestSummary(notification.Digest, notification.Emoji), ""
+	}
+	event := notification.Event
+	text := notification.Summaries[event.Hash]
+	if text == "" && event.Description != defaultDescription {
+		text = excerpt(strings.Join(strings.Fields(event.Description), " "), nostrMaxDescription)
+	}
+	link = cmp.Or(notification.ShortLinks[event.Hash], event.Link)
+	var lines []string
+	for _, line := range []string{event.Location, text, link} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return emojiTitle(notification.Emoji[event.Hash], event.Title), strings.Join(lines, "\n\n"), link
+}
+
+// newAppriseNotifier parses an Apprise URL. apiURL is the address of an
+// Apprise API server handling the schemes not supported here; without one
+// they are an error.
+func newAppriseNotifier(rawURL, apiURL string) (*appriseNotifier, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok || scheme == "" {
+		return nil, errors.New("invalid notification url")
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	n := &appriseNotifier{
+		name:   scheme + ":" + hex.EncodeToString(sum[:4]),
+		client: &http.Client{Timeout: notifyTimeout},
+	}
+	// Telegram bot tokens contain a colon, which url.Parse would take for a
+	// port.
+	if scheme == "tgram" {
+		token, chat, ok := strings.Cut(strings.Trim(rest, "/"), "/")
+		if !ok || token == "" || chat == "" || strings.Contains(chat, "/") {
+			return nil, fmt.Errorf("%s: expected tgram://bottoken/chat_id", n.name)
+		}
+		target := "https://api.telegram.org/bot" + token + "/sendMessage"
+		n.request = func(ctx context.Context, title, body, _ string) (*http.Request, error) {
+			return jsonRequest(ctx, target, map[string]string{"chat_id": chat, "text": title + "\n\n" + body})
+		}
+		return n, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid notification url", n.name)
+	}
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	// httpURL returns the address of a self-hosted service at host.
+	httpURL := func(host string, parts ...string) string {
+		scheme := "http"
+		if strings.HasSuffix(u.Scheme, "s") {
+			scheme = "https"
+		}
+		return (&url.URL{Scheme: scheme, Host: host, Path: path.Join(append([]string{"/"}, parts...)...)}).String()
+	}
+
+	switch u.Scheme {
+	case "json", "jsons":
+		target := httpURL(u.Host, segments...)
+		n.request = func(ctx context.Context, title, body, _ string) (*http.Request, error) {
+			req, err := jsonRequest(ctx, target, map[string]string{"version": "1.0", "title": title, "message": body, "type": "info"})
+			if password, ok := u.User.Password(); ok && req != nil {
+				req.SetBasicAuth(u.User.Username(), password)
+			}
+			return req, err
+		}
+	case "ntfy", "ntfys":
+		// ntfy://topic publishes on ntfy.sh, ntfy://host/topic elsewhere.
+		var target string
+		switch len(segments) {
+		case 0:
+			target = "https://ntfy.sh/" + url.PathEscape(u.Host)
+		case 1:
+			target = httpURL(u.Host, segments[0])
+		default:
+			return nil, fmt.Errorf("%s: expected ntfy://[host/]topic", n.name)
+		}
+		n.request = func(ctx context.Context, title, body, link string) (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Title", title)
+			if link != "" {
+				req.Header.Set("Click", link)
+			}
+			if password, ok := u.User.Password(); ok {
+				req.SetBasicAuth(u.User.Username(), password)
+			}
+			return req, nil
+		}
+	case "gotify", "gotifys":
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("%s: expected gotify://host/token", n.name)
+		}
+		token := segments[len(segments)-1]
+		target := httpURL(u.Host, append(segments[:len(segments)-1:len(segments)-1], "message")...)
+		n.request = func(ctx context.Context, title, body, _ string) (*http.Request, error) {
+			req, err := jsonRequest(ctx, target, map[string]any{"title": title, "message": body, "priority": 5})
+			if req != nil {
+				req.Header.Set("X-Gotify-Key", token)
+			}
+			return req, err
+		}
+	case "discord":
+		if len(segments) != 1 {
+			return nil, fmt.Errorf("%s: expected discord://webhook_id/webhook_token", n.name)
+		}
+		target := "https://discord.com/api/webhooks/" + url.PathEscape(u.Host) + "/" + url.PathEscape(segments[0])
+		n.request = func(ctx context.Context, title, body, _ string) (*http.Request, error) {
+			return jsonRequest(ctx, target, map[string]string{"content": "**" + title + "**\n" + body})
+		}
+	case "slack":
+		if len(segments) < 2 {
+			return nil, fmt.Errorf("%s: expected slack://TokenA/TokenB/TokenC", n.name)
+		}
+		target := "https://hooks.slack.com/services/" + u.Host + "/" + segments[0] + "/" + segments[1]
+		n.request = func(ctx context.Context, title, body, _ string) (*http.Request, error) {
+			return jsonRequest(ctx, target, map[string]string{"text": "*" + title + "*\n" + body})
+		}
+	default:
+		if apiURL == "" {
+			return nil, fmt.Errorf("%s: unsupported scheme, set APPRISE_API_URL to send through an Apprise API server", n.name)
+		}
+		target := strings.TrimSuffix(apiURL, "/") + "/notify/"
+		n.request = func(ctx context.Context, title, body, _ string) (*http.Request, error) {
+			return jsonRequest(ctx, target, map[string]string{"urls": rawURL, "title": title, "body": body})
+		}
+	}
+	return n, nil
+}
+
+func jsonRequest(ctx context.Context, target string, payload any) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
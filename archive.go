@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// archiveInterval is how often new events are checked for missing
+	// PDF copies.
+	archiveInterval = 5 * time.Minute
+	// archiveRetryWindow is how long after it was stored rendering an
+	// event's PDF copy is retried; older events are given up on.
+	archiveRetryWindow = 24 * time.Hour
+	archiveTimeout     = time.Minute
+	archiveBatchSize   = 20
+)
+
+// PageRenderer renders a web page to PDF.
+type PageRenderer interface {
+	RenderPDF(ctx context.Context, url string) ([]byte, error)
+}
+
+// chromiumRenderer prints pages with a headless Chromium (or Chrome) binary.
+type chromiumRenderer struct {
+	binary    string
+	noSandbox bool
+}
+
+func (c *chromiumRenderer) RenderPDF(ctx context.Context, url string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "archive")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "page.pdf")
+
+	ctx, cancel := context.WithTimeout(ctx, archiveTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	args := []string{"--headless", "--disable-gpu", "--no-pdf-header-footer"}
+	if c.noSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	args = append(args, "--user-data-dir="+filepath.Join(dir, "profile"), "--print-to-pdf="+output, url)
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	pdf, err := os.ReadFile(output)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		return nil, errors.New("browser produced no PDF")
+	}
+	return pdf, nil
+}
+
+// buildRenderer creates the configured page renderer, or nil if PDF copies
+// are disabled (the default).
+func buildRenderer(config Config) PageRenderer {
+	if config.ArchiveBrowser == "" {
+		return nil
+	}
+	return &chromiumRenderer{binary: config.ArchiveBrowser, noSandbox: config.ArchiveNoSandbox}
+}
+
+func archivePath(dir, hash string) string {
+	return filepath.Join(dir, hash+".pdf")
+}
+
+// MissingArchives returns events stored since the given time that link to a
+// detail page but have no PDF copy yet. Split off incidents are skipped,
+// their report's PDF copy covers them.
+func (s *gormStore) MissingArchives(ctx context.Context, since time.Time, limit int) ([]Event, error) {
+	var events []Event
+	err := s.db.WithContext(ctx).
+		Where("archive_size = 0 AND link <> '' AND parent_hash = '' AND created_at >= ?", since).
+		Order("created_at").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// SetArchiveSize records a stored PDF copy without touching UpdatedAt, so the
+// event doesn't show up as changed in the feeds.
+func (s *gormStore) SetArchiveSize(ctx context.Context, hash string, size int64) error {
+	return s.db.WithContext(ctx).Model(&Event{}).Where("hash = ?", hash).UpdateColumn("archive_size", size).Error
+}
+
+// archiveEvents renders the detail pages of new events to PDF, so that the
+// original report survives even if it's taken down. The feeds attach the
+// PDF copies as enclosures.
+func (a *App) archiveEvents(ctx context.Context) error {
+	events, err := a.store.MissingArchives(ctx, time.Now().Add(-archiveRetryWindow), archiveBatchSize)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(a.config.ArchiveDir, 0o755)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		pdf, err := a.renderer.RenderPDF(ctx, event.Link)
+		if err != nil {
+			log.Printf("Error rendering PDF copy of %s: %v", event.Link, err)
+			continue
+		}
+		path := archivePath(a.config.ArchiveDir, event.Hash)
+		err = os.WriteFile(path+".tmp", pdf, 0o644)
+		if err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+		if err != nil {
+			return err
+		}
+		err = a.store.SetArchiveSize(ctx, event.Hash, int64(len(pdf)))
+		if err != nil {
+			return err
+		}
+		event.ArchiveSize = int64(len(pdf))
+		a.feed.Replace(event)
+	}
+	return nil
+}
+
+// pruneArchive removes the PDF copies of events that have been pruned.
+func (a *App) pruneArchive(ctx context.Context) error {
+	paths, err := filepath.Glob(filepath.Join(a.config.ArchiveDir, "*.pdf"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		hash := strings.TrimSuffix(filepath.Base(path), ".pdf")
+		_, err := a.store.FindByHash(ctx, hash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *App) runArchive(ctx context.Context) {
+	if a.renderer == nil {
+		return
+	}
+	for {
+		err := a.archiveEvents(ctx)
+		if err != nil {
+			log.Println("Error archiving events:", err)
+		}
+		err = a.pruneArchive(ctx)
+		if err != nil {
+			log.Println("Error pruning archive:", err)
+		}
+		if sleepContext(ctx, archiveInterval) != nil {
+			return
+		}
+	}
+}
+
+func (a *App) handleArchive(w http.ResponseWriter, r *http.Request) {
+	hash, ok := strings.CutSuffix(r.PathValue("file"), ".pdf")
+	if !ok || hash == "" || strings.ContainsAny(hash, `/\.`) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	http.ServeFile(w, r, archivePath(a.config.ArchiveDir, hash))
+}
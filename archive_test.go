@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBrowser writes a script that behaves like chromium --print-to-pdf,
+// writing the URL as PDF. URLs containing "broken" fail.
+func fakeBrowser(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chromium")
+	script := `#!/bin/sh
+for arg; do url="$arg"; done
+case "$url" in *broken*) echo "navigation failed" >&2; exit 1;; esac
+for arg; do
+	case "$arg" in --print-to-pdf=*) printf '%%PDF-1.4 %s' "$url" > "${arg#--print-to-pdf=}";; esac
+done
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestArchiveEvents(t *testing.T) {
+	app := newTestApp(t)
+	app.config.ArchiveBrowser = fakeBrowser(t)
+	app.config.ArchiveDir = t.TempDir()
+	app.renderer = buildRenderer(app.config)
+	app.feed.SetBaseURL("https://feed.example.org")
+	ctx := context.Background()
+
+	events := []Event{
+		{Title: "Raub", Hash: "a", Link: "https://example.com/a"},
+		{Title: "Kurzmeldung", Hash: "a-1", Link: "https://example.com/a", ParentHash: "a"},
+		{Title: "Brand", Hash: "b", Link: "https://example.com/broken"},
+		{Title: "Ohne Link", Hash: "c"},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.feed.Add(events...)
+	if err := os.WriteFile(filepath.Join(app.config.ArchiveDir, "pruned.pdf"), []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.archiveEvents(ctx); err != nil {
+		t.Fatalf("archiveEvents failed: %v", err)
+	}
+	if err := app.pruneArchive(ctx); err != nil {
+		t.Fatalf("pruneArchive failed: %v", err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(app.config.ArchiveDir, "*.pdf"))
+	if len(paths) != 1 || filepath.Base(paths[0]) != "a.pdf" {
+		t.Fatalf("unexpected archive %v", paths)
+	}
+	event, _ := app.store.FindByHash(ctx, "a")
+	if event.ArchiveSize != int64(len("%PDF-1.4 https://example.com/a")) || event.UpdatedAt.After(event.CreatedAt) {
+		t.Fatalf("unexpected archived event %+v", event)
+	}
+	missing, _ := app.store.MissingArchives(ctx, event.CreatedAt.Add(-1), 10)
+	if len(missing) != 1 || missing[0].Hash != "b" {
+		t.Fatalf("expected only the broken page to be missing, got %+v", missing)
+	}
+
+	if !strings.Contains(app.feed.RSS(), `<enclosure url="https://feed.example.org/archive/a.pdf" length="30" type="application/pdf"></enclosure>`) {
+		t.Fatalf("rss is missing the enclosure:\n%s", app.feed.RSS())
+	}
+	if !strings.Contains(app.feed.Atom(), `href="https://feed.example.org/archive/a.pdf" rel="enclosure" type="application/pdf" length="30"`) {
+		t.Fatalf("atom is missing the enclosure:\n%s", app.feed.Atom())
+	}
+	if !strings.Contains(app.feed.JSON(), `"attachments": [`) || !strings.Contains(app.feed.JSON(), `"url": "https://feed.example.org/archive/a.pdf"`) {
+		t.Fatalf("json feed is missing the attachment:\n%s", app.feed.JSON())
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/archive/a.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.Header.Get("Content-Type") != "application/pdf" || string(body) != "%PDF-1.4 https://example.com/a" {
+		t.Fatalf("unexpected archive response %s: %q", res.Header.Get("Content-Type"), body)
+	}
+	res, err = http.Get(server.URL + "/archive/b.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing copy, got %d", res.StatusCode)
+	}
+}
+
+func TestChromiumRenderer_Sandbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chromium")
+	script := `#!/bin/sh
+for arg; do
+	case "$arg" in --print-to-pdf=*) printf '%%PDF-1.4 %s' "$*" > "${arg#--print-to-pdf=}";; esac
+done
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, noSandbox := range []bool{false, true} {
+		renderer := buildRenderer(Config{ArchiveBrowser: path, ArchiveNoSandbox: noSandbox})
+		pdf, err := renderer.RenderPDF(context.Background(), "https://example.com/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(pdf), "--no-sandbox") != noSandbox {
+			t.Errorf("expected --no-sandbox only if enabled (%t), got %q", noSandbox, pdf)
+		}
+	}
+}
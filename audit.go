@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	auditActionEventEdit = "event.edit"
+	auditActionReplay    = "events.replay"
+)
+
+// AuditEntry records a single admin action.
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"at"`
+	Actor     string    `json:"actor"`
+	Action    string    `gorm:"index" json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Payload   string    `gorm:"serializer:encrypted" json:"-"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// AuditFilter narrows down the entries returned by AuditLog.
+type AuditFilter struct {
+	Action string
+	Limit  int
+	// After leaves out the entries up to the cursor.
+	After *pageCursor
+}
+
+func (s *gormStore) RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	return s.db.WithContext(ctx).Create(entry).Error
+}
+
+// AuditLog returns the matching entries, newest first.
+func (s *gormStore) AuditLog(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	query := s.db.WithContext(ctx).Order("id DESC").Limit(filter.Limit)
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.After != nil {
+		// IDs grow with the time of the entries, so they order them alone.
+		id, err := filter.After.rowID()
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", id)
+	}
+	var entries []AuditEntry
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+// audit records an admin action performed by the actor of the request. The
+// payload is stored as JSON; failures are logged rather than failing the
+// action that has already happened.
+func (a *App) audit(ctx context.Context, action, target string, payload any) {
+	entry := &AuditEntry{
+		Actor:  actorFromContext(ctx),
+		Action: action,
+		Target: target,
+	}
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			log.Println("Error encoding audit payload:", err)
+		}
+		entry.Payload = string(encoded)
+	}
+
+	err := a.store.RecordAudit(context.WithoutCancel(ctx), entry)
+	if err != nil {
+		log.Printf("Error recording audit entry %s for %s: %v", action, target, err)
+	}
+}
+
+type apiAuditEntry struct {
+	AuditEntry
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func (a *App) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter := AuditFilter{Action: r.URL.Query().Get("action"), Limit: 100}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = n
+	}
+	cursor, err := queryCursor(r.URL.Query())
+	if err == nil && cursor != nil {
+		_, err = cursor.rowID()
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.After = cursor
+
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := a.store.AuditLog(r.Context(), filter)
+	if err != nil {
+		log.Println("Error loading audit log:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		setNextPage(w, r, pageCursor{at: last.CreatedAt.Unix(), id: strconv.FormatUint(uint64(last.ID), 10)})
+	}
+
+	response := make([]apiAuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		item := apiAuditEntry{AuditEntry: entry}
+		if entry.Payload != "" {
+			item.Payload = json.RawMessage(entry.Payload)
+		}
+		response = append(response, item)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
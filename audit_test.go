@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuditLog_RecordsEdits(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	event := Event{Title: "Old", Hash: "audited", DateTime: 1}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res := patchEvent(t, server.URL+"/api/events/audited", "secret", `{"title":"New"}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/audit?action="+auditActionEventEdit, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/audit failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	var entries []struct {
+		Actor   string      `json:"actor"`
+		Action  string      `json:"action"`
+		Target  string      `json:"target"`
+		Payload []EventEdit `json:"payload"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		t.Fatalf("decoding audit log failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Actor != tokenID("secret") || entry.Target != "audited" || len(entry.Payload) != 1 || entry.Payload[0].New != "New" {
+		t.Fatalf("unexpected audit entry: %+v", entry)
+	}
+}
+
+func TestAuditLog_RequiresToken(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/audit")
+	if err != nil {
+		t.Fatalf("GET /api/audit failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestTokenScopes(t *testing.T) {
+	app := &App{config: Config{AdminTokens: []string{"adm"}, StatsTokens: []string{"research"}}}
+
+	if scopes := app.tokenScopes("adm"); !slices.Contains(scopes, scopeAdmin) || !slices.Contains(scopes, scopeStats) {
+		t.Fatalf("admin token should grant admin and stats, got %v", scopes)
+	}
+	if scopes := app.tokenScopes("research"); !slices.Equal(scopes, []string{scopeStats}) {
+		t.Fatalf("stats token should only grant stats, got %v", scopes)
+	}
+	if scopes := app.tokenScopes("unknown"); scopes != nil {
+		t.Fatalf("unknown token should grant nothing, got %v", scopes)
+	}
+	if scopes := app.tokenScopes(""); scopes != nil {
+		t.Fatalf("empty token should grant nothing, got %v", scopes)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	app := &App{config: Config{AdminTokens: []string{"adm"}, StatsTokens: []string{"research"}}}
+	handler := app.requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"unknown", http.StatusUnauthorized},
+		{"research", http.StatusForbidden},
+		{"adm", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPatch, "/api/events/x", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != c.want {
+			t.Fatalf("token %q: expected %d, got %d", c.token, c.want, rec.Code)
+		}
+	}
+}
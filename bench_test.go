@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// benchmarkSizes are the numbers of stored events benchmarks run with: about
+// a year of reports, and the five years kept before pruning with room to grow.
+var benchmarkSizes = []int{10_000, 100_000}
+
+var budget = flag.Bool("budget", false, "check the benchmarks against the performance budget")
+
+// performanceBudget is the most time per operation the benchmarks may take
+// with 10k events, see TestPerformanceBudget. The limits leave room for slow
+// hosts like a Raspberry Pi, so that only real regressions exceed them.
+var performanceBudget = map[string]struct {
+	bench func(*testing.B, int)
+	limit time.Duration
+}{
+	"FeedRender":          {benchmarkFeedRender, time.Second},
+	"FeedAdd":             {benchmarkFeedAdd, time.Second},
+	"CheckDuplicate/new":  {benchmarkCheckDuplicateNew, time.Millisecond},
+	"StoreRecentDistrict": {benchmarkStoreRecent, 30 * time.Millisecond},
+	"StoreEachBatch":      {benchmarkStoreEachBatch, 500 * time.Millisecond},
+}
+
+var benchDistricts = []string{"Mitte", "Pankow", "Neukölln", "Spandau", "Friedrichshain-Kreuzberg", "Charlottenburg-Wilmersdorf", "Lichtenberg", "Reinickendorf"}
+
+// benchEvents returns n events resembling real reports, one every two hours
+// going back from the start of 2025.
+func benchEvents(n int) []Event {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	description := strings.Repeat("Nach bisherigen Erkenntnissen wurde ein Mann in der Nacht leicht verletzt. ", 6)
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = Event{
+			Title:       fmt.Sprintf("Festnahme nach Raub Nr. %d", i),
+			Description: description,
+			Location:    benchDistricts[i%len(benchDistricts)],
+			Link:        fmt.Sprintf("%s/polizei/polizeimeldungen/2024/pressemitteilung.%d.php", eventLinkPrefix, i),
+			Hash:        fmt.Sprintf("%016x", i),
+			DateTime:    start.Add(-time.Duration(i) * 2 * time.Hour).Unix(),
+		}
+	}
+	return events
+}
+
+// openBenchStore returns a store holding n events, in a file rather than in
+// memory to match production.
+func openBenchStore(b *testing.B, n int) EventStore {
+	b.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(b.TempDir(), "bench.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	store, err := NewGormStore(db)
+	if err != nil {
+		b.Fatal(err)
+	}
+	events := benchEvents(n)
+	err = db.CreateInBatches(events, 500).Error
+	if err != nil {
+		b.Fatal(err)
+	}
+	return store
+}
+
+func benchmarkFeedRender(b *testing.B, n int) {
+	feed := NewFeedBuilder("https://www.berlin.de/polizei/polizeimeldungen/")
+	feed.Add(benchEvents(n)...)
+	for b.Loop() {
+		feed.mu.Lock()
+		feed.render()
+		feed.mu.Unlock()
+	}
+}
+
+// benchmarkFeedAdd measures what a scrape run finding a new report costs.
+func benchmarkFeedAdd(b *testing.B, n int) {
+	events := benchEvents(n + 1)
+	feed := NewFeedBuilder("https://www.berlin.de/polizei/polizeimeldungen/")
+	feed.Add(events[1:]...)
+	for b.Loop() {
+		feed.Add(events[0])
+	}
+}
+
+func benchmarkCheckDuplicateNew(b *testing.B, n int) {
+	store := openBenchStore(b, n)
+	ctx := context.Background()
+	seen, err := loadHashCache(ctx, store, defaultDedupCacheSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	event := Event{Link: eventLinkPrefix + "/polizei/polizeimeldungen/2025/pressemitteilung.neu.php"}
+	for b.Loop() {
+		if known, _ := checkDuplicate(ctx, &event, store, seen); known {
+			b.Fatal("expected a new event")
+		}
+	}
+}
+
+func benchmarkStoreRecent(b *testing.B, n int) {
+	store := openBenchStore(b, n)
+	ctx := context.Background()
+	for b.Loop() {
+		events, err := store.Recent(ctx, EventFilter{District: "Pankow"}, 50)
+		if err != nil || len(events) != 50 {
+			b.Fatalf("unexpected result %d %v", len(events), err)
+		}
+	}
+}
+
+// benchmarkStoreEachBatch measures a full scan as done by stats and exports.
+func benchmarkStoreEachBatch(b *testing.B, n int) {
+	store := openBenchStore(b, n)
+	ctx := context.Background()
+	for b.Loop() {
+		count := 0
+		err := store.EachBatch(ctx, EventFilter{}, exportBatchSize, func(events []Event) error {
+			count += len(events)
+			return nil
+		})
+		if err != nil || count != n {
+			b.Fatalf("unexpected result %d %v", count, err)
+		}
+	}
+}
+
+func runSizes(b *testing.B, bench func(*testing.B, int)) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) { bench(b, n) })
+	}
+}
+
+func BenchmarkFeedRender(b *testing.B)          { runSizes(b, benchmarkFeedRender) }
+func BenchmarkFeedAdd(b *testing.B)             { runSizes(b, benchmarkFeedAdd) }
+func BenchmarkStoreRecentDistrict(b *testing.B) { runSizes(b, benchmarkStoreRecent) }
+func BenchmarkStoreEachBatch(b *testing.B)      { runSizes(b, benchmarkStoreEachBatch) }
+
+func BenchmarkCheckDuplicate(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		seen := newHashCache(defaultDedupCacheSize)
+		event := benchEvents(1)[0]
+		seen.Add(identityHash(&event))
+		for b.Loop() {
+			if known, _ := checkDuplicate(context.Background(), &event, nil, seen); !known {
+				b.Fatal("expected a known event")
+			}
+		}
+	})
+	b.Run("new", func(b *testing.B) { runSizes(b, benchmarkCheckDuplicateNew) })
+}
+
+// TestPerformanceBudget fails if a benchmark with 10k events takes longer
+// than its budget. It only runs with -budget, see make bench.
+func TestPerformanceBudget(t *testing.T) {
+	if !*budget {
+		t.Skip("run with -budget")
+	}
+	for name, entry := range performanceBudget {
+		t.Run(name, func(t *testing.T) {
+			result := testing.Benchmark(func(b *testing.B) { entry.bench(b, benchmarkSizes[0]) })
+			perOp := time.Duration(result.NsPerOp())
+			t.Logf("%s: %s/op (budget %s)", name, perOp, entry.limit)
+			if perOp > entry.limit {
+				t.Errorf("%s takes %s/op, over its budget of %s", name, perOp, entry.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The police of Brandenburg publish their press releases on
+// polizei.brandenburg.de, which matters to the many commuters living just
+// outside Berlin. The list names date, title and the county or police
+// directorate, the detail page holds the text.
+
+const sourceBrandenburg = "brandenburg"
+
+// brandenburgTimeFormat is how the list dates the press releases.
+const brandenburgTimeFormat = "02.01.2006, 15:04 Uhr"
+
+type brandenburgSource struct {
+	url       string
+	userAgent string
+}
+
+func newBrandenburgSource(config Config) (Source, error) {
+	return &brandenburgSource{url: config.BrandenburgURL, userAgent: config.UserAgent}, nil
+}
+
+func (s *brandenburgSource) Info() sourceInfo {
+	return sourceInfo{Name: sourceBrandenburg, Label: "Polizei Brandenburg", Title: "Polizei Brandenburg", URL: s.url}
+}
+
+func (s *brandenburgSource) Scrape(ctx context.Context, client HTTPDoer) ([]Event, error) {
+	doc, err := fetchPage(ctx, client, s.url, s.userAgent)
+	if err != nil {
+		return nil, err
+	}
+	if marker := maintenanceMarker(doc.Selection, "ul.pbb-searchlist"); marker != "" {
+		return nil, maintenanceError(marker)
+	}
+	list := doc.Find("ul.pbb-searchlist")
+	if list.Length() == 0 {
+		return nil, fmt.Errorf("%w: %s lists no press releases", errUnexpectedPage, s.url)
+	}
+	base, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	list.Find("li.pbb-article").Each(func(_ int, item *goquery.Selection) {
+		link := item.Find("h4 a")
+		href, ok := link.Attr("href")
+		date := strings.TrimSpace(item.Find(".pbb-date").Text())
+		published, err := time.ParseInLocation(brandenburgTimeFormat, date, berlin)
+		if !ok || err != nil {
+			log.Printf("Skipping Brandenburg press release without link or date %q", date)
+			return
+		}
+		target, err := base.Parse(href)
+		if err != nil {
+			log.Printf("Skipping Brandenburg press release with invalid link %q", href)
+			return
+		}
+		event := Event{
+			Title:    strings.TrimSpace(link.Text()),
+			Link:     target.String(),
+			Location: strings.TrimSpace(item.Find(".pbb-location").Text()),
+			DateTime: wallClock(published),
+		}
+		event.Hash = sourceHash(sourceBrandenburg, &event)
+		events = append(events, event)
+	})
+	return events, nil
+}
+
+// Complete reads the text of the press release from its detail page.
+func (s *brandenburgSource) Complete(ctx context.Context, client HTTPDoer, event *Event) error {
+	doc, err := fetchPage(ctx, client, event.Link, s.userAgent)
+	if err != nil {
+		return err
+	}
+	if marker := maintenanceMarker(doc.Selection, "div.pbb-article-text"); marker != "" {
+		return maintenanceError(marker)
+	}
+	var paragraphs []string
+	doc.Find("div.pbb-article-text p").Each(func(_ int, p *goquery.Selection) {
+		if text := strings.Join(strings.Fields(p.Text()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	})
+	event.Description = strings.Join(paragraphs, "\n\n")
+	if event.Description == "" {
+		event.Description = defaultDescription
+	}
+	return nil
+}
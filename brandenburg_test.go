@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBrandenburgSource_Scrape(t *testing.T) {
+	app := newFixtureApp(t)
+	source, _ := newBrandenburgSource(Config{BrandenburgURL: "https://polizei.brandenburg.de/pressemeldungen/", UserAgent: defaultUserAgent})
+	app.scrapers = []Source{source}
+	ctx := context.Background()
+
+	if err := app.scrapeSource(ctx, app.scrapers[0]); err != nil {
+		t.Fatalf("scrapeSource failed: %v", err)
+	}
+	events, err := app.store.Recent(ctx, EventFilter{Sources: []string{sourceBrandenburg}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the two dated press releases, got %+v", events)
+	}
+	event := events[0]
+	published := time.Date(2026, 3, 14, 9, 12, 0, 0, time.UTC)
+	if event.Title != "Einbruch in Gartenlaube" || event.Location != "Landkreis Oberhavel" || event.DateTime != published.Unix() {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if event.Link != "https://polizei.brandenburg.de/pressemeldung/einbruch-in-gartenlaube/1204311" {
+		t.Fatalf("expected an absolute link, got %s", event.Link)
+	}
+	if !strings.HasPrefix(event.Description, "Unbekannte sind in der Nacht zu Samstag") || !strings.Contains(event.Description, "\n\nSie stahlen Werkzeug") {
+		t.Fatalf("expected the text of the detail page, got %q", event.Description)
+	}
+
+	if err := app.scrapeSource(ctx, app.scrapers[0]); err != nil {
+		t.Fatalf("second scrapeSource failed: %v", err)
+	}
+	again, err := app.store.Recent(ctx, EventFilter{}, 10)
+	if err != nil || len(again) != 2 || again[0].Hash != event.Hash {
+		t.Fatalf("expected a second run to recognise the events, got %d %v", len(again), err)
+	}
+
+	if strings.Contains(app.feed.RSS(), "Gartenlaube") {
+		t.Fatal("expected the built-in feed to stay with berlin.de")
+	}
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, httptest.NewRequest("GET", "/rss/all?source=brandenburg", nil))
+	if !strings.Contains(rec.Body.String(), "[Polizei Brandenburg] Einbruch in Gartenlaube") {
+		t.Fatalf("expected the labelled press release in /rss/all:\n%s", rec.Body.String())
+	}
+}
+
+func TestBuildSources_Unknown(t *testing.T) {
+	if _, err := buildSources(Config{Sources: []string{"hamburg"}}); !errors.Is(err, errUnknownSource) {
+		t.Fatalf("expected an unknown source to be rejected, got %v", err)
+	}
+}
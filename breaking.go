@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"time"
+)
+
+// isBreaking reports whether the event mentions one of the breaking
+// keywords. Keywords match like those of the classification rules.
+func (a *App) isBreaking(event *Event) bool {
+	if len(a.config.BreakingKeywords) == 0 {
+		return false
+	}
+	return slices.ContainsFunc(a.config.BreakingKeywords, newStemmedText(event.Title+"\n"+event.Description).contains)
+}
+
+// startPriorityLane makes the scheduler scrape every BreakingScrapeInterval
+// until BreakingWindow after the newest breaking event among the given ones,
+// so that follow-up reports don't wait for the regular interval.
+func (a *App) startPriorityLane(events []Event, now time.Time) {
+	for _, event := range events {
+		if !a.isBreaking(&event) {
+			continue
+		}
+		until := now.Add(a.config.BreakingWindow)
+		a.scrapeState.prioritize(until)
+		log.Printf("Breaking event %s, scraping every %s until %s", event.Hash, a.config.BreakingScrapeInterval, until.Format(time.TimeOnly))
+		return
+	}
+}
+
+// nextScrape shortens the delay until the next run while the priority lane
+// is active.
+func (a *App) nextScrape(delay time.Duration, now time.Time) time.Duration {
+	if until := a.scrapeState.snapshot().PriorityUntil; until != nil && now.Before(*until) {
+		return min(delay, a.config.BreakingScrapeInterval)
+	}
+	return delay
+}
+
+// breakingFirst moves breaking events to the front, keeping the order
+// otherwise.
+func (a *App) breakingFirst(events []Event) []Event {
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(x, y Event) int {
+		bx, by := a.isBreaking(&x), a.isBreaking(&y)
+		switch {
+		case bx && !by:
+			return -1
+		case by && !bx:
+			return 1
+		}
+		return 0
+	})
+	return sorted
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIsBreaking(t *testing.T) {
+	app := newTestApp(t)
+	if app.isBreaking(&Event{Title: "Amoklage an Schule"}) {
+		t.Fatal("expected no breaking events without keywords")
+	}
+	app.config.BreakingKeywords = []string{"Amoklage", "Großeinsatz"}
+	for title, want := range map[string]bool{
+		"Amoklage an Schule":             true,
+		"Großeinsätze in Neukölln":       true,
+		"Polizei-Großeinsatz am Bahnhof": true,
+		"Einsatz nach Ruhestörung":       false,
+		"Fahrraddiebstahl in Kreuzberg":  false,
+	} {
+		if got := app.isBreaking(&Event{Title: title}); got != want {
+			t.Errorf("isBreaking(%q) = %v, want %v", title, got, want)
+		}
+	}
+}
+
+func TestPriorityLane(t *testing.T) {
+	app := newTestApp(t)
+	app.config.BreakingKeywords = []string{"Explosion"}
+	app.config.BreakingScrapeInterval = 5 * time.Minute
+	app.config.BreakingWindow = time.Hour
+	now := time.Now()
+
+	app.startPriorityLane([]Event{{Title: "Raub", Hash: "a"}}, now)
+	if next := app.nextScrape(time.Hour, now); next != time.Hour {
+		t.Fatalf("expected the regular interval without breaking events, got %s", next)
+	}
+
+	app.startPriorityLane([]Event{{Title: "Raub", Hash: "a"}, {Title: "Explosion in Wohnhaus", Hash: "b"}}, now)
+	if next := app.nextScrape(time.Hour, now.Add(30*time.Minute)); next != 5*time.Minute {
+		t.Fatalf("expected the breaking interval within the window, got %s", next)
+	}
+	if next := app.nextScrape(2*time.Minute, now.Add(30*time.Minute)); next != 2*time.Minute {
+		t.Fatalf("expected shorter retries to be kept, got %s", next)
+	}
+	if next := app.nextScrape(time.Hour, now.Add(61*time.Minute)); next != time.Hour {
+		t.Fatalf("expected the regular interval after the window, got %s", next)
+	}
+	if app.scrapeState.snapshot().PriorityUntil == nil {
+		t.Fatal("expected the priority lane in the status")
+	}
+}
+
+func TestNotify_BreakingSkipsDigestAndQuietHours(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	app.config.BreakingKeywords = []string{"Amoklage"}
+	app.config.NotifyDigestThreshold = 3
+	target := &recordingNotifier{name: "webhook:a"}
+
+	events := []Event{{Title: "Brand", Hash: "1"}, {Title: "Raub", Hash: "2"}, {Title: "Amoklage gemeldet", Hash: "3"}, {Title: "Einbruch", Hash: "4"}}
+	sent, _ := app.notify(ctx, []Notifier{target}, events, false)
+	if sent != 4 || len(target.sent) != 2 || target.sent[0].Event.Hash != "3" || len(target.sent[1].Digest) != 3 {
+		t.Fatalf("expected the breaking event first and on its own, got %+v", target.sent)
+	}
+
+	now := time.Now().In(berlin)
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	app.config.QuietHours = map[string]quietHours{"*": {start: start.Hour()*60 + start.Minute(), end: end.Hour()*60 + end.Minute()}}
+	app.config.NotifyDigestThreshold = 0
+	target.sent = nil
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.notify(ctx, []Notifier{target}, events, false)
+	if len(target.sent) != 1 || target.sent[0].Event.Hash != "3" {
+		t.Fatalf("expected only the breaking event during quiet hours, got %+v", target.sent)
+	}
+}
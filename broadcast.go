@@ -0,0 +1,86 @@
+package main
+
+import "sync"
+
+// subscriberBuffer is how many batches of new events a subscriber may fall
+// behind before it is dropped.
+const subscriberBuffer = 16
+
+// eventBroadcaster hands new events to live subscribers such as gRPC
+// streams, as soon as a scrape run stored them.
+type eventBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan []Event]struct{}
+	// shut is set once the server shuts down; new subscriptions end at once.
+	shut bool
+}
+
+func newEventBroadcaster() *eventBroadcaster {
+	return &eventBroadcaster{subscribers: make(map[chan []Event]struct{})}
+}
+
+// subscribe returns a channel receiving the batches of new events, and a
+// function to stop receiving them. The channel is closed when the
+// subscription ends, including when the subscriber falls too far behind.
+func (b *eventBroadcaster) subscribe() (<-chan []Event, func()) {
+	ch := make(chan []Event, subscriberBuffer)
+	b.mu.Lock()
+	if b.shut {
+		close(ch)
+	} else {
+		b.subscribers[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(ch)
+	}
+}
+
+func (b *eventBroadcaster) remove(ch chan []Event) {
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish sends events to all subscribers without waiting for them.
+func (b *eventBroadcaster) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- events:
+		default:
+			b.remove(ch)
+		}
+	}
+}
+
+// shutdown ends all subscriptions, so that streams don't hold up the
+// shutdown of the server.
+func (b *eventBroadcaster) shutdown() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.shut = true
+	for ch := range b.subscribers {
+		b.remove(ch)
+	}
+}
+
+// closed reports whether subscriptions ended because of shutdown.
+func (b *eventBroadcaster) closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.shut
+}
+
+func (b *eventBroadcaster) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
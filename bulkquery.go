@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxBulkQueries limits the filters of one /api/query request.
+const maxBulkQueries = 20
+
+// bulkQuery is one filter of a /api/query request. The fields are those of
+// /api/facts; key names its result set.
+type bulkQuery struct {
+	Key      string `json:"key"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	District string `json:"district"`
+	Weapon   string `json:"weapon"`
+	Vehicle  string `json:"vehicle"`
+	MinAge   int    `json:"minAge"`
+	MaxAge   int    `json:"maxAge"`
+	AgeRole  string `json:"ageRole"`
+	Limit    int    `json:"limit"`
+}
+
+func (q bulkQuery) factQuery() (FactQuery, error) {
+	values := url.Values{
+		"from":     {q.From},
+		"to":       {q.To},
+		"district": {q.District},
+		"weapon":   {q.Weapon},
+		"vehicle":  {q.Vehicle},
+		"ageRole":  {q.AgeRole},
+		"minAge":   {strconv.Itoa(q.MinAge)},
+		"maxAge":   {strconv.Itoa(q.MaxAge)},
+	}
+	if q.Limit != 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return parseFactQuery(values)
+}
+
+// handleBulkQuery runs several fact queries in one request, e.g. for a
+// dashboard showing several districts side by side. The body is an array of
+// filters, the response maps their keys to the matching events.
+func (a *App) handleBulkQuery(w http.ResponseWriter, r *http.Request) {
+	var queries []bulkQuery
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&queries)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(queries) == 0 || len(queries) > maxBulkQueries {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("between 1 and %d queries are required", maxBulkQueries))
+		return
+	}
+	factQueries := make([]FactQuery, len(queries))
+	keys := make(map[string]bool)
+	for i, query := range queries {
+		if query.Key == "" || keys[query.Key] {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("query %d: key must be set and unique", i))
+			return
+		}
+		keys[query.Key] = true
+		factQueries[i], err = query.factQuery()
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, codeInvalidFilter, fmt.Sprintf("query %s: %v", query.Key, err))
+			return
+		}
+	}
+
+	response := make(map[string][]apiEventFacts, len(queries))
+	for i, query := range factQueries {
+		events, facts, err := a.store.EventsWithFacts(r.Context(), query)
+		if err != nil {
+			log.Println("Error querying facts:", err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		events = a.redactions.events(events)
+		results := make([]apiEventFacts, 0, len(events))
+		for j := range events {
+			results = append(results, apiEventFacts{Event: toAPIEvent(&events[j]), Facts: toAPIFacts(facts[events[j].Hash])})
+		}
+		response[queries[i].Key] = results
+	}
+	newAPIMeta(a.config).setHeaders(w)
+	writeJSON(w, http.StatusOK, response)
+}
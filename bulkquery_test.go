@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBulkQuery(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	ctx := context.Background()
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, berlin)
+	events := []Event{
+		{Title: "Messerangriff", Description: "Ein 15-Jähriger hatte ein Messer dabei.", Location: "Mitte", Hash: "a", DateTime: day.Unix()},
+		{Title: "Unfall", Location: "Mitte", Hash: "b", DateTime: day.Add(-time.Hour).Unix()},
+		{Title: "Raub", Location: "Pankow", Hash: "c", DateTime: day.Unix()},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+	app.updateFacts(ctx, events...)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	post := func(body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/query", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer stats")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /api/query failed: %v", err)
+		}
+		return res
+	}
+
+	res := post(`[
+		{"key": "mitte", "district": "Mitte"},
+		{"key": "pankow", "district": "Pankow", "from": "2024-06-01"},
+		{"key": "knives", "weapon": "messer", "limit": 1}
+	]`)
+	var results map[string][]apiEventFacts
+	err := json.NewDecoder(res.Body).Decode(&results)
+	_ = res.Body.Close()
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %d: %v", res.StatusCode, err)
+	}
+	hashes := func(key string) string {
+		var list []string
+		for _, result := range results[key] {
+			list = append(list, result.Event.Hash)
+		}
+		return strings.Join(list, ",")
+	}
+	if hashes("mitte") != "a,b" || hashes("pankow") != "c" || hashes("knives") != "a" || len(results["knives"][0].Facts) != 2 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	for _, body := range []string{
+		`[]`,
+		`{"key": "mitte"}`,
+		`[{"district": "Mitte"}]`,
+		`[{"key": "a"}, {"key": "a"}]`,
+		`[{"key": "a", "ageRole": "witness"}]`,
+		`[{"key": "a", "from": "yesterday"}]`,
+		`[{"key": "a", "limit": 5000}]`,
+		`[{"key": "a", "unknown": 1}]`,
+	} {
+		res := post(body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// bundespolizei.de publishes the press releases of all federal police
+// offices in a single feed, of which the source keeps those about Berlin.
+// They overlap with berlin.de (see findBerlinDuplicate) and with the
+// presseportal source, so only one of the two should be enabled.
+
+const sourceBundespolizei = "bundespolizei"
+
+type bundespolizeiSource struct {
+	url       string
+	userAgent string
+}
+
+func newBundespolizeiSource(config Config) (Source, error) {
+	if config.BundespolizeiURL == "" {
+		return nil, errMissingSourceURL("BUNDESPOLIZEI_URL")
+	}
+	return &bundespolizeiSource{url: config.BundespolizeiURL, userAgent: config.UserAgent}, nil
+}
+
+func (s *bundespolizeiSource) Info() sourceInfo {
+	return sourceInfo{Name: sourceBundespolizei, Label: "Bundespolizei", Title: "Bundespolizei", URL: s.url, Overlaps: true}
+}
+
+func (s *bundespolizeiSource) Scrape(ctx context.Context, client HTTPDoer) ([]Event, error) {
+	feed, err := fetchRSS(ctx, client, s.url, s.userAgent)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, item := range feed.Items {
+		event := Event{
+			Title:       strings.TrimSpace(item.Title),
+			Description: htmlText(item.Description),
+			Link:        strings.TrimSpace(item.Link),
+		}
+		if !aboutBerlin(event.Title + "\n" + event.Description) {
+			continue
+		}
+		published, err := parseRSSTime(item.PubDate)
+		if err != nil || event.Link == "" {
+			log.Printf("Skipping Bundespolizei press release without link or date %q", item.PubDate)
+			continue
+		}
+		event.DateTime = wallClock(published)
+		event.Hash = sourceHash(sourceBundespolizei, &event)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Complete leaves the events as they are, as the feed carries their text.
+func (s *bundespolizeiSource) Complete(context.Context, HTTPDoer, *Event) error {
+	return nil
+}
+
+// aboutBerlin reports whether text mentions Berlin or one of its districts.
+func aboutBerlin(text string) bool {
+	return strings.Contains(strings.ToLower(text), "berlin") || defaultDistricts.find(text) != ""
+}
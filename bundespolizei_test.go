@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestBundespolizeiSource_KeepsBerlin(t *testing.T) {
+	app := newFixtureApp(t)
+	app.client = &http.Client{Transport: contentTypeTransport{fixtureTransport{dir: "testdata/fixtures"}, "application/rss+xml"}}
+	source, err := newBundespolizeiSource(Config{BundespolizeiURL: "https://www.bundespolizei.de/rss/bundespolizei.xml", UserAgent: defaultUserAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := app.scrapeSource(ctx, source); err != nil {
+		t.Fatalf("scrapeSource failed: %v", err)
+	}
+	events, err := app.store.Recent(ctx, EventFilter{Sources: []string{sourceBundespolizei}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the two press releases about Berlin, got %+v", events)
+	}
+	if events[0].Title != "Taschendieb in der S-Bahn gestellt" || events[1].Location != "Lichtenberg" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+func TestBuildSources_MissingURL(t *testing.T) {
+	if _, err := buildSources(Config{Sources: []string{sourceBundespolizei}}); err == nil {
+		t.Fatal("expected the bundespolizei source to require BUNDESPOLIZEI_URL")
+	}
+}
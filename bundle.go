@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// bundleFormat is the version of the bundle layout. Bundles of a newer
+	// format are refused, as they may hold settings this version ignores.
+	bundleFormat = 1
+
+	settingConfigBundle = "config_bundle"
+
+	auditActionBundleImport = "bundle.import"
+	auditActionBundleDelete = "bundle.delete"
+)
+
+// bundleInfo identifies a config bundle and the version of its content.
+type bundleInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// configBundle packages the classification rules, which carry the category
+// and severity weight of their keywords, and further names of districts, so
+// that rule sets can be maintained by the community and shared between
+// instances.
+type configBundle struct {
+	Format int `json:"format"`
+	bundleInfo
+	Description string               `json:"description,omitempty"`
+	Rules       []classificationRule `json:"rules"`
+	// Districts maps district names to further names reports use for them,
+	// e.g. localities like "Wedding" (see districtMap).
+	Districts map[string][]string `json:"districts,omitempty"`
+}
+
+// parseBundle decodes and validates a bundle, returning the district map it
+// describes.
+func parseBundle(data []byte) (*configBundle, *districtMap, error) {
+	var bundle configBundle
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bundle.Format < 1 || bundle.Format > bundleFormat {
+		return nil, nil, fmt.Errorf("unsupported bundle format %d, expected %d", bundle.Format, bundleFormat)
+	}
+	if bundle.Name == "" || bundle.Version == "" {
+		return nil, nil, errors.New("bundle needs a name and a version")
+	}
+	err = validateRules(bundle.Rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	districts, err := newDistrictMap(bundle.Districts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &bundle, districts, nil
+}
+
+// compareVersions orders versions like "2025.3.10" part by part, numerically
+// where both parts are numbers.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		x, errX := strconv.Atoi(as[i])
+		y, errY := strconv.Atoi(bs[i])
+		if errX == nil && errY == nil {
+			if c := cmp.Compare(x, y); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// ConfigBundle returns the imported bundle, or nothing if there is none.
+func (s *gormStore) ConfigBundle(ctx context.Context) ([]byte, error) {
+	var setting Setting
+	err := s.db.WithContext(ctx).First(&setting, "key = ?", settingConfigBundle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return []byte(setting.Value), err
+}
+
+// SetConfigBundle stores the imported bundle, or removes it if data is nil.
+func (s *gormStore) SetConfigBundle(ctx context.Context, data []byte) error {
+	if data == nil {
+		return s.db.WithContext(ctx).Delete(&Setting{Key: settingConfigBundle}).Error
+	}
+	return s.db.WithContext(ctx).Save(&Setting{Key: settingConfigBundle, Value: string(data)}).Error
+}
+
+func (a *App) districtMap() *districtMap {
+	a.rulesMu.RLock()
+	defer a.rulesMu.RUnlock()
+	return a.districts
+}
+
+func (a *App) applyBundle(bundle *configBundle, districts *districtMap) {
+	a.rulesMu.Lock()
+	defer a.rulesMu.Unlock()
+	a.classificationRules = bundle.Rules
+	a.districts = districts
+	a.bundle = &bundle.bundleInfo
+}
+
+// loadBundle applies the bundle imported before a restart. A bundle that
+// became invalid, e.g. after a downgrade, is ignored.
+func (a *App) loadBundle(ctx context.Context) error {
+	data, err := a.store.ConfigBundle(ctx)
+	if err != nil || data == nil {
+		return err
+	}
+	bundle, districts, err := parseBundle(data)
+	if err != nil {
+		log.Println("Ignoring invalid config bundle:", err)
+		return nil
+	}
+	a.applyBundle(bundle, districts)
+	log.Printf("Using config bundle %s %s", bundle.Name, bundle.Version)
+	return nil
+}
+
+// handleBundleExport returns the rules and districts in effect as a bundle,
+// ready to be imported elsewhere. Without an imported bundle it is named
+// "local".
+func (a *App) handleBundleExport(w http.ResponseWriter, r *http.Request) {
+	a.rulesMu.RLock()
+	bundle := configBundle{
+		Format:     bundleFormat,
+		bundleInfo: bundleInfo{Name: "local", Version: "0"},
+		Rules:      a.classificationRules,
+		Districts:  a.districts.aliases,
+	}
+	if a.bundle != nil {
+		bundle.bundleInfo = *a.bundle
+	}
+	a.rulesMu.RUnlock()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.json"`, bundle.Name, bundle.Version))
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+// handleBundleImport validates and applies a bundle. Replacing a bundle by an
+// older version of itself requires ?force=true.
+func (a *App) handleBundleImport(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	err := json.NewDecoder(r.Body).Decode(&raw)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	bundle, districts, err := parseBundle(raw)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, "invalid bundle: "+err.Error())
+		return
+	}
+
+	a.rulesMu.RLock()
+	active := a.bundle
+	a.rulesMu.RUnlock()
+	if active != nil && active.Name == bundle.Name && compareVersions(bundle.Version, active.Version) < 0 && r.URL.Query().Get("force") != "true" {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("version %s is older than the active version %s, use force=true to downgrade", bundle.Version, active.Version))
+		return
+	}
+
+	err = a.store.SetConfigBundle(r.Context(), raw)
+	if err != nil {
+		log.Println("Error storing config bundle:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	a.applyBundle(bundle, districts)
+	a.audit(r.Context(), auditActionBundleImport, bundle.Name, bundle.bundleInfo)
+	log.Printf("Imported config bundle %s %s with %d rules", bundle.Name, bundle.Version, len(bundle.Rules))
+	writeJSON(w, http.StatusOK, map[string]any{"name": bundle.Name, "version": bundle.Version, "rules": len(bundle.Rules), "districts": len(bundle.Districts)})
+}
+
+// handleBundleDelete drops the imported bundle and goes back to the rules of
+// CLASSIFICATION_RULES or the defaults.
+func (a *App) handleBundleDelete(w http.ResponseWriter, r *http.Request) {
+	rules, err := loadClassificationRules(a.config.ClassificationRulesFile)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	err = a.store.SetConfigBundle(r.Context(), nil)
+	if err != nil {
+		log.Println("Error deleting config bundle:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	a.rulesMu.Lock()
+	previous := a.bundle
+	a.classificationRules = rules
+	a.districts = defaultDistricts
+	a.bundle = nil
+	a.rulesMu.Unlock()
+	if previous != nil {
+		a.audit(r.Context(), auditActionBundleDelete, previous.Name, previous)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
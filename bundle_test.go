@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testBundle = `{
+  "format": 1, "name": "community", "version": "%s",
+  "rules": [{"name": "drogen", "category": "drogen", "severity": 1, "keywords": ["kokain"]}],
+  "districts": {"Mitte": ["Wedding", "Moabit"]}
+}`
+
+func TestBundleEndpoints(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res := postJSON(t, server.URL+"/admin/bundle", "secret", fmt.Sprintf(testBundle, "2025.10"))
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || len(app.rules()) != 1 || app.districtMap().find("Brand in Wedding") != "Mitte" {
+		t.Fatalf("import failed with %d", res.StatusCode)
+	}
+
+	// Downgrades have to be forced.
+	res = postJSON(t, server.URL+"/admin/bundle", "secret", fmt.Sprintf(testBundle, "2025.9"))
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected a downgrade to be refused, got %d", res.StatusCode)
+	}
+	res = postJSON(t, server.URL+"/admin/bundle?force=true", "secret", fmt.Sprintf(testBundle, "2025.9"))
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected a forced downgrade to pass, got %d", res.StatusCode)
+	}
+	for name, bundle := range map[string]string{
+		"unknown district": `{"format": 1, "name": "x", "version": "1", "rules": [{"name": "a", "keywords": ["a"]}], "districts": {"Atlantis": ["Wedding"]}}`,
+		"taken alias":      `{"format": 1, "name": "x", "version": "1", "rules": [{"name": "a", "keywords": ["a"]}], "districts": {"Mitte": ["Kreuzberg"]}}`,
+		"newer format":     `{"format": 2, "name": "x", "version": "1", "rules": [{"name": "a", "keywords": ["a"]}]}`,
+		"no rules":         `{"format": 1, "name": "x", "version": "1", "rules": []}`,
+	} {
+		res = postJSON(t, server.URL+"/admin/bundle", "secret", bundle)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected 422, got %d", name, res.StatusCode)
+		}
+	}
+
+	// The rules file can't be reloaded over a bundle.
+	res = postJSON(t, server.URL+"/admin/severity/reload", "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected reload to be refused, got %d", res.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/admin/bundle", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported configBundle
+	err = json.NewDecoder(res.Body).Decode(&exported)
+	_ = res.Body.Close()
+	if err != nil || exported.Name != "community" || exported.Version != "2025.9" || len(exported.Districts["Mitte"]) != 2 {
+		t.Fatalf("unexpected export %+v (%v)", exported, err)
+	}
+
+	// The bundle survives a restart.
+	restarted, err := NewApp(context.Background(), app.config, http.DefaultClient, app.store, NewFeedBuilder("https://example.com/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.rules()) != 1 || restarted.districtMap().find("Moabit") != "Mitte" {
+		t.Fatal("expected the bundle to be loaded on start")
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/admin/bundle", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || len(app.rules()) != len(defaultClassificationRules) || app.districtMap().find("Wedding") != "" {
+		t.Fatalf("expected the defaults after deleting the bundle, got %d", res.StatusCode)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"2025.10", "2025.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.0", "1.0", 0},
+		{"1.0-beta", "1.0-alpha", 1},
+	} {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html/charset"
+)
+
+// decodePage converts an HTML page to UTF-8 before it is parsed, so that
+// umlauts of pages served as ISO-8859-1 or Windows-1252 survive. The charset
+// is taken from contentType, a byte order mark or a <meta> tag, in this
+// order; without any, the page is taken as UTF-8 if it is valid UTF-8 and as
+// Windows-1252 otherwise, like browsers do.
+func decodePage(body []byte, contentType string) ([]byte, error) {
+	encoding, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" {
+		return body, nil
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding page as %s: %w", name, err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// latin1 encodes s as ISO-8859-1, which covers all German letters.
+func latin1(s string) []byte {
+	var b []byte
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+func TestDecodePage(t *testing.T) {
+	for _, c := range []struct {
+		name, contentType string
+		body              []byte
+	}{
+		{"header", "text/html; charset=ISO-8859-1", latin1("<p>Straße für Fußgänger</p>")},
+		{"meta", "text/html", latin1(`<meta charset="windows-1252"><p>Straße für Fußgänger</p>`)},
+		{"http-equiv", "text/html", latin1(`<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-1"><p>Straße für Fußgänger</p>`)},
+		{"undeclared", "text/html", latin1("<p>Straße für Fußgänger</p>")},
+		{"utf-8", "text/html", []byte("<p>Straße für Fußgänger</p>")},
+		{"utf-8 header", "text/html; charset=utf-8", []byte("<p>Straße für Fußgänger</p>")},
+	} {
+		decoded, err := decodePage(c.body, c.contentType)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !bytes.Contains(decoded, []byte("Straße für Fußgänger")) {
+			t.Errorf("%s: got %q", c.name, decoded)
+		}
+	}
+}
+
+func TestFetchDetailAttempt_Latin1(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write(latin1(`<html><head><meta charset="iso-8859-1"><meta name="description" content="Zeugen gesucht für Überfall in Neukölln"></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	page, _, err := fetchDetailAttempt(context.Background(), server.Client(), server.URL, defaultUserAgent)
+	if err != nil {
+		t.Fatalf("fetchDetailAttempt failed: %v", err)
+	}
+	if page.MetaTags[1].Content != "Zeugen gesucht für Überfall in Neukölln" {
+		t.Fatalf("unexpected description %q", page.MetaTags[1].Content)
+	}
+}
+
+// latin1Transport serves the fixtures as ISO-8859-1, declared in a <meta> tag
+// only.
+type latin1Transport struct {
+	fixtureTransport
+}
+
+func (t latin1Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.fixtureTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	converted := latin1(strings.Replace(string(body), `charset="utf-8"`, `charset="iso-8859-1"`, 1))
+	res.Body = io.NopCloser(bytes.NewReader(converted))
+	res.ContentLength = int64(len(converted))
+	res.Header.Set("Content-Type", "text/html")
+	res.Header.Set("Content-Length", fmt.Sprint(len(converted)))
+	return res, nil
+}
+
+func TestScrape_Latin1Fixtures(t *testing.T) {
+	app := newFixtureApp(t)
+	app.client = &http.Client{Transport: latin1Transport{fixtureTransport{dir: "testdata/fixtures"}}}
+	ctx := context.Background()
+
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	rss := app.feed.RSS()
+	for _, want := range []string{"Festnahme nach Raub in Späti", "Bezirk: Neukölln", "Spätkauf in Neukölln"} {
+		if !strings.Contains(rss, want) {
+			t.Fatalf("feed missing %q", want)
+		}
+	}
+}
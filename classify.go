@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const auditActionRulesReload = "rules.reload"
+
+// Severity levels of events, derived from keywords in their text.
+const (
+	severityLow = iota
+	severityMedium
+	severityHigh
+)
+
+// classificationRule assigns a category and a minimum severity to events
+// mentioning any of its keywords. Keywords match case-insensitively anywhere
+// in the title or description, including inflected forms (see germanStem).
+type classificationRule struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Severity int      `json:"severity"`
+	Keywords []string `json:"keywords"`
+}
+
+var defaultClassificationRules = []classificationRule{
+	{Name: "toetung", Category: "gewalt", Severity: severityHigh, Keywords: []string{"tötungsdelikt", "getötet", "leblos", "mordkommission"}},
+	{Name: "schusswaffe", Category: "gewalt", Severity: severityHigh, Keywords: []string{"schüsse", "schuss", "schusswaffe", "geschossen"}},
+	{Name: "lebensgefahr", Category: "gewalt", Severity: severityHigh, Keywords: []string{"lebensgefährlich", "lebensgefahr"}},
+	{Name: "geiselnahme", Category: "gewalt", Severity: severityHigh, Keywords: []string{"geiselnahme", "geisel"}},
+	{Name: "explosion", Category: "brand", Severity: severityHigh, Keywords: []string{"explosion", "sprengstoff", "detonation"}},
+	{Name: "messer", Category: "gewalt", Severity: severityMedium, Keywords: []string{"messer", "stichverletzung"}},
+	{Name: "koerperverletzung", Category: "gewalt", Severity: severityMedium, Keywords: []string{"körperverletzung", "schwer verletzt", "angegriffen", "schlägerei"}},
+	{Name: "raub", Category: "eigentum", Severity: severityMedium, Keywords: []string{"raub", "überfall", "überfallen"}},
+	{Name: "brand", Category: "brand", Severity: severityMedium, Keywords: []string{"brand", "feuer", "brandstiftung"}},
+	{Name: "sexualdelikt", Category: "sexualdelikt", Severity: severityMedium, Keywords: []string{"sexualdelikt", "sexuell", "vergewaltigung", "vergewaltigt", "exhibitionist"}},
+	{Name: "suizid", Category: "suizid", Severity: severityLow, Keywords: []string{"suizid", "selbsttötung", "suizidal"}},
+	{Name: "vermisst", Category: "vermisst", Severity: severityMedium, Keywords: []string{"vermisst", "vermisste", "vermisster"}},
+	{Name: "einbruch", Category: "eigentum", Severity: severityLow, Keywords: []string{"einbruch", "eingebrochen", "diebstahl", "gestohlen"}},
+	{Name: "verkehr", Category: "verkehr", Severity: severityLow, Keywords: []string{"verkehrsunfall", "unfall", "zusammenstoß", "alkoholisiert"}},
+}
+
+var severityNames = []string{"low", "medium", "high"}
+
+// validateRules rejects rules that could never match or have an unknown
+// severity.
+func validateRules(rules []classificationRule) error {
+	if len(rules) == 0 {
+		return errors.New("no rules defined")
+	}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i)
+		}
+		if rule.Severity < severityLow || rule.Severity > severityHigh {
+			return fmt.Errorf("rule %s: severity must be between %d and %d", rule.Name, severityLow, severityHigh)
+		}
+		if !slices.ContainsFunc(rule.Keywords, func(keyword string) bool { return strings.TrimSpace(keyword) != "" }) {
+			return fmt.Errorf("rule %s has no keywords", rule.Name)
+		}
+	}
+	return nil
+}
+
+// loadClassificationRules reads the rules from a JSON file, or returns the
+// built-in defaults if path is empty.
+func loadClassificationRules(path string) ([]classificationRule, error) {
+	if path == "" {
+		return defaultClassificationRules, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []classificationRule
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	err = validateRules(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func (a *App) rules() []classificationRule {
+	a.rulesMu.RLock()
+	defer a.rulesMu.RUnlock()
+	return a.classificationRules
+}
+
+// Classification is the result of running the rules over a text.
+type Classification struct {
+	Severity     int      `json:"severity"`
+	SeverityName string   `json:"severityName"`
+	Categories   []string `json:"categories"`
+	Rules        []string `json:"rules"`
+}
+
+// classifyText applies the rules to text. The severity is the highest one of
+// all triggered rules.
+func classifyText(rules []classificationRule, text string) Classification {
+	prepared := newStemmedText(text)
+	classification := Classification{Severity: severityLow, Categories: []string{}, Rules: []string{}}
+	for _, rule := range rules {
+		if !slices.ContainsFunc(rule.Keywords, prepared.contains) {
+			continue
+		}
+		classification.Rules = append(classification.Rules, rule.Name)
+		if rule.Category != "" && !slices.Contains(classification.Categories, rule.Category) {
+			classification.Categories = append(classification.Categories, rule.Category)
+		}
+		classification.Severity = max(classification.Severity, rule.Severity)
+	}
+	classification.SeverityName = severityNames[classification.Severity]
+	return classification
+}
+
+// classifyEvent classifies the text of event, adding the category of its
+// source, if it has one (see sourceCategories).
+func classifyEvent(rules []classificationRule, event *Event) Classification {
+	classification := classifyText(rules, event.Title+"\n"+event.Description)
+	if category, ok := sourceCategories[event.Source]; ok && !slices.Contains(classification.Categories, category) {
+		classification.Categories = append([]string{category}, classification.Categories...)
+	}
+	return classification
+}
+
+// handleRulesTest classifies a sample text, so rule authors can try changes
+// without waiting for matching events. A draft rule set can be passed to test
+// it before deploying it; otherwise the active rules are used.
+func (a *App) handleRulesTest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Text  string               `json:"text"`
+		Rules []classificationRule `json:"rules"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(request.Text) == "" {
+		writeJSONError(w, http.StatusBadRequest, "text must not be empty")
+		return
+	}
+	rules := a.rules()
+	if request.Rules != nil {
+		err = validateRules(request.Rules)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid rules: "+err.Error())
+			return
+		}
+		rules = request.Rules
+	}
+	writeJSON(w, http.StatusOK, classifyText(rules, request.Text))
+}
+
+// handleRulesReload re-reads the rules file. If it is invalid, the active
+// rules are kept. While an imported bundle is in effect, its rules win; it
+// has to be deleted first.
+func (a *App) handleRulesReload(w http.ResponseWriter, r *http.Request) {
+	a.rulesMu.RLock()
+	bundle := a.bundle
+	a.rulesMu.RUnlock()
+	if bundle != nil {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("config bundle %s %s is in effect, delete it to use the rules file", bundle.Name, bundle.Version))
+		return
+	}
+	rules, err := loadClassificationRules(a.config.ClassificationRulesFile)
+	if err != nil {
+		log.Println("Error reloading classification rules:", err)
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	a.rulesMu.Lock()
+	a.classificationRules = rules
+	a.rulesMu.Unlock()
+	a.audit(r.Context(), auditActionRulesReload, a.config.ClassificationRulesFile, map[string]int{"rules": len(rules)})
+	log.Printf("Reloaded %d classification rules", len(rules))
+	writeJSON(w, http.StatusOK, map[string]int{"rules": len(rules)})
+}
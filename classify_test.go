@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+)
+
+func TestClassifyText(t *testing.T) {
+	tests := []struct {
+		text       string
+		severity   int
+		categories []string
+	}{
+		{"Fahrraddiebstahl in Mitte", severityLow, []string{"eigentum"}},
+		{"Raubüberfall auf Spätkauf – Täter mit Messer", severityMedium, []string{"eigentum", "gewalt"}},
+		{"Schüsse in Neukölln – Mann lebensgefährlich verletzt", severityHigh, []string{"gewalt"}},
+		{"Pressemitteilung ohne Treffer", severityLow, []string{}},
+	}
+	for _, test := range tests {
+		got := classifyText(defaultClassificationRules, test.text)
+		slices.Sort(got.Categories)
+		if got.Severity != test.severity || !slices.Equal(got.Categories, test.categories) {
+			t.Errorf("classifyText(%q) = %+v, want severity %d and categories %v", test.text, got, test.severity, test.categories)
+		}
+	}
+}
+
+func TestDefaultRules_PublishedCategories(t *testing.T) {
+	for _, rule := range defaultClassificationRules {
+		if !slices.Contains(model.Categories, model.Category(rule.Category)) {
+			t.Errorf("category %s of rule %s is missing from model.Categories", rule.Category, rule.Name)
+		}
+	}
+	for category := range categoryEmoji {
+		if !slices.Contains(model.Categories, model.Category(category)) {
+			t.Errorf("emoji category %s is missing from model.Categories", category)
+		}
+	}
+}
+
+func TestLoadClassificationRules(t *testing.T) {
+	rules, err := loadClassificationRules("")
+	if err != nil || len(rules) != len(defaultClassificationRules) {
+		t.Fatalf("expected the default rules, got %d rules (%v)", len(rules), err)
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "rules.json")
+	writeFile(t, valid, `[{"name":"drogen","category":"drogen","severity":1,"keywords":["Cannabis","Kokain"]}]`)
+	rules, err = loadClassificationRules(valid)
+	if err != nil || len(rules) != 1 || rules[0].Name != "drogen" {
+		t.Fatalf("unexpected rules %+v (%v)", rules, err)
+	}
+
+	for name, content := range map[string]string{
+		"empty.json":    `[]`,
+		"severity.json": `[{"name":"x","severity":5,"keywords":["x"]}]`,
+		"keywords.json": `[{"name":"x","severity":1,"keywords":[" "]}]`,
+		"unknown.json":  `[{"name":"x","level":1,"keywords":["x"]}]`,
+	} {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, content)
+		if _, err := loadClassificationRules(path); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
+
+func TestRulesEndpoints(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	app.config.ClassificationRulesFile = filepath.Join(t.TempDir(), "rules.json")
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	var classification Classification
+	res := postJSON(t, server.URL+"/admin/severity/test", "secret", `{"text":"Schüsse am Hermannplatz"}`)
+	err := json.NewDecoder(res.Body).Decode(&classification)
+	_ = res.Body.Close()
+	if err != nil || classification.SeverityName != "high" || !slices.Contains(classification.Rules, "schusswaffe") {
+		t.Fatalf("unexpected classification %+v (%v)", classification, err)
+	}
+
+	draft := `{"text":"Kokain sichergestellt","rules":[{"name":"drogen","category":"drogen","severity":1,"keywords":["kokain"]}]}`
+	res = postJSON(t, server.URL+"/admin/severity/test", "secret", draft)
+	err = json.NewDecoder(res.Body).Decode(&classification)
+	_ = res.Body.Close()
+	if err != nil || classification.Severity != severityMedium || !slices.Equal(classification.Categories, []string{"drogen"}) {
+		t.Fatalf("unexpected classification with draft rules %+v (%v)", classification, err)
+	}
+
+	writeFile(t, app.config.ClassificationRulesFile, `[{"name":"drogen","category":"drogen","severity":2,"keywords":["kokain"]}]`)
+	res = postJSON(t, server.URL+"/admin/severity/reload", "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || len(app.rules()) != 1 {
+		t.Fatalf("reload failed with %d, %d rules active", res.StatusCode, len(app.rules()))
+	}
+
+	writeFile(t, app.config.ClassificationRulesFile, `not json`)
+	res = postJSON(t, server.URL+"/admin/severity/reload", "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnprocessableEntity || len(app.rules()) != 1 {
+		t.Fatalf("expected invalid rules to be rejected and the active ones kept, got %d", res.StatusCode)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing %s failed: %v", path, err)
+	}
+}
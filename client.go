@@ -0,0 +1,207 @@
+package main
+
+import (
+	"cmp"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// HTTPDoer is the subset of *http.Client the scraper depends on.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// politeTransport rate limits all requests passing through it together,
+// except those to hosts with a limiter of their own, and counts them by host
+// and status for the metrics.
+type politeTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+
+	mu sync.Mutex
+	// hostLimiters replace limiter for the hosts of sources with a rate
+	// limit of their own, see App.limitSources.
+	hostLimiters map[string]*rate.Limiter
+	counts       map[upstreamKey]*upstreamCount
+	// connections counts new connections by host. Compared to the requests
+	// it shows how well connections are reused.
+	connections map[string]int
+}
+
+type upstreamKey struct {
+	host, code string
+}
+
+type upstreamCount struct {
+	requests int
+	seconds  float64
+}
+
+func newPoliteTransport(next http.RoundTripper, limit rate.Limit, burst int) *politeTransport {
+	return &politeTransport{
+		next:    next,
+		limiter: rate.NewLimiter(limit, burst),
+		counts:  make(map[upstreamKey]*upstreamCount),
+
+		hostLimiters: make(map[string]*rate.Limiter),
+
+		connections: make(map[string]int),
+	}
+}
+
+// limitHosts rate limits the requests to hosts by limiter instead of the
+// shared limiter.
+func (t *politeTransport) limitHosts(hosts []string, limiter *rate.Limiter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, host := range hosts {
+		t.hostLimiters[host] = limiter
+	}
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	limiter, ok := t.hostLimiters[req.URL.Hostname()]
+	t.mu.Unlock()
+	if !ok {
+		limiter = t.limiter
+	}
+	err := limiter.Wait(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	fresh := false
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { fresh = !info.Reused },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := upstreamKey{host: req.URL.Host, code: code}
+	count := t.counts[key]
+	if count == nil {
+		count = &upstreamCount{}
+		t.counts[key] = count
+	}
+	count.requests++
+	count.seconds += time.Since(start).Seconds()
+	if fresh {
+		t.connections[req.URL.Host]++
+	}
+	return res, err
+}
+
+func (t *politeTransport) writeMetrics(m *metricsWriter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := slices.SortedFunc(maps.Keys(t.counts), func(a, b upstreamKey) int {
+		return cmp.Or(strings.Compare(a.host, b.host), strings.Compare(a.code, b.code))
+	})
+	var requests, seconds []metricSample
+	for _, key := range keys {
+		labels := map[string]string{"host": key.host, "code": key.code}
+		requests = append(requests, metricSample{labels: labels, value: float64(t.counts[key].requests)})
+		seconds = append(seconds, metricSample{labels: labels, value: t.counts[key].seconds})
+	}
+	m.write("policefeed_upstream_requests_total", "counter", "Number of requests to upstream sites by host and status code.", requests...)
+	m.write("policefeed_upstream_request_seconds_total", "counter", "Time spent on requests to upstream sites by host and status code.", seconds...)
+	var connections []metricSample
+	for _, host := range slices.Sorted(maps.Keys(t.connections)) {
+		connections = append(connections, metricSample{labels: map[string]string{"host": host}, value: float64(t.connections[host])})
+	}
+	m.write("policefeed_upstream_connections_total", "counter", "Number of new connections to upstream sites by host.", connections...)
+}
+
+// drainBodyLimit bounds how much of an unread body drainBody reads. Larger
+// bodies are cheaper to abandon together with their connection.
+const drainBodyLimit = 1 << 20
+
+// drainBody reads what is left of a response body and closes it, which lets
+// the transport reuse the connection for the next request.
+func drainBody(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, drainBodyLimit))
+	_ = body.Close()
+}
+
+// NewRateLimitedClient returns a client sending at most requestsPerSecond
+// requests, with bursts of up to burst requests.
+func NewRateLimitedClient(requestsPerSecond float64, burst int) *http.Client {
+	return &http.Client{
+		Transport: newPoliteTransport(http.DefaultTransport, rate.Limit(requestsPerSecond), burst),
+		Timeout:   20 * time.Second,
+	}
+}
+
+// newScraperClient builds the single HTTP stack used for everything fetched
+// from berlin.de: the list pages colly visits, the detail pages and link
+// checks. They share one rate limit of a request per ScrapeRequestInterval,
+// go through ScrapeProxy or the proxy from the environment, and are read
+// from SourceDir instead if that is set.
+func newScraperClient(config Config) (*http.Client, error) {
+	var base http.RoundTripper
+	if config.SourceDir != "" {
+		base = fixtureTransport{dir: config.SourceDir}
+	} else {
+		proxy := http.ProxyFromEnvironment
+		if config.ScrapeProxy != "" {
+			proxyURL, err := url.Parse(config.ScrapeProxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SCRAPE_PROXY: %w", err)
+			}
+			proxy = http.ProxyURL(proxyURL)
+		}
+		base = &http.Transport{
+			Proxy:             proxy,
+			TLSClientConfig:   &tls.Config{},
+			ForceAttemptHTTP2: false,
+			// Requests are spaced out by the rate limit, so connections
+			// have to stay open for a while to be reused at all.
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		}
+	}
+
+	jar, err := newScrapeJar(config.PoliceURL, config.ScrapeCookies)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := rate.Inf
+	if config.ScrapeRequestInterval > 0 {
+		limit = rate.Every(config.ScrapeRequestInterval)
+	}
+	return &http.Client{
+		Transport: newPoliteTransport(base, limit, 1),
+		Jar:       jar,
+		Timeout:   20 * time.Second,
+	}, nil
+}
+
+// transport returns the round tripper behind the app's client, which colly
+// shares.
+func (a *App) transport() http.RoundTripper {
+	if client, ok := a.client.(*http.Client); ok && client.Transport != nil {
+		return client.Transport
+	}
+	return http.DefaultTransport
+}
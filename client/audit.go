@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AuditEntry is an admin action recorded in the audit log.
+type AuditEntry struct {
+	ID     uint      `json:"id"`
+	At     time.Time `json:"at"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Target string    `json:"target,omitempty"`
+	// Payload holds the details of the action, e.g. the edits of
+	// "event.edit", as JSON.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// AuditQuery selects audit log entries. Zero values match everything.
+type AuditQuery struct {
+	// Action restricts the entries to one action, e.g. "event.edit".
+	Action string
+	// Limit is the page size, at most 1000. It defaults to 100.
+	Limit int
+}
+
+func (q AuditQuery) values() url.Values {
+	values := url.Values{}
+	if q.Action != "" {
+		values.Set("action", q.Action)
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return values
+}
+
+// AuditPage returns the page of audit log entries matching q that starts at
+// cursor, the first page if it is empty, newest first, and the cursor of the
+// next page, empty after the last one. It needs an admin token.
+func (c *Client) AuditPage(ctx context.Context, q AuditQuery, cursor string) ([]AuditEntry, string, error) {
+	values := q.values()
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+	res, err := c.do(ctx, http.MethodGet, "/api/audit", values, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	var page []AuditEntry
+	err = json.NewDecoder(res.Body).Decode(&page)
+	if err != nil {
+		return nil, "", err
+	}
+	return page, nextCursor(res.Header), nil
+}
+
+// EachAuditEntry calls fn for every audit log entry matching q, newest
+// first, following the cursors of AuditPage. It stops at the first error of
+// fn. It needs an admin token.
+func (c *Client) EachAuditEntry(ctx context.Context, q AuditQuery, fn func(AuditEntry) error) error {
+	cursor := ""
+	for {
+		page, next, err := c.AuditPage(ctx, q, cursor)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page {
+			err = fn(entry)
+			if err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
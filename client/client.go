@@ -0,0 +1,153 @@
+// Package client is a Go client for the API of the Berlin police feed, e.g.
+//
+//	c := client.New("https://feed.example.org", os.Getenv("STATS_TOKEN"))
+//	err := c.EachEvent(ctx, client.Query{District: "Neukölln"}, func(e client.EventFacts) error {
+//		fmt.Println(e.Event.PublishedAt, e.Event.Title)
+//		return nil
+//	})
+//
+// The server has no event stream; to follow new events, poll EachEvent with
+// From set to the time of the last one seen.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client talks to one instance of the feed.
+type Client struct {
+	// BaseURL is the address of the instance, e.g. https://feed.example.org.
+	BaseURL string
+	// Token is sent as bearer token. Events are public; facts, stats and
+	// exports need a stats token, the audit log an admin token.
+	Token string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// New returns a client for the instance at baseURL. token may be empty for
+// the public endpoints.
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// Error is an error answered by the API as problem details.
+type Error struct {
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	// Code tells failures apart, e.g. "not_found", "invalid_filter" or
+	// "rate_limited".
+	Code string `json:"code"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("policefeed: %d %s: %s", e.Status, e.Code, e.Detail)
+	}
+	return fmt.Sprintf("policefeed: %d %s", e.Status, e.Code)
+}
+
+// IsNotFound reports whether err says that the requested resource doesn't
+// exist.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == "not_found"
+}
+
+// IsGone reports whether err says that the requested event was taken down.
+func IsGone(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == "gone"
+}
+
+// statusCode is the code of responses that aren't problem details, such as
+// the tombstone of an event that was taken down.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusGone:
+		return "gone"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	}
+	return "http_" + strconv.Itoa(status)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// do sends a request to path and returns the response if it succeeded, an
+// *Error otherwise.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	apiErr := &Error{Status: res.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Code == "" {
+		apiErr.Title = http.StatusText(res.StatusCode)
+		apiErr.Detail = strings.TrimSpace(string(data))
+		apiErr.Code = statusCode(res.StatusCode)
+	}
+	apiErr.Status = res.StatusCode
+	return nil, apiErr
+}
+
+// getJSON decodes the response to a GET of path into v.
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	res, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// postJSON sends request as JSON body to path and decodes the response into
+// v.
+func (c *Client) postJSON(ctx context.Context, path string, request, v any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(ctx, http.MethodPost, path, nil, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
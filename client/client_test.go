@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/events/abc":
+			fmt.Fprint(w, `{"hash": "abc", "title": "Brand in Wohnhaus", "district": "Mitte", "publishedAt": "2024-05-01T10:00:00Z"}`)
+		case "/api/events/gone":
+			w.WriteHeader(http.StatusGone)
+			fmt.Fprint(w, `{"hash": "gone", "takenDownAt": "2024-05-02T10:00:00Z"}`)
+		default:
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "event not found", "code": "not_found"}`)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL+"/", "")
+
+	event, err := c.Event(context.Background(), "abc")
+	if err != nil || event.Title != "Brand in Wohnhaus" || event.District != "Mitte" {
+		t.Fatalf("got %+v, %v", event, err)
+	}
+	if _, err := c.Event(context.Background(), "missing"); !IsNotFound(err) || err.(*Error).Detail != "event not found" {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if _, err := c.Event(context.Background(), "gone"); !IsGone(err) {
+		t.Fatalf("expected a gone error, got %v", err)
+	}
+}
+
+func TestEachEvent_Pages(t *testing.T) {
+	// Seven events, two of them in the same second, which a page boundary
+	// splits.
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	var events []EventFacts
+	for i, offset := range []int{60, 50, 40, 30, 30, 20, 10} {
+		events = append(events, EventFacts{Event: Event{Hash: strconv.Itoa(i), PublishedAt: base.Add(time.Duration(offset) * time.Second)}})
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		to, _ := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+		page := []EventFacts{}
+		for _, event := range events {
+			if (to.IsZero() || event.Event.PublishedAt.Before(to)) && len(page) < limit {
+				page = append(page, event)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	var hashes []string
+	err := New(server.URL, "token").EachEvent(context.Background(), Query{Limit: 4}, func(event EventFacts) error {
+		hashes = append(hashes, event.Event.Hash)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(hashes) != "[0 1 2 3 4 5 6]" {
+		t.Fatalf("expected every event once, got %v in %d requests", hashes, requests)
+	}
+}
+
+func TestExport(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/exports":
+			var request map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			if request["format"] != "ndjson" || request["district"] != "Pankow" {
+				t.Errorf("unexpected request %v", request)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{"id": "x1", "status": "queued", "format": "ndjson"}`)
+		case r.URL.Path == "/api/exports/x1":
+			polls++
+			if polls < 2 {
+				fmt.Fprint(w, `{"id": "x1", "status": "running", "format": "ndjson"}`)
+				return
+			}
+			fmt.Fprint(w, `{"id": "x1", "status": "done", "format": "ndjson", "rows": 2, "downloadUrl": "/api/exports/x1/download"}`)
+		case r.URL.Path == "/api/exports/x1/download":
+			fmt.Fprintln(w, `{"hash": "a", "title": "Eins"}`)
+			fmt.Fprintln(w, `{"hash": "b", "title": "Zwei"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL, "token")
+	ctx := context.Background()
+
+	job, err := c.StartExport(ctx, Query{District: "Pankow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = c.WaitForExport(ctx, job.ID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	err = c.EachExportedEvent(ctx, job, func(event Event) error {
+		titles = append(titles, event.Title)
+		return nil
+	})
+	if err != nil || fmt.Sprint(titles) != "[Eins Zwei]" {
+		t.Fatalf("got %v, %v", titles, err)
+	}
+}
+
+func TestFactsByKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var queries []bulkQuery
+		if r.Method != http.MethodPost || r.URL.Path != "/api/query" || json.NewDecoder(r.Body).Decode(&queries) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		results := map[string][]EventFacts{}
+		for _, query := range queries {
+			results[query.Key] = []EventFacts{{Event: Event{Hash: query.Key, District: query.District, Title: query.From}}}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+	c := New(server.URL, "")
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	results, err := c.FactsByKey(context.Background(), map[string]Query{
+		"mitte":  {District: "Mitte", From: from},
+		"pankow": {District: "Pankow"},
+	})
+	if err != nil {
+		t.Fatalf("FactsByKey failed: %v", err)
+	}
+	if len(results) != 2 || results["mitte"][0].Event.District != "Mitte" || results["mitte"][0].Event.Title != "2024-05-01T00:00:00Z" || results["pankow"][0].Event.District != "Pankow" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
+
+func TestEachEvent_Cursors(t *testing.T) {
+	hashes := []string{"a", "b", "c", "d", "e"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(start+2, len(hashes))
+		if end < len(hashes) {
+			w.Header().Set("Link", fmt.Sprintf(`</api/facts?cursor=%d&limit=2>; rel="next"`, end))
+		}
+		page := []EventFacts{}
+		for _, hash := range hashes[start:end] {
+			page = append(page, EventFacts{Event: Event{Hash: hash}})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "token")
+	page, next, err := c.FactsPage(context.Background(), Query{Limit: 2}, "")
+	if err != nil || len(page) != 2 || next != "2" {
+		t.Fatalf("unexpected first page %v, next %q, %v", page, next, err)
+	}
+	var seen []string
+	err = c.EachEvent(context.Background(), Query{Limit: 2}, func(event EventFacts) error {
+		seen = append(seen, event.Event.Hash)
+		return nil
+	})
+	if err != nil || fmt.Sprint(seen) != "[a b c d e]" {
+		t.Fatalf("expected every event once following the cursors, got %v, %v", seen, err)
+	}
+}
+
+func TestEachAuditEntry(t *testing.T) {
+	ids := []int{5, 4, 3}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/audit" || r.URL.Query().Get("action") != "event.edit" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(start+2, len(ids))
+		if end < len(ids) {
+			w.Header().Set("Link", fmt.Sprintf(`</api/audit?action=event.edit&cursor=%d&limit=2>; rel="next"`, end))
+		}
+		page := []map[string]any{}
+		for _, id := range ids[start:end] {
+			page = append(page, map[string]any{"id": id, "action": "event.edit", "payload": []map[string]string{{"field": "title"}}})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "admin")
+	var seen []uint
+	err := c.EachAuditEntry(context.Background(), AuditQuery{Action: "event.edit", Limit: 2}, func(entry AuditEntry) error {
+		seen = append(seen, entry.ID)
+		if string(entry.Payload) != `[{"field":"title"}]` {
+			t.Errorf("unexpected payload %s", entry.Payload)
+		}
+		return nil
+	})
+	if err != nil || fmt.Sprint(seen) != "[5 4 3]" {
+		t.Fatalf("expected every entry once following the cursors, got %v, %v", seen, err)
+	}
+}
+
+func TestPlainPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "" {
+			w.Header().Set("Link", `</plain?cursor=abc&district=Mitte>; rel="next"`)
+		}
+		fmt.Fprintf(w, "Seite %s, %s\n", r.URL.Query().Get("cursor"), r.URL.Query().Get("district"))
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "")
+	text, next, err := c.PlainPage(context.Background(), Query{District: "Mitte", Weapon: "Messer"}, "")
+	if err != nil || text != "Seite , Mitte\n" || next != "abc" {
+		t.Fatalf("unexpected first page %q, next %q, %v", text, next, err)
+	}
+	text, next, err = c.PlainPage(context.Background(), Query{District: "Mitte"}, next)
+	if err != nil || text != "Seite abc, Mitte\n" || next != "" {
+		t.Fatalf("unexpected last page %q, next %q, %v", text, next, err)
+	}
+}
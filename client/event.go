@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+)
+
+// The types of the API are shared with the server, see package model.
+type (
+	Event      = model.Event
+	Edit       = model.Edit
+	Mention    = model.Mention
+	Fact       = model.Fact
+	EventFacts = model.EventFacts
+)
+
+// Query selects events. Zero values match everything.
+type Query struct {
+	// From and To bound the publication time; To is exclusive.
+	From     time.Time
+	To       time.Time
+	District string
+
+	Weapon  string
+	Vehicle string
+	MinAge  int
+	MaxAge  int
+	// AgeRole restricts MinAge and MaxAge to "suspect" or "victim".
+	AgeRole string
+
+	// Limit is the page size, at most 1000. It defaults to 100.
+	Limit int
+}
+
+// defaultLimit is the page size of the API if none is given.
+const defaultLimit = 100
+
+func (q Query) values() url.Values {
+	values := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if !q.From.IsZero() {
+		set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		set("to", q.To.Format(time.RFC3339))
+	}
+	set("district", q.District)
+	set("weapon", q.Weapon)
+	set("vehicle", q.Vehicle)
+	set("ageRole", q.AgeRole)
+	if q.MinAge > 0 {
+		set("minAge", strconv.Itoa(q.MinAge))
+	}
+	if q.MaxAge > 0 {
+		set("maxAge", strconv.Itoa(q.MaxAge))
+	}
+	if q.Limit > 0 {
+		set("limit", strconv.Itoa(q.Limit))
+	}
+	return values
+}
+
+// bulkQuery is a query as part of a request to /api/query.
+type bulkQuery struct {
+	Key      string `json:"key"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	District string `json:"district,omitempty"`
+	Weapon   string `json:"weapon,omitempty"`
+	Vehicle  string `json:"vehicle,omitempty"`
+	MinAge   int    `json:"minAge,omitempty"`
+	MaxAge   int    `json:"maxAge,omitempty"`
+	AgeRole  string `json:"ageRole,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// Event fetches a single event. Events that were taken down fail with an
+// error for which IsGone is true.
+func (c *Client) Event(ctx context.Context, hash string) (*Event, error) {
+	var event Event
+	err := c.getJSON(ctx, "/api/events/"+url.PathEscape(hash), nil, &event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Facts returns one page of the newest events matching q, with their facts.
+// It needs a stats token.
+func (c *Client) Facts(ctx context.Context, q Query) ([]EventFacts, error) {
+	var page []EventFacts
+	err := c.getJSON(ctx, "/api/facts", q.values(), &page)
+	return page, err
+}
+
+// FactsPage returns the page of events matching q that starts at cursor, the
+// first page if it is empty, and the cursor of the next page, empty after the
+// last one. Unlike paging by q.To, cursors never skip or repeat events while
+// new ones are published. It needs a stats token.
+func (c *Client) FactsPage(ctx context.Context, q Query, cursor string) ([]EventFacts, string, error) {
+	values := q.values()
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+	res, err := c.do(ctx, http.MethodGet, "/api/facts", values, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	var page []EventFacts
+	err = json.NewDecoder(res.Body).Decode(&page)
+	if err != nil {
+		return nil, "", err
+	}
+	return page, nextCursor(res.Header), nil
+}
+
+// nextCursor returns the cursor of the page linked as next in the Link
+// header of a response, if any.
+func nextCursor(header http.Header) string {
+	for _, link := range header.Values("Link") {
+		for _, value := range strings.Split(link, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(value), ";")
+			if !ok || !strings.Contains(params, `rel="next"`) {
+				continue
+			}
+			next, err := url.Parse(strings.Trim(target, "<>"))
+			if err == nil {
+				return next.Query().Get("cursor")
+			}
+		}
+	}
+	return ""
+}
+
+// EachEvent calls fn for every event matching q, newest first, fetching
+// pages of q.Limit events as it goes (see FactsPage). It stops at the first
+// error of fn. It needs a stats token.
+func (c *Client) EachEvent(ctx context.Context, q Query, fn func(EventFacts) error) error {
+	// Servers without cursors cut pages at the publication time of the
+	// oldest event seen; the events of that second are fetched again and
+	// skipped.
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	seen := map[string]bool{}
+	cursor := ""
+	for {
+		page, next, err := c.FactsPage(ctx, q, cursor)
+		if err != nil {
+			return err
+		}
+		fresh := 0
+		for _, event := range page {
+			if seen[event.Event.Hash] {
+				continue
+			}
+			fresh++
+			err = fn(event)
+			if err != nil {
+				return err
+			}
+		}
+		if next != "" {
+			cursor = next
+			continue
+		}
+		if fresh == 0 || len(page) < limit {
+			return nil
+		}
+		oldest := page[len(page)-1].Event.PublishedAt
+		clear(seen)
+		for _, event := range page {
+			if event.Event.PublishedAt.Equal(oldest) {
+				seen[event.Event.Hash] = true
+			}
+		}
+		q.To = oldest.Add(time.Second)
+	}
+}
+
+// FactsByKey runs up to 20 queries in a single request and returns the first
+// page of results of each by its key in queries. It needs a stats token.
+func (c *Client) FactsByKey(ctx context.Context, queries map[string]Query) (map[string][]EventFacts, error) {
+	request := make([]bulkQuery, 0, len(queries))
+	for _, key := range slices.Sorted(maps.Keys(queries)) {
+		q := queries[key]
+		query := bulkQuery{
+			Key: key, District: q.District, Weapon: q.Weapon, Vehicle: q.Vehicle,
+			MinAge: q.MinAge, MaxAge: q.MaxAge, AgeRole: q.AgeRole, Limit: q.Limit,
+		}
+		if !q.From.IsZero() {
+			query.From = q.From.Format(time.RFC3339)
+		}
+		if !q.To.IsZero() {
+			query.To = q.To.Format(time.RFC3339)
+		}
+		request = append(request, query)
+	}
+	var results map[string][]EventFacts
+	err := c.postJSON(ctx, "/api/query", request, &results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
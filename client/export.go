@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Export statuses.
+const (
+	ExportQueued  = "queued"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// ExportJob is a bulk export being prepared on the server.
+type ExportJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	District    string     `json:"district,omitempty"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
+
+// StartExport queues an NDJSON export of the events matching the from, to
+// and district fields of q. It needs a stats token.
+func (c *Client) StartExport(ctx context.Context, q Query) (*ExportJob, error) {
+	request := map[string]string{"format": "ndjson", "district": q.District}
+	if !q.From.IsZero() {
+		request["from"] = q.From.Format(time.RFC3339)
+	}
+	if !q.To.IsZero() {
+		request["to"] = q.To.Format(time.RFC3339)
+	}
+	var job ExportJob
+	err := c.postJSON(ctx, "/api/exports", request, &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Export returns the current state of an export.
+func (c *Client) Export(ctx context.Context, id string) (*ExportJob, error) {
+	var job ExportJob
+	err := c.getJSON(ctx, "/api/exports/"+url.PathEscape(id), nil, &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitForExport polls an export every interval until it is done, and fails
+// if it failed or expired.
+func (c *Client) WaitForExport(ctx context.Context, id string, interval time.Duration) (*ExportJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.Export(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		switch job.Status {
+		case ExportDone:
+			return job, nil
+		case ExportFailed, ExportExpired:
+			return job, fmt.Errorf("policefeed: export %s %s: %s", id, job.Status, job.Error)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// EachExportedEvent downloads a finished NDJSON export and calls fn for each
+// of its events, stopping at the first error of fn.
+func (c *Client) EachExportedEvent(ctx context.Context, job *ExportJob, fn func(Event) error) error {
+	if job.Status != ExportDone || job.DownloadURL == "" {
+		return errors.New("policefeed: export " + job.ID + " is " + job.Status)
+	}
+	if job.Format != "ndjson" {
+		return errors.New("policefeed: export " + job.ID + " is not ndjson")
+	}
+	res, err := c.do(ctx, http.MethodGet, job.DownloadURL, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	decoder := json.NewDecoder(res.Body)
+	for decoder.More() {
+		var event Event
+		err = decoder.Decode(&event)
+		if err != nil {
+			return err
+		}
+		err = fn(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// PlainPage returns the page of /plain listing the events matching the from,
+// to, district and limit fields of q as plain text, starting at cursor, the
+// first page if it is empty, and the cursor of the next page, empty after
+// the last one. Without q.Limit, pages hold 50 events.
+func (c *Client) PlainPage(ctx context.Context, q Query, cursor string) (string, string, error) {
+	values := q.values()
+	for _, name := range []string{"weapon", "vehicle", "ageRole", "minAge", "maxAge"} {
+		values.Del(name)
+	}
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+	res, err := c.do(ctx, http.MethodGet, "/plain", values, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+	text, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return string(text), nextCursor(res.Header), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestPoliteTransport_RateLimits(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	transport := newPoliteTransport(http.DefaultTransport, rate.Every(50*time.Millisecond), 1)
+	client := &http.Client{Transport: transport}
+	start := time.Now()
+	for _, path := range []string{"/", "/", "/missing"} {
+		res, err := client.Get(site.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected requests to be spaced out, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, site.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected a cancelled request to fail while waiting")
+	}
+
+	host := site.Listener.Addr().String()
+	if count := transport.counts[upstreamKey{host, "200"}]; count == nil || count.requests != 2 {
+		t.Fatalf("unexpected counts %v", transport.counts)
+	}
+	if count := transport.counts[upstreamKey{host, "404"}]; count == nil || count.requests != 1 {
+		t.Fatalf("unexpected counts %v", transport.counts)
+	}
+}
+
+func TestScrape_SharesTransport(t *testing.T) {
+	app := newFixtureApp(t)
+	if err := app.scrape(context.Background()); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	// The list page and the four detail pages, fetched by colly and the
+	// meta tag extraction respectively, go through the same transport.
+	match := regexp.MustCompile(`policefeed_upstream_requests_total\{code="200",host="www.berlin.de"\} (\d+)`).FindSubmatch(body)
+	if match == nil {
+		t.Fatalf("metrics are missing upstream requests:\n%s", body)
+	}
+	if n, _ := strconv.Atoi(string(match[1])); n < 5 {
+		t.Fatalf("expected list and detail pages to be counted, got %d", n)
+	}
+}
+
+func TestFetchDetailAttempt_ReusesConnection(t *testing.T) {
+	var connections atomic.Int32
+	failing := true
+	site := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			failing = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, strings.Repeat("Wartungsarbeiten ", 1000))
+			return
+		}
+		_, _ = io.WriteString(w, `<html><head><meta name="description" content="Raub"></head></html>`)
+	}))
+	site.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	site.Start()
+	defer site.Close()
+
+	transport := newPoliteTransport(&http.Transport{}, rate.Inf, 1)
+	client := &http.Client{Transport: transport}
+	ctx := context.Background()
+	if _, status, err := fetchDetailAttempt(ctx, client, site.URL, defaultUserAgent); status != http.StatusServiceUnavailable || err == nil {
+		t.Fatalf("expected the first attempt to fail, got %d %v", status, err)
+	}
+	page, _, err := fetchDetailAttempt(ctx, client, site.URL, defaultUserAgent)
+	if err != nil || len(page.MetaTags) == 0 {
+		t.Fatalf("unexpected second attempt %+v %v", page, err)
+	}
+	if n := connections.Load(); n != 1 {
+		t.Fatalf("expected both attempts to share a connection, got %d", n)
+	}
+	if n := transport.connections[site.Listener.Addr().String()]; n != 1 {
+		t.Fatalf("expected one counted connection, got %d", n)
+	}
+}
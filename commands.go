@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// runCommand dispatches the maintenance subcommands. Without a subcommand the
+// binary runs the scraper and web server as usual.
+func runCommand(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "migrate":
+		return runMigrate(ctx, args)
+	case "migrate-db":
+		return runMigrateDB(ctx, args)
+	case "extract-facts":
+		return runExtractFacts(ctx, args)
+	case "export":
+		return runExport(ctx, args)
+	case "import":
+		return runImport(ctx, args)
+	case "fix-encoding":
+		return runFixEncoding(ctx, args)
+	case "encrypt-db":
+		return runEncryptDB(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: migrate, migrate-db, extract-facts, export, import, fix-encoding, encrypt-db)", name)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// minCompressedResponse is the smallest body worth compressing; below it the
+// gzip header eats most of the savings.
+const minCompressedResponse = 1 << 10
+
+// brotliLevel trades a little size for speed: the highest levels take
+// seconds on a full feed, which is re-rendered with every new report.
+const brotliLevel = 9
+
+// acceptsEncoding reports whether the client accepts responses in encoding,
+// going by Accept-Encoding. Encodings with q=0 are refused; "*" stands for
+// any encoding not named.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	accepted := false
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			name = strings.TrimSpace(name)
+			if name != encoding && name != "*" {
+				continue
+			}
+			ok := true
+			for _, param := range strings.Split(params, ";") {
+				value, found := strings.CutPrefix(strings.TrimSpace(param), "q=")
+				if q, err := strconv.ParseFloat(value, 64); found && err == nil && q == 0 {
+					ok = false
+				}
+			}
+			if name == encoding {
+				return ok
+			}
+			accepted = ok
+		}
+	}
+	return accepted
+}
+
+// gzipBody compresses body once for all clients that accept gzip. It returns
+// nil if body is too small to gain from it.
+func gzipBody(body []byte) []byte {
+	if len(body) < minCompressedResponse {
+		return nil
+	}
+	var b bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	_, _ = w.Write(body)
+	if err := w.Close(); err != nil || b.Len() >= len(body) {
+		return nil
+	}
+	return b.Bytes()
+}
+
+// brotliBody is gzipBody for clients that accept Brotli.
+func brotliBody(body []byte) []byte {
+	if len(body) < minCompressedResponse {
+		return nil
+	}
+	var b bytes.Buffer
+	w := brotli.NewWriterLevel(&b, brotliLevel)
+	_, _ = w.Write(body)
+	if err := w.Close(); err != nil || b.Len() >= len(body) {
+		return nil
+	}
+	return b.Bytes()
+}
+
+// compressedBody is a response body compressed once, ahead of the requests
+// for it.
+type compressedBody struct {
+	body []byte
+	// br and gzip are the compressed bodies, nil if compressing doesn't pay
+	// off.
+	br, gzip []byte
+}
+
+func compressBody(body []byte) compressedBody {
+	return compressedBody{body: body, br: brotliBody(body), gzip: gzipBody(body)}
+}
+
+func (c compressedBody) size() int {
+	return len(c.body) + len(c.br) + len(c.gzip)
+}
+
+// negotiate picks the smallest encoding the client accepts, sets the
+// Content-Encoding header for it and returns the body to send.
+func (c compressedBody) negotiate(w http.ResponseWriter, r *http.Request) []byte {
+	if !slices.Contains(w.Header().Values("Vary"), "Accept-Encoding") {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	switch {
+	case c.br != nil && acceptsEncoding(r, "br"):
+		w.Header().Set("Content-Encoding", "br")
+		return c.br
+	case c.gzip != nil && acceptsEncoding(r, "gzip"):
+		w.Header().Set("Content-Encoding", "gzip")
+		return c.gzip
+	}
+	return c.body
+}
+
+// precompressedEncodings are the encodings of files served in place of a
+// static asset, in order of preference, with their file extensions.
+var precompressedEncodings = []struct{ encoding, extension string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// precompressedFileServer serves the files of fsys like http.FileServerFS,
+// but prefers a precompressed copy next to a file, e.g. logo.svg.br for
+// logo.svg, if the client accepts its encoding.
+func precompressedFileServer(fsys fs.FS) http.Handler {
+	files := http.FileServerFS(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+			files.ServeHTTP(w, r)
+			return
+		}
+		for _, precompressed := range precompressedEncodings {
+			if !acceptsEncoding(r, precompressed.encoding) {
+				continue
+			}
+			if servePrecompressed(w, r, fsys, name, precompressed.encoding, precompressed.extension) {
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// servePrecompressed serves the copy of name with the given extension, if
+// there is one.
+func servePrecompressed(w http.ResponseWriter, r *http.Request, fsys fs.FS, name, encoding, extension string) bool {
+	file, err := fsys.Open(name + extension)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", encoding)
+	http.ServeContent(w, r, name, stat.ModTime(), content)
+	return true
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	for _, c := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"br, gzip;q=0.5", true},
+		{"deflate, br", false},
+		{"gzip;q=0", false},
+		{"gzip; q=0.000", false},
+		{"*", true},
+		{"*, gzip;q=0", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", c.header)
+		if got := acceptsEncoding(r, "gzip"); got != c.want {
+			t.Errorf("acceptsEncoding(%q) = %v, want %v", c.header, got, c.want)
+		}
+	}
+}
+
+// getEncoded requests url accepting encoding and returns the response along
+// with its decoded body. The transport would decompress transparently unless
+// asked for an encoding explicitly.
+func getEncoded(t *testing.T, url, encoding string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", encoding)
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var body io.Reader = res.Body
+	switch res.Header.Get("Content-Encoding") {
+	case "gzip":
+		body, err = gzip.NewReader(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	case "br":
+		body = brotli.NewReader(res.Body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(data)
+}
+
+func TestCached_Compression(t *testing.T) {
+	app := newTestApp(t)
+	app.config.APICacheTTL = time.Minute
+	app.responses = newResponseCache(defaultResponseCacheSize)
+	for i := range 20 {
+		event := Event{Title: fmt.Sprintf("Meldung %d", i), Description: strings.Repeat("Text ", 50), Hash: fmt.Sprint(i), DateTime: int64(i)}
+		if err := app.store.Create(context.Background(), &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	get := func(encoding string) (*http.Response, string) {
+		return getEncoded(t, server.URL+"/plain", encoding)
+	}
+
+	_, plain := get("gzip")
+	res, body := get("gzip")
+	if res.Header.Get("X-Cache") != "HIT" || res.Header.Get("Content-Encoding") != "gzip" || body != plain {
+		t.Fatalf("expected a gzipped hit with the same body, got %v", res.Header)
+	}
+	if res.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected Vary: Accept-Encoding, got %q", res.Header.Get("Vary"))
+	}
+	res, body = get("gzip, br")
+	if res.Header.Get("X-Cache") != "HIT" || res.Header.Get("Content-Encoding") != "br" || body != plain {
+		t.Fatalf("expected a Brotli hit with the same body, got %v", res.Header)
+	}
+	res, body = get("identity")
+	if res.Header.Get("Content-Encoding") != "" || body != plain {
+		t.Fatalf("expected an uncompressed hit for identity, got %v", res.Header)
+	}
+}
+
+func TestFeed_Precompressed(t *testing.T) {
+	app := newTestApp(t)
+	app.config.APICacheTTL = time.Minute
+	app.responses = newResponseCache(defaultResponseCacheSize)
+	for i := range 20 {
+		app.feed.Add(Event{Title: fmt.Sprintf("Meldung %d", i), Description: strings.Repeat("Text ", 50), Hash: fmt.Sprint(i), DateTime: int64(i)})
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for _, c := range []struct{ path, accept, encoding, body string }{
+		{"/rss", "gzip, br", "br", app.feed.RSS()},
+		{"/atom", "gzip", "gzip", app.feed.Atom()},
+		{"/json", "identity", "", app.feed.JSON()},
+	} {
+		res, body := getEncoded(t, server.URL+c.path, c.accept)
+		if res.Header.Get("Content-Encoding") != c.encoding || body != c.body {
+			t.Errorf("%s with %q: unexpected response %v", c.path, c.accept, res.Header)
+		}
+	}
+	// The renders are compressed already, the cache would only copy them.
+	res, _ := getEncoded(t, server.URL+"/rss", "br")
+	if res.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("expected precompressed feeds to bypass the cache, got %q", res.Header.Get("X-Cache"))
+	}
+}
+
+func TestPrecompressedFileServer(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"style.css":    "body {}",
+		"style.css.br": "brotli",
+		"style.css.gz": "gzip",
+		"logo.svg":     "<svg/>",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(precompressedFileServer(os.DirFS(dir)))
+	defer server.Close()
+
+	for _, c := range []struct {
+		path, encoding, wantBody, wantEncoding string
+	}{
+		{"/style.css", "gzip, br", "brotli", "br"},
+		{"/style.css", "gzip", "gzip", "gzip"},
+		{"/style.css", "identity", "body {}", ""},
+		{"/logo.svg", "br", "<svg/>", ""},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+c.path, nil)
+		req.Header.Set("Accept-Encoding", c.encoding)
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if string(body) != c.wantBody || res.Header.Get("Content-Encoding") != c.wantEncoding {
+			t.Errorf("%s with %q: got %q encoded %q", c.path, c.encoding, body, res.Header.Get("Content-Encoding"))
+		}
+		if c.path == "/style.css" && !strings.HasPrefix(res.Header.Get("Content-Type"), "text/css") {
+			t.Errorf("%s with %q: got content type %q", c.path, c.encoding, res.Header.Get("Content-Type"))
+		}
+	}
+}
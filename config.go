@@ -0,0 +1,589 @@
+package main
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// berlin is the time zone the upstream pages use. The zone database is
+// embedded, so this works in minimal container images too.
+var berlin, _ = time.LoadLocation("Europe/Berlin")
+
+type Config struct {
+	PoliceURL string
+	// UserAgent is sent with every request to berlin.de, unless the stealth
+	// feature flag is set (see App.userAgents).
+	UserAgent string
+	// ScrapeRequestInterval is the least time between two requests to
+	// berlin.de, shared by list pages, detail pages and link checks.
+	ScrapeRequestInterval time.Duration
+	// ScrapeProxy is the proxy requests to berlin.de go through. Without it
+	// the HTTPS_PROXY and HTTP_PROXY variables apply.
+	ScrapeProxy string
+	// ScrapeCookies are name=value cookies sent to berlin.de from the start,
+	// e.g. the one recording an accepted consent banner.
+	ScrapeCookies []string
+	// ScrapeSessionURLs are requested at the start of every run, so that the
+	// cookies they set are sent along with the list and detail pages.
+	ScrapeSessionURLs []string
+	// Sources lists the portals scraped besides berlin.de, see Source.
+	Sources []string
+	// SourceIntervals and SourceRequestIntervals override ScrapeInterval and
+	// ScrapeRequestInterval for the sources named, SourceDomains the hosts
+	// each source may fetch from, see sourcelimits.go.
+	SourceIntervals        map[string]time.Duration
+	SourceRequestIntervals map[string]time.Duration
+	SourceDomains          map[string][]string
+	// SourcePlugins are name=command entries of sources scraped by external
+	// commands, see pluginSource.
+	SourcePlugins []string
+	// BrandenburgURL is the list of press releases of the Brandenburg
+	// police, see brandenburgSource.
+	BrandenburgURL string
+	// PresseportalURL is the RSS feed of the office read by the presseportal
+	// source, see presseportalSource.
+	PresseportalURL string
+	// BundespolizeiURL, BVGURL and SBahnURL are the RSS feeds of the
+	// bundespolizei, bvg and sbahn sources, which have no default.
+	BundespolizeiURL string
+	BVGURL           string
+	SBahnURL         string
+	WebPort          string
+	// ListenAddresses are the addresses the web server listens on. Hosts can
+	// also name a network interface, see listenAddresses.
+	ListenAddresses []string
+	DatabaseURL     string
+	// DatabaseReadConns and DatabaseWriteConns size the connection pools of
+	// SQLite databases, see openServerDatabase.
+	DatabaseReadConns  int
+	DatabaseWriteConns int
+	// DatabaseBackupDir receives a backup of the SQLite database on every
+	// startup that finds it healthy, the newest DatabaseBackups are kept. A
+	// corrupted database is restored from there, see ensureIntegrity.
+	DatabaseBackupDir string
+	DatabaseBackups   int
+	// DatabaseKeyFile holds the key encrypting the texts of events, see
+	// useDatabaseKey.
+	DatabaseKeyFile string
+
+	ScrapeInterval    time.Duration
+	ScrapeTimeout     time.Duration
+	ScrapeRetryDelays []time.Duration
+	ScrapeFreshness   time.Duration
+	// BreakingKeywords mark new events as breaking, e.g. Amoklage: they are
+	// sent right away, and the list page is scraped every
+	// BreakingScrapeInterval for BreakingWindow, see startPriorityLane.
+	BreakingKeywords       []string
+	BreakingScrapeInterval time.Duration
+	BreakingWindow         time.Duration
+
+	// BaseURL is the public address of this server, used for absolute links
+	// in the feeds.
+	BaseURL string
+	// StaticDir is served under /static/, e.g. for the feed image.
+	StaticDir string
+	// FeedImage and FeedFavicon are URLs or /static/ paths, see
+	// FeedBuilder.SetImage.
+	FeedImage   string
+	FeedFavicon string
+	// FeedExclusions maps subscription names to categories left out of their
+	// feeds, see App.feedExclusions.
+	FeedExclusions map[string][]string
+	// FeedVariantsFile is a JSON file of named feeds served under /f/, see
+	// feedVariant.
+	FeedVariantsFile string
+	// RobotsDisallow lists path prefixes crawlers are asked to stay out of in
+	// /robots.txt, in addition to /admin/.
+	RobotsDisallow []string
+	// RobotsFile replaces the generated /robots.txt.
+	RobotsFile string
+
+	// TrustedProxies are the addresses of reverse proxies whose
+	// X-Forwarded-* headers are honoured, see App.forwarded.
+	TrustedProxies []netip.Prefix
+
+	AdminTokens []string
+	StatsTokens []string
+
+	ExportDir       string
+	ExportRetention time.Duration
+
+	QualityWindow time.Duration
+
+	// DataLicense, DataLicenseURL and DataAttribution are passed on with all
+	// published data, see apiMeta.
+	DataLicense     string
+	DataLicenseURL  string
+	DataAttribution string
+
+	// AnalyticsExportDir, if set, receives fresh Parquet files of all events
+	// and facts every AnalyticsExportInterval.
+	AnalyticsExportDir      string
+	AnalyticsExportInterval time.Duration
+
+	// WebhookURLs receive a POST for every new event (see webhookNotifier).
+	WebhookURLs []string
+	// LifecycleWebhookURLs are told when scrape runs start, succeed or fail
+	// (see lifecycleHook).
+	LifecycleWebhookURLs []string
+	// PingURLs maps sources to a URL called after each of their successful
+	// scrapes (see App.ping).
+	PingURLs map[string]string
+	// NostrPrivateKey (nsec or hex) signs the notes published to NostrRelays
+	// (see nostrNotifier).
+	NostrPrivateKey string
+	NostrRelays     []string
+	// ActivityPubUser enables the ActivityPub actor with this name, whose
+	// signing key is kept in ActivityPubKey (see activityPub).
+	ActivityPubUser string
+	ActivityPubKey  string
+	// WebmentionSources restricts received Webmentions to sources starting
+	// with one of these prefixes; empty accepts any source.
+	WebmentionSources []string
+	// NotifyRetryDelays are the waits between delivery attempts; once they
+	// are used up the notification becomes a dead letter.
+	NotifyRetryDelays []time.Duration
+	// NotifyDigestThreshold is the number of new events from a single scrape
+	// at which they are sent as one digest per target instead of one by one.
+	// Zero disables digests.
+	NotifyDigestThreshold int
+	// QuietHours maps targets (or * for all) to daily windows during which
+	// only high severity events are sent; the rest follows as a digest.
+	QuietHours map[string]quietHours
+	// NotifyEmoji lists the targets (or * for all) whose messages get emoji
+	// before the titles, see Notification.Emoji.
+	NotifyEmoji []string
+	// AppriseURLs are notification targets in Apprise syntax, see
+	// appriseNotifier. Those not supported directly are sent through the
+	// Apprise API server at AppriseAPIURL.
+	AppriseURLs   []string
+	AppriseAPIURL string
+	// TelegramBotToken and TelegramChannel (e.g. @berlinpolizei) enable the
+	// channel mirror, see telegramChannelNotifier. The bot has to be an
+	// administrator of the channel.
+	TelegramBotToken string
+	TelegramChannel  string
+	// SMSRecipients are phone numbers texted about events of at least
+	// SMSMinSeverity in SMSDistricts (all if empty), see smsNotifier. Messages
+	// go through the Twilio API if SMSTwilioAccount is set, otherwise as JSON
+	// to SMSGatewayURL.
+	SMSRecipients    []string
+	SMSFrom          string
+	SMSGatewayURL    string
+	SMSGatewayToken  string
+	SMSTwilioAccount string
+	SMSDistricts     []string
+	SMSMinSeverity   int
+
+	// Summarizer selects the backend generating short summaries of long
+	// reports for notifications: "openai" (any compatible API) or "ollama".
+	// Summaries are disabled if empty.
+	Summarizer          string
+	SummarizerURL       string
+	SummarizerModel     string
+	SummarizerAPIKey    string
+	SummarizerTimeout   time.Duration
+	SummarizerMinLength int
+
+	// TTS selects the backend reading out the daily podcast episodes, see
+	// buildSpeaker. The podcast is disabled if empty.
+	TTS             string
+	TTSURL          string
+	TTSModel        string
+	TTSVoice        string
+	TTSAPIKey       string
+	TTSCommand      string
+	PodcastDir      string
+	PodcastEpisodes int
+
+	// ArchiveBrowser is a Chromium binary used to keep a PDF copy of every
+	// detail page in ArchiveDir (see archiveEvents). Empty disables it.
+	ArchiveBrowser string
+	ArchiveDir     string
+	// ArchiveNoSandbox starts ArchiveBrowser without its sandbox, which
+	// containers without user namespaces need.
+	ArchiveNoSandbox bool
+
+	// WaybackAccessKey and WaybackSecretKey enable submitting new detail
+	// pages to the Wayback Machine, at most one every WaybackInterval.
+	WaybackAccessKey string
+	WaybackSecretKey string
+	WaybackInterval  time.Duration
+
+	// LinkCheckInterval is how often every stored link is re-checked for
+	// link rot. Zero disables the checks.
+	LinkCheckInterval time.Duration
+
+	// GeminiAddress enables serving the feed over the Gemini protocol, see
+	// runGemini.
+	GeminiAddress string
+	GeminiCert    string
+	GeminiKey     string
+
+	// GRPCAddress is where the gRPC API listens if the feature flag "grpc"
+	// is set, see runGRPC.
+	GRPCAddress string
+
+	// DedupCacheSize is the number of recent event hashes checked in memory
+	// before asking the database whether an event is new.
+	DedupCacheSize int
+	// PruneMinEvents is the number of newest events kept when events older
+	// than five years are pruned at startup.
+	PruneMinEvents int
+
+	// APICacheTTL is how long rendered responses of hot API queries are
+	// served from memory, unless new data arrives first. APICacheSize bounds
+	// their total size in bytes; zero for either disables the cache.
+	APICacheTTL  time.Duration
+	APICacheSize int
+	// ExpensiveRequestLimit is how many requests to expensive endpoints
+	// (stats, facts, exports, analytics) are served at once; zero for no
+	// limit.
+	ExpensiveRequestLimit int
+
+	// ClassificationRulesFile is a JSON file replacing the built-in keyword
+	// rules for categories and severity.
+	ClassificationRulesFile string
+	// RedactionRulesFile is a JSON file of patterns blanked out whenever
+	// events are rendered, see redactor.
+	RedactionRulesFile string
+
+	// Features lists the enabled feature flags.
+	Features []string
+
+	// SourceDir, if set, makes the scraper read pages from local files
+	// instead of fetching them (see fixtureTransport).
+	SourceDir string
+}
+
+func loadConfig() Config {
+	policeURL, exists := os.LookupEnv("POLICE_URL")
+	if !exists {
+		policeURL = "https://www.berlin.de/polizei/polizeimeldungen/"
+		log.Println("POLICE_URL environment variable not set, defaulting")
+	}
+
+	webPort, exists := os.LookupEnv("WEB_PORT")
+	if !exists {
+		webPort = "8080"
+		log.Printf("WEB_PORT not set, defaulting to port %s", webPort)
+	}
+
+	databaseURL, exists := os.LookupEnv("DATABASE_URL")
+	if !exists {
+		databasePath, exists := os.LookupEnv("DATABASE_PATH")
+		if !exists {
+			databasePath = "/data/policeEvents.db"
+		}
+		databaseURL = "sqlite:" + databasePath
+	}
+
+	exportDir, exists := os.LookupEnv("EXPORT_DIR")
+	if !exists {
+		exportDir = "/data/exports"
+	}
+
+	archiveDir, exists := os.LookupEnv("ARCHIVE_DIR")
+	if !exists {
+		archiveDir = "/data/archive"
+	}
+
+	podcastDir, exists := os.LookupEnv("PODCAST_DIR")
+	if !exists {
+		podcastDir = "/data/podcast"
+	}
+
+	geminiCert, exists := os.LookupEnv("GEMINI_CERT")
+	if !exists {
+		geminiCert = "/data/gemini.crt"
+	}
+	geminiKey, exists := os.LookupEnv("GEMINI_KEY")
+	if !exists {
+		geminiKey = "/data/gemini.key"
+	}
+
+	activityPubKey, exists := os.LookupEnv("ACTIVITYPUB_KEY")
+	if !exists {
+		activityPubKey = "/data/activitypub.pem"
+	}
+
+	grpcAddress, exists := os.LookupEnv("GRPC_ADDRESS")
+	if !exists {
+		grpcAddress = ":9090"
+	}
+
+	// Without a host the server listens on all IPv4 and IPv6 addresses.
+	listenAddresses := listEnv("LISTEN_ADDRESSES")
+	if len(listenAddresses) == 0 {
+		listenAddresses = []string{":" + webPort}
+	}
+
+	userAgent, exists := os.LookupEnv("USER_AGENT")
+	if !exists {
+		userAgent = defaultUserAgent
+	}
+
+	brandenburgURL, exists := os.LookupEnv("BRANDENBURG_URL")
+	if !exists {
+		brandenburgURL = "https://polizei.brandenburg.de/pressemeldungen/"
+	}
+
+	presseportalURL, exists := os.LookupEnv("PRESSEPORTAL_URL")
+	if !exists {
+		presseportalURL = "https://www.presseportal.de/rss/dienststelle_70238.rss2"
+	}
+
+	attribution, exists := os.LookupEnv("DATA_ATTRIBUTION")
+	if !exists {
+		attribution = defaultAttribution
+	}
+
+	return Config{
+		PoliceURL: policeURL,
+		UserAgent: userAgent,
+		WebPort:   webPort,
+
+		ScrapeRequestInterval:  durationEnv("SCRAPE_REQUEST_INTERVAL", 2*time.Second),
+		ScrapeProxy:            os.Getenv("SCRAPE_PROXY"),
+		ScrapeCookies:          listEnv("SCRAPE_COOKIES"),
+		ScrapeSessionURLs:      listEnv("SCRAPE_SESSION_URLS"),
+		Sources:                listEnv("SOURCES"),
+		SourceIntervals:        sourceDurationsEnv("SOURCE_INTERVALS"),
+		SourceRequestIntervals: sourceDurationsEnv("SOURCE_REQUEST_INTERVALS"),
+		SourceDomains:          sourceDomainsEnv("SOURCE_DOMAINS"),
+		SourcePlugins:          listEnv("SOURCE_PLUGINS"),
+		BrandenburgURL:         brandenburgURL,
+		PresseportalURL:        presseportalURL,
+		BundespolizeiURL:       os.Getenv("BUNDESPOLIZEI_URL"),
+		BVGURL:                 os.Getenv("BVG_URL"),
+		SBahnURL:               os.Getenv("SBAHN_URL"),
+
+		ListenAddresses: listenAddresses,
+		DatabaseURL:     databaseURL,
+
+		DatabaseReadConns:  positiveIntEnv("DATABASE_READ_CONNS", 4),
+		DatabaseBackupDir:  os.Getenv("DATABASE_BACKUP_DIR"),
+		DatabaseBackups:    intEnv("DATABASE_BACKUPS", 3),
+		DatabaseKeyFile:    os.Getenv("DATABASE_KEY_FILE"),
+		DatabaseWriteConns: positiveIntEnv("DATABASE_WRITE_CONNS", 1),
+
+		ScrapeInterval:    durationEnv("SCRAPE_INTERVAL", 1*time.Hour),
+		ScrapeTimeout:     durationEnv("SCRAPE_TIMEOUT", 15*time.Minute),
+		ScrapeFreshness:   durationEnv("SCRAPE_FRESHNESS", 15*time.Minute),
+		ScrapeRetryDelays: durationListEnv("SCRAPE_RETRY_DELAYS", []time.Duration{5 * time.Minute, 15 * time.Minute, 30 * time.Minute}),
+
+		BreakingKeywords:       listEnv("BREAKING_KEYWORDS"),
+		BreakingScrapeInterval: durationEnv("BREAKING_SCRAPE_INTERVAL", 5*time.Minute),
+		BreakingWindow:         durationEnv("BREAKING_WINDOW", time.Hour),
+
+		BaseURL:        os.Getenv("BASE_URL"),
+		StaticDir:      os.Getenv("STATIC_DIR"),
+		FeedImage:      os.Getenv("FEED_IMAGE"),
+		FeedFavicon:    os.Getenv("FEED_FAVICON"),
+		FeedExclusions: feedExclusionsEnv("FEED_EXCLUSIONS"),
+		TrustedProxies: parseTrustedProxies(listEnv("TRUSTED_PROXIES")),
+
+		FeedVariantsFile: os.Getenv("FEED_VARIANTS"),
+		RobotsDisallow:   listEnv("ROBOTS_DISALLOW"),
+		RobotsFile:       os.Getenv("ROBOTS_FILE"),
+
+		AdminTokens: listEnv("ADMIN_TOKENS"),
+		StatsTokens: listEnv("STATS_TOKENS"),
+
+		ExportDir:       exportDir,
+		ExportRetention: durationEnv("EXPORT_RETENTION", 24*time.Hour),
+
+		QualityWindow: durationEnv("QUALITY_WINDOW", 7*24*time.Hour),
+
+		DataLicense:     os.Getenv("DATA_LICENSE"),
+		DataLicenseURL:  os.Getenv("DATA_LICENSE_URL"),
+		DataAttribution: attribution,
+
+		AnalyticsExportDir:      os.Getenv("ANALYTICS_EXPORT_DIR"),
+		AnalyticsExportInterval: durationEnv("ANALYTICS_EXPORT_INTERVAL", 24*time.Hour),
+
+		WebhookURLs: listEnv("WEBHOOK_URLS"),
+
+		LifecycleWebhookURLs: listEnv("LIFECYCLE_WEBHOOK_URLS"),
+		PingURLs:             pingURLsEnv("SCRAPE_PING_URLS"),
+
+		NostrPrivateKey:   os.Getenv("NOSTR_PRIVATE_KEY"),
+		NostrRelays:       listEnv("NOSTR_RELAYS"),
+		ActivityPubUser:   os.Getenv("ACTIVITYPUB_USER"),
+		ActivityPubKey:    activityPubKey,
+		WebmentionSources: listEnv("WEBMENTION_SOURCES"),
+		NotifyRetryDelays: durationListEnv("NOTIFY_RETRY_DELAYS", []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute}),
+
+		NotifyDigestThreshold: intEnv("NOTIFY_DIGEST_THRESHOLD", 10),
+		QuietHours:            quietHoursEnv("NOTIFY_QUIET_HOURS"),
+		NotifyEmoji:           listEnv("NOTIFY_EMOJI"),
+
+		AppriseURLs:   listEnv("APPRISE_URLS"),
+		AppriseAPIURL: os.Getenv("APPRISE_API_URL"),
+
+		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramChannel:  os.Getenv("TELEGRAM_CHANNEL"),
+
+		SMSRecipients:    listEnv("SMS_RECIPIENTS"),
+		SMSFrom:          os.Getenv("SMS_FROM"),
+		SMSGatewayURL:    os.Getenv("SMS_GATEWAY_URL"),
+		SMSGatewayToken:  os.Getenv("SMS_GATEWAY_TOKEN"),
+		SMSTwilioAccount: os.Getenv("SMS_TWILIO_ACCOUNT"),
+		SMSDistricts:     listEnv("SMS_DISTRICTS"),
+		SMSMinSeverity:   intEnv("SMS_MIN_SEVERITY", severityHigh),
+
+		Summarizer:          os.Getenv("SUMMARIZER"),
+		SummarizerURL:       os.Getenv("SUMMARIZER_URL"),
+		SummarizerModel:     os.Getenv("SUMMARIZER_MODEL"),
+		SummarizerAPIKey:    os.Getenv("SUMMARIZER_API_KEY"),
+		SummarizerTimeout:   durationEnv("SUMMARIZER_TIMEOUT", 10*time.Second),
+		SummarizerMinLength: intEnv("SUMMARIZER_MIN_LENGTH", 600),
+
+		TTS:             os.Getenv("TTS"),
+		TTSURL:          os.Getenv("TTS_URL"),
+		TTSModel:        os.Getenv("TTS_MODEL"),
+		TTSVoice:        os.Getenv("TTS_VOICE"),
+		TTSAPIKey:       os.Getenv("TTS_API_KEY"),
+		TTSCommand:      os.Getenv("TTS_COMMAND"),
+		PodcastDir:      podcastDir,
+		PodcastEpisodes: intEnv("PODCAST_EPISODES", 30),
+
+		ArchiveBrowser:   os.Getenv("ARCHIVE_BROWSER"),
+		ArchiveNoSandbox: boolEnv("ARCHIVE_BROWSER_NO_SANDBOX", false),
+		ArchiveDir:       archiveDir,
+
+		WaybackAccessKey: os.Getenv("WAYBACK_ACCESS_KEY"),
+		WaybackSecretKey: os.Getenv("WAYBACK_SECRET_KEY"),
+		WaybackInterval:  durationEnv("WAYBACK_INTERVAL", 10*time.Second),
+
+		LinkCheckInterval: durationEnv("LINK_CHECK_INTERVAL", 0),
+
+		GeminiAddress: os.Getenv("GEMINI_ADDRESS"),
+		GeminiCert:    geminiCert,
+		GeminiKey:     geminiKey,
+
+		GRPCAddress: grpcAddress,
+
+		DedupCacheSize: intEnv("DEDUP_CACHE_SIZE", defaultDedupCacheSize),
+		PruneMinEvents: intEnv("PRUNE_MIN_EVENTS", 500),
+
+		APICacheTTL:  optionalDurationEnv("API_CACHE_TTL", 30*time.Second),
+		APICacheSize: intEnv("API_CACHE_SIZE", defaultResponseCacheSize),
+
+		ExpensiveRequestLimit: intEnv("EXPENSIVE_REQUEST_LIMIT", 4),
+
+		ClassificationRulesFile: os.Getenv("CLASSIFICATION_RULES"),
+		RedactionRulesFile:      os.Getenv("REDACTION_RULES"),
+
+		Features: listEnv("FEATURE_FLAGS"),
+
+		SourceDir: os.Getenv("SOURCE_DIR"),
+	}
+}
+
+// durationEnv reads a time.ParseDuration formatted value from the environment,
+// falling back to def if it is unset or invalid.
+func durationEnv(name string, def time.Duration) time.Duration {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, defaulting to %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// optionalDurationEnv is durationEnv for settings that 0 turns off.
+func optionalDurationEnv(name string, def time.Duration) time.Duration {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Invalid %s %q, defaulting to %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// intEnv reads a non-negative integer from the environment, falling back to
+// def if it is unset or invalid.
+func intEnv(name string, def int) int {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, defaulting to %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+// boolEnv reads a strconv.ParseBool formatted value from the environment,
+// falling back to def if it is unset or invalid.
+func boolEnv(name string, def bool) bool {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, defaulting to %t", name, value, def)
+		return def
+	}
+	return b
+}
+
+// positiveIntEnv is intEnv for settings that must be at least 1, like pool
+// sizes, where database/sql takes 0 for unlimited.
+func positiveIntEnv(name string, def int) int {
+	n := intEnv(name, def)
+	if n < 1 {
+		log.Printf("Invalid %s %d, defaulting to %d", name, n, def)
+		return def
+	}
+	return n
+}
+
+// durationListEnv reads a comma separated list of durations from the
+// environment, falling back to def if it is unset or any entry is invalid.
+func durationListEnv(name string, def []time.Duration) []time.Duration {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return def
+	}
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			log.Printf("Invalid %s %q, defaulting to %v", name, value, def)
+			return def
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
+
+// listEnv reads a comma separated list from the environment, dropping empty
+// entries.
+func listEnv(name string) []string {
+	var values []string
+	for _, part := range strings.Split(os.Getenv(name), ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// Some berlin.de pages only show the report text once a consent banner was
+// accepted, and otherwise fall back to the meta description without any
+// error. The scraper client therefore keeps cookies like a browser, can be
+// given the consent cookies up front (SCRAPE_COOKIES) and visits the pages
+// that set them at the start of every run (SCRAPE_SESSION_URLS).
+
+// newScrapeJar returns a cookie jar holding the given name=value cookies for
+// the host of policeURL.
+func newScrapeJar(policeURL string, cookies []string) (*cookiejar.Jar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(cookies) == 0 {
+		return jar, nil
+	}
+	u, err := url.Parse(policeURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid POLICE_URL %q", policeURL)
+	}
+	var parsed []*http.Cookie
+	for _, entry := range cookies {
+		name, value, ok := strings.Cut(entry, "=")
+		cookie := &http.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value), Path: "/"}
+		if !ok || cookie.Valid() != nil {
+			return nil, fmt.Errorf("invalid SCRAPE_COOKIES entry %q, expected name=value", entry)
+		}
+		parsed = append(parsed, cookie)
+	}
+	jar.SetCookies(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}, parsed)
+	return jar, nil
+}
+
+// cookieJar returns the jar of the app's client, which colly shares, or nil
+// if it has none.
+func (a *App) cookieJar() http.CookieJar {
+	if client, ok := a.client.(*http.Client); ok {
+		return client.Jar
+	}
+	return nil
+}
+
+// prepareSession requests the SCRAPE_SESSION_URLS in order, so the cookies
+// they set are sent with the list and detail pages of the run. Failures are
+// logged only: the run still works, if maybe with less text.
+func (a *App) prepareSession(ctx context.Context) {
+	userAgent := a.userAgents()[0]
+	for _, sessionURL := range a.config.ScrapeSessionURLs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, sessionURL, nil)
+		if err != nil {
+			log.Println("Error preparing scrape session:", err)
+			continue
+		}
+		req.Header.Set("User-Agent", userAgent)
+		res, err := a.client.Do(req)
+		if err != nil {
+			log.Println("Error preparing scrape session:", err)
+			continue
+		}
+		drainBody(res.Body)
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			log.Printf("Error preparing scrape session: %s responded with %s", sessionURL, res.Status)
+		}
+	}
+}
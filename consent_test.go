@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNewScrapeJar(t *testing.T) {
+	jar, err := newScrapeJar("https://www.berlin.de/polizei/polizeimeldungen/", []string{"consent=all", " banner = hidden "})
+	if err != nil {
+		t.Fatalf("newScrapeJar failed: %v", err)
+	}
+	u, _ := url.Parse("https://www.berlin.de/polizei/polizeimeldungen/2026/pressemitteilung.1001.php")
+	cookies := jar.Cookies(u)
+	if len(cookies) != 2 || cookies[0].String() != "consent=all" || cookies[1].String() != "banner=hidden" {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	other, _ := url.Parse("https://example.com/")
+	if len(jar.Cookies(other)) != 0 {
+		t.Fatal("expected the cookies to be sent to berlin.de only")
+	}
+
+	for _, cookies := range [][]string{{"consent"}, {"=all"}, {"con sent=all"}} {
+		if _, err := newScrapeJar("https://www.berlin.de/", cookies); err == nil {
+			t.Errorf("expected an error for %q", cookies)
+		}
+	}
+}
+
+func TestPrepareSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/consent":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+		case "/report":
+			consent, err1 := r.Cookie("consent")
+			_, err2 := r.Cookie("session")
+			if err1 != nil || err2 != nil || consent.Value != "all" {
+				fmt.Fprintln(w, `<html><head><meta name="description" content="Kurzfassung"></head><body><div id="consent">Bitte zustimmen</div></body></html>`)
+				return
+			}
+			fmt.Fprintln(w, `<html><head><meta name="description" content="Kurzfassung"></head><body><div class="textile"><p>Der ganze Bericht.</p></div></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	config := Config{PoliceURL: server.URL + "/", ScrapeCookies: []string{"consent=all"}}
+	client, err := newScraperClient(config)
+	if err != nil {
+		t.Fatalf("newScraperClient failed: %v", err)
+	}
+	app := newTestApp(t)
+	app.client = client
+	app.config.ScrapeSessionURLs = []string{server.URL + "/consent"}
+	ctx := context.Background()
+
+	page, _, err := fetchDetailAttempt(ctx, client, server.URL+"/report", defaultUserAgent)
+	if err != nil || page.HasText {
+		t.Fatalf("expected the consent page before preparing the session, got %v %+v", err, page)
+	}
+	app.prepareSession(ctx)
+	page, _, err = fetchDetailAttempt(ctx, client, server.URL+"/report", defaultUserAgent)
+	if err != nil || !page.HasText {
+		t.Fatalf("expected the report text after preparing the session, got %v %+v", err, page)
+	}
+	if app.cookieJar() != client.Jar {
+		t.Fatal("expected colly to share the client's cookie jar")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Corrections follow up on events that changed after they were sent, so that
+// no stale posts are left out there.
+const (
+	// correctionUpdated is sent when an event was edited or amended.
+	correctionUpdated = model.PayloadUpdated
+	// correctionDeleted is sent when an event was taken down.
+	correctionDeleted = model.PayloadDeleted
+)
+
+// Correction tells a target that an event it was sent changed or is gone.
+type Correction struct {
+	Type string
+	// Event is the current version of the event; only its hash is set for
+	// event.deleted.
+	Event Event
+	// Changes names the changed fields of event.updated, e.g. "title".
+	Changes []string
+	// Emoji is the emoji prefix of the event for targets listed in
+	// NOTIFY_EMOJI, see Notification.Emoji.
+	Emoji string
+	// MessageID is the ID the target gave the original message, if it
+	// returned one (see Notification.receipt).
+	MessageID string
+}
+
+// correctingNotifier is implemented by targets that can update or withdraw
+// what they were sent.
+type correctingNotifier interface {
+	Correct(ctx context.Context, correction Correction) error
+}
+
+// NotificationReceipt remembers the ID a target gave the message about an
+// event, e.g. a Telegram message ID, for correcting it later.
+type NotificationReceipt struct {
+	ID        uint   `gorm:"primaryKey"`
+	Target    string `gorm:"uniqueIndex:idx_receipt;not null"`
+	EventHash string `gorm:"uniqueIndex:idx_receipt;not null"`
+	MessageID string
+	CreatedAt time.Time
+}
+
+// SaveReceipt stores the receipt, replacing an earlier one of a replay.
+func (s *gormStore) SaveReceipt(ctx context.Context, receipt *NotificationReceipt) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "target"}, {Name: "event_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"message_id"}),
+	}).Create(receipt).Error
+}
+
+func (s *gormStore) Receipt(ctx context.Context, target, hash string) (*NotificationReceipt, error) {
+	var receipt NotificationReceipt
+	err := s.db.WithContext(ctx).First(&receipt, "target = ? AND event_hash = ?", target, hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// receipts returns the function recording the message IDs a target reports
+// for the events it was sent.
+func (a *App) receipts(ctx context.Context, notifier Notifier) func(hash, messageID string) {
+	return func(hash, messageID string) {
+		err := a.store.SaveReceipt(context.WithoutCancel(ctx), &NotificationReceipt{Target: notifier.Name(), EventHash: hash, MessageID: messageID})
+		if err != nil {
+			log.Printf("Error saving receipt of %s for %s: %v", notifier.Name(), hash, err)
+		}
+	}
+}
+
+// posted returns the function looking up the message IDs a target reported
+// for events it was sent before, see Notification.posted.
+func (a *App) posted(ctx context.Context, notifier Notifier) func(hash string) string {
+	return func(hash string) string {
+		receipt, err := a.store.Receipt(context.WithoutCancel(ctx), notifier.Name(), hash)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Error loading receipt of %s for %s: %v", notifier.Name(), hash, err)
+			}
+			return ""
+		}
+		return receipt.MessageID
+	}
+}
+
+// correct sends a correction to every target that supports them, in the
+// background like notifyInBackground, as corrections are sent from requests
+// and scrape runs which must not wait for slow targets. Unlike
+// notifications, corrections aren't retried or parked as dead letters: a
+// failed one leaves the post as it was, and the next change corrects it
+// again.
+func (a *App) correct(ctx context.Context, correction Correction) {
+	ctx = context.WithoutCancel(ctx)
+	if correction.Type != correctionDeleted {
+		correction.Event = *a.redactions.event(&correction.Event)
+	}
+	for _, notifier := range a.notifiers {
+		target, ok := notifier.(correctingNotifier)
+		if !ok {
+			continue
+		}
+		a.deliveries.Go(func() {
+			a.correctTarget(ctx, notifier, target, correction)
+		})
+	}
+}
+
+// correctTarget sends a correction to one target. It takes turns with the
+// deliveries to the target (see notifyTarget), so that a correction never
+// overtakes the message it corrects.
+func (a *App) correctTarget(ctx context.Context, notifier Notifier, target correctingNotifier, correction Correction) {
+	lock, _ := a.targetLocks.LoadOrStore(notifier.Name(), new(sync.Mutex))
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if correction.Type != correctionDeleted {
+		correction.Emoji = a.emoji(notifier, []Event{correction.Event})[correction.Event.Hash]
+	}
+	receipt, err := a.store.Receipt(ctx, notifier.Name(), correction.Event.Hash)
+	if err == nil {
+		correction.MessageID = receipt.MessageID
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error loading receipt of %s for %s: %v", notifier.Name(), correction.Event.Hash, err)
+	}
+	correctCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	err = target.Correct(correctCtx, correction)
+	cancel()
+	if err != nil {
+		log.Printf("Error sending %s of %s to %s: %v", correction.Type, correction.Event.Hash, notifier.Name(), err)
+	}
+}
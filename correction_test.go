@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// correctingRecorder records corrections and hands out a message ID for every
+// event it is notified about.
+type correctingRecorder struct {
+	recordingNotifier
+	corrections []Correction
+}
+
+func (n *correctingRecorder) Notify(ctx context.Context, notification Notification) error {
+	err := n.recordingNotifier.Notify(ctx, notification)
+	if err == nil && notification.receipt != nil {
+		notification.receipt(notification.Event.Hash, "msg-"+notification.Event.Hash)
+	}
+	return err
+}
+
+func (n *correctingRecorder) Correct(_ context.Context, correction Correction) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.corrections = append(n.corrections, correction)
+	return nil
+}
+
+func TestCorrect_RefersToReceipt(t *testing.T) {
+	app := newTestApp(t)
+	target := &correctingRecorder{recordingNotifier: recordingNotifier{name: "correcting"}}
+	plain := &recordingNotifier{name: "plain"}
+	app.notifiers = []Notifier{target, plain}
+	ctx := context.Background()
+
+	event := Event{Title: "Brand", Hash: "h1", DateTime: 1}
+	app.notify(ctx, app.notifiers, []Event{event}, false)
+
+	event.Title = "Brand in Mitte"
+	app.correct(ctx, Correction{Type: correctionUpdated, Event: event, Changes: []string{"title"}})
+	app.deliveries.Wait()
+	app.correct(ctx, Correction{Type: correctionDeleted, Event: Event{Hash: "unsent"}})
+	app.deliveries.Wait()
+
+	if len(target.corrections) != 2 {
+		t.Fatalf("expected 2 corrections, got %+v", target.corrections)
+	}
+	updated := target.corrections[0]
+	if updated.Type != correctionUpdated || updated.Event.Title != "Brand in Mitte" || updated.MessageID != "msg-h1" {
+		t.Fatalf("unexpected update: %+v", updated)
+	}
+	if deleted := target.corrections[1]; deleted.Type != correctionDeleted || deleted.MessageID != "" {
+		t.Fatalf("unexpected deletion of an event without receipt: %+v", deleted)
+	}
+}
+
+func TestEventPatch_SendsCorrection(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	target := &correctingRecorder{recordingNotifier: recordingNotifier{name: "correcting"}}
+	app.notifiers = []Notifier{target}
+	if err := app.store.Create(context.Background(), &Event{Title: "Alt", Location: "Mitte", Hash: "h1", DateTime: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res := patchEvent(t, server.URL+"/api/events/h1", "secret", `{"title":"Neu","district":"Pankow"}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	app.deliveries.Wait()
+	if len(target.corrections) != 1 {
+		t.Fatalf("expected one correction, got %+v", target.corrections)
+	}
+	correction := target.corrections[0]
+	if correction.Type != correctionUpdated || correction.Event.Title != "Neu" || !reflect.DeepEqual(correction.Changes, []string{"title", "district"}) {
+		t.Fatalf("unexpected correction: %+v", correction)
+	}
+}
+
+func TestWebhookNotifier_Correct(t *testing.T) {
+	var payloads []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload failed: %v", err)
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	notifier, err := newWebhookNotifier(server.URL)
+	if err != nil {
+		t.Fatalf("newWebhookNotifier failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := notifier.Correct(ctx, Correction{Type: correctionUpdated, Event: Event{Title: "Neu", Hash: "h1"}, Changes: []string{"title"}}); err != nil {
+		t.Fatalf("Correct failed: %v", err)
+	}
+	if err := notifier.Correct(ctx, Correction{Type: correctionDeleted, Event: Event{Hash: "h2"}}); err != nil {
+		t.Fatalf("Correct failed: %v", err)
+	}
+
+	if len(payloads) != 2 {
+		t.Fatalf("expected 2 payloads, got %d", len(payloads))
+	}
+	if updated := payloads[0]; updated.Type != "event.updated" || updated.Event == nil || updated.Event.Title != "Neu" || !reflect.DeepEqual(updated.Changes, []string{"title"}) {
+		t.Fatalf("unexpected event.updated payload: %+v", updated)
+	}
+	if deleted := payloads[1]; deleted.Type != "event.deleted" || deleted.Hash != "h2" || deleted.Event != nil {
+		t.Fatalf("unexpected event.deleted payload: %+v", deleted)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openDatabase opens the database described by dsn. postgres:// and
+// postgresql:// URLs select the Postgres backend, anything else (optionally
+// prefixed with sqlite:) is treated as the path of a SQLite database file.
+func openDatabase(dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		dialector = postgres.Open(dsn)
+	default:
+		dialector = sqlite.Open(strings.TrimPrefix(dsn, "sqlite:"))
+	}
+
+	return gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+}
+
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// sqliteBusyTimeout is how long, in milliseconds, a SQLite connection waits
+// for a lock before failing with SQLITE_BUSY.
+const sqliteBusyTimeout = 5000
+
+// openServerDatabase checks the integrity of SQLite databases (see
+// ensureIntegrity) and opens the database like openDatabase, but with separate
+// connection pools for SQLite: a single writer (DATABASE_WRITE_CONNS) with
+// transactions taking the write lock up front, and a pool of read-only
+// connections (DATABASE_READ_CONNS) that serves all queries outside of
+// transactions. With the write-ahead log, reads of the web server neither
+// block the scraper's writes nor wait for them.
+func openServerDatabase(config Config) (*gorm.DB, error) {
+	dsn := config.DatabaseURL
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || strings.Contains(dsn, ":memory:") {
+		return openConfiguredDatabase(config)
+	}
+	path := strings.TrimPrefix(dsn, "sqlite:")
+	err := ensureIntegrity(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openDatabase(sqliteParams(path, fmt.Sprintf("_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate", sqliteBusyTimeout)))
+	if err != nil {
+		return nil, err
+	}
+	writer, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(config.DatabaseWriteConns)
+
+	reader, err := sql.Open("sqlite3", sqliteParams(path, fmt.Sprintf("_query_only=1&_busy_timeout=%d", sqliteBusyTimeout)))
+	if err != nil {
+		return nil, err
+	}
+	reader.SetMaxOpenConns(config.DatabaseReadConns)
+	reader.SetMaxIdleConns(config.DatabaseReadConns)
+
+	// Transactions have their own connection pool, a *sql.Tx of the writer,
+	// so they keep reading what they wrote.
+	useReader := func(tx *gorm.DB) {
+		if pool, ok := tx.Statement.ConnPool.(*sql.DB); ok && pool == writer {
+			tx.Statement.ConnPool = reader
+		}
+	}
+	err = db.Callback().Query().Before("gorm:query").Register("policefeed:read_pool", useReader)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Callback().Row().Before("gorm:row").Register("policefeed:read_pool", useReader)
+	if err != nil {
+		return nil, err
+	}
+	err = useDatabaseKey(db, config.DatabaseKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// openConfiguredDatabase opens the database of config like openDatabase,
+// encrypted with the key in DATABASE_KEY_FILE if set.
+func openConfiguredDatabase(config Config) (*gorm.DB, error) {
+	db, err := openDatabase(config.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	err = useDatabaseKey(db, config.DatabaseKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// sqliteParams appends connection parameters to a SQLite file name.
+func sqliteParams(path, params string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + params
+	}
+	return path + "?" + params
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenServerDatabase_SplitsPools(t *testing.T) {
+	config := Config{DatabaseURL: "sqlite:" + filepath.Join(t.TempDir(), "events.db"), DatabaseReadConns: 2, DatabaseWriteConns: 1}
+	db, err := openServerDatabase(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	store, err := NewGormStore(db)
+	if err != nil {
+		t.Fatalf("migrating through the writer failed: %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Create(ctx, &Event{Title: "Raub", Hash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// Reads go through the read-only pool and see committed writes.
+	if event, err := store.FindByHash(ctx, "a"); err != nil || event.Title != "Raub" {
+		t.Fatalf("unexpected read %+v %v", event, err)
+	}
+	var mode string
+	if err := db.Raw("PRAGMA journal_mode").Scan(&mode).Error; err != nil || mode != "wal" {
+		t.Fatalf("expected the write-ahead log, got %q %v", mode, err)
+	}
+	// Writes that are routed to the reader are refused.
+	if err := db.Raw("DELETE FROM events RETURNING hash").Scan(&[]Event{}).Error; err == nil {
+		t.Fatal("expected the read pool to be read-only")
+	}
+	if _, err := store.FindByHash(ctx, "a"); err != nil {
+		t.Fatal("expected the event to be kept")
+	}
+}
+
+func TestDatabaseConnsEnv_RejectsZero(t *testing.T) {
+	t.Setenv("DATABASE_WRITE_CONNS", "0")
+	t.Setenv("DATABASE_READ_CONNS", "0")
+	config := loadConfig()
+	if config.DatabaseWriteConns != 1 || config.DatabaseReadConns != 4 {
+		t.Fatalf("expected the defaults instead of unlimited pools, got %d writers and %d readers", config.DatabaseWriteConns, config.DatabaseReadConns)
+	}
+}
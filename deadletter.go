@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const auditActionRedeliver = "notification.redeliver"
+
+// DeadLetter is a notification that could not be delivered even after all
+// retries. It is kept until an admin redelivers it.
+type DeadLetter struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+	Target    string
+	EventHash string
+	Replay    bool
+	Attempts  int
+	LastError string
+	// RedeliveredAt is set once a redelivery succeeded.
+	RedeliveredAt *time.Time
+}
+
+func (s *gormStore) SaveDeadLetter(ctx context.Context, letter *DeadLetter) error {
+	return s.db.WithContext(ctx).Save(letter).Error
+}
+
+func (s *gormStore) FindDeadLetter(ctx context.Context, id uint) (*DeadLetter, error) {
+	var letter DeadLetter
+	err := s.db.WithContext(ctx).First(&letter, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// DeadLetterFilter narrows down the dead letters returned by DeadLetters.
+type DeadLetterFilter struct {
+	// IncludeRedelivered adds the successfully redelivered ones.
+	IncludeRedelivered bool
+	Limit              int
+	// After leaves out the dead letters up to the cursor.
+	After *pageCursor
+}
+
+// DeadLetters returns the matching dead letters, newest first.
+func (s *gormStore) DeadLetters(ctx context.Context, filter DeadLetterFilter) ([]DeadLetter, error) {
+	query := s.db.WithContext(ctx).Order("id DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if !filter.IncludeRedelivered {
+		query = query.Where("redelivered_at IS NULL")
+	}
+	if filter.After != nil {
+		// IDs grow with the time of the dead letters, so they order them
+		// alone.
+		id, err := filter.After.rowID()
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", id)
+	}
+	var letters []DeadLetter
+	err := query.Find(&letters).Error
+	return letters, err
+}
+
+// deliver sends a notification, retrying with the configured delays. It
+// returns the number of attempts made and the last error. The whole delivery
+// is bounded by the time all attempts may take, as notify runs it on a
+// context that is never cancelled.
+func (a *App) deliver(ctx context.Context, notifier Notifier, notification Notification) (int, error) {
+	budget := time.Duration(len(a.config.NotifyRetryDelays)+1) * notifyTimeout
+	for _, delay := range a.config.NotifyRetryDelays {
+		budget += delay
+	}
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	attempts := 0
+	for {
+		attempts++
+		notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err := notifier.Notify(notifyCtx, notification)
+		cancel()
+		if err == nil || attempts > len(a.config.NotifyRetryDelays) {
+			return attempts, err
+		}
+		log.Printf("Error notifying %s (attempt %d), retrying: %v", notifier.Name(), attempts, err)
+		if sleepContext(ctx, a.config.NotifyRetryDelays[attempts-1]) != nil {
+			return attempts, err
+		}
+	}
+}
+
+// deadLetter parks a notification that failed for good.
+func (a *App) deadLetter(ctx context.Context, notifier Notifier, notification Notification, attempts int, cause error) {
+	err := a.store.SaveDeadLetter(context.WithoutCancel(ctx), &DeadLetter{
+		Target:    notifier.Name(),
+		EventHash: notification.Event.Hash,
+		Replay:    notification.Replay,
+		Attempts:  attempts,
+		LastError: cause.Error(),
+	})
+	if err != nil {
+		log.Printf("Error saving dead letter for %s about %s: %v", notifier.Name(), notification.Event.Hash, err)
+	}
+}
+
+type apiDeadLetter struct {
+	ID            uint       `json:"id"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	Target        string     `json:"target"`
+	EventHash     string     `json:"eventHash"`
+	Replay        bool       `json:"replay,omitempty"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError"`
+	RedeliveredAt *time.Time `json:"redeliveredAt,omitempty"`
+}
+
+func toAPIDeadLetter(letter *DeadLetter) apiDeadLetter {
+	return apiDeadLetter{
+		ID:            letter.ID,
+		CreatedAt:     letter.CreatedAt,
+		Target:        letter.Target,
+		EventHash:     letter.EventHash,
+		Replay:        letter.Replay,
+		Attempts:      letter.Attempts,
+		LastError:     letter.LastError,
+		RedeliveredAt: letter.RedeliveredAt,
+	}
+}
+
+func (a *App) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter := DeadLetterFilter{IncludeRedelivered: r.URL.Query().Get("all") == "true", Limit: 100}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = n
+	}
+	cursor, err := queryCursor(r.URL.Query())
+	if err == nil && cursor != nil {
+		_, err = cursor.rowID()
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.After = cursor
+
+	limit := filter.Limit
+	filter.Limit++
+	letters, err := a.store.DeadLetters(r.Context(), filter)
+	if err != nil {
+		log.Println("Error loading dead letters:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if len(letters) > limit {
+		letters = letters[:limit]
+		last := letters[limit-1]
+		setNextPage(w, r, pageCursor{at: last.CreatedAt.Unix(), id: strconv.FormatUint(uint64(last.ID), 10)})
+	}
+	response := make([]apiDeadLetter, 0, len(letters))
+	for i := range letters {
+		response = append(response, toAPIDeadLetter(&letters[i]))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleRedeliver sends a dead letter again, once and without further
+// retries. The current version of the event is sent.
+func (a *App) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "dead letter not found")
+		return
+	}
+	letter, err := a.store.FindDeadLetter(r.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "dead letter not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading dead letter:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if letter.RedeliveredAt != nil {
+		writeJSONError(w, http.StatusConflict, "dead letter was already redelivered")
+		return
+	}
+
+	var notifier Notifier
+	for _, n := range a.notifiers {
+		if n.Name() == letter.Target {
+			notifier = n
+		}
+	}
+	if notifier == nil {
+		writeJSONError(w, http.StatusConflict, "target "+letter.Target+" is no longer configured")
+		return
+	}
+	event, err := a.store.FindByHash(r.Context(), letter.EventHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusConflict, "event no longer exists")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	summaries := a.summarize(r.Context(), []Event{*event})
+	notifyCtx, cancel := context.WithTimeout(r.Context(), notifyTimeout)
+	shortLinks := a.shortLinks(r.Context(), notifier, []Event{*event})
+	err = notifier.Notify(notifyCtx, Notification{Event: *event, Replay: letter.Replay, Summaries: summaries, ShortLinks: shortLinks, receipt: a.receipts(r.Context(), notifier), posted: a.posted(r.Context(), notifier)})
+	cancel()
+	letter.Attempts++
+	if err != nil {
+		letter.LastError = err.Error()
+	} else {
+		now := time.Now().UTC()
+		letter.RedeliveredAt = &now
+	}
+	saveErr := a.store.SaveDeadLetter(r.Context(), letter)
+	if saveErr != nil {
+		log.Println("Error updating dead letter:", saveErr)
+	}
+	a.audit(r.Context(), auditActionRedeliver, strconv.FormatUint(uint64(letter.ID), 10), map[string]any{
+		"target":  letter.Target,
+		"event":   letter.EventHash,
+		"success": err == nil,
+	})
+
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, "redelivery failed: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIDeadLetter(letter))
+}
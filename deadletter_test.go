@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify_RetriesThenDeadLetters(t *testing.T) {
+	app := newTestApp(t)
+	app.config.NotifyRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	down := &recordingNotifier{name: "webhook:down", err: errors.New("connection refused")}
+	up := &recordingNotifier{name: "webhook:up"}
+
+	sent, failed := app.notify(context.Background(), []Notifier{down, up}, []Event{{Hash: "h1"}}, false)
+	if sent != 1 || failed != 1 {
+		t.Fatalf("expected 1 sent and 1 failed, got %d and %d", sent, failed)
+	}
+
+	letters, err := app.store.DeadLetters(context.Background(), DeadLetterFilter{})
+	if err != nil {
+		t.Fatalf("DeadLetters failed: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Target != "webhook:down" || letters[0].EventHash != "h1" || letters[0].Attempts != 3 {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+}
+
+// blockingNotifier hangs until released, like a target that stopped
+// answering.
+type blockingNotifier struct {
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Name() string { return "webhook:hanging" }
+
+func (n *blockingNotifier) Notify(ctx context.Context, _ Notification) error {
+	select {
+	case <-n.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestStoreEvents_NotifiesInBackground(t *testing.T) {
+	app := newTestApp(t)
+	hanging := &blockingNotifier{release: make(chan struct{})}
+	up := &recordingNotifier{name: "webhook:up"}
+	app.notifiers = []Notifier{hanging, up}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, created, _ := app.storeEvents(ctx, []Event{{Title: "Brand", Hash: "h1", DateTime: 1}}, map[string]bool{})
+	if len(created) != 1 {
+		t.Fatalf("expected the event to be created, got %+v", created)
+	}
+	// The scrape run is over; its deliveries must not be cut short.
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		up.mu.Lock()
+		got := len(up.sent)
+		up.mu.Unlock()
+		if got == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the hanging target held up the other one")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(hanging.release)
+	app.deliveries.Wait()
+	letters, err := app.store.DeadLetters(context.Background(), DeadLetterFilter{})
+	if err != nil || len(letters) != 0 {
+		t.Fatalf("expected no dead letters, got %+v (%v)", letters, err)
+	}
+}
+
+func TestDeadLetters_Redeliver(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	target := &recordingNotifier{name: "webhook:flaky", err: errors.New("503")}
+	app.notifiers = []Notifier{target}
+
+	event := Event{Title: "Brand", Hash: "h1", DateTime: 1}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	app.notify(context.Background(), app.notifiers, []Event{event}, false)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/dead-letters", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/dead-letters failed: %v", err)
+	}
+	var letters []apiDeadLetter
+	err = json.NewDecoder(res.Body).Decode(&letters)
+	_ = res.Body.Close()
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected one dead letter, got %+v (%v)", letters, err)
+	}
+	redeliverURL := server.URL + "/api/dead-letters/" + strconv.FormatUint(uint64(letters[0].ID), 10) + "/redeliver"
+
+	res = postJSON(t, redeliverURL, "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 while the target is down, got %d", res.StatusCode)
+	}
+
+	target.err = nil
+	res = postJSON(t, redeliverURL, "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || len(target.sent) != 1 {
+		t.Fatalf("expected successful redelivery, got %d with %d sent", res.StatusCode, len(target.sent))
+	}
+
+	res = postJSON(t, redeliverURL, "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for a redelivered letter, got %d", res.StatusCode)
+	}
+
+	pending, _ := app.store.DeadLetters(context.Background(), DeadLetterFilter{})
+	if len(pending) != 0 {
+		t.Fatalf("redelivered letter is still listed: %+v", pending)
+	}
+}
+
+func TestStoreEvents_NotifiesOnlyBerlinEvents(t *testing.T) {
+	app := newTestApp(t)
+	target := &recordingNotifier{name: "webhook:berlin"}
+	app.notifiers = []Notifier{target}
+	events, cancel := app.live.subscribe()
+	defer cancel()
+
+	app.storeEvents(context.Background(), []Event{
+		{Title: "Brand in Potsdam", Hash: "bb", Source: "brandenburg", DateTime: 1},
+		{Title: "S-Bahn gestört", Hash: "sb", Source: "sbahn", DateTime: 1},
+		{Title: "Brand in Mitte", Hash: "be", DateTime: 1},
+	}, map[string]bool{})
+	app.deliveries.Wait()
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if len(target.sent) != 1 || target.sent[0].Event.Hash != "be" {
+		t.Fatalf("expected only the Berlin event to be sent, got %+v", target.sent)
+	}
+	if batch := <-events; len(batch) != 1 || batch[0].Hash != "be" {
+		t.Fatalf("expected only the Berlin event to be published, got %+v", batch)
+	}
+}
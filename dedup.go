@@ -0,0 +1,140 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDedupCacheSize is the number of recent event hashes kept in memory.
+// It comfortably covers the listing page, so that a regular scrape run only
+// hits the database for events it hasn't seen before.
+const defaultDedupCacheSize = 1000
+
+// hashCache remembers the most recently seen event identity hashes, evicting the least
+// recently used one once it is full.
+type hashCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newHashCache(size int) *hashCache {
+	if size <= 0 {
+		size = defaultDedupCacheSize
+	}
+	return &hashCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Contains reports whether hash is cached, marking it as recently used.
+func (c *hashCache) Contains(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.items[hash]
+	if ok {
+		c.order.MoveToFront(element)
+	}
+	return ok
+}
+
+// Add caches the hashes; the last one counts as the most recently used.
+func (c *hashCache) Add(hashes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, hash := range hashes {
+		if element, ok := c.items[hash]; ok {
+			c.order.MoveToFront(element)
+			continue
+		}
+		c.items[hash] = c.order.PushFront(hash)
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(string))
+		}
+	}
+}
+
+func (c *hashCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// loadHashCache fills a cache with the hashes of the newest events by
+// publication time, so that its content after a restart doesn't depend on
+// the order rows happen to come back from the database.
+func loadHashCache(ctx context.Context, store EventStore, size int) (*hashCache, error) {
+	cache := newHashCache(size)
+	events, err := store.Recent(ctx, EventFilter{}, cache.size)
+	if err != nil {
+		return nil, err
+	}
+	// Add oldest first, so that the newest events are the least likely to be
+	// evicted.
+	for i := len(events) - 1; i >= 0; i-- {
+		cache.Add(identityHash(&events[i]))
+	}
+	return cache, nil
+}
+
+// An event of a source overlapping berlin.de is taken for a report of
+// berlin.de published within duplicateWindow whose words it shares to at
+// least duplicateSimilarity.
+const (
+	duplicateWindow     = 24 * time.Hour
+	duplicateSimilarity = 0.5
+)
+
+// duplicateWords returns the stems of the words in the title and the first
+// sentence of event, leaving out words of three letters or less, which are
+// mostly articles and prepositions.
+func duplicateWords(event *Event) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range tokenize(strings.ToLower(event.Title + " " + firstSentence(event.Description))) {
+		if len([]rune(word)) > 3 {
+			words[germanStem(word)] = true
+		}
+	}
+	return words
+}
+
+// similarity returns the share of words two word sets have in common
+// (Jaccard index).
+func similarity(a, b map[string]bool) float64 {
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	if total := len(a) + len(b) - shared; total > 0 {
+		return float64(shared) / float64(total)
+	}
+	return 0
+}
+
+// findBerlinDuplicate returns the report of berlin.de that tells the same as
+// event, or nil if there is none. Reports of berlin.de are never dropped in
+// turn, so that one stored first stays.
+func (a *App) findBerlinDuplicate(ctx context.Context, event *Event) (*Event, error) {
+	published := time.Unix(event.DateTime, 0)
+	filter := EventFilter{From: published.Add(-duplicateWindow), To: published.Add(duplicateWindow), Sources: []string{sourceBerlin}}
+	words := duplicateWords(event)
+	var duplicate *Event
+	best := duplicateSimilarity
+	err := a.store.EachBatch(ctx, filter, exportBatchSize, func(batch []Event) error {
+		for i := range batch {
+			if s := similarity(words, duplicateWords(&batch[i])); s >= best {
+				// Copied, as the batch is reused.
+				found := batch[i]
+				best, duplicate = s, &found
+			}
+		}
+		return nil
+	})
+	return duplicate, err
+}
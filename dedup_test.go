@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestHashCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newHashCache(2)
+	cache.Add("a", "b")
+	cache.Contains("a")
+	cache.Add("c")
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 cached hashes, got %d", cache.Len())
+	}
+	if !cache.Contains("a") || !cache.Contains("c") {
+		t.Fatal("expected a and c to be cached")
+	}
+	if cache.Contains("b") {
+		t.Fatal("expected b to be evicted")
+	}
+}
+
+func TestLoadHashCache_NewestEvents(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+	ctx := context.Background()
+	// Insert out of order, so that insertion order can't be mistaken for age.
+	for _, i := range []int{3, 0, 4, 1, 2} {
+		event := Event{Title: "t", Hash: fmt.Sprintf("h%d", i), Link: fmt.Sprintf("https://www.berlin.de/%d", i), DateTime: int64(i)}
+		if err := store.Create(ctx, &event); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	cache, err := loadHashCache(ctx, store, 3)
+	if err != nil {
+		t.Fatalf("loadHashCache failed: %v", err)
+	}
+	cached := func(i int) bool {
+		return cache.Contains(identityHash(&Event{Link: fmt.Sprintf("https://www.berlin.de/%d", i)}))
+	}
+	for _, i := range []int{2, 3, 4} {
+		if !cached(i) {
+			t.Fatalf("expected event %d to be cached", i)
+		}
+	}
+	if cached(0) || cached(1) {
+		t.Fatal("expected old events not to be cached")
+	}
+	// The oldest loaded event is evicted first.
+	cache.Add("new")
+	if cached(2) {
+		t.Fatal("expected event 2 to be evicted first")
+	}
+}
+
+func TestScrape_NoDuplicatesAfterRestart(t *testing.T) {
+	app := newFixtureApp(t)
+	ctx := context.Background()
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	if app.seen.Len() != 6 {
+		t.Fatal("expected scraped events to be cached")
+	}
+
+	// A restarted app with a cache too small for all events still finds the
+	// evicted ones in the database.
+	config := app.config
+	config.DedupCacheSize = 1
+	client := &http.Client{Transport: fixtureTransport{dir: config.SourceDir}}
+	restarted, err := NewApp(ctx, config, client, app.store, NewFeedBuilder(config.PoliceURL))
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	if restarted.seen.Len() != 1 {
+		t.Fatalf("expected 1 cached hash after restart, got %d", restarted.seen.Len())
+	}
+	if err := restarted.scrape(ctx); err != nil {
+		t.Fatalf("scrape after restart failed: %v", err)
+	}
+	events, err := restarted.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events after restart, got %d", len(events))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// severityEmoji marks events of high severity.
+const severityEmoji = "🚨"
+
+// categoryEmoji makes the categories of the default rules recognizable at a
+// glance in chat channels and feed readers.
+var categoryEmoji = map[string]string{
+	"gewalt":       "🔪",
+	"brand":        "🔥",
+	"verkehr":      "🚗",
+	"eigentum":     "💰",
+	"vermisst":     "🔍",
+	"sexualdelikt": "⚠️",
+	"drogen":       "💊",
+}
+
+// emojiPrefix returns the emoji for a classification, e.g. "🚨🔪" for a
+// high severity act of violence, or an empty string if none applies. Only
+// the first category with an emoji is shown.
+func emojiPrefix(classification Classification) string {
+	var prefix string
+	if classification.Severity == severityHigh {
+		prefix = severityEmoji
+	}
+	for _, category := range classification.Categories {
+		if emoji, ok := categoryEmoji[strings.ToLower(category)]; ok {
+			return prefix + emoji
+		}
+	}
+	return prefix
+}
+
+// withEmoji prefixes title with the emoji for the classification.
+func withEmoji(title string, classification Classification) string {
+	return emojiTitle(emojiPrefix(classification), title)
+}
+
+// emojiTitle prefixes title with prefix, if there is one.
+func emojiTitle(prefix, title string) string {
+	if prefix == "" {
+		return title
+	}
+	return prefix + " " + title
+}
+
+// wantsEmoji reports whether the titles sent to target get emoji prefixes,
+// see NOTIFY_EMOJI. Targets are named as in NOTIFY_QUIET_HOURS, by the names
+// logged at startup.
+func (c Config) wantsEmoji(target string) bool {
+	return slices.Contains(c.NotifyEmoji, target) || slices.Contains(c.NotifyEmoji, "*")
+}
+
+// emoji returns the emoji prefixes of events by hash for the text renderers
+// of notifier, see Notification.Emoji, or nil if it doesn't want them.
+func (a *App) emoji(notifier Notifier, events []Event) map[string]string {
+	if len(events) == 0 || !a.config.wantsEmoji(notifier.Name()) {
+		return nil
+	}
+	rules := a.rules()
+	prefixes := make(map[string]string, len(events))
+	for i := range events {
+		if prefix := emojiPrefix(classifyEvent(rules, &events[i])); prefix != "" {
+			prefixes[events[i].Hash] = prefix
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEmojiPrefix(t *testing.T) {
+	for _, c := range []struct {
+		title, want string
+	}{
+		{"Schüsse in Neukölln", "🚨🔪 Schüsse in Neukölln"},
+		{"Messerangriff am Alexanderplatz", "🔪 Messerangriff am Alexanderplatz"},
+		{"Verkehrsunfall auf der A100", "🚗 Verkehrsunfall auf der A100"},
+		{"Explosion in Lagerhalle", "🚨🔥 Explosion in Lagerhalle"},
+		{"Person nach Suizidversuch gerettet", "Person nach Suizidversuch gerettet"},
+		{"Pressemitteilung", "Pressemitteilung"},
+	} {
+		if got := withEmoji(c.title, classifyText(defaultClassificationRules, c.title)); got != c.want {
+			t.Errorf("withEmoji(%q) = %q, want %q", c.title, got, c.want)
+		}
+	}
+}
+
+func TestNotify_EmojiPerTarget(t *testing.T) {
+	app := newTestApp(t)
+	app.config.NotifyEmoji = []string{"chat"}
+	chat := &recordingNotifier{name: "chat"}
+	webhook := &recordingNotifier{name: "webhook:example.org"}
+
+	event := Event{Title: "Brand in Wohnhaus", Hash: "fire", DateTime: 1}
+	app.notify(context.Background(), []Notifier{chat, webhook}, []Event{event}, false)
+
+	if len(chat.sent) != 1 || chat.sent[0].Emoji["fire"] != "🔥" || chat.sent[0].Event.Title != "Brand in Wohnhaus" {
+		t.Fatalf("expected an emoji prefix for chat beside the unchanged event, got %+v", chat.sent)
+	}
+	if title, _, _ := appriseMessage(chat.sent[0]); title != "🔥 Brand in Wohnhaus" {
+		t.Fatalf("expected the emoji before the title of the message, got %q", title)
+	}
+	if len(webhook.sent) != 1 || webhook.sent[0].Emoji != nil || webhook.sent[0].Event.Title != "Brand in Wohnhaus" {
+		t.Fatalf("expected the plain title for the webhook, got %+v", webhook.sent)
+	}
+}
+
+func TestFeedVariants_Emoji(t *testing.T) {
+	app := newTestApp(t)
+	var err error
+	app.feedVariants, err = loadFeedVariants(writeFeedVariants(t, `[{"name": "bunt", "emoji": true}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := Event{Title: "Brand in Wohnhaus", Hash: "fire", DateTime: 1}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatal(err)
+	}
+	app.feed.Add(event)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	for path, want := range map[string]string{
+		"/f/bunt.rss": "<title>🔥 Brand in Wohnhaus</title>",
+		"/rss":        "<title>Brand in Wohnhaus</title>",
+	} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in %s:\n%s", want, path, body)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// With DATABASE_KEY_FILE set, the texts of events (titles, descriptions,
+// edits, revisions, summaries and the scrape journal) and the payloads of
+// the audit log are stored encrypted, for
+// operators on shared hosts. SQLCipher would need cgo bindings to a
+// different SQLite build, so the columns are encrypted by the application
+// instead, through the "encrypted" and "encryptedjson" serializers. Hashes, districts, links and
+// times stay readable, as queries filter and sort by them.
+//
+// The nonce is derived from the text, so equal texts give equal ciphertexts
+// and comparisons in queries keep working (see storedTexts). This reveals
+// which events share a text, but nothing about the texts themselves.
+
+// encryptedPrefix marks encrypted values. Values without it are read as they
+// are, so a database can be switched to encryption without converting it
+// first; the encrypt-db command converts it anyway.
+const encryptedPrefix = "enc1:"
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+	schema.RegisterSerializer("encryptedjson", encryptedJSONSerializer{})
+}
+
+// columnEncryption is a gorm plugin binding a cipher to a database. gorm
+// serializers are global, so the cipher travels with the statements of that
+// database instead: they carry it in their context, where encryptedSerializer
+// finds it.
+type columnEncryption struct {
+	cipher *textCipher
+}
+
+type cipherContextKey struct{}
+
+func (columnEncryption) Name() string { return "column_encryption" }
+
+func (e columnEncryption) Initialize(db *gorm.DB) error {
+	bind := func(tx *gorm.DB) {
+		tx.Statement.Context = context.WithValue(tx.Statement.Context, cipherContextKey{}, e.cipher)
+	}
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("policefeed:encryption", bind),
+		callbacks.Query().Before("*").Register("policefeed:encryption", bind),
+		callbacks.Update().Before("*").Register("policefeed:encryption", bind),
+		callbacks.Delete().Before("*").Register("policefeed:encryption", bind),
+		callbacks.Row().Before("*").Register("policefeed:encryption", bind),
+		callbacks.Raw().Before("*").Register("policefeed:encryption", bind),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnCipher returns the cipher bound to db, nil if encryption is off.
+func columnCipher(db *gorm.DB) *textCipher {
+	plugin, ok := db.Config.Plugins[columnEncryption{}.Name()].(columnEncryption)
+	if !ok {
+		return nil
+	}
+	return plugin.cipher
+}
+
+// textCipher encrypts texts deterministically with AES-256-GCM.
+type textCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+func newTextCipher(key []byte) (*textCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the database key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(deriveKey(key, "policefeed column encryption"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &textCipher{aead: aead, nonceKey: deriveKey(key, "policefeed column nonce")}, nil
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func (c *textCipher) encrypt(text string) string {
+	mac := hmac.New(sha256.New, c.nonceKey)
+	mac.Write([]byte(text))
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+	sealed := c.aead.Seal(nonce, nonce, []byte(text), nil)
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+func (c *textCipher) decrypt(value string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	size := c.aead.NonceSize()
+	text, err := c.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value, wrong DATABASE_KEY_FILE?")
+	}
+	return string(text), nil
+}
+
+// readDatabaseKey reads a key of 32 bytes from path, given in hex, base64
+// or as raw bytes.
+func readDatabaseKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 32 {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s holds no key of 32 bytes in hex or base64, create one with: openssl rand -hex 32", path)
+}
+
+// useDatabaseKey turns on encryption for db with the key in path, or leaves
+// it off if path is empty.
+func useDatabaseKey(db *gorm.DB, path string) error {
+	if path == "" {
+		return nil
+	}
+	key, err := readDatabaseKey(path)
+	if err != nil {
+		return fmt.Errorf("reading DATABASE_KEY_FILE: %w", err)
+	}
+	c, err := newTextCipher(key)
+	if err != nil {
+		return err
+	}
+	return db.Use(columnEncryption{cipher: c})
+}
+
+// storedTexts returns the ways text may be stored in the encrypted columns
+// of db, for comparisons in queries. Rows written before encryption was
+// turned on keep the plain text until encrypt-db converts them, so both
+// forms are returned once a key is set.
+func storedTexts(db *gorm.DB, text string) []string {
+	c := columnCipher(db)
+	if c == nil || text == "" {
+		return []string{text}
+	}
+	return []string{text, c.encrypt(text)}
+}
+
+// encryptedSerializer encrypts string fields tagged serializer:encrypted.
+// Empty strings are stored as they are, so checks for missing texts work.
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	value, err := decryptColumn(ctx, field, dbValue)
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+// decryptColumn returns the text of a value read from an encrypted column.
+// Values without encryptedPrefix are returned as they are.
+func decryptColumn(ctx context.Context, field *schema.Field, dbValue any) (string, error) {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return "", fmt.Errorf("unexpected value of type %T in encrypted column %s", dbValue, field.DBName)
+	}
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	c, _ := ctx.Value(cipherContextKey{}).(*textCipher)
+	if c == nil {
+		return "", fmt.Errorf("column %s is encrypted, but DATABASE_KEY_FILE is not set", field.DBName)
+	}
+	text, err := c.decrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("column %s: %w", field.DBName, err)
+	}
+	return text, nil
+}
+
+func (encryptedSerializer) Value(ctx context.Context, _ *schema.Field, _ reflect.Value, fieldValue any) (any, error) {
+	text, _ := fieldValue.(string)
+	c, _ := ctx.Value(cipherContextKey{}).(*textCipher)
+	if c == nil || text == "" {
+		return text, nil
+	}
+	return c.encrypt(text), nil
+}
+
+// encryptedJSONSerializer stores fields tagged serializer:encryptedjson as
+// JSON like gorm's json serializer, encrypted like encryptedSerializer.
+type encryptedJSONSerializer struct{}
+
+func (encryptedJSONSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	if dbValue == nil {
+		return schema.JSONSerializer{}.Scan(ctx, field, dst, nil)
+	}
+	value, err := decryptColumn(ctx, field, dbValue)
+	if err != nil {
+		return err
+	}
+	return schema.JSONSerializer{}.Scan(ctx, field, dst, value)
+}
+
+func (encryptedJSONSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	value, err := schema.JSONSerializer{}.Value(ctx, field, dst, fieldValue)
+	if err != nil || value == nil {
+		return value, err
+	}
+	return encryptedSerializer{}.Value(ctx, field, dst, value)
+}
+
+// encryptTable rewrites the encrypted columns of all rows of T, so that
+// values stored before encryption was turned on are encrypted as well. It
+// returns the number of rows.
+func encryptTable[T any](ctx context.Context, db *gorm.DB, columns ...string) (int, error) {
+	db = db.WithContext(ctx).Unscoped()
+	var rows []T
+	count := 0
+	err := db.FindInBatches(&rows, exportBatchSize, func(*gorm.DB, int) error {
+		for i := range rows {
+			err := db.Model(&rows[i]).Select(columns).UpdateColumns(&rows[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		count += len(rows)
+		return nil
+	}).Error
+	return count, err
+}
+
+// encryptDatabase encrypts the texts in all tables, see encryptTable.
+func encryptDatabase(ctx context.Context, db *gorm.DB) error {
+	tables := []struct {
+		name    string
+		encrypt func() (int, error)
+	}{
+		{"events", func() (int, error) { return encryptTable[Event](ctx, db, "title", "description", "edits") }},
+		{"event_revisions", func() (int, error) { return encryptTable[EventRevision](ctx, db, "old", "new") }},
+		{"event_summaries", func() (int, error) { return encryptTable[EventSummary](ctx, db, "summary") }},
+		{"journal_entries", func() (int, error) { return encryptTable[JournalEntry](ctx, db, "title") }},
+		{"audit_log", func() (int, error) { return encryptTable[AuditEntry](ctx, db, "payload") }},
+	}
+	for _, table := range tables {
+		count, err := table.encrypt()
+		if err != nil {
+			return fmt.Errorf("encrypting %s: %w", table.name, err)
+		}
+		log.Printf("Encrypted %d rows of %s", count, table.name)
+	}
+	return nil
+}
+
+func runEncryptDB(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("encrypt-db", flag.ContinueOnError)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	config := loadConfig()
+	if config.DatabaseKeyFile == "" {
+		return errors.New("encrypt-db: DATABASE_KEY_FILE is not set")
+	}
+	db, err := openConfiguredDatabase(config)
+	if err != nil {
+		return err
+	}
+	_, err = NewGormStore(db)
+	if err != nil {
+		return err
+	}
+	return encryptDatabase(ctx, db)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// useTestKey turns on encryption for db with a key file holding key.
+func useTestKey(t *testing.T, db *gorm.DB, key string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := useDatabaseKey(db, path); err != nil {
+		t.Fatalf("useDatabaseKey failed: %v", err)
+	}
+}
+
+const testKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestTextCipher(t *testing.T) {
+	key, _ := hex.DecodeString(testKey)
+	c, err := newTextCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := c.encrypt("Raub in Neukölln")
+	if !strings.HasPrefix(encrypted, encryptedPrefix) || strings.Contains(encrypted, "Raub") {
+		t.Fatalf("expected an encrypted value, got %q", encrypted)
+	}
+	if again := c.encrypt("Raub in Neukölln"); again != encrypted {
+		t.Fatalf("expected equal texts to give equal values, got %q and %q", encrypted, again)
+	}
+	if text, err := c.decrypt(encrypted); err != nil || text != "Raub in Neukölln" {
+		t.Fatalf("expected the text back, got %q %v", text, err)
+	}
+
+	other, err := newTextCipher(bytes.Repeat([]byte{0xff}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.decrypt(encrypted); err == nil {
+		t.Fatal("expected decrypting with another key to fail")
+	}
+}
+
+func TestUseDatabaseKey_PerDatabase(t *testing.T) {
+	keys := []string{testKey, strings.Repeat("ff", 32)}
+	titles := make([]string, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		db, err := openDatabase("sqlite:" + filepath.Join(t.TempDir(), "events.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			sqlDB, _ := db.DB()
+			_ = sqlDB.Close()
+		})
+		useTestKey(t, db, key)
+		store, err := NewGormStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Databases with different keys are used side by side.
+		wg.Go(func() {
+			ctx := context.Background()
+			for n := range 20 {
+				hash := fmt.Sprintf("e%d", n)
+				if err := store.Create(ctx, &Event{Title: "Raub", Hash: hash, DateTime: int64(n)}); err != nil {
+					t.Error(err)
+					return
+				}
+				if event, err := store.FindByHash(ctx, hash); err != nil || event.Title != "Raub" {
+					t.Errorf("expected the decrypted event, got %+v %v", event, err)
+					return
+				}
+			}
+			var stored []string
+			_ = db.Model(&Event{}).Where("hash = ?", "e0").Pluck("title", &stored)
+			if len(stored) == 1 {
+				titles[i] = stored[0]
+			}
+		})
+	}
+	wg.Wait()
+	if !strings.HasPrefix(titles[0], encryptedPrefix) || titles[0] == titles[1] {
+		t.Fatalf("expected each database to be encrypted with its own key, got %q", titles)
+	}
+}
+
+func TestReadDatabaseKey(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"hex":    testKey,
+		"base64": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=\n",
+		"raw":    string([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31}),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		key, err := readDatabaseKey(path)
+		if err != nil || len(key) != 32 || key[31] != 31 {
+			t.Errorf("%s: unexpected key %x %v", name, key, err)
+		}
+	}
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(short, []byte("abcd"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDatabaseKey(short); err == nil {
+		t.Fatal("expected a short key to be rejected")
+	}
+}
+
+func TestEncryptDatabase(t *testing.T) {
+	store, db := openTestStore(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	ctx := context.Background()
+	now := time.Now()
+	plain := Event{Title: "Raub", Description: defaultDescription, Location: "Mitte", Link: eventLinkPrefix + "/a", Hash: "a", DateTime: now.Unix(),
+		Edits: []EventEdit{{Field: "title", Old: "Rab", New: "Raub"}}}
+	if err := store.Create(ctx, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordAudit(ctx, &AuditEntry{Action: auditActionEventEdit, Target: "a", Payload: `[{"field":"title","old":"Rab"}]`}); err != nil {
+		t.Fatal(err)
+	}
+
+	useTestKey(t, db, testKey)
+	// Values stored before encryption was turned on are still readable.
+	if event, err := store.FindByHash(ctx, "a"); err != nil || event.Title != "Raub" {
+		t.Fatalf("expected the plain event, got %+v %v", event, err)
+	}
+	encrypted := Event{Title: "Brand", Description: defaultDescription, Location: "Mitte", Link: eventLinkPrefix + "/b", Hash: "b", DateTime: now.Unix()}
+	if err := store.Create(ctx, &encrypted); err != nil {
+		t.Fatal(err)
+	}
+	// Half converted, the default description is found in both forms.
+	assertDefaultDescriptions(t, store, now, 2)
+	if err := db.Model(&Event{}).Where("hash = ?", "b").Update("description", "Ein Keller brannte.").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := encryptDatabase(ctx, db); err != nil {
+		t.Fatalf("encryptDatabase failed: %v", err)
+	}
+	var titles []string
+	if err := db.Model(&Event{}).Order("hash").Pluck("title", &titles).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range titles {
+		if !strings.HasPrefix(title, encryptedPrefix) {
+			t.Fatalf("expected encrypted titles, got %q", titles)
+		}
+	}
+	var edits, payloads []string
+	if err := db.Model(&Event{}).Where("hash = ?", "a").Pluck("edits", &edits).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&AuditEntry{}).Pluck("payload", &payloads).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || !strings.HasPrefix(edits[0], encryptedPrefix) || len(payloads) != 1 || !strings.HasPrefix(payloads[0], encryptedPrefix) {
+		t.Fatalf("expected encrypted edits and audit payloads, got %q and %q", edits, payloads)
+	}
+	if event, err := store.FindByHash(ctx, "a"); err != nil || len(event.Edits) != 1 || event.Edits[0].Old != "Rab" {
+		t.Fatalf("expected the decrypted edits, got %+v %v", event, err)
+	}
+	if entries, err := store.AuditLog(ctx, AuditFilter{Limit: 10}); err != nil || len(entries) != 1 || !strings.Contains(entries[0].Payload, "Rab") {
+		t.Fatalf("expected the decrypted audit payload, got %+v %v", entries, err)
+	}
+	if event, err := store.FindByHash(ctx, "b"); err != nil || event.Title != "Brand" || event.Description != "Ein Keller brannte." {
+		t.Fatalf("expected the decrypted event, got %+v %v", event, err)
+	}
+
+	assertDefaultDescriptions(t, store, now, 1)
+
+	// The same database opened without the key.
+	withoutKey := openTestDB(t)
+	t.Cleanup(func() {
+		sqlDB, _ := withoutKey.DB()
+		_ = sqlDB.Close()
+	})
+	keyless, err := NewGormStore(withoutKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyless.FindByHash(ctx, "b"); err == nil {
+		t.Fatal("expected reading encrypted events without a key to fail")
+	}
+}
+
+// assertDefaultDescriptions checks the count of the default_description
+// quality check.
+func assertDefaultDescriptions(t *testing.T, store EventStore, now time.Time, want int64) {
+	t.Helper()
+	report, err := store.QualityReport(context.Background(), now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range report.Issues {
+		if issue.Check == "default_description" && issue.Count != want {
+			t.Fatalf("expected %d default descriptions, got %d", want, issue.Count)
+		}
+	}
+}
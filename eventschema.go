@@ -0,0 +1,22 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+)
+
+// webhookSchemaVersion is sent as "schema" in every webhook payload, see
+// model.SchemaVersion.
+const webhookSchemaVersion = model.SchemaVersion
+
+// handleEventSchema serves the JSON Schema of the webhook payload.
+func handleEventSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	_, err := io.WriteString(w, model.Schema)
+	if err != nil {
+		log.Println("Error writing event schema:", err)
+	}
+}
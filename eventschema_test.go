@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventSchema_Endpoint(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/schema/event")
+	if err != nil {
+		t.Fatalf("GET /api/schema/event failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.Header.Get("Content-Type") != "application/schema+json" {
+		t.Fatalf("unexpected content type %q", res.Header.Get("Content-Type"))
+	}
+	var schema struct {
+		Required []string `json:"required"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&schema); err != nil || len(schema.Required) == 0 {
+		t.Fatalf("unexpected schema response: %+v (%v)", schema, err)
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	exportQueued  = "queued"
+	exportRunning = "running"
+	exportDone    = "done"
+	exportFailed  = "failed"
+	exportExpired = "expired"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// maxActiveExportsPerActor bounds how many unfinished jobs a single token may
+// have, so one client can't monopolise the export worker.
+const maxActiveExportsPerActor = 2
+
+const (
+	exportBatchSize = 500
+	exportQueueSize = 16
+)
+
+// ExportJob is a bulk export that is produced in the background.
+type ExportJob struct {
+	ID         string `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt *time.Time
+	Actor      string      `gorm:"index"`
+	Format     string      `gorm:"not null"`
+	Filter     EventFilter `gorm:"serializer:json"`
+	Status     string      `gorm:"index;not null"`
+	Rows       int
+	Error      string
+}
+
+func (s *gormStore) SaveExportJob(ctx context.Context, job *ExportJob) error {
+	return s.db.WithContext(ctx).Save(job).Error
+}
+
+func (s *gormStore) FindExportJob(ctx context.Context, id string) (*ExportJob, error) {
+	var job ExportJob
+	err := s.db.WithContext(ctx).First(&job, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ExportJobs returns the jobs in any of the given states, oldest first.
+func (s *gormStore) ExportJobs(ctx context.Context, statuses ...string) ([]ExportJob, error) {
+	var jobs []ExportJob
+	err := s.db.WithContext(ctx).Where("status IN ?", statuses).Order("created_at").Find(&jobs).Error
+	return jobs, err
+}
+
+func newExportID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (a *App) exportPath(job *ExportJob) string {
+	return filepath.Join(a.config.ExportDir, job.ID+"."+job.Format)
+}
+
+// runExports works through queued export jobs one at a time until ctx is
+// cancelled. Jobs left unfinished by a previous process are picked up again.
+func (a *App) runExports(ctx context.Context) {
+	pending, err := a.store.ExportJobs(ctx, exportQueued, exportRunning)
+	if err != nil {
+		log.Println("Error loading pending exports:", err)
+	}
+	go func() {
+		for _, job := range pending {
+			select {
+			case a.exports <- job.ID:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.expireExports(ctx)
+		case id := <-a.exports:
+			a.expireExports(ctx)
+			a.processExport(ctx, id)
+		}
+	}
+}
+
+func (a *App) processExport(ctx context.Context, id string) {
+	job, err := a.store.FindExportJob(ctx, id)
+	if err != nil {
+		log.Printf("Error loading export %s: %v", id, err)
+		return
+	}
+
+	job.Status = exportRunning
+	err = a.store.SaveExportJob(ctx, job)
+	if err != nil {
+		log.Printf("Error updating export %s: %v", id, err)
+		return
+	}
+
+	job.Rows, err = a.writeExport(ctx, job)
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = exportDone
+	if err != nil {
+		log.Printf("Export %s failed: %v", id, err)
+		job.Status = exportFailed
+		job.Error = err.Error()
+	}
+
+	err = a.store.SaveExportJob(context.WithoutCancel(ctx), job)
+	if err != nil {
+		log.Printf("Error updating export %s: %v", id, err)
+	}
+}
+
+// writeExport streams the matching events into the job's file batch by batch
+// and returns the number of rows written.
+func (a *App) writeExport(ctx context.Context, job *ExportJob) (int, error) {
+	err := os.MkdirAll(a.config.ExportDir, 0o755)
+	if err != nil {
+		return 0, err
+	}
+
+	path := a.exportPath(job)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(path + ".tmp")
+
+	rows, err := writeEvents(ctx, a.store, job.Filter, a.redactions, job.Format, file)
+	closeErr := file.Close()
+	if err != nil {
+		return rows, err
+	}
+	if closeErr != nil {
+		return rows, closeErr
+	}
+	return rows, os.Rename(path+".tmp", path)
+}
+
+var exportCSVHeader = []string{"hash", "title", "description", "district", "link", "published_at", "incident_at"}
+
+// writeEvents writes the matching events, redacted, in format to w and
+// returns the number of rows written.
+func writeEvents(ctx context.Context, store EventStore, filter EventFilter, redactions redactor, format string, w io.Writer) (int, error) {
+	buffered := bufio.NewWriter(w)
+	rows := 0
+
+	var writeBatch func([]Event) error
+	switch format {
+	case exportFormatCSV:
+		csvWriter := csv.NewWriter(buffered)
+		err := csvWriter.Write(exportCSVHeader)
+		if err != nil {
+			return 0, err
+		}
+		writeBatch = func(events []Event) error {
+			for _, event := range events {
+				err := csvWriter.Write([]string{
+					event.Hash,
+					event.Title,
+					event.Description,
+					event.Location,
+					event.Link,
+					time.Unix(event.DateTime, 0).UTC().Format(time.RFC3339),
+					formatIncidentTime(event.IncidentTime),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	case exportFormatNDJSON:
+		encoder := json.NewEncoder(buffered)
+		writeBatch = func(events []Event) error {
+			for i := range events {
+				err := encoder.Encode(toAPIEvent(&events[i]))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	err := store.EachBatch(ctx, filter, exportBatchSize, func(events []Event) error {
+		rows += len(events)
+		return writeBatch(redactions.events(events))
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, buffered.Flush()
+}
+
+// expireExports deletes the files of finished jobs older than the retention.
+func (a *App) expireExports(ctx context.Context) {
+	jobs, err := a.store.ExportJobs(ctx, exportDone)
+	if err != nil {
+		log.Println("Error loading finished exports:", err)
+		return
+	}
+	cutoff := time.Now().Add(-a.config.ExportRetention)
+	for _, job := range jobs {
+		if job.FinishedAt == nil || job.FinishedAt.After(cutoff) {
+			continue
+		}
+		a.expireExport(ctx, &job)
+	}
+}
+
+// expireExportsSince expires the finished exports written after since, e.g.
+// because they contain an event that was taken down. It returns how many
+// were expired.
+func (a *App) expireExportsSince(ctx context.Context, since time.Time) int {
+	jobs, err := a.store.ExportJobs(ctx, exportDone)
+	if err != nil {
+		log.Println("Error loading finished exports:", err)
+		return 0
+	}
+	expired := 0
+	for _, job := range jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(since) {
+			continue
+		}
+		if a.expireExport(ctx, &job) {
+			expired++
+		}
+	}
+	return expired
+}
+
+// expireExport deletes the file of a finished export and marks it expired.
+func (a *App) expireExport(ctx context.Context, job *ExportJob) bool {
+	err := os.Remove(a.exportPath(job))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error removing export %s: %v", job.ID, err)
+		return false
+	}
+	job.Status = exportExpired
+	err = a.store.SaveExportJob(ctx, job)
+	if err != nil {
+		log.Printf("Error updating export %s: %v", job.ID, err)
+		return false
+	}
+	return true
+}
+
+type apiExportJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	District    string     `json:"district,omitempty"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
+
+func toAPIExportJob(job *ExportJob) apiExportJob {
+	response := apiExportJob{
+		ID:         job.ID,
+		Status:     job.Status,
+		Format:     job.Format,
+		District:   job.Filter.District,
+		Rows:       job.Rows,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if !job.Filter.From.IsZero() {
+		response.From = &job.Filter.From
+	}
+	if !job.Filter.To.IsZero() {
+		response.To = &job.Filter.To
+	}
+	if job.Status == exportDone {
+		response.DownloadURL = "/api/exports/" + job.ID + "/download"
+	}
+	return response
+}
+
+func (a *App) handleExportCreate(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Format   string `json:"format"`
+		From     string `json:"from"`
+		To       string `json:"to"`
+		District string `json:"district"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if request.Format != exportFormatCSV && request.Format != exportFormatNDJSON {
+		writeJSONError(w, http.StatusBadRequest, "format must be csv or ndjson")
+		return
+	}
+	filter, err := parseEventFilter(url.Values{
+		"from":     {request.From},
+		"to":       {request.To},
+		"district": {request.District},
+	})
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, codeInvalidFilter, err.Error())
+		return
+	}
+
+	actor := actorFromContext(r.Context())
+	active, err := a.store.ExportJobs(r.Context(), exportQueued, exportRunning)
+	if err != nil {
+		log.Println("Error loading active exports:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	owned := 0
+	for _, job := range active {
+		if job.Actor == actor {
+			owned++
+		}
+	}
+	if owned >= maxActiveExportsPerActor {
+		w.Header().Set("Retry-After", strconv.Itoa(60))
+		writeJSONError(w, http.StatusTooManyRequests, "too many unfinished exports for this token")
+		return
+	}
+
+	job := &ExportJob{
+		ID:     newExportID(),
+		Actor:  actor,
+		Format: request.Format,
+		Filter: filter,
+		Status: exportQueued,
+	}
+	err = a.store.SaveExportJob(r.Context(), job)
+	if err != nil {
+		log.Println("Error creating export:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	select {
+	case a.exports <- job.ID:
+	default:
+		job.Status = exportFailed
+		job.Error = "export queue is full"
+		_ = a.store.SaveExportJob(r.Context(), job)
+		w.Header().Set("Retry-After", strconv.Itoa(60))
+		writeJSONError(w, http.StatusServiceUnavailable, "export queue is full")
+		return
+	}
+
+	w.Header().Set("Location", "/api/exports/"+job.ID)
+	writeJSON(w, http.StatusAccepted, toAPIExportJob(job))
+}
+
+func (a *App) loadExportJob(w http.ResponseWriter, r *http.Request) *ExportJob {
+	job, err := a.store.FindExportJob(r.Context(), r.PathValue("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "export not found")
+		return nil
+	}
+	if err != nil {
+		log.Println("Error loading export:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return nil
+	}
+	return job
+}
+
+func (a *App) handleExportStatus(w http.ResponseWriter, r *http.Request) {
+	job := a.loadExportJob(w, r)
+	if job == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIExportJob(job))
+}
+
+func (a *App) handleExportDownload(w http.ResponseWriter, r *http.Request) {
+	job := a.loadExportJob(w, r)
+	if job == nil {
+		return
+	}
+	if job.Status != exportDone {
+		writeJSONError(w, http.StatusConflict, "export is "+job.Status)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if job.Format == exportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="polizeimeldungen-%s.%s"`, job.ID, job.Format))
+	newAPIMeta(a.config).setHeaders(w)
+	http.ServeFile(w, r, a.exportPath(job))
+}
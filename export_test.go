@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func seedExportEvents(t *testing.T, store EventStore) {
+	t.Helper()
+	events := []Event{
+		{Title: "Raub", Location: "Mitte", Hash: "e1", DateTime: time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC).Unix()},
+		{Title: "Brand, groß", Location: "Pankow", Hash: "e2", DateTime: time.Date(2024, 2, 10, 12, 0, 0, 0, time.UTC).Unix()},
+		{Title: "Unfall", Location: "Mitte", Hash: "e3", DateTime: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC).Unix()},
+	}
+	for i := range events {
+		if err := store.Create(context.Background(), &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+}
+
+func TestWriteEvents_CSV(t *testing.T) {
+	app := newTestApp(t)
+	seedExportEvents(t, app.store)
+
+	var buf bytes.Buffer
+	rows, err := writeEvents(context.Background(), app.store, EventFilter{District: "Mitte"}, nil, exportFormatCSV, &buf)
+	if err != nil {
+		t.Fatalf("writeEvents failed: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("expected 2 rows, got %d", rows)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(exportCSVHeader, ",") {
+		t.Fatalf("unexpected csv output:\n%s", buf.String())
+	}
+}
+
+func TestWriteEvents_NDJSON(t *testing.T) {
+	app := newTestApp(t)
+	seedExportEvents(t, app.store)
+
+	var buf bytes.Buffer
+	filter := EventFilter{From: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+	rows, err := writeEvents(context.Background(), app.store, filter, nil, exportFormatNDJSON, &buf)
+	if err != nil {
+		t.Fatalf("writeEvents failed: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("expected 2 rows, got %d", rows)
+	}
+	var first apiEvent
+	if err := json.Unmarshal([]byte(strings.Split(buf.String(), "\n")[0]), &first); err != nil {
+		t.Fatalf("invalid ndjson line: %v", err)
+	}
+	if first.Hash != "e2" || first.Title != "Brand, groß" {
+		t.Fatalf("unexpected first record: %+v", first)
+	}
+}
+
+func TestExportJob_Lifecycle(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"research"}
+	app.config.ExportDir = t.TempDir()
+	seedExportEvents(t, app.store)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	do := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer research")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return res
+	}
+
+	res := do(http.MethodPost, "/api/exports", `{"format":"csv","district":"Mitte"}`)
+	var job apiExportJob
+	_ = json.NewDecoder(res.Body).Decode(&job)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusAccepted || job.Status != exportQueued {
+		t.Fatalf("expected queued job, got %d %+v", res.StatusCode, job)
+	}
+
+	res = do(http.MethodGet, "/api/exports/"+job.ID+"/download", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 before job finished, got %d", res.StatusCode)
+	}
+
+	app.processExport(context.Background(), <-app.exports)
+
+	res = do(http.MethodGet, "/api/exports/"+job.ID, "")
+	_ = json.NewDecoder(res.Body).Decode(&job)
+	_ = res.Body.Close()
+	if job.Status != exportDone || job.Rows != 2 || job.DownloadURL == "" {
+		t.Fatalf("unexpected finished job: %+v", job)
+	}
+
+	res = do(http.MethodGet, job.DownloadURL, "")
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(string(body), "hash,title") {
+		t.Fatalf("unexpected download: %d %s", res.StatusCode, body)
+	}
+}
+
+func TestExportJob_LimitsActiveJobsPerToken(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"research"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	statuses := []int{}
+	for range maxActiveExportsPerActor + 1 {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/exports", strings.NewReader(`{"format":"ndjson"}`))
+		req.Header.Set("Authorization", "Bearer research")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		_ = res.Body.Close()
+		statuses = append(statuses, res.StatusCode)
+	}
+	if statuses[len(statuses)-1] != http.StatusTooManyRequests {
+		t.Fatalf("expected last request to be rate limited, got %v", statuses)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+	"gorm.io/gorm"
+)
+
+// Kinds of facts extracted from reports.
+const (
+	factAge     = model.FactAge
+	factVehicle = model.FactVehicle
+	factWeapon  = model.FactWeapon
+)
+
+// Roles of the people an age refers to, guessed from the sentence it is in.
+const (
+	roleSuspect = "suspect"
+	roleVictim  = "victim"
+)
+
+// EventFact is a structured fact extracted from the text of an event, e.g.
+// the age of a suspect or a weapon that was used.
+type EventFact struct {
+	ID        uint   `gorm:"primaryKey" json:"-"`
+	EventHash string `gorm:"index" json:"-"`
+	Kind      string `gorm:"index" json:"kind"`
+	// Value is the normalized fact, e.g. "messer" or "17".
+	Value string `json:"value"`
+	// Number is set for ages, so that they can be queried as ranges.
+	Number int `json:"-"`
+	// Role is set for ages if the sentence makes it clear whom they belong to.
+	Role string `json:"role,omitempty"`
+}
+
+// factTerms maps normalized vehicle and weapon names to the words they are
+// recognized by. Terms match whole words including inflected forms (see
+// stemmedText.containsWords); compounds have to be listed explicitly.
+var factTerms = map[string]map[string][]string{
+	factVehicle: {
+		"auto":            {"auto", "pkw", "wagen", "fahrzeug"},
+		"lkw":             {"lkw", "lastwagen", "sattelzug"},
+		"transporter":     {"transporter", "kleintransporter"},
+		"motorrad":        {"motorrad", "kraftrad", "motorroller"},
+		"fahrrad":         {"fahrrad", "radfahrer", "radfahrerin", "pedelec"},
+		"e-scooter":       {"e-scooter", "escooter", "elektroroller"},
+		"bus":             {"bus", "linienbus"},
+		"straßenbahn":     {"straßenbahn", "tram"},
+		"taxi":            {"taxi"},
+		"einsatzfahrzeug": {"einsatzfahrzeug", "streifenwagen", "rettungswagen"},
+	},
+	factWeapon: {
+		"messer":             {"messer", "klappmesser", "küchenmesser", "messerangriff", "messerstich", "stichwaffe"},
+		"schusswaffe":        {"schusswaffe", "pistole", "revolver", "gewehr"},
+		"schreckschusswaffe": {"schreckschusswaffe", "schreckschusspistole"},
+		"reizgas":            {"reizgas", "pfefferspray", "tierabwehrspray"},
+		"schlagwerkzeug":     {"schlagstock", "baseballschläger", "eisenstange", "hammer"},
+		"machete":            {"machete", "axt", "beil"},
+	},
+}
+
+var (
+	agePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(\d{1,3})\s?-?\s?[jJ]ährige`),
+		regexp.MustCompile(`(\d{1,3}) Jahre alte`),
+	}
+	sentenceEnd  = regexp.MustCompile(`[.!?]\s+`)
+	suspectWords = []string{"tatverdächtig", "täter", "festgenommen", "beschuldigt"}
+	victimWords  = []string{"opfer", "verletzt", "geschädigt", "angegriffen", "überfallen"}
+)
+
+// extractFacts finds ages, vehicles and weapons in the text of an event.
+func extractFacts(event *Event) []EventFact {
+	text := event.Title + ". " + event.Description
+	var facts []EventFact
+
+	for _, sentence := range sentenceEnd.Split(text, -1) {
+		role := ageRole(sentence)
+		for _, pattern := range agePatterns {
+			for _, match := range pattern.FindAllStringSubmatch(sentence, -1) {
+				age, err := strconv.Atoi(match[1])
+				if err != nil || age > 120 {
+					continue
+				}
+				facts = append(facts, EventFact{Kind: factAge, Value: match[1], Number: age, Role: role})
+			}
+		}
+	}
+
+	prepared := newStemmedText(text)
+	for _, kind := range []string{factVehicle, factWeapon} {
+		names := make([]string, 0, len(factTerms[kind]))
+		for name := range factTerms[kind] {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if slices.ContainsFunc(factTerms[kind][name], prepared.containsWords) {
+				facts = append(facts, EventFact{Kind: kind, Value: name})
+			}
+		}
+	}
+
+	for i := range facts {
+		facts[i].EventHash = event.Hash
+	}
+	return facts
+}
+
+// ageRole guesses whether ages in a sentence refer to a suspect or a victim.
+// Sentences mentioning both or neither stay unassigned.
+func ageRole(sentence string) string {
+	sentence = strings.ToLower(sentence)
+	containsAny := func(words []string) bool {
+		return slices.ContainsFunc(words, func(word string) bool { return strings.Contains(sentence, word) })
+	}
+	suspect, victim := containsAny(suspectWords), containsAny(victimWords)
+	switch {
+	case suspect && !victim:
+		return roleSuspect
+	case victim && !suspect:
+		return roleVictim
+	default:
+		return ""
+	}
+}
+
+// ReplaceFacts stores the facts of an event, dropping its previous ones.
+func (s *gormStore) ReplaceFacts(ctx context.Context, hash string, facts []EventFact) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("event_hash = ?", hash).Delete(&EventFact{}).Error
+		if err != nil || len(facts) == 0 {
+			return err
+		}
+		return tx.Create(&facts).Error
+	})
+}
+
+// maxFactLimit is the most events a fact query returns.
+const maxFactLimit = 1000
+
+// FactQuery selects events by their facts. All set conditions must hold.
+type FactQuery struct {
+	EventFilter
+	Weapon  string
+	Vehicle string
+	// MinAge and MaxAge match events mentioning an age in the range, of a
+	// person in AgeRole if set. Zero values are ignored.
+	MinAge  int
+	MaxAge  int
+	AgeRole string
+	Limit   int
+}
+
+// EventsWithFacts returns the matching events, newest first, along with all
+// their facts.
+func (s *gormStore) EventsWithFacts(ctx context.Context, query FactQuery) ([]Event, map[string][]EventFact, error) {
+	db := s.db.WithContext(ctx)
+	events := query.EventFilter.apply(db.Model(&Event{}))
+	if query.Weapon != "" {
+		events = events.Where("hash IN (?)", db.Model(&EventFact{}).Select("event_hash").Where("kind = ? AND value = ?", factWeapon, query.Weapon))
+	}
+	if query.Vehicle != "" {
+		events = events.Where("hash IN (?)", db.Model(&EventFact{}).Select("event_hash").Where("kind = ? AND value = ?", factVehicle, query.Vehicle))
+	}
+	if query.MinAge > 0 || query.MaxAge > 0 || query.AgeRole != "" {
+		ages := db.Model(&EventFact{}).Select("event_hash").Where("kind = ?", factAge)
+		if query.MinAge > 0 {
+			ages = ages.Where("number >= ?", query.MinAge)
+		}
+		if query.MaxAge > 0 {
+			ages = ages.Where("number <= ?", query.MaxAge)
+		}
+		if query.AgeRole != "" {
+			ages = ages.Where("role = ?", query.AgeRole)
+		}
+		events = events.Where("hash IN (?)", ages)
+	}
+
+	var found []Event
+	err := events.Order("date_time DESC").Order("hash").Limit(query.Limit).Find(&found).Error
+	if err != nil || len(found) == 0 {
+		return found, nil, err
+	}
+
+	hashes := make([]string, 0, len(found))
+	for _, event := range found {
+		hashes = append(hashes, event.Hash)
+	}
+	facts, err := s.Facts(ctx, hashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return found, facts, nil
+}
+
+// Facts returns the facts of the given events by event hash.
+func (s *gormStore) Facts(ctx context.Context, hashes []string) (map[string][]EventFact, error) {
+	var facts []EventFact
+	err := s.db.WithContext(ctx).Where("event_hash IN ?", hashes).Order("id").Find(&facts).Error
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string][]EventFact)
+	for _, fact := range facts {
+		byHash[fact.EventHash] = append(byHash[fact.EventHash], fact)
+	}
+	return byHash, nil
+}
+
+// updateFacts extracts and stores the facts of the given events. Failures
+// are logged, as facts can always be rebuilt with the extract-facts command.
+func (a *App) updateFacts(ctx context.Context, events ...Event) {
+	for i := range events {
+		err := a.store.ReplaceFacts(context.WithoutCancel(ctx), events[i].Hash, extractFacts(&events[i]))
+		if err != nil {
+			log.Printf("Error storing facts of %s: %v", events[i].Hash, err)
+		}
+	}
+}
+
+type apiEventFacts = model.EventFacts
+
+func toAPIFacts(facts []EventFact) []model.Fact {
+	apiFacts := make([]model.Fact, len(facts))
+	for i, fact := range facts {
+		apiFacts[i] = model.Fact{Kind: fact.Kind, Value: fact.Value, Role: fact.Role}
+	}
+	return apiFacts
+}
+
+func parseFactQuery(query url.Values) (FactQuery, error) {
+	filter, err := parseEventFilter(query)
+	if err != nil {
+		return FactQuery{}, err
+	}
+	q := FactQuery{
+		EventFilter: filter,
+		Weapon:      query.Get("weapon"),
+		Vehicle:     query.Get("vehicle"),
+		AgeRole:     query.Get("ageRole"),
+		Limit:       100,
+	}
+	for name, target := range map[string]*int{"minAge": &q.MinAge, "maxAge": &q.MaxAge} {
+		if value := query.Get(name); value != "" {
+			*target, err = strconv.Atoi(value)
+			if err != nil || *target < 0 {
+				return q, fmt.Errorf("%s must be a non-negative number", name)
+			}
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return q, errors.New("limit must be a number")
+		}
+	}
+	q.After, err = queryCursor(query)
+	if err != nil {
+		return q, err
+	}
+	return q, q.validate()
+}
+
+// validate checks the values of a query, however it was given.
+func (q FactQuery) validate() error {
+	if q.AgeRole != "" && q.AgeRole != roleSuspect && q.AgeRole != roleVictim {
+		return fmt.Errorf("ageRole must be %s or %s", roleSuspect, roleVictim)
+	}
+	if q.MinAge < 0 || q.MaxAge < 0 {
+		return errors.New("ages must not be negative")
+	}
+	if q.Limit < 1 || q.Limit > maxFactLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxFactLimit)
+	}
+	return nil
+}
+
+// handleFacts lists events by extracted facts, e.g.
+// ?weapon=messer&maxAge=17&from=2024-01-01&to=2025-01-01 for knife incidents
+// involving minors in 2024. Pages of ?limit= events link the next one, see
+// setNextPage.
+func (a *App) handleFacts(w http.ResponseWriter, r *http.Request) {
+	query, err := parseFactQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, codeInvalidFilter, err.Error())
+		return
+	}
+	limit := query.Limit
+	query.Limit++
+	events, facts, err := a.store.EventsWithFacts(r.Context(), query)
+	if err != nil {
+		log.Println("Error querying facts:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		setNextPage(w, r, eventCursor(&events[limit-1]))
+	}
+	events = a.redactions.events(events)
+	response := make([]apiEventFacts, 0, len(events))
+	for i := range events {
+		response = append(response, apiEventFacts{Event: toAPIEvent(&events[i]), Facts: toAPIFacts(facts[events[i].Hash])})
+	}
+	newAPIMeta(a.config).setHeaders(w)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// runExtractFacts implements the extract-facts command, which (re)builds the
+// facts and incident times of all stored events, e.g. after the extraction
+// rules changed.
+func runExtractFacts(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("extract-facts", flag.ContinueOnError)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	db, err := openConfiguredDatabase(config)
+	if err != nil {
+		return err
+	}
+	store, err := NewGormStore(db)
+	if err != nil {
+		return err
+	}
+
+	processed, found, incidents := 0, 0, 0
+	err = store.EachBatch(ctx, EventFilter{}, exportBatchSize, func(events []Event) error {
+		for i := range events {
+			previous := events[i].IncidentTime
+			setIncidentTime(&events[i])
+			if !equalIncidentTimes(previous, events[i].IncidentTime) {
+				err := store.Update(ctx, &events[i])
+				if err != nil {
+					return fmt.Errorf("updating incident time of %s: %w", events[i].Hash, err)
+				}
+			}
+			if events[i].IncidentTime != nil {
+				incidents++
+			}
+
+			facts := extractFacts(&events[i])
+			err := store.ReplaceFacts(ctx, events[i].Hash, facts)
+			if err != nil {
+				return fmt.Errorf("storing facts of %s: %w", events[i].Hash, err)
+			}
+			processed++
+			found += len(facts)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Extracted %d facts and %d incident times from %d events", found, incidents, processed)
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExtractFacts(t *testing.T) {
+	event := &Event{
+		Hash:  "h",
+		Title: "Raub mit Messer in Neukölln",
+		Description: "Ein 16-Jähriger wurde gestern Abend mit einem Messer bedroht und leicht verletzt. " +
+			"Die Tatverdächtigen, ein 19-Jähriger und eine 17 Jahre alte Frau, flüchteten mit einem E-Scooter. " +
+			"Am Busch fand die Polizei später Pfefferspray.",
+	}
+	facts := extractFacts(event)
+
+	want := []EventFact{
+		{EventHash: "h", Kind: factAge, Value: "16", Number: 16, Role: roleVictim},
+		{EventHash: "h", Kind: factAge, Value: "19", Number: 19, Role: roleSuspect},
+		{EventHash: "h", Kind: factAge, Value: "17", Number: 17, Role: roleSuspect},
+		{EventHash: "h", Kind: factVehicle, Value: "e-scooter"},
+		{EventHash: "h", Kind: factWeapon, Value: "messer"},
+		{EventHash: "h", Kind: factWeapon, Value: "reizgas"},
+	}
+	if len(facts) != len(want) {
+		t.Fatalf("expected %d facts, got %+v", len(want), facts)
+	}
+	for i := range want {
+		if facts[i] != want[i] {
+			t.Errorf("fact %d: got %+v, want %+v", i, facts[i], want[i])
+		}
+	}
+}
+
+func TestFacts_Endpoint(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	ctx := context.Background()
+	year := time.Date(2024, 6, 1, 12, 0, 0, 0, berlin)
+	events := []Event{
+		{Title: "Messerangriff", Description: "Ein 15-Jähriger wurde festgenommen, er hatte ein Messer dabei.", Hash: "minor-knife", DateTime: year.Unix()},
+		{Title: "Messerangriff", Description: "Ein 34-Jähriger wurde festgenommen, er hatte ein Messer dabei.", Hash: "adult-knife", DateTime: year.Unix()},
+		{Title: "Unfall", Description: "Ein 15-Jähriger stürzte mit dem Fahrrad.", Hash: "minor-bike", DateTime: year.Unix()},
+		{Title: "Messerangriff", Description: "Ein 16-Jähriger wurde festgenommen, er hatte ein Messer dabei.", Hash: "minor-knife-2023", DateTime: year.AddDate(-1, 0, 0).Unix()},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+	app.updateFacts(ctx, events...)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/facts?weapon=messer&maxAge=17&from=2024-01-01&to=2025-01-01", nil)
+	req.Header.Set("Authorization", "Bearer stats")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/facts failed: %v", err)
+	}
+	var result []apiEventFacts
+	err = json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	if len(result) != 1 || result[0].Event.Hash != "minor-knife" || len(result[0].Facts) != 2 {
+		t.Fatalf("expected only the knife incident involving a minor in 2024, got %+v", result)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/facts?ageRole=witness", nil)
+	req.Header.Set("Authorization", "Bearer stats")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/facts failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown role, got %d", res.StatusCode)
+	}
+}
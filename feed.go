@@ -0,0 +1,472 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// FeedBuilder owns the feed and its rendered representations. Renders are
+// cached and compressed so that serving a request never has to touch the feed
+// itself.
+type FeedBuilder struct {
+	mu   sync.RWMutex
+	feed *feeds.Feed
+	rss  string
+	json string
+	atom string
+	// compressed holds the renders by format, along with their Brotli and
+	// gzip encodings.
+	compressed map[string]compressedBody
+	// baseURL is the public address of this server, see SetBaseURL.
+	baseURL string
+	// image and favicon are set by SetImage.
+	image, favicon string
+	// redactor is applied to events as they are added, see SetRedactor.
+	redactor redactor
+	// self is the path of a feed variant without the format extension, e.g.
+	// "/f/mitte", empty for the built-in feeds. See selfLink.
+	self string
+}
+
+func NewFeedBuilder(link string) *FeedBuilder {
+	b := &FeedBuilder{
+		feed: &feeds.Feed{
+			Title:       "Berliner Polizeimeldungen",
+			Link:        &feeds.Link{Href: link},
+			Description: "Ein RSS Feed für Berliner Polizeimeldungen",
+			Author:      &feeds.Author{Name: "Aron", Email: "github@luiggi33.de"},
+			Created:     time.Now(),
+		},
+	}
+	b.render()
+	return b
+}
+
+// Add appends the events to the feed and re-renders all formats.
+func (b *FeedBuilder) Add(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range events {
+		b.feed.Add(b.item(&events[i]))
+	}
+	b.render()
+}
+
+// Replace swaps the feed item belonging to event for an up to date one and
+// re-renders all formats. It reports whether the item was found.
+func (b *FeedBuilder) Replace(event Event) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, item := range b.feed.Items {
+		if item.Id != event.Hash {
+			continue
+		}
+		b.feed.Items[i] = b.item(&event)
+		b.render()
+		return true
+	}
+	return false
+}
+
+// Remove drops the feed item belonging to hash, if there is one, and
+// re-renders all formats.
+func (b *FeedBuilder) Remove(hash string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, item := range b.feed.Items {
+		if item.Id == hash {
+			b.feed.Items = slices.Delete(b.feed.Items, i, i+1)
+			b.render()
+			return
+		}
+	}
+}
+
+// SetCopyright sets the rights notice of the feed channel.
+func (b *FeedBuilder) SetCopyright(notice string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.feed.Copyright = notice
+	b.render()
+}
+
+// SetBaseURL sets the public address of this server, e.g.
+// "https://feed.example.org". Once set, the feeds link to themselves and
+// identify their items by permalinks instead of bare hashes.
+func (b *FeedBuilder) SetBaseURL(baseURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.baseURL = strings.TrimSuffix(baseURL, "/")
+	b.render()
+}
+
+// SetImage sets the logo of the feed and a small icon for it, which defaults
+// to the logo. Both may be absolute URLs or paths on this server like
+// "/static/logo.png", which need a base URL to be used.
+func (b *FeedBuilder) SetImage(image, favicon string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if favicon == "" {
+		favicon = image
+	}
+	b.image, b.favicon = image, favicon
+	b.render()
+}
+
+// SetRedactor sets the redaction rules applied to events added from now on.
+func (b *FeedBuilder) SetRedactor(r redactor) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.redactor = r
+}
+
+// absoluteURL resolves paths on this server against the base URL. It returns
+// an empty string if that isn't possible.
+func (b *FeedBuilder) absoluteURL(value string) string {
+	if !strings.HasPrefix(value, "/") {
+		return value
+	}
+	if b.baseURL == "" {
+		return ""
+	}
+	return b.baseURL + value
+}
+
+// item translates event into a feed item. Items of events whose link died
+// point to the Wayback Machine capture, the PDF copy or the permalink
+// instead, whichever is available first.
+func (b *FeedBuilder) item(event *Event) *feeds.Item {
+	event = b.redactor.event(event)
+	item, _ := translateEventToItem(event)
+	if event.LinkDeadSince == nil {
+		return item
+	}
+	fallback := event.WaybackURL
+	if fallback == "" && event.ArchiveSize > 0 {
+		fallback = b.absoluteURL("/archive/" + event.Hash + ".pdf")
+	}
+	if fallback == "" && b.baseURL != "" {
+		fallback = b.permalink(event.Hash)
+	}
+	if fallback != "" {
+		item.Link = &feeds.Link{Href: fallback}
+	}
+	return item
+}
+
+// permalink returns the address identifying the item with the given hash.
+func (b *FeedBuilder) permalink(hash string) string {
+	if b.baseURL == "" {
+		return hash
+	}
+	return b.baseURL + "/api/events/" + hash
+}
+
+// selfLink returns the address of the feed itself in format.
+func (b *FeedBuilder) selfLink(format string) string {
+	if b.self == "" {
+		return b.baseURL + "/" + format
+	}
+	return b.baseURL + b.self + "." + format
+}
+
+// pageInfo returns the title of the feed, the absolute address of the page
+// of the event with the given hash and the feed's logo, for link previews.
+// The addresses are empty without a base URL.
+func (b *FeedBuilder) pageInfo(hash string) (title, url, image string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.feed.Title, b.absoluteURL("/event/" + hash), b.absoluteURL(b.image)
+}
+
+// rssFeedXML adds the Atom namespace to the rss element, which gorilla/feeds
+// doesn't know about, so that the channel can carry a self link.
+type rssFeedXML struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	AtomNamespace    string   `xml:"xmlns:atom,attr,omitempty"`
+	Channel          *rssChannel
+}
+
+type rssChannel struct {
+	*feeds.RssFeed
+	SelfLink *rssSelfLink
+	// Items replaces the items of the embedded feed.
+	Items []*rssItem `xml:"item"`
+}
+
+// rssItem lets items name their source with the url attribute RSS asks for,
+// where gorilla/feeds writes the URL as the name.
+type rssItem struct {
+	*feeds.RssItem
+	Source *rssSource
+}
+
+type rssSource struct {
+	XMLName xml.Name `xml:"source"`
+	URL     string   `xml:"url,attr"`
+	Title   string   `xml:",chardata"`
+}
+
+type rssSelfLink struct {
+	XMLName xml.Name `xml:"atom:link"`
+	Href    string   `xml:"href,attr"`
+	Rel     string   `xml:"rel,attr"`
+	Type    string   `xml:"type,attr"`
+}
+
+func (f *rssFeedXML) FeedXml() any { return f }
+
+// atomFeedXML is an Atom feed with a self link next to the alternate one.
+// Links replaces the single link of the embedded feed.
+type atomFeedXML struct {
+	*feeds.AtomFeed
+	Links []*feeds.AtomLink
+}
+
+func (f *atomFeedXML) FeedXml() any { return f }
+
+// render must be called with the write lock held (or before the builder is shared).
+func (b *FeedBuilder) render() {
+	var err error
+	b.rss, err = feeds.ToXML(b.rssFeed())
+	if err != nil {
+		log.Println("Error rendering rss:", err)
+	}
+	b.json, err = b.jsonFeed().ToJSON()
+	if err != nil {
+		log.Println("Error rendering json:", err)
+	}
+	b.atom, err = feeds.ToXML(b.atomFeed())
+	if err != nil {
+		log.Println("Error rendering atom:", err)
+	}
+	b.compressed = map[string]compressedBody{
+		"rss":  compressBody([]byte(b.rss)),
+		"json": compressBody([]byte(b.json)),
+		"atom": compressBody([]byte(b.atom)),
+	}
+}
+
+func (b *FeedBuilder) rssFeed() *rssFeedXML {
+	channel := (&feeds.Rss{Feed: b.feed}).RssFeed()
+	if image := b.absoluteURL(b.image); image != "" {
+		channel.Image = &feeds.RssImage{Url: image, Title: channel.Title, Link: channel.Link}
+	}
+	items := make([]*rssItem, len(channel.Items))
+	for i, item := range channel.Items {
+		if item.Guid != nil {
+			item.Guid.Id = b.permalink(b.feed.Items[i].Id)
+		}
+		if item.Enclosure != nil {
+			item.Enclosure.Url = b.absoluteURL(item.Enclosure.Url)
+			if item.Enclosure.Url == "" {
+				item.Enclosure = nil
+			}
+		}
+		items[i] = &rssItem{RssItem: item}
+	}
+	feed := &rssFeedXML{
+		Version:          "2.0",
+		ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+		Channel:          &rssChannel{RssFeed: channel, Items: items},
+	}
+	if b.baseURL != "" {
+		feed.AtomNamespace = "http://www.w3.org/2005/Atom"
+		feed.Channel.SelfLink = &rssSelfLink{Href: b.selfLink("rss"), Rel: "self", Type: "application/rss+xml"}
+	}
+	return feed
+}
+
+func (b *FeedBuilder) atomFeed() *atomFeedXML {
+	atom := (&feeds.Atom{Feed: b.feed}).AtomFeed()
+	atom.Logo = b.absoluteURL(b.image)
+	atom.Icon = b.absoluteURL(b.favicon)
+	for i, entry := range atom.Entries {
+		entry.Id = b.permalink(b.feed.Items[i].Id)
+		links := entry.Links[:0]
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				if link.Href = b.absoluteURL(link.Href); link.Href == "" {
+					continue
+				}
+			}
+			links = append(links, link)
+		}
+		entry.Links = links
+	}
+	feed := &atomFeedXML{AtomFeed: atom, Links: []*feeds.AtomLink{atom.Link}}
+	atom.Link = nil
+	if b.baseURL != "" {
+		atom.Id = b.selfLink("atom")
+		feed.Links = append(feed.Links, &feeds.AtomLink{Href: atom.Id, Rel: "self", Type: "application/atom+xml"})
+	}
+	return feed
+}
+
+func (b *FeedBuilder) jsonFeed() *feeds.JSONFeed {
+	feed := (&feeds.JSON{Feed: b.feed}).JSONFeed()
+	feed.Icon = b.absoluteURL(b.image)
+	feed.Favicon = b.absoluteURL(b.favicon)
+	for i, item := range feed.Items {
+		item.Id = b.permalink(b.feed.Items[i].Id)
+		if enclosure := b.feed.Items[i].Enclosure; enclosure != nil {
+			if url := b.absoluteURL(enclosure.Url); url != "" {
+				size, _ := strconv.ParseInt(enclosure.Length, 10, 32)
+				item.Attachments = append(item.Attachments, feeds.JSONAttachment{Url: url, MIMEType: enclosure.Type, Size: int32(size)})
+			}
+		}
+	}
+	if b.baseURL != "" {
+		feed.FeedUrl = b.selfLink("json")
+	}
+	return feed
+}
+
+// Filtered renders the feed in format ("rss", "atom" or "json") without the
+// items drop reports true for. Unlike the full feed, it isn't cached.
+func (b *FeedBuilder) Filtered(format string, drop func(*feeds.Item) bool) (string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	feed := *b.feed
+	feed.Items = slices.DeleteFunc(slices.Clone(b.feed.Items), drop)
+	filtered := &FeedBuilder{feed: &feed, baseURL: b.baseURL, image: b.image, favicon: b.favicon, redactor: b.redactor}
+	return filtered.format(format)
+}
+
+// Variant renders the given events in format as the feed variant. Without a
+// title or description of its own, the variant keeps those of the full feed.
+// If formatItem is set, it may change the items, given their redacted event.
+func (b *FeedBuilder) Variant(format string, variant *feedVariant, events []Event, formatItem func(*Event, *feeds.Item)) (string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	feed := *b.feed
+	feed.Title = cmp.Or(variant.Title, feed.Title)
+	feed.Description = cmp.Or(variant.Description, feed.Description)
+	feed.Items = make([]*feeds.Item, 0, len(events))
+	for i := range events {
+		item := b.item(&events[i])
+		if formatItem != nil {
+			formatItem(b.redactor.event(&events[i]), item)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	rendered := &FeedBuilder{feed: &feed, baseURL: b.baseURL, image: b.image, favicon: b.favicon, redactor: b.redactor, self: variant.path()}
+	return rendered.format(format)
+}
+
+// Sourced renders the given events as an RSS feed combining several sources,
+// served at path. Item titles start with the label of their source, which
+// their <source> element names as well.
+func (b *FeedBuilder) Sourced(path string, events []Event, source func(*Event) sourceInfo) (string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	feed := *b.feed
+	feed.Title += " – alle Quellen"
+	feed.Items = make([]*feeds.Item, 0, len(events))
+	for i := range events {
+		info := source(&events[i])
+		item := b.item(&events[i])
+		item.Title = "[" + info.Label + "] " + item.Title
+		item.Author = &feeds.Author{Name: info.Title}
+		feed.Items = append(feed.Items, item)
+	}
+	rendered := &FeedBuilder{feed: &feed, baseURL: b.baseURL, image: b.image, favicon: b.favicon, redactor: b.redactor}
+	rss := rendered.rssFeed()
+	for i, item := range rss.Channel.Items {
+		info := source(&events[i])
+		item.Source = &rssSource{URL: info.URL, Title: info.Title}
+	}
+	if rss.Channel.SelfLink != nil {
+		rss.Channel.SelfLink.Href = b.baseURL + path
+	}
+	return feeds.ToXML(rss)
+}
+
+// format renders the feed in format ("rss", "atom" or "json").
+func (b *FeedBuilder) format(format string) (string, error) {
+	switch format {
+	case "rss":
+		return feeds.ToXML(b.rssFeed())
+	case "atom":
+		return feeds.ToXML(b.atomFeed())
+	default:
+		return b.jsonFeed().ToJSON()
+	}
+}
+
+// Rendered returns the cached render of the feed in format ("rss", "atom" or
+// "json"), compressed ahead of time.
+func (b *FeedBuilder) Rendered(format string) compressedBody {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if body, ok := b.compressed[format]; ok {
+		return body
+	}
+	return b.compressed["json"]
+}
+
+func (b *FeedBuilder) RSS() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.rss
+}
+
+func (b *FeedBuilder) JSON() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.json
+}
+
+func (b *FeedBuilder) Atom() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.atom
+}
+
+func translateEventToItem(event *Event) (*feeds.Item, error) {
+	feederItem := feeds.Item{
+		Id:          event.Hash,
+		Title:       event.Title,
+		Link:        &feeds.Link{Href: event.Link},
+		Description: event.Description + "\n\nBezirk: " + event.Location,
+		Author:      &feeds.Author{Name: "Presseabteilung", Email: "pressestelle@polizei.berlin.de"},
+		Created:     time.Unix(event.DateTime, 0),
+	}
+	// Creating an event sets both timestamps to the same instant, so a later
+	// UpdatedAt means it was corrected or refreshed by a re-scrape since.
+	if event.UpdatedAt.After(event.CreatedAt) {
+		feederItem.Updated = event.UpdatedAt
+	}
+	if event.ChangeSummary != "" {
+		feederItem.Description = event.ChangeSummary + "\n\n" + feederItem.Description
+	}
+	// The path is made absolute when rendering, see absoluteURL.
+	if event.ArchiveSize > 0 {
+		feederItem.Enclosure = &feeds.Enclosure{
+			Url:    "/archive/" + event.Hash + ".pdf",
+			Length: strconv.FormatInt(event.ArchiveSize, 10),
+			Type:   "application/pdf",
+		}
+	}
+	return &feederItem, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestTranslateEventToItem(t *testing.T) {
+	e := &Event{
+		Title:       "MyTitle",
+		Description: "Desc",
+		Location:    "Mitte",
+		Link:        "https://example.com/1",
+		DateTime:    time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC).Unix(),
+		Hash:        "thehash",
+	}
+
+	item, err := translateEventToItem(e)
+	if err != nil {
+		t.Fatalf("translateEventToItem error: %v", err)
+	}
+	if item.Id != e.Hash {
+		t.Fatalf("expected id %s, got %s", e.Hash, item.Id)
+	}
+	if item.Title != e.Title {
+		t.Fatalf("expected title %s, got %s", e.Title, item.Title)
+	}
+	if item.Link == nil || item.Link.Href != e.Link {
+		t.Fatalf("expected link %s, got %v", e.Link, item.Link)
+	}
+	if !strings.Contains(item.Description, e.Description) {
+		t.Fatalf("description missing original: %s", item.Description)
+	}
+	if !strings.Contains(item.Description, "Bezirk: "+e.Location) {
+		t.Fatalf("description missing location: %s", item.Description)
+	}
+	if !item.Created.Equal(time.Unix(e.DateTime, 0)) {
+		t.Fatalf("created mismatch, expected %v got %v", time.Unix(e.DateTime, 0), item.Created)
+	}
+}
+
+func TestFeedsIntegrationSanity(t *testing.T) {
+	e := &Event{
+		Title:       "X",
+		Description: "Y",
+		Location:    "L",
+		Link:        "https://x",
+		DateTime:    time.Now().Unix(),
+		Hash:        "h",
+	}
+	it, err := translateEventToItem(e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed := &feeds.Feed{
+		Title:       "t",
+		Link:        &feeds.Link{Href: "u"},
+		Description: "d",
+		Author:      &feeds.Author{Name: "A"},
+		Created:     time.Now(),
+	}
+	feed.Add(it)
+	_, err = feed.ToRss()
+	if err != nil {
+		t.Fatalf("ToRss failed: %v", err)
+	}
+	_, err = feed.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	_, err = feed.ToAtom()
+	if err != nil {
+		t.Fatalf("ToAtom failed: %v", err)
+	}
+}
+
+func TestFeedBuilder_SelfLinks(t *testing.T) {
+	b := NewFeedBuilder("https://example.com/")
+	b.Add(Event{Title: "T", Link: "https://example.com/1", Hash: "h1"})
+	if strings.Contains(b.RSS(), `rel="self"`) || strings.Contains(b.Atom(), `rel="self"`) {
+		t.Fatal("expected no self links without a base URL")
+	}
+	if !strings.Contains(b.Atom(), "<id>h1</id>") {
+		t.Fatalf("expected the hash as id without a base URL:\n%s", b.Atom())
+	}
+
+	b.SetBaseURL("https://feed.example.org/")
+	for _, want := range []string{
+		`<atom:link href="https://feed.example.org/rss" rel="self" type="application/rss+xml">`,
+		`<guid>https://feed.example.org/api/events/h1</guid>`,
+		`<link>https://example.com/1</link>`,
+	} {
+		if !strings.Contains(b.RSS(), want) {
+			t.Fatalf("rss misses %s:\n%s", want, b.RSS())
+		}
+	}
+	for _, want := range []string{
+		`<link href="https://feed.example.org/atom" rel="self" type="application/atom+xml">`,
+		`<link href="https://example.com/">`,
+		`<id>https://feed.example.org/api/events/h1</id>`,
+	} {
+		if !strings.Contains(b.Atom(), want) {
+			t.Fatalf("atom misses %s:\n%s", want, b.Atom())
+		}
+	}
+	if !strings.Contains(b.JSON(), `"feed_url": "https://feed.example.org/json"`) {
+		t.Fatalf("json feed misses feed_url:\n%s", b.JSON())
+	}
+
+	// Items keep their hash, so they can still be replaced.
+	if !b.Replace(Event{Title: "Updated", Hash: "h1"}) {
+		t.Fatal("expected the item to be replaced")
+	}
+}
+
+func TestFeedBuilder_Image(t *testing.T) {
+	b := NewFeedBuilder("https://example.com/")
+	b.SetImage("/static/logo.png", "https://cdn.example.org/favicon.png")
+	if strings.Contains(b.RSS(), "<image>") || strings.Contains(b.Atom(), "<logo>") {
+		t.Fatal("expected paths to be ignored without a base URL")
+	}
+	if !strings.Contains(b.Atom(), "<icon>https://cdn.example.org/favicon.png</icon>") {
+		t.Fatalf("expected absolute favicon to be used:\n%s", b.Atom())
+	}
+
+	b.SetBaseURL("https://feed.example.org")
+	if !strings.Contains(b.RSS(), "<url>https://feed.example.org/static/logo.png</url>") {
+		t.Fatalf("rss misses image:\n%s", b.RSS())
+	}
+	if !strings.Contains(b.Atom(), "<logo>https://feed.example.org/static/logo.png</logo>") {
+		t.Fatalf("atom misses logo:\n%s", b.Atom())
+	}
+	for _, want := range []string{
+		`"icon": "https://feed.example.org/static/logo.png"`,
+		`"favicon": "https://cdn.example.org/favicon.png"`,
+	} {
+		if !strings.Contains(b.JSON(), want) {
+			t.Fatalf("json feed misses %s:\n%s", want, b.JSON())
+		}
+	}
+}
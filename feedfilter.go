@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// feedExclusionsEnv reads named exclusion lists like
+// "schule=sexualdelikt|suizid", one per subscription. Invalid entries are
+// logged and skipped.
+func feedExclusionsEnv(name string) map[string][]string {
+	exclusions := make(map[string][]string)
+	for _, entry := range listEnv(name) {
+		subscription, value, ok := strings.Cut(entry, "=")
+		subscription = strings.TrimSpace(subscription)
+		categories := splitCategories(value, "|")
+		if !ok || subscription == "" || len(categories) == 0 {
+			log.Printf("Invalid %s entry %q, expected subscription=category|category", name, entry)
+			continue
+		}
+		exclusions[subscription] = categories
+	}
+	return exclusions
+}
+
+func splitCategories(value, sep string) []string {
+	var categories []string
+	for _, category := range strings.Split(value, sep) {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category != "" && !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+var errUnknownSubscription = errors.New("unknown subscription")
+
+// feedExclusions returns the categories to leave out of a feed: those given
+// by ?exclude= and those of the subscription named by ?subscription=, see
+// FEED_EXCLUSIONS.
+func (a *App) feedExclusions(query url.Values) ([]string, error) {
+	exclude := splitCategories(query.Get("exclude"), ",")
+	if name := query.Get("subscription"); name != "" {
+		categories, ok := a.config.FeedExclusions[name]
+		if !ok {
+			return nil, errUnknownSubscription
+		}
+		for _, category := range categories {
+			if !slices.Contains(exclude, category) {
+				exclude = append(exclude, category)
+			}
+		}
+	}
+	return exclude, nil
+}
+
+var errInvalidSince = errors.New("since must be an RFC 3339 timestamp or a Unix time")
+
+// parseSince reads ?since=, an RFC 3339 timestamp or a Unix time, for feeds
+// of only the items published after it, e.g. since a script's last poll. It
+// returns the time as Event.DateTime stores it (see wallClock), or zero
+// without the parameter.
+func parseSince(query url.Values) (int64, error) {
+	value := query.Get("since")
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return wallClock(time.Unix(seconds, 0)), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, errInvalidSince
+	}
+	return wallClock(t), nil
+}
+
+// excludedItem reports whether an item falls into one of the categories,
+// classifying it by its text with the active rules.
+func (a *App) excludedItem(categories []string) func(*feeds.Item) bool {
+	rules := a.rules()
+	return func(item *feeds.Item) bool {
+		classification := classifyText(rules, item.Title+"\n"+item.Description)
+		return slices.ContainsFunc(classification.Categories, func(category string) bool {
+			return slices.Contains(categories, strings.ToLower(category))
+		})
+	}
+}
+
+// handleFeed serves the default variant in format, see defaultFeedVariant.
+func (a *App) handleFeed(format, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.serveVariant(w, r, a.feedVariants[defaultFeedVariant], format, contentType)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedExclusions(t *testing.T) {
+	app := newTestApp(t)
+	app.config.FeedExclusions = map[string][]string{"schule": {"sexualdelikt", "suizid"}}
+	app.feed.Add(
+		Event{Title: "Exhibitionist im Park festgenommen", Hash: "sexual", DateTime: 1},
+		Event{Title: "Einbruch in Kita", Hash: "burglary", DateTime: 2},
+		Event{Title: "Person nach Suizidversuch gerettet", Description: "Suizidal", Hash: "suicide", DateTime: 3},
+	)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for _, c := range []struct {
+		path string
+		want []string
+	}{
+		{"/rss", []string{"sexual", "burglary", "suicide"}},
+		{"/rss?exclude=sexualdelikt", []string{"burglary", "suicide"}},
+		{"/atom?subscription=schule", []string{"burglary"}},
+		{"/json?exclude=suizid", []string{"sexual", "burglary"}},
+		{"/json?subscription=schule&exclude=Eigentum", nil},
+	} {
+		res, err := http.Get(server.URL + c.path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", c.path, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d", c.path, res.StatusCode)
+		}
+		var got []string
+		for _, hash := range []string{"sexual", "burglary", "suicide"} {
+			if strings.Contains(string(body), ">"+hash+"<") || strings.Contains(string(body), `"id": "`+hash+`"`) {
+				got = append(got, hash)
+			}
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("GET %s: got items %v, want %v", c.path, got, c.want)
+		}
+	}
+
+	res, err := http.Get(server.URL + "/rss?subscription=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown subscription, got %d", res.StatusCode)
+	}
+}
+
+func TestFeedExclusionsEnv(t *testing.T) {
+	t.Setenv("FEED_EXCLUSIONS", "schule=Sexualdelikt|suizid,invalid,leer=")
+	got := feedExclusionsEnv("FEED_EXCLUSIONS")
+	if len(got) != 1 || !slices.Equal(got["schule"], []string{"sexualdelikt", "suizid"}) {
+		t.Fatalf("unexpected exclusions %v", got)
+	}
+}
+
+func TestFeedSince(t *testing.T) {
+	app := newTestApp(t)
+	events := []Event{
+		{Title: "Früh", Hash: "early", DateTime: time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC).Unix()},
+		{Title: "Spät", Hash: "late", DateTime: time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC).Unix()},
+	}
+	for i := range events {
+		if err := app.store.Create(context.Background(), &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.feed.Add(events...)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	// 09:30 in Berlin, given as an offset and as a Unix time.
+	unix := strconv.FormatInt(time.Date(2026, 3, 14, 9, 30, 0, 0, berlin).Unix(), 10)
+	for _, path := range []string{"/rss?since=2026-03-14T09:30:00%2B01:00", "/json?since=" + unix, "/rss/all?since=2026-03-14T08:30:00Z"} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK || strings.Contains(string(body), "Früh") || !strings.Contains(string(body), "Spät") {
+			t.Fatalf("GET %s: expected only the later item, got %d\n%s", path, res.StatusCode, body)
+		}
+	}
+
+	res, err := http.Get(server.URL + "/atom?since=gestern")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid since, got %d", res.StatusCode)
+	}
+}
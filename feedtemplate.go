@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// feedItemData is what the item templates of a feed variant can refer to,
+// e.g. "[{{.Bezirk}}] {{.Title}}".
+type feedItemData struct {
+	Title        string
+	Description  string
+	Bezirk       string
+	Link         string
+	Hash         string
+	ParentHash   string
+	Published    time.Time
+	Severity     int
+	SeverityName string
+	Categories   []string
+	// Emoji is the emoji prefix for the severity and categories, see
+	// emojiPrefix.
+	Emoji string
+	// ChangeSummary says what the latest re-scrape changed, if anything.
+	ChangeSummary string
+}
+
+func newFeedItemData(event *Event, classification Classification) feedItemData {
+	return feedItemData{
+		Title:         event.Title,
+		Description:   event.Description,
+		Bezirk:        event.Location,
+		Link:          event.Link,
+		Hash:          event.Hash,
+		ParentHash:    event.ParentHash,
+		Published:     time.Unix(event.DateTime, 0).In(berlin),
+		Severity:      classification.Severity,
+		SeverityName:  classification.SeverityName,
+		Categories:    classification.Categories,
+		Emoji:         emojiPrefix(classification),
+		ChangeSummary: event.ChangeSummary,
+	}
+}
+
+// parseItemTemplate parses an item template of a variant. It is tried on
+// empty data once, so that misspelled fields fail at startup rather than on
+// every render.
+func parseItemTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	err = tmpl.Execute(&bytes.Buffer{}, feedItemData{})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// templated reports whether the variant formats its items itself.
+func (v *feedVariant) templated() bool {
+	return v.itemTitle != nil || v.itemDescription != nil || v.Emoji
+}
+
+// formatItem applies the item templates of the variant, then the emoji
+// prefix if enabled. Should a template fail, the item keeps its usual text.
+func (v *feedVariant) formatItem(item *feeds.Item, data feedItemData) {
+	execute := func(tmpl *template.Template, target *string) {
+		if tmpl == nil {
+			return
+		}
+		var b strings.Builder
+		err := tmpl.Execute(&b, data)
+		if err != nil {
+			log.Printf("Error formatting item %s of %s: %v", data.Hash, v.Name, err)
+			return
+		}
+		*target = b.String()
+	}
+	execute(v.itemTitle, &item.Title)
+	execute(v.itemDescription, &item.Description)
+	if v.Emoji && data.Emoji != "" {
+		item.Title = data.Emoji + " " + item.Title
+	}
+}
+
+// compileItemTemplates parses the item templates of the variant.
+func (v *feedVariant) compileItemTemplates() error {
+	var err error
+	v.itemTitle, err = parseItemTemplate("itemTitle", v.ItemTitle)
+	if err != nil {
+		return fmt.Errorf("itemTitle: %w", err)
+	}
+	v.itemDescription, err = parseItemTemplate("itemDescription", v.ItemDescription)
+	if err != nil {
+		return fmt.Errorf("itemDescription: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFeedVariants_ItemTemplates(t *testing.T) {
+	app := newTestApp(t)
+	var err error
+	app.feedVariants, err = loadFeedVariants(writeFeedVariants(t, `[{
+		"name": "kurz",
+		"itemTitle": "[{{.Bezirk}}] {{.Title}}",
+		"itemDescription": "{{.SeverityName}}: {{range .Categories}}#{{.}} {{end}}"
+	}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := Event{Title: "Messerangriff am Alexanderplatz", Location: "Mitte", Hash: "knife", DateTime: 1}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/f/kurz.rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	for _, want := range []string{"<title>[Mitte] Messerangriff am Alexanderplatz</title>", "<description>medium: #gewalt </description>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+
+	for _, content := range []string{
+		`[{"name": "kaputt", "itemTitle": "{{.Title"}]`,
+		`[{"name": "kaputt", "itemDescription": "{{.District}}"}]`,
+	} {
+		if _, err := loadFeedVariants(writeFeedVariants(t, content)); err == nil {
+			t.Errorf("expected %s to be rejected", content)
+		}
+	}
+}
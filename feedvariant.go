@@ -0,0 +1,249 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/gorilla/feeds"
+)
+
+// defaultFeedVariant is the variant served at /rss, /atom and /json. Defining
+// a variant of this name in FEED_VARIANTS changes the built-in feeds.
+const defaultFeedVariant = "alle"
+
+const (
+	// defaultVariantLimit is the number of items of custom variants that
+	// don't set a limit.
+	defaultVariantLimit = 100
+	maxVariantLimit     = 1000
+	// variantBatchSize is the number of events classified at once when a
+	// variant selects by category or severity.
+	variantBatchSize = 500
+)
+
+// feedFormats are the formats every feed can be rendered in, with the content
+// type served for each under /f/.
+var feedFormats = map[string]string{
+	"rss":  "application/rss+xml",
+	"atom": "application/atom+xml",
+	"json": "application/json",
+}
+
+var variantName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// feedVariant is a named feed defined by the operator, served under
+// /f/{name}.{rss|atom|json}. Zero values select everything.
+type feedVariant struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Limit is the number of newest events the variant holds, see
+	// defaultVariantLimit.
+	Limit int `json:"limit"`
+	// Formats restricts the formats the variant is served in.
+	Formats  []string `json:"formats"`
+	District string   `json:"district"`
+	// Categories keeps only events of one of these categories, Exclude
+	// drops those of any of them, as classified by the active rules.
+	Categories  []string `json:"categories"`
+	Exclude     []string `json:"exclude"`
+	MinSeverity int      `json:"minSeverity"`
+	// ItemTitle and ItemDescription are Go templates replacing the title
+	// and description of the items, see feedItemData.
+	ItemTitle       string `json:"itemTitle"`
+	ItemDescription string `json:"itemDescription"`
+	// Emoji prefixes the item titles with emoji for their severity and
+	// category, see emojiPrefix.
+	Emoji bool `json:"emoji"`
+
+	itemTitle, itemDescription *template.Template
+}
+
+// loadFeedVariants reads the variants from a JSON file. The default variant is
+// always defined, as the full feed unless the file says otherwise.
+func loadFeedVariants(path string) (map[string]*feedVariant, error) {
+	variants := map[string]*feedVariant{defaultFeedVariant: {Name: defaultFeedVariant}}
+	if path == "" {
+		return variants, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var defined []*feedVariant
+	err = json.Unmarshal(data, &defined)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for i, variant := range defined {
+		if !variantName.MatchString(variant.Name) {
+			return nil, fmt.Errorf("%s: variant %d needs a name of lower case letters, digits, - and _", path, i)
+		}
+		if seen[variant.Name] {
+			return nil, fmt.Errorf("%s: variant %s is defined twice", path, variant.Name)
+		}
+		seen[variant.Name] = true
+		if variant.Limit < 0 || variant.Limit > maxVariantLimit {
+			return nil, fmt.Errorf("%s: variant %s: limit must not exceed %d", path, variant.Name, maxVariantLimit)
+		}
+		for _, format := range variant.Formats {
+			if _, ok := feedFormats[format]; !ok {
+				return nil, fmt.Errorf("%s: variant %s: unknown format %q", path, variant.Name, format)
+			}
+		}
+		if variant.MinSeverity < severityLow || variant.MinSeverity > severityHigh {
+			return nil, fmt.Errorf("%s: variant %s: minSeverity must be between %d and %d", path, variant.Name, severityLow, severityHigh)
+		}
+		err = variant.compileItemTemplates()
+		if err != nil {
+			return nil, fmt.Errorf("%s: variant %s: %w", path, variant.Name, err)
+		}
+		variant.Categories = splitCategories(strings.Join(variant.Categories, ","), ",")
+		variant.Exclude = splitCategories(strings.Join(variant.Exclude, ","), ",")
+		variants[variant.Name] = variant
+	}
+	return variants, nil
+}
+
+func (v *feedVariant) path() string { return "/f/" + v.Name }
+
+func (v *feedVariant) serves(format string) bool {
+	return len(v.Formats) == 0 || slices.Contains(v.Formats, format)
+}
+
+// custom reports whether the variant differs from the full feed, which is
+// served from the cached renders.
+func (v *feedVariant) custom() bool {
+	return v.Title != "" || v.Description != "" || v.Limit > 0 || v.District != "" ||
+		len(v.Categories) > 0 || len(v.Exclude) > 0 || v.MinSeverity > severityLow || v.templated()
+}
+
+// selects reports whether the classification of an event matches the
+// category and severity filters, leaving out those in exclude.
+func (v *feedVariant) selects(classification Classification, exclude []string) bool {
+	if classification.Severity < v.MinSeverity {
+		return false
+	}
+	var included bool
+	for _, category := range classification.Categories {
+		category = strings.ToLower(category)
+		if slices.Contains(exclude, category) {
+			return false
+		}
+		included = included || slices.Contains(v.Categories, category)
+	}
+	return included || len(v.Categories) == 0
+}
+
+// variantEvents returns the newest events of the variant, newest first,
+// without those of the categories in exclude.
+func (a *App) variantEvents(ctx context.Context, variant *feedVariant, exclude []string) ([]Event, error) {
+	limit := cmp.Or(variant.Limit, defaultVariantLimit)
+	filter := EventFilter{District: variant.District, Sources: []string{sourceBerlin}}
+	if len(variant.Categories) == 0 && len(exclude) == 0 && variant.MinSeverity == severityLow {
+		return a.store.Recent(ctx, filter, limit)
+	}
+
+	rules := a.rules()
+	var events []Event
+	err := a.store.EachBatch(ctx, filter, variantBatchSize, func(batch []Event) error {
+		for _, event := range batch {
+			if variant.selects(classifyEvent(rules, &event), exclude) {
+				events = append(events, event)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(events, func(a, b Event) int {
+		return cmp.Or(cmp.Compare(b.DateTime, a.DateTime), strings.Compare(a.Hash, b.Hash))
+	})
+	return events[:min(len(events), limit)], nil
+}
+
+// renderVariant renders a variant in format, leaving out the categories given
+// by the request (see feedExclusions) and the items published before ?since=
+// (see parseSince) too. The full feed comes from the cached renders, which
+// are compressed already; other renders are not compressed.
+func (a *App) renderVariant(r *http.Request, variant *feedVariant, format string) (compressedBody, error) {
+	exclude, err := a.feedExclusions(r.URL.Query())
+	if err != nil {
+		return compressedBody{}, err
+	}
+	since, err := parseSince(r.URL.Query())
+	if err != nil {
+		return compressedBody{}, err
+	}
+	if !variant.custom() {
+		if len(exclude) == 0 && since == 0 {
+			return a.feed.Rendered(format), nil
+		}
+		excluded := a.excludedItem(exclude)
+		body, err := a.feed.Filtered(format, func(item *feeds.Item) bool {
+			return item.Created.Unix() <= since || (len(exclude) > 0 && excluded(item))
+		})
+		return compressedBody{body: []byte(body)}, err
+	}
+	for _, category := range variant.Exclude {
+		if !slices.Contains(exclude, category) {
+			exclude = append(exclude, category)
+		}
+	}
+	events, err := a.variantEvents(r.Context(), variant, exclude)
+	if err != nil {
+		return compressedBody{}, err
+	}
+	events = slices.DeleteFunc(events, func(event Event) bool { return event.DateTime <= since })
+	var formatItem func(*Event, *feeds.Item)
+	if variant.templated() {
+		rules := a.rules()
+		formatItem = func(event *Event, item *feeds.Item) {
+			variant.formatItem(item, newFeedItemData(event, classifyEvent(rules, event)))
+		}
+	}
+	body, err := a.feed.Variant(format, variant, events, formatItem)
+	return compressedBody{body: []byte(body)}, err
+}
+
+// handleVariant serves /f/{name}.{format}.
+func (a *App) handleVariant(w http.ResponseWriter, r *http.Request) {
+	name, format, _ := strings.Cut(r.PathValue("file"), ".")
+	variant, ok := a.feedVariants[name]
+	contentType, known := feedFormats[format]
+	if !ok || !known || !variant.serves(format) {
+		http.NotFound(w, r)
+		return
+	}
+	a.serveVariant(w, r, variant, format, contentType)
+}
+
+func (a *App) serveVariant(w http.ResponseWriter, r *http.Request, variant *feedVariant, format, contentType string) {
+	body, err := a.renderVariant(r, variant, format)
+	if errors.Is(err, errUnknownSubscription) || errors.Is(err, errInvalidSince) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error rendering %s of %s: %v", format, variant.Name, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, err = w.Write(body.negotiate(w, r))
+	if err != nil {
+		log.Printf("Error writing %s: %v", format, err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFeedVariants(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "variants.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFeedVariants(t *testing.T) {
+	variants, err := loadFeedVariants("")
+	if err != nil || len(variants) != 1 || variants[defaultFeedVariant].custom() {
+		t.Fatalf("expected only the plain default variant, got %v, %v", variants, err)
+	}
+
+	for _, content := range []string{
+		`[{"name": "Mitte"}]`,
+		`[{"name": "mitte"}, {"name": "mitte"}]`,
+		`[{"name": "mitte", "limit": 5000}]`,
+		`[{"name": "mitte", "formats": ["html"]}]`,
+		`[{"name": "mitte", "minSeverity": 3}]`,
+	} {
+		if _, err := loadFeedVariants(writeFeedVariants(t, content)); err == nil {
+			t.Errorf("expected %s to be rejected", content)
+		}
+	}
+}
+
+func TestFeedVariants(t *testing.T) {
+	app := newTestApp(t)
+	app.config.BaseURL = "https://feed.example.org"
+	app.feed.SetBaseURL(app.config.BaseURL)
+	var err error
+	app.feedVariants, err = loadFeedVariants(writeFeedVariants(t, `[
+		{"name": "mitte", "title": "Polizeimeldungen Mitte", "district": "Mitte", "limit": 1, "formats": ["rss", "json"]},
+		{"name": "gewalt", "categories": ["Gewalt"], "exclude": ["sexualdelikt"]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{
+		{Title: "Schlägerei in Mitte", Location: "Mitte", Hash: "old-mitte", DateTime: 1},
+		{Title: "Einbruch in Mitte", Location: "Mitte", Hash: "new-mitte", DateTime: 2},
+		{Title: "Messerangriff in Pankow", Location: "Pankow", Hash: "knife", DateTime: 3},
+		{Title: "Exhibitionist hat Passantin angegriffen", Location: "Pankow", Hash: "sexual", DateTime: 4},
+	}
+	for i := range events {
+		if err := app.store.Create(context.Background(), &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.feed.Add(events...)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+	items := func(body string) []string {
+		var got []string
+		for _, event := range events {
+			if strings.Contains(body, "/api/events/"+event.Hash) {
+				got = append(got, event.Hash)
+			}
+		}
+		return got
+	}
+
+	status, body := get("/f/mitte.rss")
+	if status != http.StatusOK || !slices.Equal(items(body), []string{"new-mitte"}) {
+		t.Fatalf("unexpected /f/mitte.rss (%d): %v", status, items(body))
+	}
+	if !strings.Contains(body, "<title>Polizeimeldungen Mitte</title>") || !strings.Contains(body, `href="https://feed.example.org/f/mitte.rss" rel="self"`) {
+		t.Fatalf("variant title or self link missing:\n%s", body)
+	}
+	if status, _ := get("/f/mitte.atom"); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for a format the variant isn't served in, got %d", status)
+	}
+	if status, _ := get("/f/unknown.rss"); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown variant, got %d", status)
+	}
+
+	if status, body := get("/f/gewalt.json"); status != http.StatusOK || !slices.Equal(items(body), []string{"old-mitte", "knife"}) {
+		t.Fatalf("unexpected /f/gewalt.json (%d): %v", status, items(body))
+	}
+	if status, body := get("/f/alle.atom?exclude=gewalt"); status != http.StatusOK || !slices.Equal(items(body), []string{"new-mitte"}) {
+		t.Fatalf("unexpected /f/alle.atom (%d): %v", status, items(body))
+	}
+}
+
+func TestFeedVariants_DefaultReplacesBuiltIn(t *testing.T) {
+	app := newTestApp(t)
+	var err error
+	app.feedVariants, err = loadFeedVariants(writeFeedVariants(t, `[{"name": "alle", "title": "Nur das Neueste", "limit": 1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []Event{{Title: "Älter", Hash: "older", DateTime: 1}, {Title: "Neuer", Hash: "newer", DateTime: 2}} {
+		if err := app.store.Create(context.Background(), &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if !strings.Contains(string(body), "Nur das Neueste") || !strings.Contains(string(body), ">newer<") || strings.Contains(string(body), ">older<") {
+		t.Fatalf("/rss doesn't serve the redefined default variant:\n%s", body)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newFixtureApp(t *testing.T) *App {
+	t.Helper()
+	store, db := openTestStore(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+
+	config := Config{
+		PoliceURL: "https://www.berlin.de/polizei/polizeimeldungen/",
+		SourceDir: "testdata/fixtures",
+	}
+	client, err := newScraperClient(config)
+	if err != nil {
+		t.Fatalf("newScraperClient failed: %v", err)
+	}
+	app, err := NewApp(context.Background(), config, client, store, NewFeedBuilder(config.PoliceURL))
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	return app
+}
+
+func TestFixtureTransport_NotFound(t *testing.T) {
+	client := &http.Client{Transport: fixtureTransport{dir: "testdata/fixtures"}}
+	res, err := client.Get("https://www.berlin.de/does/not/exist.php")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing fixture, got %d", res.StatusCode)
+	}
+}
+
+func TestScrape_FromFixtures(t *testing.T) {
+	app := newFixtureApp(t)
+	ctx := context.Background()
+
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	events, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	// Four reports, one of them split into two incidents.
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events, got %d", len(events))
+	}
+	for _, event := range events {
+		if event.Description == defaultDescription || event.Location == "" || !strings.HasPrefix(event.Link, eventLinkPrefix+"/polizei/") {
+			t.Fatalf("event not fully parsed: %+v", event)
+		}
+	}
+
+	rss := app.feed.RSS()
+	for _, want := range []string{"Festnahme nach Raub in Späti", "Bezirk: Neukölln", "Spätkauf in Neukölln", "Bezirk: Spandau"} {
+		if !strings.Contains(rss, want) {
+			t.Fatalf("feed missing %q", want)
+		}
+	}
+
+	// A second run must not store anything twice.
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+	events, err = app.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events after rescrape, got %d", len(events))
+	}
+}
+
+func TestScrape_RefreshesKnownEvents(t *testing.T) {
+	app := newFixtureApp(t)
+	ctx := context.Background()
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	events, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+
+	// Simulate a failed detail page fetch and an outdated district on one
+	// event, and a manual correction on another.
+	stale, edited := events[0], events[1]
+	stale.Description, stale.Location = defaultDescription, "Unbekannt"
+	edited.Location = "Korrigiert"
+	edited.Edits = []EventEdit{{Field: "district", Old: events[1].Location, New: "Korrigiert"}}
+	for _, event := range []*Event{&stale, &edited} {
+		if err := app.store.Update(ctx, event); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+	}
+
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+	refreshed, err := app.store.FindByHash(ctx, stale.Hash)
+	if err != nil {
+		t.Fatalf("FindByHash failed: %v", err)
+	}
+	if refreshed.Description != events[0].Description || refreshed.Location != events[0].Location {
+		t.Fatalf("expected stale event to be refreshed, got %+v", refreshed)
+	}
+	kept, err := app.store.FindByHash(ctx, edited.Hash)
+	if err != nil {
+		t.Fatalf("FindByHash failed: %v", err)
+	}
+	if kept.Location != "Korrigiert" {
+		t.Fatalf("expected manual correction to be kept, got %q", kept.Location)
+	}
+
+	all, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(all) != len(events) {
+		t.Fatalf("expected %d events after refresh, got %d", len(events), len(all))
+	}
+}
+
+func TestScrape_AmendedReportKeepsIdentity(t *testing.T) {
+	app := newFixtureApp(t)
+	ctx := context.Background()
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	events, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+
+	// Pretend the report was stored under its original title before the
+	// police amended it to the one on the list page now.
+	amended := events[0]
+	title := amended.Title
+	amended.Title = "Ursprünglicher Titel"
+	if err := app.store.Update(ctx, &amended); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	if err := app.scrape(ctx); err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+	all, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(all) != len(events) {
+		t.Fatalf("expected amended report not to be stored twice, got %d events", len(all))
+	}
+	refreshed, err := app.store.FindByHash(ctx, amended.Hash)
+	if err != nil {
+		t.Fatalf("FindByHash failed: %v", err)
+	}
+	if refreshed.Title != title || refreshed.ContentHash == amended.ContentHash {
+		t.Fatalf("expected amended title to be picked up, got %+v", refreshed)
+	}
+
+	revisions, err := app.store.Revisions(ctx, amended.Hash)
+	if err != nil {
+		t.Fatalf("Revisions failed: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Field != "title" || revisions[0].Old != "Ursprünglicher Titel" || revisions[0].New != title {
+		t.Fatalf("expected the amendment to be recorded, got %+v", revisions)
+	}
+	if refreshed.ChangeSummary != "Aktualisiert: Titel" || !strings.Contains(app.feed.RSS(), "Aktualisiert: Titel") {
+		t.Fatalf("expected the feed item to carry a change summary, got %q", refreshed.ChangeSummary)
+	}
+}
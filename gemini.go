@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// geminiMaxRequest is the longest request line the protocol allows: a
+	// URL of up to 1024 bytes plus CRLF.
+	geminiMaxRequest = 1026
+	geminiTimeout    = 30 * time.Second
+	geminiEvents     = 50
+)
+
+// Gemini status codes, see https://geminiprotocol.net/docs/protocol-specification.gmi.
+const (
+	geminiSuccess      = 20
+	geminiTempFailure  = 40
+	geminiNotFound     = 51
+	geminiProxyRefused = 53
+	geminiBadRequest   = 59
+)
+
+// geminiHostname is the name the generated certificate is issued for.
+func geminiHostname(config Config) string {
+	if u, err := url.Parse(config.BaseURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "localhost"
+}
+
+// loadGeminiCertificate loads the certificate for the Gemini server. Gemini
+// clients pin certificates on first use instead of relying on certificate
+// authorities, so a self-signed one is generated and stored if there is none
+// yet, to be reused on every start.
+func loadGeminiCertificate(certFile, keyFile, hostname string) (tls.Certificate, error) {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist) {
+		err := generateGeminiCertificate(certFile, keyFile, hostname)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("generating certificate: %w", err)
+		}
+		log.Printf("Generated self-signed Gemini certificate for %s in %s", hostname, certFile)
+	}
+	return tls.LoadX509KeyPair(certFile, keyFile)
+}
+
+func generateGeminiCertificate(certFile, keyFile, hostname string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{hostname},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range []string{certFile, keyFile} {
+		err = os.MkdirAll(filepath.Dir(file), 0o755)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+// runGemini serves the feed over the Gemini protocol on GeminiAddress until
+// ctx is cancelled.
+func (a *App) runGemini(ctx context.Context) {
+	if a.config.GeminiAddress == "" {
+		return
+	}
+	cert, err := loadGeminiCertificate(a.config.GeminiCert, a.config.GeminiKey, geminiHostname(a.config))
+	if err != nil {
+		log.Println("Error loading Gemini certificate:", err)
+		return
+	}
+	listener, err := tls.Listen("tcp", a.config.GeminiAddress, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		log.Println("Error starting Gemini server:", err)
+		return
+	}
+	log.Println("Serving Gemini on", listener.Addr())
+	a.serveGemini(ctx, listener)
+}
+
+// serveGemini accepts connections on listener until ctx is cancelled.
+func (a *App) serveGemini(ctx context.Context, listener net.Listener) {
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("Error accepting Gemini connection:", err)
+			}
+			return
+		}
+		go a.handleGemini(ctx, conn)
+	}
+}
+
+// handleGemini answers a single Gemini request.
+func (a *App) handleGemini(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(geminiTimeout))
+
+	line, err := bufio.NewReaderSize(io.LimitReader(conn, geminiMaxRequest), geminiMaxRequest).ReadString('\n')
+	if err != nil || !strings.HasSuffix(line, "\r\n") {
+		writeGeminiHeader(conn, geminiBadRequest, "Bad request")
+		return
+	}
+	u, err := url.Parse(strings.TrimSuffix(line, "\r\n"))
+	if err != nil || !u.IsAbs() {
+		writeGeminiHeader(conn, geminiBadRequest, "Bad request")
+		return
+	}
+	if u.Scheme != "gemini" {
+		writeGeminiHeader(conn, geminiProxyRefused, "Only gemini URLs are served")
+		return
+	}
+
+	switch u.Path {
+	case "", "/":
+		events, err := a.store.Recent(ctx, EventFilter{}, geminiEvents)
+		if err != nil {
+			log.Println("Error loading events:", err)
+			writeGeminiHeader(conn, geminiTempFailure, "Internal error")
+			return
+		}
+		writeGeminiHeader(conn, geminiSuccess, "text/gemini; charset=utf-8; lang=de")
+		_, err = io.WriteString(conn, renderGemtext(a.redactions.events(events), newAPIMeta(a.config)))
+	case "/atom.xml":
+		writeGeminiHeader(conn, geminiSuccess, "application/atom+xml")
+		_, err = io.WriteString(conn, a.feed.Atom())
+	default:
+		writeGeminiHeader(conn, geminiNotFound, "Not found")
+	}
+	if err != nil {
+		log.Println("Error writing Gemini response:", err)
+	}
+}
+
+func writeGeminiHeader(w io.Writer, status int, meta string) {
+	_, _ = fmt.Fprintf(w, "%d %s\r\n", status, meta)
+}
+
+// renderGemtext lists the events newest first. Each event starts with a
+// link line carrying its date, so that Gemini feed readers can subscribe to
+// the page itself (the "gemfeed" convention).
+func renderGemtext(events []Event, meta apiMeta) string {
+	var b strings.Builder
+	b.WriteString("# Berliner Polizeimeldungen\n\n")
+	fmt.Fprintf(&b, "%s\n\n", meta.notice())
+	b.WriteString("=> /atom.xml Atom-Feed\n")
+	for _, event := range events {
+		// Event times are Berlin wall clock times stored as UTC.
+		published := time.Unix(event.DateTime, 0).UTC()
+		fmt.Fprintf(&b, "\n## %s\n\n", event.Title)
+		if event.Link != "" {
+			fmt.Fprintf(&b, "=> %s %s %s\n", event.Link, published.Format(time.DateOnly), event.Title)
+		}
+		fmt.Fprintf(&b, "%s, %s\n", event.Location, published.Format("02.01.2006, 15:04 Uhr"))
+		if description := strings.TrimSpace(event.Description); description != "" {
+			// Lines starting with gemtext markup would be misinterpreted.
+			for _, line := range strings.Split(description, "\n") {
+				if strings.ContainsAny(line[:min(len(line), 1)], "=#*>`") {
+					line = " " + line
+				}
+				fmt.Fprintf(&b, "%s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
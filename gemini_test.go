@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGemini_ServesGemtextAndAtom(t *testing.T) {
+	app := newTestApp(t)
+	seedExportEvents(t, app.store)
+	dir := t.TempDir()
+	cert, err := loadGeminiCertificate(filepath.Join(dir, "gemini.crt"), filepath.Join(dir, "gemini.key"), "localhost")
+	if err != nil {
+		t.Fatalf("loadGeminiCertificate failed: %v", err)
+	}
+	// The second load must reuse the stored certificate.
+	again, err := loadGeminiCertificate(filepath.Join(dir, "gemini.crt"), filepath.Join(dir, "gemini.key"), "localhost")
+	if err != nil || string(again.Certificate[0]) != string(cert.Certificate[0]) {
+		t.Fatalf("expected the stored certificate to be reused, got %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go app.serveGemini(ctx, listener)
+
+	request := func(line string) string {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		if _, err := io.WriteString(conn, line); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		response, _ := io.ReadAll(conn)
+		return string(response)
+	}
+
+	response := request("gemini://localhost/\r\n")
+	if !strings.HasPrefix(response, "20 text/gemini; charset=utf-8; lang=de\r\n# Berliner Polizeimeldungen") {
+		t.Fatalf("unexpected response:\n%s", response)
+	}
+	if strings.Index(response, "## Unfall") > strings.Index(response, "## Raub") {
+		t.Fatalf("expected newest events first:\n%s", response)
+	}
+
+	if response := request("gemini://localhost/atom.xml\r\n"); !strings.HasPrefix(response, "20 application/atom+xml\r\n<?xml") {
+		t.Fatalf("unexpected atom response:\n%s", response)
+	}
+	for line, want := range map[string]string{
+		"gemini://localhost/missing\r\n": "51 ",
+		"https://localhost/\r\n":         "53 ",
+		"/relative\r\n":                  "59 ",
+		strings.Repeat("a", 2000):        "59 ",
+	} {
+		if response := request(line); !strings.HasPrefix(response, want) {
+			t.Fatalf("expected %q for %.20q, got %q", want, line, response)
+		}
+	}
+}
+
+func TestRenderGemtext_EscapesMarkup(t *testing.T) {
+	text := renderGemtext([]Event{{Title: "T", Location: "Mitte", Description: "# keine Überschrift\n=> kein Link\nnormal"}}, apiMeta{Source: "src"})
+	for _, want := range []string{"\n # keine Überschrift\n", "\n => kein Link\n", "\nnormal\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The Grafana endpoints implement the contract of the JSON datasource
+// (https://grafana.com/grafana/plugins/simpod-json-datasource/), so event
+// counts can be graphed in existing dashboards. Targets are "events" for all
+// of Berlin, "district:<name>" and "category:<name>".
+
+const (
+	grafanaTargetEvents   = "events"
+	grafanaPrefixDistrict = "district:"
+	grafanaPrefixCategory = "category:"
+	// grafanaMaxBuckets bounds the data points of a target; coarser buckets
+	// are used for longer ranges.
+	grafanaMaxBuckets = 2000
+)
+
+// grafanaQuery is the body of a query request; fields not needed here are
+// left out.
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs int64 `json:"intervalMs"`
+	Targets    []struct {
+		Target string `json:"target"`
+		Type   string `json:"type"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string     `json:"target"`
+	Datapoints [][2]int64 `json:"datapoints"`
+}
+
+type grafanaTable struct {
+	Type    string              `json:"type"`
+	Columns []map[string]string `json:"columns"`
+	Rows    [][2]any            `json:"rows"`
+}
+
+// grafanaBucket returns the start of the bucket t falls in and the start of
+// the next one. Buckets follow the calendar in Berlin time: hours, days,
+// weeks starting on Monday or months, whichever is the first not finer than
+// interval.
+func grafanaBucket(t time.Time, interval time.Duration) (start, next time.Time) {
+	t = t.In(berlin)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, berlin)
+	switch {
+	case interval <= time.Hour:
+		start = t.Truncate(time.Hour)
+		return start, start.Add(time.Hour)
+	case interval <= 24*time.Hour:
+		return day, day.AddDate(0, 0, 1)
+	case interval <= 7*24*time.Hour:
+		start = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	default:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, berlin)
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// handleGrafanaSearch lists the targets: Berlin, every district and every
+// category of the active rules.
+func (a *App) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	targets := []string{grafanaTargetEvents}
+	for _, district := range berlinDistricts {
+		targets = append(targets, grafanaPrefixDistrict+district)
+	}
+	var categories []string
+	for _, rule := range a.rules() {
+		if rule.Category != "" && !slices.Contains(categories, rule.Category) {
+			categories = append(categories, rule.Category)
+		}
+	}
+	slices.Sort(categories)
+	for _, category := range categories {
+		targets = append(targets, grafanaPrefixCategory+category)
+	}
+	writeJSON(w, http.StatusOK, targets)
+}
+
+// handleGrafanaQuery counts the events of each target per bucket of the
+// requested range, by publication time. Targets of type "table" are answered
+// with a table of the same numbers.
+func (a *App) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var query grafanaQuery
+	err := json.NewDecoder(r.Body).Decode(&query)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if query.Range.From.IsZero() || !query.Range.From.Before(query.Range.To) {
+		writeJSONError(w, http.StatusBadRequest, "invalid range")
+		return
+	}
+	interval := time.Duration(query.IntervalMs) * time.Millisecond
+	if span := query.Range.To.Sub(query.Range.From); interval < span/grafanaMaxBuckets {
+		interval = span / grafanaMaxBuckets
+	}
+
+	var buckets []time.Time
+	index := make(map[int64]int)
+	for t, _ := grafanaBucket(query.Range.From, interval); t.Before(query.Range.To); _, t = grafanaBucket(t, interval) {
+		index[t.Unix()] = len(buckets)
+		buckets = append(buckets, t)
+	}
+	counts := make(map[string][]int64, len(query.Targets))
+	for _, target := range query.Targets {
+		if target.Target != grafanaTargetEvents && !strings.HasPrefix(target.Target, grafanaPrefixDistrict) && !strings.HasPrefix(target.Target, grafanaPrefixCategory) {
+			writeJSONError(w, http.StatusBadRequest, "unknown target "+target.Target)
+			return
+		}
+		counts[target.Target] = make([]int64, len(buckets))
+	}
+
+	rules := a.rules()
+	filter := EventFilter{From: query.Range.From, To: query.Range.To}
+	err = a.store.EachBatch(r.Context(), filter, exportBatchSize, func(events []Event) error {
+		for _, event := range events {
+			start, _ := grafanaBucket(time.Unix(event.DateTime, 0), interval)
+			i, ok := index[start.Unix()]
+			if !ok {
+				continue
+			}
+			var categories []string
+			classified := false
+			for target, series := range counts {
+				switch {
+				case target == grafanaTargetEvents:
+				case strings.HasPrefix(target, grafanaPrefixDistrict):
+					if !strings.EqualFold(strings.TrimPrefix(target, grafanaPrefixDistrict), event.Location) {
+						continue
+					}
+				case strings.HasPrefix(target, grafanaPrefixCategory):
+					if !classified {
+						categories, classified = classifyEvent(rules, &event).Categories, true
+					}
+					if !slices.Contains(categories, strings.TrimPrefix(target, grafanaPrefixCategory)) {
+						continue
+					}
+				}
+				series[i]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Error querying events for Grafana:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	response := make([]any, 0, len(query.Targets))
+	for _, target := range query.Targets {
+		series := counts[target.Target]
+		if target.Type == "table" {
+			table := grafanaTable{
+				Type:    "table",
+				Columns: []map[string]string{{"text": "Time", "type": "time"}, {"text": target.Target, "type": "number"}},
+				Rows:    make([][2]any, len(buckets)),
+			}
+			for i, start := range buckets {
+				table.Rows[i] = [2]any{start.UnixMilli(), series[i]}
+			}
+			response = append(response, table)
+			continue
+		}
+		result := grafanaSeries{Target: target.Target, Datapoints: make([][2]int64, len(buckets))}
+		for i, start := range buckets {
+			result.Datapoints[i] = [2]int64{series[i], start.UnixMilli()}
+		}
+		response = append(response, result)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
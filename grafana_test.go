@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGrafanaBucket(t *testing.T) {
+	at := time.Date(2025, 3, 13, 14, 30, 0, 0, berlin)
+	for _, c := range []struct {
+		interval    time.Duration
+		start, next time.Time
+	}{
+		{time.Minute, time.Date(2025, 3, 13, 14, 0, 0, 0, berlin), time.Date(2025, 3, 13, 15, 0, 0, 0, berlin)},
+		{6 * time.Hour, time.Date(2025, 3, 13, 0, 0, 0, 0, berlin), time.Date(2025, 3, 14, 0, 0, 0, 0, berlin)},
+		{48 * time.Hour, time.Date(2025, 3, 10, 0, 0, 0, 0, berlin), time.Date(2025, 3, 17, 0, 0, 0, 0, berlin)},
+		{30 * 24 * time.Hour, time.Date(2025, 3, 1, 0, 0, 0, 0, berlin), time.Date(2025, 4, 1, 0, 0, 0, 0, berlin)},
+	} {
+		start, next := grafanaBucket(at, c.interval)
+		if !start.Equal(c.start) || !next.Equal(c.next) {
+			t.Errorf("%s: got %s to %s", c.interval, start, next)
+		}
+	}
+}
+
+func TestGrafanaEndpoints(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	ctx := context.Background()
+	for _, event := range []Event{
+		{Title: "Schüsse", Hash: "a", Location: "Neukölln", DateTime: time.Date(2025, 3, 1, 10, 0, 0, 0, berlin).Unix()},
+		{Title: "Brand", Hash: "b", Location: "Mitte", DateTime: time.Date(2025, 3, 1, 23, 30, 0, 0, berlin).Unix()},
+		{Title: "Raub", Hash: "c", Location: "Neukölln", DateTime: time.Date(2025, 3, 3, 8, 0, 0, 0, berlin).Unix()},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/grafana/", nil)
+	req.Header.Set("Authorization", "Bearer stats")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the health check to pass, got %d", res.StatusCode)
+	}
+
+	res = postJSON(t, server.URL+"/grafana/search", "stats", `{"target": ""}`)
+	var targets []string
+	err = json.NewDecoder(res.Body).Decode(&targets)
+	_ = res.Body.Close()
+	if err != nil || !slices.Contains(targets, "events") || !slices.Contains(targets, "district:Neukölln") || !slices.Contains(targets, "category:gewalt") {
+		t.Fatalf("unexpected targets %v (%v)", targets, err)
+	}
+
+	res = postJSON(t, server.URL+"/grafana/query", "stats", `{
+		"range": {"from": "2025-02-28T23:00:00Z", "to": "2025-03-03T23:00:00Z"},
+		"intervalMs": 86400000,
+		"targets": [{"target": "events"}, {"target": "district:Neukölln"}, {"target": "category:gewalt", "type": "table"}]
+	}`)
+	var response []json.RawMessage
+	err = json.NewDecoder(res.Body).Decode(&response)
+	_ = res.Body.Close()
+	if err != nil || len(response) != 3 {
+		t.Fatalf("unexpected response %s (%v)", response, err)
+	}
+	var events, neukoelln grafanaSeries
+	_ = json.Unmarshal(response[0], &events)
+	_ = json.Unmarshal(response[1], &neukoelln)
+	day := func(d int) int64 { return time.Date(2025, 3, d, 0, 0, 0, 0, berlin).UnixMilli() }
+	wantEvents := [][2]int64{{2, day(1)}, {0, day(2)}, {1, day(3)}}
+	wantNeukoelln := [][2]int64{{1, day(1)}, {0, day(2)}, {1, day(3)}}
+	if !slices.Equal(events.Datapoints, wantEvents) || !slices.Equal(neukoelln.Datapoints, wantNeukoelln) {
+		t.Fatalf("unexpected series %+v %+v", events, neukoelln)
+	}
+	var table grafanaTable
+	_ = json.Unmarshal(response[2], &table)
+	if table.Type != "table" || len(table.Rows) != 3 || table.Rows[0][1] != float64(1) {
+		t.Fatalf("unexpected table %+v", table)
+	}
+
+	res = postJSON(t, server.URL+"/grafana/query", "stats", `{"range": {"from": "2025-03-01T00:00:00Z", "to": "2025-03-02T00:00:00Z"}, "targets": [{"target": "bogus"}]}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown target, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"gorm.io/gorm"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+)
+
+// /graphql answers read-only queries over events, facts, stats and
+// districts, so that dashboards fetch exactly the fields they need in one
+// request. The schema is policefeed.graphql, executed by graph-gophers
+// against the resolvers below. Unlike gqlgen, graph-gophers executes the
+// schema file itself, so the SDL served under /api/schema/graphql is the one
+// in effect and there is no generated code to keep in step with it; for a
+// small read-only schema, that outweighs gqlgen's typed resolvers.
+//
+// Requests are bounded in size (maxGraphQLQuery), nesting (maxGraphQLDepth)
+// and cost (maxGraphQLCost), so that aliases can't multiply expensive
+// fields.
+
+const (
+	maxGraphQLRequest = 64 << 10
+	// maxGraphQLQuery limits the length of the query document.
+	maxGraphQLQuery = 8 << 10
+	// maxGraphQLDepth limits the nesting of selections, fragments included.
+	maxGraphQLDepth = 8
+	// maxGraphQLCost limits the events a request may load, counted by the
+	// page size or limit each list field asks for before it is resolved,
+	// see chargeGraphQL. Stats count as graphQLStatsCost events.
+	maxGraphQLCost      = 1000
+	graphQLStatsCost    = 100
+	defaultGraphQLPage  = 20
+	maxGraphQLPage      = 100
+	defaultGraphQLFacts = 100
+)
+
+//go:embed policefeed.graphql
+var graphQLSchemaSource string
+
+// errGraphQL is an error that is reported to the client as is, unlike
+// internal errors.
+type errGraphQL string
+
+func (e errGraphQL) Error() string {
+	return string(e)
+}
+
+// gqlDateTime is the DateTime scalar. Inputs are parsed like the query
+// parameters of the REST API, outputs are RFC 3339 timestamps in UTC.
+type gqlDateTime struct {
+	time.Time
+}
+
+func (gqlDateTime) ImplementsGraphQLType(name string) bool {
+	return name == "DateTime"
+}
+
+func (t *gqlDateTime) UnmarshalGraphQL(input any) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("expected DateTime, got %T", input)
+	}
+	parsed, err := parseTime(s)
+	if err != nil {
+		return fmt.Errorf("invalid DateTime %q", s)
+	}
+	t.Time = parsed
+	return nil
+}
+
+func (t gqlDateTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format(time.RFC3339))
+}
+
+// errGraphQLTooComplex is reported once a request exceeds maxGraphQLCost.
+var errGraphQLTooComplex = errGraphQL(fmt.Sprintf("query too complex, a request may load at most %d events", maxGraphQLCost))
+
+type graphQLBudgetKey struct{}
+
+// chargeGraphQL takes cost from the budget of the request, failing with
+// errGraphQLTooComplex once it is used up.
+func chargeGraphQL(ctx context.Context, cost int) error {
+	budget, _ := ctx.Value(graphQLBudgetKey{}).(*atomic.Int64)
+	if budget != nil && budget.Add(-int64(cost)) < 0 {
+		return errGraphQLTooComplex
+	}
+	return nil
+}
+
+// gqlEventFilter builds the filter from the arguments every event query
+// takes.
+func gqlEventFilter(from, to *gqlDateTime, district *string) EventFilter {
+	filter := EventFilter{}
+	if from != nil {
+		filter.From = from.Time
+	}
+	if to != nil {
+		filter.To = to.Time
+	}
+	if district != nil {
+		filter.District = *district
+	}
+	return filter
+}
+
+// gqlQuery resolves the Query type.
+type gqlQuery struct {
+	app *App
+}
+
+func (q *gqlQuery) Events(ctx context.Context, args struct {
+	From     *gqlDateTime
+	To       *gqlDateTime
+	District *string
+	First    *int32
+	After    *string
+}) (*gqlConnection, error) {
+	first := defaultGraphQLPage
+	if args.First != nil {
+		first = int(*args.First)
+	}
+	if first < 1 || first > maxGraphQLPage {
+		return nil, errGraphQL(fmt.Sprintf("first must be between 1 and %d", maxGraphQLPage))
+	}
+	err := chargeGraphQL(ctx, first)
+	if err != nil {
+		return nil, err
+	}
+	after := ""
+	if args.After != nil {
+		after = *args.After
+	}
+	page, next, err := q.app.eventPage(ctx, gqlEventFilter(args.From, args.To, args.District), first, after)
+	if errors.Is(err, errInvalidCursor) {
+		return nil, errGraphQL("invalid cursor")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gqlConnection{nodes: q.app.gqlEvents(page, nil), endCursor: next}, nil
+}
+
+func (q *gqlQuery) Event(ctx context.Context, args struct{ Hash graphql.ID }) (*gqlEvent, error) {
+	err := chargeGraphQL(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	found, err := q.app.store.FindByHash(ctx, string(args.Hash))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if found.TakenDownAt != nil {
+		return nil, nil
+	}
+	return q.app.gqlEvents([]Event{*found}, nil)[0], nil
+}
+
+func (q *gqlQuery) Facts(ctx context.Context, args struct {
+	From     *gqlDateTime
+	To       *gqlDateTime
+	District *string
+	Weapon   *string
+	Vehicle  *string
+	MinAge   *int32
+	MaxAge   *int32
+	AgeRole  *string
+	Limit    *int32
+}) (*[]*gqlEvent, error) {
+	query := FactQuery{EventFilter: gqlEventFilter(args.From, args.To, args.District), Limit: defaultGraphQLFacts}
+	if args.Weapon != nil {
+		query.Weapon = *args.Weapon
+	}
+	if args.Vehicle != nil {
+		query.Vehicle = *args.Vehicle
+	}
+	if args.MinAge != nil {
+		query.MinAge = int(*args.MinAge)
+	}
+	if args.MaxAge != nil {
+		query.MaxAge = int(*args.MaxAge)
+	}
+	if args.AgeRole != nil {
+		query.AgeRole = *args.AgeRole
+	}
+	if args.Limit != nil {
+		query.Limit = int(*args.Limit)
+	}
+	err := query.validate()
+	if err != nil {
+		return nil, errGraphQL(err.Error())
+	}
+	err = chargeGraphQL(ctx, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	events, facts, err := q.app.store.EventsWithFacts(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if facts == nil {
+		facts = map[string][]EventFact{}
+	}
+	values := q.app.gqlEvents(events, facts)
+	return &values, nil
+}
+
+func (q *gqlQuery) Stats(ctx context.Context, args struct {
+	From     *gqlDateTime
+	To       *gqlDateTime
+	District *string
+	GroupBy  *string
+	Time     *string
+}) (*gqlStats, error) {
+	groupBy, timeBasis := "", ""
+	if args.GroupBy != nil {
+		groupBy = *args.GroupBy
+	}
+	if args.Time != nil {
+		timeBasis = *args.Time
+	}
+	err := chargeGraphQL(ctx, graphQLStatsCost)
+	if err != nil {
+		return nil, err
+	}
+	result, err := q.app.stats(ctx, gqlEventFilter(args.From, args.To, args.District), groupBy, timeBasis)
+	var invalid errInvalidStats
+	if errors.As(err, &invalid) {
+		return nil, errGraphQL(err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gqlStats{result}, nil
+}
+
+func (q *gqlQuery) Districts() []*gqlDistrict {
+	districts := q.app.districtMap()
+	values := make([]*gqlDistrict, len(berlinDistricts))
+	for i, name := range berlinDistricts {
+		values[i] = &gqlDistrict{name: name, aliases: districts.aliases[name]}
+		if values[i].aliases == nil {
+			values[i].aliases = []string{}
+		}
+	}
+	return values
+}
+
+// gqlEvent resolves the Event type. The facts of all events of a list are
+// loaded together on first use. Fields are resolved concurrently, hence the
+// locking.
+type gqlEvent struct {
+	app            *App
+	event          Event
+	classification func() Classification
+	batch          *gqlEventBatch
+}
+
+type gqlEventBatch struct {
+	mu     sync.Mutex
+	hashes []string
+	facts  map[string][]EventFact
+}
+
+func (a *App) gqlEvents(events []Event, facts map[string][]EventFact) []*gqlEvent {
+	events = a.redactions.events(events)
+	batch := &gqlEventBatch{facts: facts}
+	values := make([]*gqlEvent, len(events))
+	for i := range events {
+		batch.hashes = append(batch.hashes, events[i].Hash)
+		value := &gqlEvent{app: a, event: events[i], batch: batch}
+		value.classification = sync.OnceValue(func() Classification {
+			return classifyEvent(a.rules(), &value.event)
+		})
+		values[i] = value
+	}
+	return values
+}
+
+func (e *gqlEvent) Hash() graphql.ID {
+	return graphql.ID(e.event.Hash)
+}
+
+func (e *gqlEvent) Title() string {
+	return e.event.Title
+}
+
+func (e *gqlEvent) Description() string {
+	return e.event.Description
+}
+
+func (e *gqlEvent) District() string {
+	return e.event.Location
+}
+
+func (e *gqlEvent) Link() string {
+	return e.event.Link
+}
+
+func (e *gqlEvent) PublishedAt() gqlDateTime {
+	return gqlDateTime{time.Unix(e.event.DateTime, 0)}
+}
+
+func (e *gqlEvent) IncidentAt() *gqlDateTime {
+	if e.event.IncidentTime == nil {
+		return nil
+	}
+	return &gqlDateTime{time.Unix(*e.event.IncidentTime, 0)}
+}
+
+func (e *gqlEvent) ParentHash() *graphql.ID {
+	if e.event.ParentHash == "" {
+		return nil
+	}
+	hash := graphql.ID(e.event.ParentHash)
+	return &hash
+}
+
+func (e *gqlEvent) Severity() int32 {
+	return int32(e.classification().Severity)
+}
+
+func (e *gqlEvent) SeverityName() string {
+	return e.classification().SeverityName
+}
+
+func (e *gqlEvent) Categories() []string {
+	return e.classification().Categories
+}
+
+func (e *gqlEvent) Facts(ctx context.Context) ([]*gqlFact, error) {
+	e.batch.mu.Lock()
+	defer e.batch.mu.Unlock()
+	if e.batch.facts == nil {
+		facts, err := e.app.store.Facts(ctx, e.batch.hashes)
+		if err != nil {
+			return nil, err
+		}
+		e.batch.facts = facts
+	}
+	facts := toAPIFacts(e.batch.facts[e.event.Hash])
+	values := make([]*gqlFact, len(facts))
+	for i := range facts {
+		values[i] = &gqlFact{facts[i]}
+	}
+	return values, nil
+}
+
+type gqlFact struct {
+	fact model.Fact
+}
+
+func (f *gqlFact) Kind() string {
+	return f.fact.Kind
+}
+
+func (f *gqlFact) Value() string {
+	return f.fact.Value
+}
+
+func (f *gqlFact) Role() *string {
+	if f.fact.Role == "" {
+		return nil
+	}
+	return &f.fact.Role
+}
+
+// gqlConnection resolves both EventConnection and its PageInfo.
+type gqlConnection struct {
+	nodes     []*gqlEvent
+	endCursor string
+}
+
+func (c *gqlConnection) Nodes() []*gqlEvent {
+	return c.nodes
+}
+
+func (c *gqlConnection) PageInfo() *gqlConnection {
+	return c
+}
+
+func (c *gqlConnection) EndCursor() *string {
+	if c.endCursor == "" {
+		return nil
+	}
+	return &c.endCursor
+}
+
+func (c *gqlConnection) HasNextPage() bool {
+	return c.endCursor != ""
+}
+
+type gqlStats struct {
+	result statsResult
+}
+
+func (s *gqlStats) GroupBy() string {
+	return s.result.GroupBy
+}
+
+func (s *gqlStats) Time() string {
+	return s.result.Time
+}
+
+func (s *gqlStats) Buckets() []*gqlStatsBucket {
+	values := make([]*gqlStatsBucket, len(s.result.Buckets))
+	for i := range s.result.Buckets {
+		values[i] = &gqlStatsBucket{s.result.Buckets[i]}
+	}
+	return values
+}
+
+func (s *gqlStats) Unknown() int32 {
+	return int32(s.result.Unknown)
+}
+
+type gqlStatsBucket struct {
+	bucket statsBucket
+}
+
+func (b *gqlStatsBucket) Key() string {
+	return b.bucket.Key
+}
+
+func (b *gqlStatsBucket) Count() int32 {
+	return int32(b.bucket.Count)
+}
+
+type gqlDistrict struct {
+	name    string
+	aliases []string
+}
+
+func (d *gqlDistrict) Name() string {
+	return d.name
+}
+
+func (d *gqlDistrict) Aliases() []string {
+	return d.aliases
+}
+
+// graphQLSchema returns the schema of /graphql, resolving against a.
+func (a *App) graphQLSchema() *graphql.Schema {
+	return graphql.MustParseSchema(graphQLSchemaSource, &gqlQuery{app: a},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxGraphQLDepth),
+		graphql.MaxQueryLength(maxGraphQLQuery),
+	)
+}
+
+// graphQLRequest is the body of POST /graphql.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// handleGraphQL answers queries sent as JSON body or, for GET requests, as
+// query, operationName and variables parameters. Requests that can't be
+// executed at all are answered with status 400.
+func (a *App) handleGraphQL() http.HandlerFunc {
+	schema := a.graphQLSchema()
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+		if r.Method == http.MethodGet {
+			query := r.URL.Query()
+			request.Query = query.Get("query")
+			request.OperationName = query.Get("operationName")
+			if variables := query.Get("variables"); variables != "" {
+				err := json.Unmarshal([]byte(variables), &request.Variables)
+				if err != nil {
+					writeGraphQLError(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+					return
+				}
+			}
+		} else {
+			decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequest))
+			err := decoder.Decode(&request)
+			if err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+				return
+			}
+		}
+		if strings.TrimSpace(request.Query) == "" {
+			writeGraphQLError(w, http.StatusBadRequest, "query must not be empty")
+			return
+		}
+
+		budget := new(atomic.Int64)
+		budget.Store(maxGraphQLCost)
+		ctx := context.WithValue(r.Context(), graphQLBudgetKey{}, budget)
+		response := schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+		hideInternalErrors(response.Errors)
+		// Field errors come with a path. Errors without one concern the
+		// request as a whole, e.g. a variable that isn't a DateTime, and so
+		// does a request that is too complex.
+		if response.Data == nil || slices.ContainsFunc(response.Errors, func(err *gqlerrors.QueryError) bool {
+			return len(err.Path) == 0 || errors.Is(err.ResolverError, errGraphQLTooComplex)
+		}) {
+			writeJSON(w, http.StatusBadRequest, graphql.Response{Errors: response.Errors})
+			return
+		}
+		newAPIMeta(a.config).setHeaders(w)
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// hideInternalErrors logs the errors of resolvers other than errGraphQL and
+// replaces their message.
+func hideInternalErrors(queryErrors []*gqlerrors.QueryError) {
+	for _, err := range queryErrors {
+		var public errGraphQL
+		if err.ResolverError == nil || errors.As(err.ResolverError, &public) {
+			continue
+		}
+		log.Println("Error resolving GraphQL field:", err.ResolverError)
+		err.Message = "internal error"
+	}
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, graphql.Response{Errors: []*gqlerrors.QueryError{{Message: message}}})
+}
+
+// handleGraphQLSchema serves the schema of /graphql as SDL, e.g. for code
+// generators.
+func (a *App) handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, err := w.Write([]byte(graphQLSchemaSource))
+	if err != nil {
+		log.Println("Error writing GraphQL schema:", err)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postGraphQL runs a query against /graphql and returns the status and the
+// decoded response.
+func postGraphQL(t *testing.T, server *httptest.Server, query string, variables map[string]any) (int, map[string]any) {
+	t.Helper()
+	body, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/graphql", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer stats")
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /graphql failed: %v", err)
+	}
+	defer res.Body.Close()
+	var response map[string]any
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	return res.StatusCode, response
+}
+
+func TestGraphQL_Query(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	ctx := context.Background()
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, berlin)
+	events := []Event{
+		{Title: "Messerangriff", Description: "Ein 15-Jähriger hatte ein Messer dabei.", Location: "Mitte", Hash: "a", DateTime: day.Unix()},
+		{Title: "Unfall", Description: "Verkehrsunfall an der Kreuzung.", Location: "Mitte", Hash: "b", DateTime: day.Unix()},
+		{Title: "Brand", Description: "Ein Feuer im Keller.", Location: "Mitte", Hash: "c", DateTime: day.Add(-time.Hour).Unix()},
+		{Title: "Raub", Location: "Pankow", Hash: "d", DateTime: day.Add(-time.Hour).Unix()},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+	app.updateFacts(ctx, events...)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	query := `query Page($after: String) {
+		__typename
+		events(district: "Mitte", first: 2, after: $after) {
+			nodes { ...summary }
+			pageInfo { endCursor hasNextPage }
+		}
+	}
+	fragment summary on Event { hash title severityName facts { kind value } }`
+	var hashes []string
+	variables := map[string]any{}
+	for range 3 {
+		status, response := postGraphQL(t, server, query, variables)
+		if status != http.StatusOK || response["errors"] != nil {
+			t.Fatalf("unexpected response %d %v", status, response)
+		}
+		data := response["data"].(map[string]any)
+		if data["__typename"] != "Query" {
+			t.Fatalf("unexpected __typename %v", data["__typename"])
+		}
+		connection := data["events"].(map[string]any)
+		for _, node := range connection["nodes"].([]any) {
+			node := node.(map[string]any)
+			hashes = append(hashes, node["hash"].(string))
+			if node["hash"] == "a" && (node["severityName"] != severityNames[severityMedium] || len(node["facts"].([]any)) != 2) {
+				t.Errorf("unexpected event %v", node)
+			}
+		}
+		pageInfo := connection["pageInfo"].(map[string]any)
+		if pageInfo["hasNextPage"] == false {
+			if pageInfo["endCursor"] != nil {
+				t.Errorf("expected no cursor on the last page, got %v", pageInfo["endCursor"])
+			}
+			break
+		}
+		variables["after"] = pageInfo["endCursor"]
+	}
+	if strings.Join(hashes, ",") != "a,b,c" {
+		t.Fatalf("expected the events of Mitte once, newest first, got %v", hashes)
+	}
+
+	status, response := postGraphQL(t, server, `query($role: String, $withDistricts: Boolean!) {
+		minors: facts(weapon: "messer", maxAge: 17, ageRole: $role) { hash }
+		stats(groupBy: "month", from: "2024-01-01") { groupBy buckets { key count } }
+		districts @include(if: $withDistricts) { name aliases }
+		event(hash: "unknown") { hash }
+	}`, map[string]any{"withDistricts": true})
+	if status != http.StatusOK || response["errors"] != nil {
+		t.Fatalf("unexpected response %d %v", status, response)
+	}
+	data, _ := json.Marshal(response["data"])
+	want := `{"districts":[` // keys are sorted by the decoding map
+	if !strings.HasPrefix(string(data), want) {
+		t.Fatalf("unexpected data %s", data)
+	}
+	for _, part := range []string{
+		`"minors":[{"hash":"a"}]`,
+		`"stats":{"buckets":[{"count":4,"key":"2024-06"}],"groupBy":"month"}`,
+		`{"aliases":[],"name":"Mitte"}`,
+		`"event":null`,
+	} {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("expected %s in %s", part, data)
+		}
+	}
+
+	res, err := http.Get(server.URL + "/graphql?query=" + url.QueryEscape("{ districts { name } }"))
+	if err != nil {
+		t.Fatalf("GET /graphql failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", res.StatusCode)
+	}
+}
+
+func TestGraphQL_FieldOrder(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	body, _ := json.Marshal(graphQLRequest{Query: `{ districts { name n: name } stats { time groupBy } }`})
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/graphql", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer stats")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /graphql failed: %v", err)
+	}
+	data, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if !strings.HasPrefix(string(data), `{"data":{"districts":[{"name":"Charlottenburg-Wilmersdorf","n":"Charlottenburg-Wilmersdorf"}`) ||
+		!strings.Contains(string(data), `"stats":{"time":"published","groupBy":"day"}`) {
+		t.Fatalf("expected the fields in selection order, got %s", data)
+	}
+}
+
+func TestGraphQL_Errors(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for _, query := range []string{
+		`{ events { nodes { secret } } }`,
+		`{ events(limit: 5) { nodes { hash } } }`,
+		`{ events }`,
+		`{ districts { name { first } } }`,
+		`{ districts { ...missing } }`,
+		`{ events { nodes { ...f } } } fragment f on District { name }`,
+		`{ a: districts { name } a: stats { time } }`,
+		`{ events { nodes { hash @deprecated } } }`,
+		`{ events { pageInfo { hasNextPage } } } { districts { name } }`,
+		`query($from: DateTime) { stats(from: $from) { time } }`,
+		`{ e { e { e { e { e { e { e { e { e } } } } } } } } }`,
+	} {
+		variables := map[string]any{}
+		if strings.Contains(query, "$from") {
+			variables["from"] = "yesterday"
+		}
+		status, response := postGraphQL(t, server, query, variables)
+		if status != http.StatusBadRequest || response["errors"] == nil || response["data"] != nil {
+			t.Errorf("expected a request error for %q, got %d %v", query, status, response)
+		}
+	}
+
+	// Field errors null the field, the other fields are still answered.
+	status, response := postGraphQL(t, server, `{ events(first: 1000) { nodes { hash } } districts { name } }`, nil)
+	errors, _ := response["errors"].([]any)
+	data, _ := response["data"].(map[string]any)
+	if status != http.StatusOK || len(errors) != 1 || data["events"] != nil || len(data["districts"].([]any)) != len(berlinDistricts) {
+		t.Fatalf("unexpected response %d %v", status, response)
+	}
+	if path := errors[0].(map[string]any)["path"].([]any); len(path) != 1 || path[0] != "events" {
+		t.Fatalf("unexpected error path %v", path)
+	}
+}
+
+func TestGraphQL_Limits(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	// Ten full pages and a facts query at its limit fit the budget alone.
+	var pages strings.Builder
+	for i := range 10 {
+		fmt.Fprintf(&pages, "p%d: events(first: 100) { nodes { hash } } ", i)
+	}
+	for _, query := range []string{"{ " + pages.String() + "}", `{ facts(limit: 1000) { hash } }`} {
+		if status, response := postGraphQL(t, server, query, nil); status != http.StatusOK || response["errors"] != nil {
+			t.Fatalf("expected %q to be answered, got %d %v", query, status, response)
+		}
+	}
+
+	for _, query := range []string{
+		"{ " + pages.String() + "more: event(hash: \"x\") { hash } }",
+		`{ facts(limit: 1000) { hash } stats { time } }`,
+		"{ " + strings.Repeat("districts { name } ", 500) + "}",
+	} {
+		status, response := postGraphQL(t, server, query, nil)
+		if status != http.StatusBadRequest || response["errors"] == nil || response["data"] != nil {
+			t.Errorf("expected a request error for %.60q, got %d %v", query, status, response)
+		}
+	}
+}
+
+func TestGraphQL_Schema(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/api/schema/graphql")
+	if err != nil {
+		t.Fatalf("GET /api/schema/graphql failed: %v", err)
+	}
+	sdl, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	for _, part := range []string{"scalar DateTime", "type Query {", "  events(\n", "    first: Int\n", "  ): EventConnection\n", "  categories: [String!]!\n"} {
+		if !strings.Contains(string(sdl), part) {
+			t.Errorf("expected %q in the schema:\n%s", part, sdl)
+		}
+	}
+
+	status, response := postGraphQL(t, server, `{ __type(name: "Event") { fields { name } } }`, nil)
+	data, _ := json.Marshal(response["data"])
+	if status != http.StatusOK || !strings.Contains(string(data), `{"name":"publishedAt"}`) {
+		t.Fatalf("expected the fields of Event from introspection, got %d %v", status, response)
+	}
+}
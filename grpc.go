@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Luiggi33/berlin-police-feed/policefeedpb"
+)
+
+// featureGRPC serves the gRPC API on GRPCAddress, see runGRPC.
+const featureGRPC = "grpc"
+
+const (
+	// grpcMaxMessage bounds request messages; all of them are small.
+	grpcMaxMessage = 1 << 20
+	// defaultGRPCPageSize and maxGRPCPageSize bound ListEvents pages.
+	defaultGRPCPageSize = 100
+	maxGRPCPageSize     = 1000
+)
+
+// policefeedProto is the service definition for clients. The code in
+// policefeedpb is generated from it with "make proto".
+//
+//go:embed policefeed.proto
+var policefeedProto string
+
+// runGRPC serves the gRPC API until ctx is cancelled. Running calls get a
+// moment to finish; streams end once the web server shuts down the
+// broadcaster.
+func (a *App) runGRPC(ctx context.Context) {
+	if !slices.Contains(a.config.Features, featureGRPC) {
+		return
+	}
+	listener, err := net.Listen("tcp", a.config.GRPCAddress)
+	if err != nil {
+		log.Println("Error starting gRPC server:", err)
+		return
+	}
+	healthServer := health.NewServer()
+	server := a.newGRPCServer(healthServer)
+	go func() {
+		<-ctx.Done()
+		healthServer.Shutdown()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			server.Stop()
+		}
+	}()
+	log.Println("Serving gRPC on", listener.Addr())
+	err = server.Serve(listener)
+	if err != nil {
+		log.Println("Error serving gRPC:", err)
+	}
+}
+
+// newGRPCServer returns a server for the PoliceFeed service, the health
+// service reporting healthServer and server reflection.
+func (a *App) newGRPCServer(healthServer *health.Server) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(grpcMaxMessage),
+		grpc.UnaryInterceptor(a.grpcUnaryInterceptor),
+		grpc.StreamInterceptor(a.grpcStreamInterceptor),
+	)
+	policefeedpb.RegisterPoliceFeedServer(server, &grpcServer{app: a})
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	return server
+}
+
+func (a *App) grpcUnaryInterceptor(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	err := a.authorizeGRPC(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	response, err := handler(ctx, request)
+	return response, grpcStatus(info.FullMethod, err)
+}
+
+func (a *App) grpcStreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := a.authorizeGRPC(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return grpcStatus(info.FullMethod, handler(srv, stream))
+}
+
+// authorizeGRPC lets calls of the PoliceFeed service through for holders of
+// a stats token, passed as "authorization: Bearer <token>" metadata. The
+// health service and reflection are open to everyone.
+func (a *App) authorizeGRPC(ctx context.Context, method string) error {
+	if !strings.HasPrefix(method, "/"+policefeedpb.PoliceFeed_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+	token := ""
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, _ = strings.CutPrefix(values[0], "Bearer ")
+	}
+	scopes := a.tokenScopes(strings.TrimSpace(token))
+	if scopes == nil {
+		return status.Error(codes.Unauthenticated, "missing or invalid token")
+	}
+	if !slices.Contains(scopes, scopeStats) {
+		return status.Errorf(codes.PermissionDenied, "token lacks the %s scope", scopeStats)
+	}
+	return nil
+}
+
+// grpcStatus logs errors without a gRPC status and reports them as
+// internal.
+func grpcStatus(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	log.Printf("Error in gRPC call %s: %v", method, err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// grpcServer implements the PoliceFeed service of policefeed.proto.
+type grpcServer struct {
+	policefeedpb.UnimplementedPoliceFeedServer
+	app *App
+}
+
+func grpcEvent(event *Event) *policefeedpb.Event {
+	m := &policefeedpb.Event{
+		Hash:        event.Hash,
+		Title:       event.Title,
+		Description: event.Description,
+		District:    event.Location,
+		Link:        event.Link,
+		PublishedAt: timestamppb.New(time.Unix(event.DateTime, 0)),
+		ParentHash:  event.ParentHash,
+	}
+	if event.IncidentTime != nil {
+		m.IncidentAt = timestamppb.New(time.Unix(*event.IncidentTime, 0))
+	}
+	return m
+}
+
+// grpcTime returns the zero time for unset timestamps.
+func grpcTime(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+// ListEvents returns the events matching the request, newest first, in
+// pages of page_size.
+func (s *grpcServer) ListEvents(ctx context.Context, request *policefeedpb.ListEventsRequest) (*policefeedpb.ListEventsResponse, error) {
+	filter := EventFilter{From: grpcTime(request.GetFrom()), To: grpcTime(request.GetTo()), District: request.GetDistrict()}
+	pageSize := int(request.GetPageSize())
+	if pageSize == 0 {
+		pageSize = defaultGRPCPageSize
+	}
+	if pageSize < 0 || pageSize > maxGRPCPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 1 and %d", maxGRPCPageSize)
+	}
+	page, next, err := s.app.eventPage(ctx, filter, pageSize, request.GetPageToken())
+	if errors.Is(err, errInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &policefeedpb.ListEventsResponse{NextPageToken: next}
+	page = s.app.redactions.events(page)
+	for i := range page {
+		response.Events = append(response.Events, grpcEvent(&page[i]))
+	}
+	return response, nil
+}
+
+// StreamEvents sends new events as they are scraped until the client hangs
+// up.
+func (s *grpcServer) StreamEvents(request *policefeedpb.StreamEventsRequest, stream grpc.ServerStreamingServer[policefeedpb.Event]) error {
+	events, cancel := s.app.live.subscribe()
+	defer cancel()
+	// Send the headers, so that the client knows the stream is up.
+	err := stream.SendHeader(metadata.MD{})
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case batch, ok := <-events:
+			if !ok {
+				if s.app.live.closed() {
+					return status.Error(codes.Unavailable, "server is shutting down")
+				}
+				return status.Error(codes.ResourceExhausted, "stream fell behind")
+			}
+			for i := range batch {
+				if request.GetDistrict() != "" && batch[i].Location != request.GetDistrict() {
+					continue
+				}
+				err = stream.Send(grpcEvent(&batch[i]))
+				if err != nil {
+					// The client is gone.
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// GetStats counts events like /api/stats.
+func (s *grpcServer) GetStats(ctx context.Context, request *policefeedpb.GetStatsRequest) (*policefeedpb.Stats, error) {
+	filter := EventFilter{From: grpcTime(request.GetFrom()), To: grpcTime(request.GetTo()), District: request.GetDistrict()}
+	result, err := s.app.stats(ctx, filter, request.GetGroupBy(), request.GetTime())
+	var invalid errInvalidStats
+	if errors.As(err, &invalid) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := &policefeedpb.Stats{
+		GroupBy: result.GroupBy,
+		Time:    result.Time,
+		Unknown: int64(result.Unknown),
+	}
+	for _, bucket := range result.Buckets {
+		response.Buckets = append(response.Buckets, &policefeedpb.StatsBucket{Key: bucket.Key, Count: int64(bucket.Count)})
+	}
+	return response, nil
+}
+
+func handleGRPCProto(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, err := io.WriteString(w, policefeedProto)
+	if err != nil {
+		log.Println("Error writing proto file:", err)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/Luiggi33/berlin-police-feed/policefeedpb"
+)
+
+// newGRPCTestClient serves the gRPC API of app and returns a connection to
+// it.
+func newGRPCTestClient(t *testing.T, app *App) *grpc.ClientConn {
+	t.Helper()
+	app.config.StatsTokens = []string{"stats-token"}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := app.newGRPCServer(health.NewServer())
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPC_ListEventsAndStats(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	// Three events share a second, so that a page boundary splits them.
+	for _, event := range []Event{
+		{Title: "Eins", Hash: "a", Location: "Mitte", DateTime: 300},
+		{Title: "Zwei", Hash: "b", Location: "Mitte", DateTime: 200},
+		{Title: "Drei", Hash: "c", Location: "Mitte", DateTime: 200},
+		{Title: "Vier", Hash: "d", Location: "Mitte", DateTime: 200},
+		{Title: "Fünf", Hash: "e", Location: "Pankow", DateTime: 100},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := policefeedpb.NewPoliceFeedClient(newGRPCTestClient(t, app))
+	authorized := withToken(ctx, "stats-token")
+
+	var titles []string
+	token := ""
+	for range 5 {
+		response, err := client.ListEvents(authorized, &policefeedpb.ListEventsRequest{District: "Mitte", PageSize: 2, PageToken: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range response.GetEvents() {
+			titles = append(titles, event.GetTitle())
+		}
+		if token = response.GetNextPageToken(); token == "" {
+			break
+		}
+	}
+	if strings.Join(titles, ",") != "Eins,Zwei,Drei,Vier" {
+		t.Fatalf("expected all events of Mitte once, newest first, got %v", titles)
+	}
+
+	stats, err := client.GetStats(authorized, &policefeedpb.GetStatsRequest{GroupBy: "hour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.GetGroupBy() != "hour" || len(stats.GetBuckets()) != 1 || stats.GetBuckets()[0].GetCount() != 5 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+
+	if _, err := client.GetStats(authorized, &policefeedpb.GetStatsRequest{GroupBy: "year"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected INVALID_ARGUMENT for an unknown grouping, got %v", err)
+	}
+	if _, err := client.ListEvents(authorized, &policefeedpb.ListEventsRequest{PageToken: "nonsense"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected INVALID_ARGUMENT for an invalid page token, got %v", err)
+	}
+	if _, err := client.GetStats(ctx, &policefeedpb.GetStatsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected UNAUTHENTICATED without token, got %v", err)
+	}
+}
+
+func TestGRPC_StreamEvents(t *testing.T) {
+	app := newTestApp(t)
+	client := policefeedpb.NewPoliceFeedClient(newGRPCTestClient(t, app))
+	ctx, cancel := context.WithCancel(withToken(context.Background(), "stats-token"))
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, &policefeedpb.StreamEventsRequest{District: "Mitte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+	if app.live.count() != 1 {
+		t.Fatal("stream did not subscribe")
+	}
+
+	app.live.publish([]Event{
+		{Title: "Anderswo", Hash: "x", Location: "Pankow", DateTime: 1},
+		{Title: "Brand in Mitte", Hash: "y", Location: "Mitte", DateTime: 2},
+	})
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetTitle() != "Brand in Mitte" || !event.GetPublishedAt().AsTime().Equal(time.Unix(2, 0)) {
+		t.Fatalf("unexpected event %v", event)
+	}
+
+	cancel()
+	for deadline := time.Now().Add(5 * time.Second); app.live.count() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not unsubscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGRPC_ReflectionAndHealth(t *testing.T) {
+	app := newTestApp(t)
+	conn := newGRPCTestClient(t, app)
+	ctx := context.Background()
+	service := policefeedpb.PoliceFeed_ServiceDesc.ServiceName
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = stream.CloseSend() }()
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []string
+	for _, s := range response.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	if !slices.Contains(services, service) || !slices.Contains(services, healthpb.Health_ServiceDesc.ServiceName) {
+		t.Fatalf("expected the service and the health service to be listed, got %v", services)
+	}
+
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, data := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		var file descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(data, &file); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file.GetName())
+	}
+	if !slices.Contains(files, "policefeed.proto") {
+		t.Fatalf("expected policefeed.proto, got %v", files)
+	}
+
+	check, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %v", check.GetStatus())
+	}
+}
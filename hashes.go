@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+)
+
+// Besides Hash, which has always been the public ID of an event (feed GUIDs,
+// API paths) and is derived from title and publication time, every event
+// carries two hashes for internal use:
+//
+//   - IdentityHash identifies the report by its link. When the police amend a
+//     report ("Nachtrag") the title usually changes but the page stays the
+//     same, so deduplication uses this one.
+//   - ContentHash covers the text of the event, so that a re-scrape can tell
+//     whether anything changed.
+
+func shortHash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// identityHash returns the identity of an event. Events without a link fall
+// back to their public hash, so that they never collide with each other.
+func identityHash(event *Event) string {
+	if event.Link == "" || event.Link == eventLinkPrefix {
+		return shortHash("hash", event.Hash)
+	}
+	return shortHash("link", event.Link)
+}
+
+func contentHash(event *Event) string {
+	return shortHash(event.Title, event.Description, event.Location)
+}
+
+// BeforeSave keeps the identity and content hashes in sync with every write,
+// including manual corrections.
+func (e *Event) BeforeSave(_ *gorm.DB) error {
+	e.IdentityHash = identityHash(e)
+	e.ContentHash = contentHash(e)
+	return nil
+}
+
+// backfillEventHashes computes the identity and content hashes of events
+// stored before they existed.
+func backfillEventHashes(db *gorm.DB) error {
+	var batch []Event
+	return db.Model(&Event{}).Where("identity_hash = '' OR identity_hash IS NULL").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				err := db.Model(&batch[i]).UpdateColumns(map[string]any{
+					"identity_hash": identityHash(&batch[i]),
+					"content_hash":  contentHash(&batch[i]),
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
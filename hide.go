@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+const auditActionHide = "event.hide"
+
+// errHiddenMissing is returned by Hide while the events.hidden column of the
+// event_metadata migrations doesn't exist.
+var errHiddenMissing = errors.New("events.hidden does not exist, apply the event_metadata migrations")
+
+// Hide marks the event and the incidents split off it as hidden, keeping
+// their content unlike TakeDown. It returns the hashes newly hidden.
+func (s *gormStore) Hide(ctx context.Context, hash string) ([]string, error) {
+	db := s.db.WithContext(ctx)
+	if !db.Migrator().HasColumn("events", "hidden") {
+		return nil, errHiddenMissing
+	}
+	var hashes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Event{}).Where("(hash = ? OR parent_hash = ?) AND NOT hidden", hash, hash).Pluck("hash", &hashes).Error
+		if err != nil || len(hashes) == 0 {
+			return err
+		}
+		err = tx.Model(&Event{}).Where("hash IN ?", hashes).UpdateColumn("hidden", true).Error
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changeDeleted, hashes...)
+	})
+	return hashes, err
+}
+
+// handleHide hides an event from the feeds and asks targets that support
+// corrections to delete what they were sent about it, like handleTakedown
+// does, but leaves its content in the database.
+func (a *App) handleHide(w http.ResponseWriter, r *http.Request) {
+	event, err := a.store.FindByHash(r.Context(), r.PathValue("hash"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "event not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if event.TakenDownAt != nil {
+		writeTombstone(w, event)
+		return
+	}
+
+	hashes, err := a.store.Hide(r.Context(), event.Hash)
+	if errors.Is(err, errHiddenMissing) {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Println("Error hiding event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	for _, hash := range hashes {
+		a.feed.Remove(hash)
+		a.correct(r.Context(), Correction{Type: correctionDeleted, Event: Event{Hash: hash}})
+	}
+	a.responses.invalidate()
+	a.audit(r.Context(), auditActionHide, event.Hash, map[string]any{"events": hashes})
+	log.Printf("Event %s hidden by %s (%d events)", event.Hash, actorFromContext(r.Context()), len(hashes))
+	writeJSON(w, http.StatusOK, map[string]any{"hidden": hashes})
+}
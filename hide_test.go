@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHide_SendsCorrection(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	target := &correctingRecorder{recordingNotifier: recordingNotifier{name: "correcting"}}
+	app.notifiers = []Notifier{target}
+	ctx := context.Background()
+	for _, event := range []Event{
+		{Title: "Raub", Hash: "h1", DateTime: 1},
+		{Title: "Zweiter Vorfall", Hash: "h2", ParentHash: "h1", DateTime: 1},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+		app.feed.Add(event)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res := postJSON(t, server.URL+"/api/events/h1/hide", "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 without events.hidden, got %d", res.StatusCode)
+	}
+
+	if err := applyMigrations(app.store.(*gormStore).db, []string{featureEventMetadata}); err != nil {
+		t.Fatal(err)
+	}
+	res = postJSON(t, server.URL+"/api/events/h1/hide", "secret", "")
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	app.deliveries.Wait()
+	if len(target.corrections) != 2 {
+		t.Fatalf("expected a deletion for both events, got %+v", target.corrections)
+	}
+	for _, correction := range target.corrections {
+		if correction.Type != correctionDeleted {
+			t.Fatalf("unexpected correction %+v", correction)
+		}
+	}
+	if feed := app.feed.RSS(); strings.Contains(feed, "Raub") || strings.Contains(feed, "Zweiter Vorfall") {
+		t.Fatalf("expected the events to leave the feed: %s", feed)
+	}
+
+	// Hiding again changes nothing.
+	res = postJSON(t, server.URL+"/api/events/h1/hide", "secret", "")
+	_ = res.Body.Close()
+	app.deliveries.Wait()
+	if res.StatusCode != http.StatusOK || len(target.corrections) != 2 {
+		t.Fatalf("expected no further corrections, got %d and %+v", res.StatusCode, target.corrections)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// haStateLength is the longest state Home Assistant accepts; longer titles
+// are cut.
+const haStateLength = 255
+
+// haSensor is a "latest police event" sensor for Home Assistant, shaped like
+// the states of its REST API: the title of the latest event as state, the
+// rest as attributes.
+type haSensor struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	State      string         `json:"state"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// haSlug turns a district into the part of an entity ID Home Assistant would
+// derive from it, e.g. "Neukölln" into "neukoelln".
+func haSlug(name string) string {
+	name = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss").Replace(strings.ToLower(name))
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// haSensors returns a sensor for Berlin as a whole and one per district.
+func (a *App) haSensors(r *http.Request) ([]haSensor, error) {
+	midnight := time.Now().In(berlin)
+	midnight = time.Date(midnight.Year(), midnight.Month(), midnight.Day(), 0, 0, 0, 0, berlin)
+	today, err := a.store.Recent(r.Context(), EventFilter{From: midnight}, maxPlainLimit)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, event := range today {
+		counts[event.Location]++
+	}
+
+	sensors := make([]haSensor, 0, len(berlinDistricts)+1)
+	for _, district := range append([]string{""}, berlinDistricts...) {
+		sensor := haSensor{ID: "berlin", Name: "Polizeimeldung Berlin", Attributes: map[string]any{"events_today": len(today)}}
+		if district != "" {
+			sensor.ID = haSlug(district)
+			sensor.Name = "Polizeimeldung " + district
+			sensor.Attributes["events_today"] = counts[district]
+		}
+		latest, err := a.store.Recent(r.Context(), EventFilter{District: district}, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) == 0 {
+			sensor.State = "unknown"
+			sensors = append(sensors, sensor)
+			continue
+		}
+		event := *a.redactions.event(&latest[0])
+		classification := classifyEvent(a.rules(), &event)
+		sensor.State = excerpt(event.Title, haStateLength)
+		sensor.Attributes["hash"] = event.Hash
+		sensor.Attributes["district"] = event.Location
+		sensor.Attributes["published_at"] = time.Unix(event.DateTime, 0).UTC().Format(time.RFC3339)
+		if event.IncidentTime != nil {
+			sensor.Attributes["incident_at"] = time.Unix(*event.IncidentTime, 0).UTC().Format(time.RFC3339)
+		}
+		sensor.Attributes["link"] = event.Link
+		if _, url, _ := a.feed.pageInfo(event.Hash); url != "" {
+			sensor.Attributes["permalink"] = url
+		}
+		sensor.Attributes["severity"] = classification.SeverityName
+		sensor.Attributes["categories"] = classification.Categories
+		sensors = append(sensors, sensor)
+	}
+	return sensors, nil
+}
+
+// handleHomeAssistant returns the sensors, all of them or the one given by
+// ?sensor=, e.g. for Home Assistant's RESTful sensor.
+func (a *App) handleHomeAssistant(w http.ResponseWriter, r *http.Request) {
+	sensors, err := a.haSensors(r)
+	if err != nil {
+		log.Println("Error loading Home Assistant sensors:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	id := r.URL.Query().Get("sensor")
+	if id == "" {
+		writeJSON(w, http.StatusOK, map[string]any{"sensors": sensors})
+		return
+	}
+	for _, sensor := range sensors {
+		if sensor.ID == id {
+			writeJSON(w, http.StatusOK, sensor)
+			return
+		}
+	}
+	writeJSONError(w, http.StatusNotFound, "unknown sensor")
+}
+
+// handleHomeAssistantPackage returns a Home Assistant package defining all
+// sensors, to be dropped into the packages directory as is.
+func (a *App) handleHomeAssistantPackage(w http.ResponseWriter, r *http.Request) {
+	if a.config.BaseURL == "" {
+		writeJSONError(w, http.StatusNotFound, "the package requires BASE_URL")
+		return
+	}
+	var b strings.Builder
+	b.WriteString("# Polizeimeldungen Berlin, generated by " + strings.TrimSuffix(a.config.BaseURL, "/") + "\n")
+	b.WriteString("rest:\n")
+	fmt.Fprintf(&b, "  - resource: %s/api/ha\n", strings.TrimSuffix(a.config.BaseURL, "/"))
+	b.WriteString("    scan_interval: 300\n")
+	b.WriteString("    sensor:\n")
+	// The sensors are listed in the same order as by haSensors.
+	for index, district := range append([]string{"Berlin"}, berlinDistricts...) {
+		id := haSlug(district)
+		fmt.Fprintf(&b, "      - name: %q\n", "Polizeimeldung "+district)
+		fmt.Fprintf(&b, "        unique_id: policefeed_%s\n", id)
+		b.WriteString("        icon: mdi:police-badge\n")
+		fmt.Fprintf(&b, "        value_template: \"{{ value_json.sensors[%d].state }}\"\n", index)
+		fmt.Fprintf(&b, "        json_attributes_path: \"$.sensors[%d].attributes\"\n", index)
+		b.WriteString("        json_attributes: [hash, district, published_at, incident_at, link, permalink, severity, categories, events_today]\n")
+	}
+	w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="polizeimeldungen.yaml"`)
+	_, _ = w.Write([]byte(b.String()))
+}
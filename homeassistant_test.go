@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHomeAssistantSensors(t *testing.T) {
+	app := newTestApp(t)
+	app.config.BaseURL = "https://feed.example.org"
+	ctx := context.Background()
+	now := time.Now().Unix()
+	for _, event := range []Event{
+		{Title: "Brand", Hash: "a", Location: "Neukölln", DateTime: now - 1},
+		{Title: "Schüsse in Neukölln", Hash: "b", Location: "Neukölln", DateTime: now},
+		{Title: "Raub", Hash: "c", Location: "Mitte", DateTime: now - 86400*3},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/ha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all struct {
+		Sensors []haSensor `json:"sensors"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&all)
+	_ = res.Body.Close()
+	if err != nil || len(all.Sensors) != len(berlinDistricts)+1 {
+		t.Fatalf("unexpected sensors %+v (%v)", all, err)
+	}
+	if all.Sensors[0].ID != "berlin" || all.Sensors[0].State != "Schüsse in Neukölln" {
+		t.Fatalf("unexpected Berlin sensor %+v", all.Sensors[0])
+	}
+
+	res, err = http.Get(server.URL + "/api/ha?sensor=neukoelln")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sensor haSensor
+	err = json.NewDecoder(res.Body).Decode(&sensor)
+	_ = res.Body.Close()
+	if err != nil || sensor.State != "Schüsse in Neukölln" || sensor.Attributes["severity"] != "high" || sensor.Attributes["events_today"] != float64(2) {
+		t.Fatalf("unexpected sensor %+v (%v)", sensor, err)
+	}
+	res, err = http.Get(server.URL + "/api/ha?sensor=spandau")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.NewDecoder(res.Body).Decode(&sensor)
+	_ = res.Body.Close()
+	if err != nil || sensor.State != "unknown" {
+		t.Fatalf("expected an unknown state without events, got %+v", sensor)
+	}
+
+	res, err = http.Get(server.URL + "/api/ha/package.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	for _, want := range []string{
+		"  - resource: https://feed.example.org/api/ha\n",
+		"        unique_id: policefeed_tempelhof_schoeneberg\n",
+		`value_template: "{{ value_json.sensors[0].state }}"`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("package is missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// importColumns maps the fields of imported records to the names datasets
+// commonly use for them, in our own exports, the API and third-party dumps.
+// Names are compared case-insensitively.
+var importColumns = map[string][]string{
+	"title":        {"title", "titel", "headline", "überschrift"},
+	"description":  {"description", "beschreibung", "text", "body", "content", "inhalt"},
+	"district":     {"district", "bezirk", "location", "ort", "ereignisort"},
+	"link":         {"link", "url", "permalink"},
+	"published_at": {"published_at", "publishedat", "published", "date", "datum", "datetime", "veröffentlicht"},
+	"incident_at":  {"incident_at", "incidentat", "tatzeit"},
+}
+
+// importTimeLayouts are the timestamp formats accepted for imported records.
+// Times without a zone are taken to be Berlin time, as on the police's pages.
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"02.01.2006 15:04 Uhr",
+	"02.01.2006 15:04",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+func parseImportTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	for _, layout := range importTimeLayouts {
+		t, err := time.ParseInLocation(layout, value, berlin)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// importRecord turns a record of the dataset into an event. Its hash is
+// computed the way the scraper does, so that the hashes of events in our own
+// exports are kept.
+func importRecord(record map[string]string, mapping map[string]string) (Event, error) {
+	field := func(name string) string {
+		if column, ok := mapping[name]; ok {
+			return strings.TrimSpace(record[strings.ToLower(column)])
+		}
+		for _, column := range importColumns[name] {
+			if value, ok := record[column]; ok {
+				return strings.TrimSpace(value)
+			}
+		}
+		return ""
+	}
+
+	event := Event{
+		Title:       field("title"),
+		Description: field("description"),
+		Location:    field("district"),
+		Link:        field("link"),
+	}
+	if event.Title == "" {
+		return Event{}, errors.New("missing title")
+	}
+	published := field("published_at")
+	if published == "" {
+		return Event{}, errors.New("missing publication time")
+	}
+	t, err := parseImportTime(published)
+	if err != nil {
+		return Event{}, err
+	}
+	event.DateTime = t.Unix()
+	if event.Description == "" {
+		event.Description = defaultDescription
+	}
+	if strings.HasPrefix(event.Link, "/") {
+		event.Link = eventLinkPrefix + event.Link
+	}
+	if incident := field("incident_at"); incident != "" {
+		t, err := parseImportTime(incident)
+		if err != nil {
+			return Event{}, err
+		}
+		unix := t.Unix()
+		event.IncidentTime = &unix
+	} else {
+		setIncidentTime(&event)
+	}
+	event.Hash = eventHash("", &event)
+	return event, nil
+}
+
+// readImportRecords calls fn with each record of a CSV file with a header
+// line, or a JSON file holding an array of objects or one object per line.
+// Keys are lowercased.
+func readImportRecords(r io.Reader, format string, fn func(n int, record map[string]string) error) error {
+	switch format {
+	case "csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return err
+		}
+		for i := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+		}
+		for n := 1; ; n++ {
+			row, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			record := make(map[string]string, len(header))
+			for i, value := range row {
+				if i < len(header) {
+					record[header[i]] = value
+				}
+			}
+			err = fn(n, record)
+			if err != nil {
+				return err
+			}
+		}
+	case "json", "ndjson":
+		buffered := bufio.NewReader(r)
+		first, err := peekNonSpace(buffered)
+		if err != nil {
+			return err
+		}
+		decoder := json.NewDecoder(buffered)
+		decoder.UseNumber()
+		if first == '[' {
+			_, err = decoder.Token()
+			if err != nil {
+				return err
+			}
+		}
+		for n := 1; decoder.More(); n++ {
+			var object map[string]any
+			err := decoder.Decode(&object)
+			if err != nil {
+				return fmt.Errorf("record %d: %w", n, err)
+			}
+			record := make(map[string]string, len(object))
+			for key, value := range object {
+				if value != nil {
+					record[strings.ToLower(key)] = fmt.Sprint(value)
+				}
+			}
+			err = fn(n, record)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// peekNonSpace returns the first byte of r that isn't white space, without
+// consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			return b[0], nil
+		}
+		_, _ = r.ReadByte()
+	}
+}
+
+// importEvents stores the events of a dataset that aren't stored yet. Events
+// count as stored if an event with the same link (see identityHash) or hash
+// exists, so that scraped data always wins over imported data.
+func importEvents(ctx context.Context, store EventStore, r io.Reader, format string, mapping map[string]string, dryRun bool) (imported, duplicates, invalid int, err error) {
+	err = readImportRecords(r, format, func(n int, record map[string]string) error {
+		event, err := importRecord(record, mapping)
+		if err != nil {
+			log.Printf("Skipping record %d: %v", n, err)
+			invalid++
+			return nil
+		}
+		_, err = store.FindByIdentity(ctx, identityHash(&event))
+		if err == nil {
+			duplicates++
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		_, err = store.FindByHash(ctx, event.Hash)
+		if err == nil {
+			duplicates++
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		imported++
+		if dryRun {
+			return nil
+		}
+		err = store.Create(ctx, &event)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		return store.ReplaceFacts(ctx, event.Hash, extractFacts(&event))
+	})
+	return imported, duplicates, invalid, err
+}
+
+// runImport implements the import command, which bootstraps the history from
+// published datasets of police reports instead of scraping it.
+func runImport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "csv, json or ndjson (default: by file extension)")
+	columns := flags.String("map", "", "column names of the dataset, e.g. title=Headline,published_at=Zeit")
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("import: no files given")
+	}
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(*columns, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, column, ok := strings.Cut(pair, "=")
+		if _, known := importColumns[name]; !ok || !known {
+			return fmt.Errorf("import: invalid mapping %q", pair)
+		}
+		mapping[name] = column
+	}
+
+	config := loadConfig()
+	db, err := openConfiguredDatabase(config)
+	if err != nil {
+		return err
+	}
+	store, err := NewGormStore(db)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range flags.Args() {
+		fileFormat := *format
+		if fileFormat == "" {
+			fileFormat = strings.TrimPrefix(filepath.Ext(path), ".")
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		imported, duplicates, invalid, err := importEvents(ctx, store, file, fileFormat, mapping, *dryRun)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("import %s: %w", path, err)
+		}
+		log.Printf("%s: imported %d events, skipped %d already stored and %d invalid records", path, imported, duplicates, invalid)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportEvents_CSV(t *testing.T) {
+	store, db := openTestStore(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	ctx := context.Background()
+	scraped := Event{Title: "Raub in Mitte", Hash: "scraped", Link: eventLinkPrefix + "/polizei/polizeimeldungen/2019/pressemitteilung.1.php"}
+	if err := store.Create(ctx, &scraped); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := "\ufeffTitel,Text,Bezirk,URL,Datum\n" +
+		"Raub in Mitte,Anderer Text,Mitte,/polizei/polizeimeldungen/2019/pressemitteilung.1.php,10.01.2019 12:30\n" +
+		"Brand in Pankow,Ein Keller brannte.,Pankow,/polizei/polizeimeldungen/2019/pressemitteilung.2.php,11.01.2019 08:00\n" +
+		"Ohne Datum,,,,\n"
+	imported, duplicates, invalid, err := importEvents(ctx, store, strings.NewReader(dump), "csv", nil, false)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if imported != 1 || duplicates != 1 || invalid != 1 {
+		t.Fatalf("unexpected counts: %d imported, %d duplicates, %d invalid", imported, duplicates, invalid)
+	}
+	events, _ := store.All(ctx)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	event := events[1]
+	want := time.Date(2019, 1, 11, 8, 0, 0, 0, berlin).Unix()
+	if event.Title != "Brand in Pankow" || event.Location != "Pankow" || event.DateTime != want ||
+		event.Link != eventLinkPrefix+"/polizei/polizeimeldungen/2019/pressemitteilung.2.php" || event.Hash == "" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	// Importing the same dump again changes nothing.
+	imported, duplicates, _, err = importEvents(ctx, store, strings.NewReader(dump), "csv", nil, false)
+	if err != nil || imported != 0 || duplicates != 2 {
+		t.Fatalf("expected a repeated import to be skipped, got %d imported, %d duplicates: %v", imported, duplicates, err)
+	}
+}
+
+func TestImportEvents_JSON(t *testing.T) {
+	store, db := openTestStore(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	ctx := context.Background()
+
+	array := `[{"title": "Unfall", "district": "Spandau", "link": "https://www.berlin.de/a", "publishedAt": "2020-05-01T10:00:00Z"},
+	           {"title": "Ohne Zeit"}]`
+	imported, _, invalid, err := importEvents(ctx, store, strings.NewReader(array), "json", nil, false)
+	if err != nil || imported != 1 || invalid != 1 {
+		t.Fatalf("unexpected result: %d imported, %d invalid: %v", imported, invalid, err)
+	}
+
+	// Columns can be mapped explicitly.
+	lines := `{"Headline": "Unfall", "Zeit": "2020-05-01T10:00:00Z", "link": "https://www.berlin.de/a"}
+{"Headline": "Einbruch", "Zeit": 1588327200}
+`
+	mapping := map[string]string{"title": "Headline", "published_at": "Zeit"}
+	imported, duplicates, _, err := importEvents(ctx, store, strings.NewReader(lines), "ndjson", mapping, true)
+	if err != nil || imported != 1 || duplicates != 1 {
+		t.Fatalf("unexpected result: %d imported, %d duplicates: %v", imported, duplicates, err)
+	}
+	// A dry run stores nothing.
+	if events, _ := store.All(ctx); len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+}
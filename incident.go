@@ -0,0 +1,166 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reports are usually published hours after the incident they describe
+// ("Dienstagabend gegen 22:30 Uhr"). parseIncidentTime reconstructs the
+// incident time from such mentions relative to the publication time.
+//
+// Both times are Berlin wall clock times in the UTC location, matching how
+// Event.DateTime has always been stored.
+
+var (
+	incidentDayParts = map[string]int{
+		"morgen":     8,
+		"vormittag":  10,
+		"mittag":     12,
+		"nachmittag": 15,
+		"abend":      20,
+		"nacht":      1,
+	}
+	incidentWeekdays = map[string]time.Weekday{
+		"montag":     time.Monday,
+		"dienstag":   time.Tuesday,
+		"mittwoch":   time.Wednesday,
+		"donnerstag": time.Thursday,
+		"freitag":    time.Friday,
+		"samstag":    time.Saturday,
+		"sonntag":    time.Sunday,
+	}
+	incidentMonths = map[string]time.Month{
+		"januar": time.January, "februar": time.February, "märz": time.March, "april": time.April,
+		"mai": time.May, "juni": time.June, "juli": time.July, "august": time.August,
+		"september": time.September, "oktober": time.October, "november": time.November, "dezember": time.December,
+	}
+
+	dayPartPattern       = `(morgen|vormittag|mittag|nachmittag|abend|nacht)`
+	weekdayPattern       = `(montag|dienstag|mittwoch|donnerstag|freitag|samstag|sonntag)`
+	incidentNightRe      = regexp.MustCompile(`nacht zu(?:m)? ` + weekdayPattern)
+	incidentWeekdayRe    = regexp.MustCompile(weekdayPattern + dayPartPattern + `?`)
+	incidentRelativeRe   = regexp.MustCompile(`\b(vorgestern|gestern|heute)(?:\s` + dayPartPattern + `)?`)
+	incidentNumericDayRe = regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\.(\d{4})?`)
+	incidentNamedDayRe   = regexp.MustCompile(`\b(\d{1,2})\.\s?(januar|februar|märz|april|mai|juni|juli|august|september|oktober|november|dezember)(?:\s(\d{4}))?`)
+	incidentClockRe      = regexp.MustCompile(`\b(\d{1,2})(?:[:.](\d{2}))?\s?uhr`)
+)
+
+// parseIncidentTime returns the incident time mentioned in text, if any.
+func parseIncidentTime(text string, published time.Time) (time.Time, bool) {
+	text = strings.ToLower(text)
+	published = published.UTC()
+	day := time.Date(published.Year(), published.Month(), published.Day(), 0, 0, 0, 0, time.UTC)
+
+	var date time.Time
+	hour, minute, hasTime := 0, 0, false
+	// fallbackDays moves incidents that would be after the publication
+	// back: a week for weekdays, a day for bare times.
+	fallbackDays := 0
+	night := false
+
+	if m := incidentNightRe.FindStringSubmatch(text); m != nil {
+		date = lastWeekday(day, incidentWeekdays[m[1]])
+		hour, hasTime, fallbackDays, night = incidentDayParts["nacht"], true, 7, true
+	} else if m := incidentNamedDayRe.FindStringSubmatch(text); m != nil {
+		date = explicitDate(day, m[1], incidentMonths[m[2]], m[3])
+	} else if m := incidentNumericDayRe.FindStringSubmatch(text); m != nil {
+		month, _ := strconv.Atoi(m[2])
+		date = explicitDate(day, m[1], time.Month(month), m[3])
+	} else if m := incidentWeekdayRe.FindStringSubmatch(text); m != nil {
+		date = lastWeekday(day, incidentWeekdays[m[1]])
+		fallbackDays = 7
+		if m[2] != "" {
+			hour, hasTime = incidentDayParts[m[2]], true
+		}
+	} else if m := incidentRelativeRe.FindStringSubmatch(text); m != nil {
+		date = day.AddDate(0, 0, -map[string]int{"heute": 0, "gestern": 1, "vorgestern": 2}[m[1]])
+		if m[2] != "" {
+			hour, hasTime = incidentDayParts[m[2]], true
+		}
+	}
+
+	if m := incidentClockRe.FindStringSubmatch(text); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mins, _ := strconv.Atoi(m[2])
+		if h < 24 && mins < 60 {
+			hour, minute, hasTime = h, mins, true
+			if night && h >= 12 {
+				// "In der Nacht zu Mittwoch gegen 23 Uhr" is Tuesday.
+				date = date.AddDate(0, 0, -1)
+			}
+		}
+	}
+
+	if date.IsZero() {
+		if !hasTime {
+			return time.Time{}, false
+		}
+		date = day
+		fallbackDays = 1
+	}
+	incident := date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	if incident.After(published) {
+		incident = incident.AddDate(0, 0, -fallbackDays)
+	}
+	if incident.After(published) {
+		return time.Time{}, false
+	}
+	return incident, true
+}
+
+// lastWeekday returns the latest day on or before day that is a weekday.
+func lastWeekday(day time.Time, weekday time.Weekday) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) - int(weekday) + 7) % 7))
+}
+
+// explicitDate builds a date from a day and month, taking the year from the
+// text or else the most recent one that doesn't put it after day. Invalid
+// dates yield the zero time.
+func explicitDate(day time.Time, dayOfMonth string, month time.Month, year string) time.Time {
+	d, err := strconv.Atoi(dayOfMonth)
+	if err != nil || d < 1 || d > 31 || month < time.January || month > time.December {
+		return time.Time{}
+	}
+	y := day.Year()
+	if year != "" {
+		y, _ = strconv.Atoi(year)
+	}
+	date := time.Date(y, month, d, 0, 0, 0, 0, time.UTC)
+	if date.Day() != d {
+		return time.Time{}
+	}
+	if year == "" && date.After(day) {
+		date = date.AddDate(-1, 0, 0)
+	}
+	return date
+}
+
+func formatIncidentTime(incidentTime *int64) string {
+	if incidentTime == nil {
+		return ""
+	}
+	return time.Unix(*incidentTime, 0).UTC().Format(time.RFC3339)
+}
+
+func equalIncidentTimes(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// setIncidentTime updates the incident time of an event from its text.
+func setIncidentTime(event *Event) {
+	event.IncidentTime = nil
+	if event.Description == defaultDescription {
+		return
+	}
+	incident, ok := parseIncidentTime(event.Title+". "+event.Description, time.Unix(event.DateTime, 0))
+	if ok {
+		unix := incident.Unix()
+		event.IncidentTime = &unix
+	}
+}
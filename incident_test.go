@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIncidentTime(t *testing.T) {
+	// Wednesday morning.
+	published := time.Date(2026, 3, 11, 9, 15, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"Dienstagabend gegen 22:30 Uhr wurde ein Mann angegriffen.", at(3, 10, 22, 30)},
+		{"Am Dienstagabend kam es zu einem Brand.", at(3, 10, 20, 0)},
+		{"In der Nacht zu Mittwoch brannte ein Auto.", at(3, 11, 1, 0)},
+		{"In der Nacht zu Mittwoch gegen 23:40 Uhr brannte ein Auto.", at(3, 10, 23, 40)},
+		{"Gestern Nachmittag wurde eingebrochen.", at(3, 10, 15, 0)},
+		{"Heute Morgen gegen 7.45 Uhr kam es zu einem Unfall.", at(3, 11, 7, 45)},
+		{"Gegen 23 Uhr wurde die Polizei alarmiert.", at(3, 10, 23, 0)},
+		{"Am 3. März gegen 14 Uhr wurde ein Fahrrad gestohlen.", at(3, 3, 14, 0)},
+		{"Der Vorfall ereignete sich am 28.12. in Mitte.", time.Date(2025, 12, 28, 0, 0, 0, 0, time.UTC)},
+		{"Mittwochabend wurde ein Mann festgenommen.", at(3, 4, 20, 0)},
+	}
+	for _, test := range tests {
+		got, ok := parseIncidentTime(test.text, published)
+		if !ok || !got.Equal(test.want) {
+			t.Errorf("parseIncidentTime(%q) = %s, %v; want %s", test.text, got, ok, test.want)
+		}
+	}
+
+	for _, text := range []string{
+		"Die Polizei sucht Zeugen.",
+		"Am 31.02. wurde ein Auto beschädigt.",
+	} {
+		if got, ok := parseIncidentTime(text, published); ok {
+			t.Errorf("expected no incident time in %q, got %s", text, got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// A SQLite file on flaky storage, e.g. the SD card of a Raspberry Pi, can get
+// corrupted, which otherwise only shows as odd errors from gorm later on. The
+// server therefore checks the file at startup. With DATABASE_BACKUP_DIR set,
+// every healthy startup also leaves a backup there, and a corrupted file is
+// replaced by the newest healthy backup.
+
+// errDatabaseCorrupt is returned if the database is corrupted and couldn't
+// be restored.
+var errDatabaseCorrupt = errors.New("database is corrupted")
+
+// backupTimeFormat names backups after the time they were taken, so that
+// they sort chronologically.
+const backupTimeFormat = "20060102T150405Z"
+
+// checkIntegrity runs PRAGMA integrity_check on the SQLite database at path
+// and returns the problems found, none for a healthy database.
+func checkIntegrity(path string) ([]string, error) {
+	db, err := sql.Open("sqlite3", sqliteParams(path, fmt.Sprintf("_busy_timeout=%d", sqliteBusyTimeout)))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return corruptionProblem(err)
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var problem string
+		err := rows.Scan(&problem)
+		if err != nil {
+			return nil, err
+		}
+		if problem != "ok" {
+			problems = append(problems, problem)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return corruptionProblem(err)
+	}
+	return problems, nil
+}
+
+// corruptionProblem turns errors saying the file is damaged or no database
+// at all into a problem, and returns other errors as they are.
+func corruptionProblem(err error) ([]string, error) {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB) {
+		return []string{err.Error()}, nil
+	}
+	return nil, err
+}
+
+// ensureIntegrity checks the SQLite database at path before the server opens
+// it. A healthy database is backed up to DATABASE_BACKUP_DIR, if set; a
+// corrupted one is moved aside and replaced by the newest healthy backup.
+// Without a backup to restore, it fails with errDatabaseCorrupt.
+func ensureIntegrity(path string, config Config) error {
+	path, _, _ = strings.Cut(path, "?")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	problems, err := checkIntegrity(path)
+	if err != nil {
+		return fmt.Errorf("checking database integrity: %w", err)
+	}
+	if len(problems) == 0 {
+		if config.DatabaseBackupDir != "" {
+			err := backupDatabase(path, config.DatabaseBackupDir, config.DatabaseBackups, time.Now())
+			if err != nil {
+				log.Println("Error backing up database:", err)
+			}
+		}
+		return nil
+	}
+
+	log.Printf("!!! DATABASE %s IS CORRUPTED: %s", path, strings.Join(problems, "; "))
+	if config.DatabaseBackupDir == "" {
+		return fmt.Errorf("%w: %s, set DATABASE_BACKUP_DIR to restore backups automatically", errDatabaseCorrupt, path)
+	}
+	backup, err := latestHealthyBackup(config.DatabaseBackupDir, path)
+	if err != nil {
+		return fmt.Errorf("%w: %s, and no backup to restore: %v", errDatabaseCorrupt, path, err)
+	}
+	aside, err := moveAside(path, time.Now())
+	if err != nil {
+		return err
+	}
+	err = copyFile(backup, path)
+	if err != nil {
+		return fmt.Errorf("restoring %s: %w", backup, err)
+	}
+	log.Printf("!!! RESTORED DATABASE %s FROM BACKUP %s, the corrupted file was moved to %s. Events stored since the backup are lost, unless they are still on the list page", path, backup, aside)
+	return nil
+}
+
+// backupDatabase writes a copy of the database at path to dir, keeping the
+// newest keep backups.
+func backupDatabase(path, dir string, keep int, now time.Time) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", sqliteParams(path, fmt.Sprintf("_busy_timeout=%d", sqliteBusyTimeout)))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	target := filepath.Join(dir, backupName(path, now))
+	_, err = db.Exec("VACUUM INTO ?", target)
+	if err != nil {
+		return err
+	}
+
+	backups, err := listBackups(dir, path)
+	if err != nil {
+		return err
+	}
+	for len(backups) > max(keep, 1) {
+		err := os.Remove(backups[len(backups)-1])
+		if err != nil {
+			return err
+		}
+		backups = backups[:len(backups)-1]
+	}
+	return nil
+}
+
+func backupName(path string, t time.Time) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return base + "-" + t.UTC().Format(backupTimeFormat) + filepath.Ext(path)
+}
+
+// listBackups returns the backups of the database at path in dir, newest
+// first.
+func listBackups(dir, path string) ([]string, error) {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	backups, err := filepath.Glob(filepath.Join(dir, base+"-*"+filepath.Ext(path)))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(backups)
+	slices.Reverse(backups)
+	return backups, nil
+}
+
+// latestHealthyBackup returns the newest backup of the database at path that
+// passes the integrity check itself.
+func latestHealthyBackup(dir, path string) (string, error) {
+	backups, err := listBackups(dir, path)
+	if err != nil {
+		return "", err
+	}
+	for _, backup := range backups {
+		problems, err := checkIntegrity(backup)
+		if err == nil && len(problems) == 0 {
+			return backup, nil
+		}
+		log.Printf("Skipping damaged backup %s", backup)
+	}
+	return "", fmt.Errorf("no healthy backup in %s", dir)
+}
+
+// moveAside renames the database at path, together with its write-ahead log,
+// so it is kept for inspection but not opened again. The log must not be
+// applied to the restored backup.
+func moveAside(path string, now time.Time) (string, error) {
+	aside := path + ".corrupt-" + now.UTC().Format(backupTimeFormat)
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(path+suffix, aside+suffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return aside, nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		_ = dst.Close()
+		return err
+	}
+	err = dst.Sync()
+	if err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
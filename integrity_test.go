@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTestDatabase creates a SQLite database at path holding one event.
+func createTestDatabase(t *testing.T, path string) {
+	t.Helper()
+	db, err := openDatabase("sqlite:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+	store, err := NewGormStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(context.Background(), &Event{Title: "Raub", Hash: "a"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.db")
+	createTestDatabase(t, path)
+	if problems, err := checkIntegrity(path); err != nil || len(problems) != 0 {
+		t.Fatalf("expected a healthy database, got %v %v", problems, err)
+	}
+
+	// Overwrite everything after the first page, where the tables are.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 4096; i < len(data); i++ {
+		data[i] = 0xff
+	}
+	damaged := filepath.Join(dir, "damaged.db")
+	if err := os.WriteFile(damaged, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := checkIntegrity(damaged); err != nil || len(problems) == 0 {
+		t.Fatalf("expected problems for a damaged database, got %v %v", problems, err)
+	}
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte(strings.Repeat("not a database ", 500)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := checkIntegrity(garbage); err != nil || len(problems) == 0 {
+		t.Fatalf("expected problems for a file that is no database, got %v %v", problems, err)
+	}
+}
+
+func TestBackupDatabase_KeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.db")
+	createTestDatabase(t, path)
+	backups := filepath.Join(dir, "backups")
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		if err := backupDatabase(path, backups, 2, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("backupDatabase failed: %v", err)
+		}
+	}
+	names, err := listBackups(backups, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(backups, "events-20260301T150000Z.db"), filepath.Join(backups, "events-20260301T140000Z.db")}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Fatalf("expected the two newest backups, got %v", names)
+	}
+	if problems, err := checkIntegrity(names[0]); err != nil || len(problems) != 0 {
+		t.Fatalf("expected a healthy backup, got %v %v", problems, err)
+	}
+}
+
+func TestOpenServerDatabase_RestoresBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.db")
+	createTestDatabase(t, path)
+	config := Config{DatabaseURL: "sqlite:" + path, DatabaseReadConns: 1, DatabaseWriteConns: 1}
+
+	if err := ensureIntegrity(path, config); err != nil {
+		t.Fatalf("expected a healthy database to pass, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 8192)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureIntegrity(path, config); !errors.Is(err, errDatabaseCorrupt) {
+		t.Fatalf("expected a corrupted database without backups to fail, got %v", err)
+	}
+
+	config.DatabaseBackupDir = filepath.Join(dir, "backups")
+	config.DatabaseBackups = 3
+	healthy := filepath.Join(dir, "healthy.db")
+	createTestDatabase(t, healthy)
+	if err := os.MkdirAll(config.DatabaseBackupDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(healthy, filepath.Join(config.DatabaseBackupDir, "events-20260301T120000Z.db")); err != nil {
+		t.Fatal(err)
+	}
+	// A newer but damaged backup is skipped.
+	if err := os.WriteFile(filepath.Join(config.DatabaseBackupDir, "events-20260302T120000Z.db"), []byte("damaged"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := openServerDatabase(config)
+	if err != nil {
+		t.Fatalf("expected the backup to be restored, got %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	store, err := NewGormStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event, err := store.FindByHash(context.Background(), "a"); err != nil || event.Title != "Raub" {
+		t.Fatalf("expected the restored event, got %+v %v", event, err)
+	}
+	aside, _ := filepath.Glob(path + ".corrupt-*")
+	if len(aside) != 1 {
+		t.Fatalf("expected the corrupted file to be kept aside, got %v", aside)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// journalMaxAttempts is how often the detail page of a journaled report is
+// tried before it is given up, e.g. because it was taken down.
+const journalMaxAttempts = 10
+
+// JournalEntry is a report read from the list page whose detail page hasn't
+// been fetched and stored yet. Reports are journaled before their detail page
+// is fetched and removed once stored, so a run that crashes or fails to fetch
+// a detail page leaves them behind for the next run to resume, even if they
+// dropped off the list page in the meantime.
+type JournalEntry struct {
+	Hash      string `gorm:"primaryKey"`
+	Title     string `gorm:"serializer:encrypted"`
+	Link      string
+	Location  string
+	DateTime  int64
+	Attempts  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (e JournalEntry) event() Event {
+	return Event{
+		Title:       e.Title,
+		Link:        e.Link,
+		Location:    e.Location,
+		DateTime:    e.DateTime,
+		Hash:        e.Hash,
+		Description: defaultDescription,
+	}
+}
+
+// SaveJournalEntry journals the report, counting another attempt if it
+// already is.
+func (s *gormStore) SaveJournalEntry(ctx context.Context, event *Event) error {
+	entry := JournalEntry{
+		Hash:     event.Hash,
+		Title:    event.Title,
+		Link:     event.Link,
+		Location: event.Location,
+		DateTime: event.DateTime,
+		Attempts: 1,
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]any{"attempts": gorm.Expr("journal_entries.attempts + 1"), "updated_at": time.Now()}),
+	}).Create(&entry).Error
+}
+
+// JournalEntries returns the journaled reports, oldest first.
+func (s *gormStore) JournalEntries(ctx context.Context) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := s.db.WithContext(ctx).Order("date_time, hash").Find(&entries).Error
+	return entries, err
+}
+
+func (s *gormStore) DeleteJournalEntries(ctx context.Context, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("hash IN ?", hashes).Delete(&JournalEntry{}).Error
+}
+
+// resumeJournal enriches the journaled reports left behind by earlier runs
+// that weren't handled by the current one, skipping those in handled. Reports
+// stored in the meantime or tried too often are dropped from the journal.
+func (a *App) resumeJournal(ctx context.Context, handled map[string]bool) []Event {
+	entries, err := a.store.JournalEntries(ctx)
+	if err != nil {
+		log.Println("Error loading scrape journal:", err)
+		return nil
+	}
+	var resumed []Event
+	var done []string
+	for _, entry := range entries {
+		if handled[entry.Hash] {
+			continue
+		}
+		if _, err := a.store.FindByHash(ctx, entry.Hash); err == nil {
+			done = append(done, entry.Hash)
+			continue
+		}
+		if entry.Attempts >= journalMaxAttempts {
+			log.Printf("Giving up on journaled report %s after %d attempts", entry.Link, entry.Attempts)
+			done = append(done, entry.Hash)
+			continue
+		}
+		event := entry.event()
+		err := a.store.SaveJournalEntry(ctx, &event)
+		if err != nil {
+			log.Println("Error journaling event:", err)
+		}
+		events, err := a.enrichEvent(ctx, event)
+		if err != nil {
+			log.Printf("Error resuming journaled report %s: %v", entry.Link, err)
+			continue
+		}
+		log.Println("Resumed journaled report:", entry.Link)
+		resumed = append(resumed, events...)
+	}
+	err = a.store.DeleteJournalEntries(ctx, done)
+	if err != nil {
+		log.Println("Error cleaning up scrape journal:", err)
+	}
+	return resumed
+}
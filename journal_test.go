@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScrapeJournal(t *testing.T) {
+	app := newFixtureApp(t)
+	app.config.ScrapeTimeout = time.Minute
+	ctx := context.Background()
+
+	// Left behind by a run that crashed after reading the list page, and by
+	// runs failing to fetch a report that was taken down.
+	left := Event{
+		Title:    "Aus dem Journal",
+		Link:     "https://www.berlin.de/polizei/polizeimeldungen/2026/pressemitteilung.1002.php",
+		Location: "Mitte",
+		DateTime: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC).Unix(),
+		Hash:     "journaled",
+	}
+	if err := app.store.SaveJournalEntry(ctx, &left); err != nil {
+		t.Fatal(err)
+	}
+	gone := Event{Title: "Entfernt", Link: "https://www.berlin.de/polizei/missing.php", Hash: "gone"}
+	for range journalMaxAttempts {
+		if err := app.store.SaveJournalEntry(ctx, &gone); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := app.store.JournalEntries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Hash != "gone" || entries[0].Attempts != journalMaxAttempts {
+		t.Fatalf("unexpected journal %+v", entries)
+	}
+
+	if err := app.scrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := app.store.FindByHash(ctx, "journaled")
+	if err != nil {
+		t.Fatalf("journaled report wasn't stored: %v", err)
+	}
+	if stored.Description == defaultDescription || stored.Title != left.Title {
+		t.Fatalf("journaled report wasn't enriched: %+v", stored)
+	}
+	entries, err = app.store.JournalEntries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected an empty journal, got %+v", entries)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Lifecycle events report on the scrape pipeline itself rather than on
+// police reports, for monitoring systems like healthchecks.io or Uptime Kuma.
+const (
+	lifecycleStarted   = "scrape.started"
+	lifecycleSucceeded = "scrape.succeeded"
+	lifecycleFailed    = "scrape.failed"
+	// lifecycleEmpty is sent instead of lifecycleSucceeded if the list page
+	// held no reports at all, which it never does in practice.
+	lifecycleEmpty = "scrape.empty"
+	// lifecycleParserMismatch is sent instead of lifecycleSucceeded if the
+	// list page no longer looks the way the scraper expects, i.e. berlin.de
+	// changed its markup.
+	lifecycleParserMismatch = "scrape.parser_mismatch"
+	// lifecycleMaintenance is sent instead of lifecycleFailed if berlin.de
+	// served a maintenance page, see errMaintenance.
+	lifecycleMaintenance = "scrape.maintenance"
+)
+
+// scrapeRun counts what a scrape run found on the list page.
+type scrapeRun struct {
+	// Lists is the number of report lists found; zero means the markup
+	// changed.
+	Lists int `json:"lists"`
+	Items int `json:"items"`
+	// Unparsed counts items that couldn't be read, e.g. for an unknown date
+	// format.
+	Unparsed int `json:"unparsed"`
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+
+	// aborted is why the list page was rejected instead of read, e.g.
+	// errMaintenance.
+	aborted error
+}
+
+// outcome names the lifecycle event for a run that didn't fail.
+func (r scrapeRun) outcome() string {
+	switch {
+	case r.Lists == 0 || r.Unparsed > 0:
+		return lifecycleParserMismatch
+	case r.Items == 0:
+		return lifecycleEmpty
+	default:
+		return lifecycleSucceeded
+	}
+}
+
+type lifecyclePayload struct {
+	Schema  string     `json:"schema"`
+	Type    string     `json:"type"`
+	At      time.Time  `json:"at"`
+	Seconds float64    `json:"seconds,omitempty"`
+	Run     *scrapeRun `json:"run,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// lifecycleHook is a target for lifecycle events. Plain URLs receive every
+// event as JSON. URLs prefixed with "healthchecks:" are pinged the way
+// healthchecks.io expects (…/start, …, …/fail), those prefixed with "kuma:"
+// like an Uptime Kuma push monitor (?status=up or down), without starts.
+type lifecycleHook struct {
+	kind, url string
+}
+
+func parseLifecycleHook(raw string) (lifecycleHook, error) {
+	hook := lifecycleHook{url: raw}
+	for _, kind := range []string{"healthchecks", "kuma"} {
+		if rest, ok := strings.CutPrefix(raw, kind+":"); ok {
+			hook = lifecycleHook{kind: kind, url: rest}
+		}
+	}
+	u, err := url.Parse(hook.url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return lifecycleHook{}, fmt.Errorf("invalid lifecycle webhook url %q", raw)
+	}
+	return hook, nil
+}
+
+// request builds the request reporting payload, or nil if the hook doesn't
+// take this kind of event.
+func (h lifecycleHook) request(ctx context.Context, payload lifecyclePayload) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	healthy := payload.Type == lifecycleSucceeded
+	switch h.kind {
+	case "healthchecks":
+		target := strings.TrimSuffix(h.url, "/")
+		switch {
+		case payload.Type == lifecycleStarted:
+			target += "/start"
+		case !healthy:
+			target += "/fail"
+		}
+		return http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	case "kuma":
+		if payload.Type == lifecycleStarted {
+			return nil, nil
+		}
+		u, _ := url.Parse(h.url)
+		query := u.Query()
+		query.Set("status", "down")
+		if healthy {
+			query.Set("status", "up")
+		}
+		query.Set("msg", strings.TrimSpace(payload.Type+" "+payload.Error))
+		if payload.Seconds > 0 {
+			query.Set("ping", fmt.Sprint(int(payload.Seconds*1000)))
+		}
+		u.RawQuery = query.Encode()
+		return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, err
+	}
+}
+
+// lifecycle reports a lifecycle event to every LIFECYCLE_WEBHOOK_URLS target.
+// Deliveries aren't retried: the next run reports again anyway.
+func (a *App) lifecycle(ctx context.Context, payload lifecyclePayload) {
+	if len(a.lifecycleHooks) == 0 {
+		return
+	}
+	payload.Schema = webhookSchemaVersion
+	payload.At = time.Now().UTC()
+	for _, hook := range a.lifecycleHooks {
+		hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		err := a.sendLifecycle(hookCtx, hook, payload)
+		cancel()
+		if err != nil {
+			log.Printf("Error sending %s to lifecycle webhook: %v", payload.Type, err)
+		}
+	}
+}
+
+func (a *App) sendLifecycle(ctx context.Context, hook lifecycleHook, payload lifecyclePayload) error {
+	req, err := hook.request(ctx, payload)
+	if err != nil || req == nil {
+		return err
+	}
+	res, err := a.lifecycleClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", req.URL.Host, res.Status)
+	}
+	return nil
+}
+
+// sourceBerlin names the police reports of berlin.de, the source scraped by
+// default.
+const sourceBerlin = "berlin"
+
+// pingURLsEnv reads the dead man's switch URLs from a comma separated list of
+// source=URL entries; a bare URL is taken for the berlin source. Invalid
+// entries are logged and skipped.
+func pingURLsEnv(name string) map[string]string {
+	urls := make(map[string]string)
+	for _, entry := range listEnv(name) {
+		source, rawURL, ok := strings.Cut(entry, "=")
+		if !ok || strings.Contains(source, "/") {
+			source, rawURL = sourceBerlin, entry
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Printf("Invalid %s entry %q, expected [source=]URL", name, entry)
+			continue
+		}
+		urls[source] = rawURL
+	}
+	return urls
+}
+
+// ping calls the dead man's switch of source after a successful scrape, e.g.
+// a healthchecks.io check. As it is only called when everything worked, the
+// missing pings raise the alarm if runs fail, find nothing or the process
+// hangs altogether.
+func (a *App) ping(ctx context.Context, source string) {
+	pingURL, ok := a.config.PingURLs[source]
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pingURL, nil)
+	if err != nil {
+		log.Printf("Error pinging %s dead man's switch: %v", source, err)
+		return
+	}
+	res, err := a.lifecycleClient.Do(req)
+	if err != nil {
+		log.Printf("Error pinging %s dead man's switch: %v", source, err)
+		return
+	}
+	drainBody(res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		log.Printf("Error pinging %s dead man's switch: %s", source, res.Status)
+	}
+}
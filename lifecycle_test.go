@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifecycleWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []lifecyclePayload
+	var pings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/hook" {
+			var payload lifecyclePayload
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			payloads = append(payloads, payload)
+			return
+		}
+		pings = append(pings, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("status")+" "+r.URL.Query().Get("msg"))
+	}))
+	defer server.Close()
+
+	app := newFixtureApp(t)
+	app.config.ScrapeTimeout = time.Minute
+	for _, raw := range []string{server.URL + "/hook", "healthchecks:" + server.URL + "/hc/uuid", "kuma:" + server.URL + "/api/push/token?status=up"} {
+		hook, err := parseLifecycleHook(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.lifecycleHooks = append(app.lifecycleHooks, hook)
+	}
+	ctx := context.Background()
+
+	if err := app.runScrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A report page has none of the markup of the list page.
+	app.config.PoliceURL = "https://www.berlin.de/polizei/polizeimeldungen/2026/pressemitteilung.1001.php"
+	if err := app.runScrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	app.config.PoliceURL = "https://www.berlin.de/polizei/missing/"
+	if err := app.runScrape(ctx); err == nil {
+		t.Fatal("expected the scrape to fail")
+	}
+
+	types := make([]string, len(payloads))
+	for i, payload := range payloads {
+		types[i] = payload.Type
+	}
+	want := []string{lifecycleStarted, lifecycleSucceeded, lifecycleStarted, lifecycleParserMismatch, lifecycleStarted, lifecycleFailed}
+	if !slices.Equal(types, want) {
+		t.Fatalf("unexpected events %v", types)
+	}
+	if run := payloads[1].Run; run == nil || run.Lists != 1 || run.Items != 4 || run.Created != 6 || run.Unparsed != 0 {
+		t.Fatalf("unexpected run %+v", payloads[1].Run)
+	}
+	if payloads[5].Error == "" || payloads[5].Schema != webhookSchemaVersion {
+		t.Fatalf("unexpected failure payload %+v", payloads[5])
+	}
+
+	wantPings := []string{
+		"POST /hc/uuid/start  ", "POST /hc/uuid  ", "GET /api/push/token up scrape.succeeded",
+		"POST /hc/uuid/start  ", "POST /hc/uuid/fail  ", "GET /api/push/token down scrape.parser_mismatch",
+		"POST /hc/uuid/start  ", "POST /hc/uuid/fail  ",
+	}
+	if len(pings) != len(wantPings)+1 || !slices.Equal(pings[:len(wantPings)], wantPings) || !strings.HasPrefix(pings[len(wantPings)], "GET /api/push/token down scrape.failed ") {
+		t.Fatalf("unexpected pings %q", pings)
+	}
+}
+
+func TestScrapeRunOutcome(t *testing.T) {
+	for _, c := range []struct {
+		run  scrapeRun
+		want string
+	}{
+		{scrapeRun{Lists: 1, Items: 20}, lifecycleSucceeded},
+		{scrapeRun{Lists: 1}, lifecycleEmpty},
+		{scrapeRun{Items: 0}, lifecycleParserMismatch},
+		{scrapeRun{Lists: 1, Items: 20, Unparsed: 2}, lifecycleParserMismatch},
+	} {
+		if got := c.run.outcome(); got != c.want {
+			t.Errorf("%+v: got %s, want %s", c.run, got, c.want)
+		}
+	}
+}
+
+func TestDeadMansSwitchPing(t *testing.T) {
+	var mu sync.Mutex
+	var pings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		pings = append(pings, r.Method+" "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	t.Setenv("SCRAPE_PING_URLS", server.URL+"/berlin,other="+server.URL+"/other,invalid=ftp://example.com")
+	urls := pingURLsEnv("SCRAPE_PING_URLS")
+	if len(urls) != 2 || urls[sourceBerlin] != server.URL+"/berlin" || urls["other"] != server.URL+"/other" {
+		t.Fatalf("unexpected ping urls %v", urls)
+	}
+
+	app := newFixtureApp(t)
+	app.config.ScrapeTimeout = time.Minute
+	app.config.PingURLs = urls
+	ctx := context.Background()
+	if err := app.runScrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	app.config.PoliceURL = "https://www.berlin.de/polizei/polizeimeldungen/2026/pressemitteilung.1001.php"
+	if err := app.runScrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	app.config.PoliceURL = "https://www.berlin.de/polizei/missing/"
+	if err := app.runScrape(ctx); err == nil {
+		t.Fatal("expected the scrape to fail")
+	}
+	if !slices.Equal(pings, []string{"GET /berlin"}) {
+		t.Fatalf("expected a single ping after the successful run, got %q", pings)
+	}
+}
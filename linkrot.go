@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// linkCheckPass is how often due links are checked.
+	linkCheckPass = time.Hour
+	// linkCheckBatchSize bounds the links checked per pass. Checks go
+	// through the scraper's rate limited client, so a pass takes a while.
+	linkCheckBatchSize = 100
+)
+
+// Results of a link check.
+const (
+	linkAlive = "alive"
+	linkDead  = "dead"
+	linkError = "error"
+)
+
+// LinksToCheck returns events whose link hasn't been checked since before,
+// never checked ones first.
+func (s *gormStore) LinksToCheck(ctx context.Context, before time.Time, limit int) ([]Event, error) {
+	var events []Event
+	err := s.db.WithContext(ctx).
+		Where("link <> '' AND (link_checked_at IS NULL OR link_checked_at < ?)", before).
+		Order("link_checked_at IS NOT NULL").Order("link_checked_at").Order("id").
+		Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// SetLinkStatus records a link check without touching UpdatedAt. Only a
+// link going dead or coming back counts as a change of the event.
+func (s *gormStore) SetLinkStatus(ctx context.Context, hash string, checkedAt time.Time, deadSince *time.Time) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous Event
+		err := tx.Select("link_dead_since").Where("hash = ?", hash).First(&previous).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&Event{}).Where("hash = ?", hash).UpdateColumns(map[string]any{
+			"link_checked_at": checkedAt,
+			"link_dead_since": deadSince,
+		}).Error
+		if err != nil || (previous.LinkDeadSince == nil) == (deadSince == nil) {
+			return err
+		}
+		return recordChanges(tx, changeUpdated, hash)
+	})
+}
+
+// LinkRotCount is the number of checked and dead links among the events
+// published in one month.
+type LinkRotCount struct {
+	Month   string
+	Checked int
+	Dead    int
+}
+
+// LinkRot counts checked and dead links by the month the events were
+// published, showing how links decay with age.
+func (s *gormStore) LinkRot(ctx context.Context) ([]LinkRotCount, error) {
+	var rows []struct {
+		DateTime      int64
+		LinkDeadSince *time.Time
+	}
+	err := s.db.WithContext(ctx).Model(&Event{}).Select("date_time", "link_dead_since").
+		Where("link_checked_at IS NOT NULL").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]*LinkRotCount{}
+	for _, row := range rows {
+		month := time.Unix(row.DateTime, 0).UTC().Format("2006-01")
+		count := counts[month]
+		if count == nil {
+			count = &LinkRotCount{Month: month}
+			counts[month] = count
+		}
+		count.Checked++
+		if row.LinkDeadSince != nil {
+			count.Dead++
+		}
+	}
+	result := make([]LinkRotCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	slices.SortFunc(result, func(a, b LinkRotCount) int { return strings.Compare(a.Month, b.Month) })
+	return result, nil
+}
+
+// linkRotState holds the results of link checks for the metrics.
+type linkRotState struct {
+	mu     sync.Mutex
+	checks map[string]int
+	counts []LinkRotCount
+}
+
+func (s *linkRotState) count(result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checks == nil {
+		s.checks = map[string]int{}
+	}
+	s.checks[result]++
+}
+
+func (s *linkRotState) set(counts []LinkRotCount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = counts
+}
+
+// checkLink finds link alive, dead (404 or 410) or failing otherwise, which
+// leaves the question open.
+func checkLink(ctx context.Context, client HTTPDoer, link string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return linkError
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return linkError
+	}
+	defer drainBody(res.Body)
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return linkDead
+	case res.StatusCode < 400:
+		return linkAlive
+	}
+	return linkError
+}
+
+// checkLinks re-checks the links that are due and flags dead ones. Feed items
+// of dead links point to a fallback instead, see FeedBuilder.item.
+func (a *App) checkLinks(ctx context.Context) error {
+	now := time.Now()
+	events, err := a.store.LinksToCheck(ctx, now.Add(-a.config.LinkCheckInterval), linkCheckBatchSize)
+	if err != nil {
+		return err
+	}
+	// Split off incidents share their report's link, which is checked once.
+	results := map[string]string{}
+	for _, event := range events {
+		result, ok := results[event.Link]
+		if !ok {
+			result = checkLink(ctx, a.client, event.Link)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			results[event.Link] = result
+			a.linkRot.count(result)
+		}
+
+		deadSince := event.LinkDeadSince
+		switch {
+		case result == linkDead && deadSince == nil:
+			deadSince = &now
+			log.Printf("Link of %s is dead: %s", event.Hash, event.Link)
+		case result == linkAlive:
+			deadSince = nil
+		}
+		err := a.store.SetLinkStatus(ctx, event.Hash, now, deadSince)
+		if err != nil {
+			return err
+		}
+		if (deadSince == nil) != (event.LinkDeadSince == nil) {
+			event.LinkDeadSince = deadSince
+			a.feed.Replace(event)
+			a.responses.invalidate()
+		}
+	}
+
+	counts, err := a.store.LinkRot(ctx)
+	if err != nil {
+		return err
+	}
+	a.linkRot.set(counts)
+	return nil
+}
+
+func (a *App) runLinkChecks(ctx context.Context) {
+	if a.config.LinkCheckInterval <= 0 {
+		return
+	}
+	for {
+		err := a.checkLinks(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Error checking links:", err)
+		}
+		if sleepContext(ctx, linkCheckPass) != nil {
+			return
+		}
+	}
+}
+
+func (a *App) writeLinkRotMetrics(m *metricsWriter) {
+	a.linkRot.mu.Lock()
+	defer a.linkRot.mu.Unlock()
+	if a.linkRot.counts == nil && a.linkRot.checks == nil {
+		return
+	}
+	var checks []metricSample
+	for _, result := range []string{linkAlive, linkDead, linkError} {
+		checks = append(checks, metricSample{labels: map[string]string{"result": result}, value: float64(a.linkRot.checks[result])})
+	}
+	m.write("policefeed_link_checks_total", "counter", "Number of link checks by result.", checks...)
+	var checked, dead []metricSample
+	for _, count := range a.linkRot.counts {
+		labels := map[string]string{"month": count.Month}
+		checked = append(checked, metricSample{labels: labels, value: float64(count.Checked)})
+		dead = append(dead, metricSample{labels: labels, value: float64(count.Dead)})
+	}
+	m.write("policefeed_links_checked", "gauge", "Number of checked event links by publication month.", checked...)
+	m.write("policefeed_links_dead", "gauge", "Number of dead event links by publication month.", dead...)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckLinks(t *testing.T) {
+	requests := map[string]int{}
+	gone := true
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch {
+		case r.URL.Path == "/flaky":
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.HasPrefix(r.URL.Path, "/gone") && gone:
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer site.Close()
+
+	app := newTestApp(t)
+	app.config.LinkCheckInterval = 24 * time.Hour
+	app.feed.SetBaseURL("https://feed.example.org")
+	ctx := context.Background()
+	january := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC).Unix()
+	events := []Event{
+		{Title: "Raub", Hash: "a", Link: site.URL + "/alive", DateTime: january},
+		{Title: "Brand", Hash: "b", Link: site.URL + "/gone-b", DateTime: january, WaybackURL: "https://web.archive.org/web/1/b"},
+		{Title: "Kurzmeldungen", Hash: "c", Link: site.URL + "/gone-c", DateTime: january},
+		{Title: "Kurzmeldung", Hash: "c-1", Link: site.URL + "/gone-c", DateTime: january, ParentHash: "c"},
+		{Title: "Unfall", Hash: "d", Link: site.URL + "/flaky", DateTime: january},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.feed.Add(events...)
+
+	if err := app.checkLinks(ctx); err != nil {
+		t.Fatalf("checkLinks failed: %v", err)
+	}
+	if requests["/gone-c"] != 1 {
+		t.Fatalf("expected shared links to be checked once, got %v", requests)
+	}
+	for hash, dead := range map[string]bool{"a": false, "b": true, "c": true, "c-1": true, "d": false} {
+		event, _ := app.store.FindByHash(ctx, hash)
+		if (event.LinkDeadSince != nil) != dead || event.LinkCheckedAt == nil {
+			t.Fatalf("unexpected link status of %s: %+v", hash, event)
+		}
+	}
+	rss := app.feed.RSS()
+	for _, want := range []string{
+		"<link>" + site.URL + "/alive</link>",
+		"<link>https://web.archive.org/web/1/b</link>",
+		"<link>https://feed.example.org/api/events/c</link>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Fatalf("rss is missing %s:\n%s", want, rss)
+		}
+	}
+
+	// Checked links aren't due again until the interval passed.
+	if err := app.checkLinks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if requests["/alive"] != 1 {
+		t.Fatalf("expected no new checks, got %v", requests)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	for _, want := range []string{
+		`policefeed_link_checks_total{result="dead"} 2`,
+		`policefeed_link_checks_total{result="error"} 1`,
+		`policefeed_links_checked{month="2024-01"} 5`,
+		`policefeed_links_dead{month="2024-01"} 3`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("metrics are missing %s:\n%s", want, body)
+		}
+	}
+
+	// Links that come back are unflagged.
+	gone = false
+	app.config.LinkCheckInterval = time.Nanosecond
+	if err := app.checkLinks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	event, _ := app.store.FindByHash(ctx, "b")
+	if event.LinkDeadSince != nil || !strings.Contains(app.feed.RSS(), "<link>"+site.URL+"/gone-b</link>") {
+		t.Fatalf("expected link of b to be restored, got %+v", event)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// listenAddresses expands addresses whose host names a network interface
+// (e.g. "eth0:8080") into the addresses of that interface. Other addresses,
+// like ":8080", "[::1]:8080" or "localhost:8080", are kept as they are.
+func listenAddresses(addresses []string) ([]string, error) {
+	var expanded []string
+	for _, address := range addresses {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+		if host == "" || net.ParseIP(host) != nil {
+			expanded = append(expanded, address)
+			continue
+		}
+		iface, err := net.InterfaceByName(host)
+		if err != nil {
+			// Not an interface, so leave it to the resolver.
+			expanded = append(expanded, address)
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("listing addresses of %s: %w", host, err)
+		}
+		found := false
+		for _, addr := range addrs {
+			ip, ok := addr.(*net.IPNet)
+			if !ok || ip.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			expanded = append(expanded, net.JoinHostPort(ip.IP.String(), port))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("interface %s has no usable addresses", host)
+		}
+	}
+	return expanded, nil
+}
+
+// listen opens a TCP listener for every address. If any of them fails, the
+// ones already opened are closed again.
+func listen(addresses []string) ([]net.Listener, error) {
+	expanded, err := listenAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+	var listeners []net.Listener
+	for _, address := range expanded {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	if len(listeners) == 0 {
+		return nil, errors.New("no listen addresses configured")
+	}
+	return listeners, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestListenAddresses_ExpandsInterfaces(t *testing.T) {
+	if _, err := net.InterfaceByName("lo"); err != nil {
+		t.Skip("no loopback interface named lo")
+	}
+
+	addresses, err := listenAddresses([]string{":8080", "[::1]:8080", "localhost:8080", "lo:9090"})
+	if err != nil {
+		t.Fatalf("listenAddresses failed: %v", err)
+	}
+	for _, want := range []string{":8080", "[::1]:8080", "localhost:8080", "127.0.0.1:9090"} {
+		if !slices.Contains(addresses, want) {
+			t.Fatalf("expected %s in %v", want, addresses)
+		}
+	}
+
+	if _, err := listenAddresses([]string{"8080"}); err == nil {
+		t.Fatal("expected an address without port to be rejected")
+	}
+}
+
+func TestListen_MultipleAddresses(t *testing.T) {
+	listeners, err := listen([]string{"127.0.0.1:0", "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+	if len(listeners) != 2 || listeners[0].Addr().String() == listeners[1].Addr().String() {
+		t.Fatalf("expected two distinct listeners, got %v", listeners)
+	}
+
+	// A failing address closes the listeners opened before it.
+	taken := listeners[0].Addr().String()
+	if _, err := listen([]string{"127.0.0.1:0", taken}); err == nil {
+		t.Fatal("expected listening on a taken address to fail")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// shedRetryAfter is the Retry-After sent with shed requests, in seconds.
+const shedRetryAfter = 10
+
+// loadShedder bounds how many expensive requests are served at once, so that
+// a burst of them can't starve the feeds.
+type loadShedder struct {
+	slots chan struct{}
+
+	mu   sync.Mutex
+	shed map[string]int
+}
+
+func newLoadShedder(limit int) *loadShedder {
+	return &loadShedder{slots: make(chan struct{}, limit), shed: make(map[string]int)}
+}
+
+func (l *loadShedder) writeMetrics(m *metricsWriter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m.gauge("policefeed_expensive_requests_in_flight", "Number of expensive requests being served.", float64(len(l.slots)))
+	var samples []metricSample
+	for _, pattern := range slices.Sorted(maps.Keys(l.shed)) {
+		samples = append(samples, metricSample{labels: map[string]string{"route": pattern}, value: float64(l.shed[pattern])})
+	}
+	m.write("policefeed_shed_requests_total", "counter", "Number of expensive requests rejected as too many were in progress.", samples...)
+}
+
+// shed marks h as expensive: requests beyond EXPENSIVE_REQUEST_LIMIT served
+// at once get 503 right away instead of queueing up.
+func (a *App) shed(h http.HandlerFunc) http.HandlerFunc {
+	if a.shedder == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case a.shedder.slots <- struct{}{}:
+			defer func() { <-a.shedder.slots }()
+		default:
+			a.shedder.mu.Lock()
+			a.shedder.shed[r.Pattern]++
+			a.shedder.mu.Unlock()
+			w.Header().Set("Retry-After", strconv.Itoa(shedRetryAfter))
+			writeJSONError(w, http.StatusServiceUnavailable, "server busy, try again later")
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShed_RejectsWhenSaturated(t *testing.T) {
+	app := newTestApp(t)
+	app.shedder = newLoadShedder(1)
+	entered, release := make(chan struct{}), make(chan struct{})
+	slow := app.shed(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		slow(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	slow(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+
+	// Feeds aren't limited.
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/rss")
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the feed to be served, got %v %v", res, err)
+	}
+	_ = res.Body.Close()
+
+	close(release)
+	<-done
+	rec = httptest.NewRecorder()
+	app.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{"policefeed_expensive_requests_in_flight 0", "policefeed_shed_requests_total{route=\"\"} 1"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("metrics are missing %s:\n%s", want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// errMaintenance is returned for pages that berlin.de serves in place of the
+// real one while it is down, e.g. "Wartungsarbeiten". They usually come with
+// a 200 status, so without checking for them a run would find no reports and
+// raise a false alarm, and detail pages would lend their text to events.
+var errMaintenance = errors.New("berlin.de serves a maintenance page")
+
+// maintenanceMarkers identify maintenance and error pages by their title or
+// text, compared case-insensitively.
+var maintenanceMarkers = []string{
+	"Wartungsarbeiten",
+	"Wartungsmodus",
+	"vorübergehend nicht erreichbar",
+	"vorübergehend nicht verfügbar",
+	"Service Unavailable",
+	"Service Temporarily Unavailable",
+	"Bad Gateway",
+}
+
+// maintenanceMarker returns the marker that identifies page as a maintenance
+// page, or "" if it is none. Pages with the content expected, given by the
+// selector content, are never maintenance pages, so a report that happens to
+// mention Wartungsarbeiten, even in its title, doesn't count.
+func maintenanceMarker(page *goquery.Selection, content string) string {
+	if page.Find(content).Length() > 0 {
+		return ""
+	}
+	title := strings.ToLower(page.Find("title").First().Text())
+	text := strings.ToLower(strings.Join(strings.Fields(page.Find("body").Text()), " "))
+	for _, marker := range maintenanceMarkers {
+		lower := strings.ToLower(marker)
+		if strings.Contains(title, lower) || strings.Contains(text, lower) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// maintenanceError wraps errMaintenance with the marker that was found.
+func maintenanceError(marker string) error {
+	return fmt.Errorf("%w (%q)", errMaintenance, marker)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestMaintenanceMarker(t *testing.T) {
+	for _, c := range []struct {
+		page, want string
+	}{
+		{`<title>Wartungsarbeiten - Berlin.de</title><body>Bitte später</body>`, "Wartungsarbeiten"},
+		{`<title>Berlin.de</title><body><h1>503 Service Unavailable</h1></body>`, "Service Unavailable"},
+		{`<title>Berlin.de</title><body>Das Angebot ist VORÜBERGEHEND NICHT ERREICHBAR.</body>`, "vorübergehend nicht erreichbar"},
+		// Reports may mention the markers, even in their titles, but their pages
+		// have the content.
+		{`<title>Unfall bei Wartungsarbeiten</title><body><div class="textile"><p>Unfall</p></div></body>`, ""},
+		{`<title>Polizeimeldung</title><body><div class="textile"><p>Ein Arbeiter wurde bei Wartungsarbeiten verletzt.</p></div></body>`, ""},
+		{`<title>Polizeimeldung</title><body><p>Keine Meldungen</p></body>`, ""},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(c.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := maintenanceMarker(doc.Selection, "div.textile"); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.page, got, c.want)
+		}
+	}
+}
+
+func TestFetchDetailAttempt_Maintenance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html><head><meta name="description" content="Berlin.de wird gewartet"><title>Wartungsmodus</title></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	page, status, err := fetchDetailAttempt(context.Background(), server.Client(), server.URL, defaultUserAgent)
+	if !errors.Is(err, errMaintenance) || page != nil || status != 0 {
+		t.Fatalf("expected a maintenance error, got %v %d %+v", err, status, page)
+	}
+}
+
+func TestScrape_MaintenancePage(t *testing.T) {
+	var payloads []lifecyclePayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload lifecyclePayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	app := newFixtureApp(t)
+	app.config.ScrapeTimeout = time.Minute
+	app.config.PoliceURL = "https://www.berlin.de/polizei/wartung/"
+	hook, err := parseLifecycleHook(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.lifecycleHooks = []lifecycleHook{hook}
+	ctx := context.Background()
+
+	err = app.runScrape(ctx)
+	if !errors.Is(err, errMaintenance) {
+		t.Fatalf("expected a maintenance error, got %v", err)
+	}
+	if len(payloads) != 2 || payloads[1].Type != lifecycleMaintenance || !strings.Contains(payloads[1].Error, "Wartungsarbeiten") {
+		t.Fatalf("unexpected lifecycle events %+v", payloads)
+	}
+	if status := app.scrapeState.snapshot(); status.ConsecutiveFailures != 1 {
+		t.Fatalf("expected the run to count as failed, got %+v", status)
+	}
+	events, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events from a maintenance page, got %+v", events)
+	}
+}
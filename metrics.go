@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type metricSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// metricsWriter renders metrics in the Prometheus text exposition format.
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (m *metricsWriter) write(name, typ, help string, samples ...metricSample) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, sample := range samples {
+		if m.err != nil {
+			return
+		}
+		_, m.err = fmt.Fprintf(m.w, "%s%s %v\n", name, formatLabels(sample.labels), sample.value)
+	}
+}
+
+func (m *metricsWriter) gauge(name, help string, value float64) {
+	m.write(name, "gauge", help, metricSample{value: value})
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (a *App) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m := &metricsWriter{w: w}
+
+	status := a.scrapeState.snapshot()
+	m.gauge("policefeed_scrape_last_run_timestamp_seconds", "Start time of the last scrape run.", unixSeconds(status.LastRun))
+	m.gauge("policefeed_scrape_last_success_timestamp_seconds", "Start time of the last successful scrape run.", unixSeconds(status.LastSuccess))
+	m.gauge("policefeed_scrape_consecutive_failures", "Number of scrape runs that failed in a row.", float64(status.ConsecutiveFailures))
+	m.gauge("policefeed_scrape_next_run_timestamp_seconds", "Time the next scrape run is scheduled for.", unixSeconds(status.NextRun))
+	m.gauge("policefeed_scrape_retry_pending", "Whether the next scrape run is a retry of a failed one.", boolValue(status.RetryPending))
+
+	a.writeQualityMetrics(m)
+	a.writeLinkRotMetrics(m)
+	if a.responses != nil {
+		a.responses.writeMetrics(m)
+	}
+	if a.shedder != nil {
+		a.shedder.writeMetrics(m)
+	}
+	if transport, ok := a.transport().(*politeTransport); ok {
+		transport.writeMetrics(m)
+	}
+}
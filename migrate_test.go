@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateDB_CopiesAndResumes(t *testing.T) {
+	dir := t.TempDir()
+	from := "sqlite:" + filepath.Join(dir, "from.db")
+	to := "sqlite:" + filepath.Join(dir, "to.db")
+	ctx := context.Background()
+
+	srcDB, err := openDatabase(from)
+	if err != nil {
+		t.Fatalf("opening source failed: %v", err)
+	}
+	src, err := NewGormStore(srcDB)
+	if err != nil {
+		t.Fatalf("migrating source failed: %v", err)
+	}
+	for i, hash := range []string{"a", "b", "c"} {
+		if err := src.Create(ctx, &Event{Title: hash, Hash: hash, DateTime: int64(i)}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+	if err := src.SetLastScrape(ctx, time.Unix(42, 0)); err != nil {
+		t.Fatalf("SetLastScrape failed: %v", err)
+	}
+	if err := src.RecordAudit(ctx, &AuditEntry{Action: "event.edit", Target: "a"}); err != nil {
+		t.Fatalf("RecordAudit failed: %v", err)
+	}
+	if err := src.SaveReceipt(ctx, &NotificationReceipt{Target: "telegram", EventHash: "a", MessageID: "17"}); err != nil {
+		t.Fatalf("SaveReceipt failed: %v", err)
+	}
+	if err := applyMigrations(srcDB, nil); err != nil {
+		t.Fatalf("applying migrations failed: %v", err)
+	}
+
+	args := []string{"-from", from, "-to", to, "-batch-size", "2"}
+	if err := runMigrateDB(ctx, args); err != nil {
+		t.Fatalf("migrate-db failed: %v", err)
+	}
+
+	// A second run must be a no-op rather than failing on existing rows.
+	if err := src.Create(ctx, &Event{Title: "d", Hash: "d", DateTime: 3}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := runMigrateDB(ctx, args); err != nil {
+		t.Fatalf("resumed migrate-db failed: %v", err)
+	}
+
+	dstDB, err := openDatabase(to)
+	if err != nil {
+		t.Fatalf("opening target failed: %v", err)
+	}
+	dst, err := NewGormStore(dstDB)
+	if err != nil {
+		t.Fatalf("migrating target failed: %v", err)
+	}
+	events, err := dst.All(ctx)
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events in target, got %d", len(events))
+	}
+	last, err := dst.LastScrape(ctx)
+	if err != nil || !last.Equal(time.Unix(42, 0)) {
+		t.Fatalf("settings not copied: %v %v", last, err)
+	}
+	entries, err := dst.AuditLog(ctx, AuditFilter{Limit: 10})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("audit log not copied: %v %v", entries, err)
+	}
+	receipt, err := dst.Receipt(ctx, "telegram", "a")
+	if err != nil || receipt.MessageID != "17" {
+		t.Fatalf("notification receipt not copied: %+v %v", receipt, err)
+	}
+	statuses, err := migrationStatuses(dstDB, nil)
+	if err != nil {
+		t.Fatalf("migrationStatuses failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Enabled && !status.Applied {
+			t.Fatalf("migration %s not marked as applied in target", status.ID)
+		}
+	}
+}
+
+func TestMigrateDB_RequiresFlags(t *testing.T) {
+	if err := runMigrateDB(context.Background(), []string{"-from", "sqlite:x.db"}); err == nil {
+		t.Fatalf("expected error without -to")
+	}
+}
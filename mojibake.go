@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Before pages were decoded by their charset (see decodePage), UTF-8 text
+// could end up read as Windows-1252 and stored encoded once more, turning
+// "für" into "fÃ¼r". The fix-encoding command repairs such events.
+
+// repairMojibake undoes double encoding in s. Each run of non-ASCII
+// characters is encoded back to Windows-1252 bytes; if those are valid UTF-8,
+// they replace the run. Correct text stays as it is, as its umlauts become
+// single bytes that are never valid UTF-8 on their own. Pairs of them can be,
+// as "ß“" gives the bytes of U+07D3, so a repair must also decode to Latin-1
+// letters and punctuation only, the characters of German texts. Repeated
+// double encoding is undone by repeating this.
+func repairMojibake(s string) string {
+	for range 3 {
+		repaired := repairMojibakeOnce(s)
+		if repaired == s {
+			break
+		}
+		s = repaired
+	}
+	return s
+}
+
+func repairMojibakeOnce(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		ascii := strings.IndexFunc(s, func(r rune) bool { return r >= utf8.RuneSelf })
+		if ascii == -1 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:ascii])
+		s = s[ascii:]
+		end := strings.IndexFunc(s, func(r rune) bool { return r < utf8.RuneSelf })
+		if end == -1 {
+			end = len(s)
+		}
+		b.WriteString(repairRun(s[:end]))
+		s = s[end:]
+	}
+	return b.String()
+}
+
+// repairRun repairs a run of non-ASCII characters, see repairMojibake.
+func repairRun(run string) string {
+	raw := make([]byte, 0, len(run))
+	for _, r := range run {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			if r > 0xff {
+				return run
+			}
+			// Bytes Windows-1252 leaves undefined come out of ISO-8859-1
+			// decoding as C1 controls.
+			c = byte(r)
+		}
+		raw = append(raw, c)
+	}
+	if !utf8.Valid(raw) {
+		return run
+	}
+	for _, r := range string(raw) {
+		if !repairedRune(r) {
+			return run
+		}
+	}
+	return string(raw)
+}
+
+// repairedRune reports whether r may come out of a repair: a character of
+// the Latin-1 Supplement or of General Punctuation.
+func repairedRune(r rune) bool {
+	return r >= 0x80 && r <= 0xff || r >= 0x2000 && r <= 0x206f
+}
+
+// fixEncoding repairs the titles and descriptions of all events, reporting
+// each change to report. Unless apply is set, nothing is written. It returns
+// the number of events that need repairs.
+func fixEncoding(ctx context.Context, store EventStore, apply bool, report io.Writer) (int, error) {
+	broken := 0
+	err := store.EachBatch(ctx, EventFilter{}, exportBatchSize, func(events []Event) error {
+		for i := range events {
+			event := &events[i]
+			title, description := repairMojibake(event.Title), repairMojibake(event.Description)
+			if title == event.Title && description == event.Description {
+				continue
+			}
+			broken++
+			if title != event.Title {
+				fmt.Fprintf(report, "%s title: %q -> %q\n", event.Hash, event.Title, title)
+			}
+			if description != event.Description {
+				fmt.Fprintf(report, "%s description: %q -> %q\n", event.Hash, event.Description, description)
+			}
+			if !apply {
+				continue
+			}
+			event.Title, event.Description = title, description
+			err := store.Update(ctx, event)
+			if err != nil {
+				return fmt.Errorf("updating %s: %w", event.Hash, err)
+			}
+			err = store.ReplaceFacts(ctx, event.Hash, extractFacts(event))
+			if err != nil {
+				return fmt.Errorf("storing facts of %s: %w", event.Hash, err)
+			}
+		}
+		return nil
+	})
+	return broken, err
+}
+
+func runFixEncoding(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("fix-encoding", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "write the repairs instead of only reporting them")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	db, err := openConfiguredDatabase(config)
+	if err != nil {
+		return err
+	}
+	store, err := NewGormStore(db)
+	if err != nil {
+		return err
+	}
+
+	broken, err := fixEncoding(ctx, store, *apply, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if !*apply {
+		log.Printf("%d events need repairs, run with -apply to write them", broken)
+		return nil
+	}
+	log.Printf("Repaired %d events", broken)
+	return nil
+}
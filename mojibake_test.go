@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRepairMojibake(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"Zeugen gesucht fÃ¼r Ãœberfall", "Zeugen gesucht für Überfall"},
+		{"StraÃŸe in NeukÃ¶lln", "Straße in Neukölln"},
+		{"â€žNachtragâ€œ â€“ Festnahme", "„Nachtrag“ – Festnahme"},
+		// Encoded twice.
+		{"fÃƒÂ¼r", "für"},
+		{"Korrekt: für Straße in Neukölln", "Korrekt: für Straße in Neukölln"},
+		{"Preis: 5 € – 10 °C", "Preis: 5 € – 10 °C"},
+		// "ß“" are the bytes of U+07D3 in Windows-1252.
+		{"„Spaß“ im Park", "„Spaß“ im Park"},
+		{"plain ASCII", "plain ASCII"},
+		{"", ""},
+	} {
+		if got := repairMojibake(c.in); got != c.want {
+			t.Errorf("repairMojibake(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFixEncoding(t *testing.T) {
+	store := newTestApp(t).store
+	ctx := context.Background()
+	events := []Event{
+		{Title: "Messerangriff in NeukÃ¶lln", Description: "Ein 17-JÃ¤hriger wurde verletzt.", Hash: "a", DateTime: 1},
+		{Title: "Unfall in Neukölln", Description: "Korrekt kodiert.", Hash: "b", DateTime: 2},
+	}
+	for i := range events {
+		if err := store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	var report bytes.Buffer
+	broken, err := fixEncoding(ctx, store, false, &report)
+	if err != nil || broken != 1 {
+		t.Fatalf("expected one broken event, got %d %v", broken, err)
+	}
+	if !strings.Contains(report.String(), `a title: "Messerangriff in NeukÃ¶lln" -> "Messerangriff in Neukölln"`) || strings.Contains(report.String(), "b ") {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+	stored, err := store.FindByHash(ctx, "a")
+	if err != nil || stored.Title != events[0].Title {
+		t.Fatalf("expected a dry run to leave the event alone, got %+v %v", stored, err)
+	}
+
+	broken, err = fixEncoding(ctx, store, true, &bytes.Buffer{})
+	if err != nil || broken != 1 {
+		t.Fatalf("expected one repaired event, got %d %v", broken, err)
+	}
+	stored, err = store.FindByHash(ctx, "a")
+	if err != nil || stored.Title != "Messerangriff in Neukölln" || stored.Description != "Ein 17-Jähriger wurde verletzt." {
+		t.Fatalf("unexpected event after repair %+v %v", stored, err)
+	}
+	facts, err := store.Facts(ctx, []string{"a"})
+	if err != nil || len(facts) == 0 {
+		t.Fatalf("expected the facts to be extracted again, got %v %v", facts, err)
+	}
+	if broken, _ := fixEncoding(ctx, store, false, &bytes.Buffer{}); broken != 0 {
+		t.Fatalf("expected nothing left to repair, got %d", broken)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	nostrKindTextNote = 1
+	// nostrKindDeletion requests the deletion of earlier events (NIP-09).
+	nostrKindDeletion = 5
+	// nostrMaxDescription caps the description in notes, in characters.
+	nostrMaxDescription = 1000
+)
+
+// nostrEvent is a signed Nostr event as defined by NIP-01.
+type nostrEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// nostrString encodes s as JSON with exactly the escaping NIP-01 requires for
+// computing event ids, which is less than encoding/json does.
+func nostrString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// commitment returns the serialization the event id is the hash of.
+func (e *nostrEvent) commitment() string {
+	var b strings.Builder
+	b.WriteString(`[0,` + nostrString(e.PubKey) + `,` + strconv.FormatInt(e.CreatedAt, 10) + `,` + strconv.Itoa(e.Kind) + `,[`)
+	for i, tag := range e.Tags {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('[')
+		for j, value := range tag {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(nostrString(value))
+		}
+		b.WriteByte(']')
+	}
+	b.WriteString(`],` + nostrString(e.Content) + `]`)
+	return b.String()
+}
+
+// sign sets the public key, id and signature of the event.
+func (e *nostrEvent) sign(secretKey *btcec.PrivateKey) error {
+	e.PubKey = hex.EncodeToString(schnorr.SerializePubKey(secretKey.PubKey()))
+	id := sha256.Sum256([]byte(e.commitment()))
+	e.ID = hex.EncodeToString(id[:])
+
+	sig, err := schnorr.Sign(secretKey, id[:])
+	if err != nil {
+		return err
+	}
+	e.Sig = hex.EncodeToString(sig.Serialize())
+	return nil
+}
+
+// parseNostrKey accepts a secret key as nsec (NIP-19) or hex.
+func parseNostrKey(value string) (*btcec.PrivateKey, error) {
+	var key []byte
+	var err error
+	if strings.HasPrefix(value, "nsec1") {
+		var hrp string
+		var data []byte
+		hrp, data, err = bech32.Decode(value)
+		if err == nil && hrp != "nsec" {
+			err = errors.New("not an nsec key")
+		}
+		if err == nil {
+			key, err = bech32.ConvertBits(data, 5, 8, false)
+		}
+	} else {
+		key, err = hex.DecodeString(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var scalar btcec.ModNScalar
+	if len(key) != 32 || scalar.SetByteSlice(key) || scalar.IsZero() {
+		return nil, errors.New("secret key out of range")
+	}
+	return btcec.PrivKeyFromScalar(&scalar), nil
+}
+
+// nostrHashtag turns a district into a hashtag, e.g. "Friedrichshain-Kreuzberg"
+// into "FriedrichshainKreuzberg".
+func nostrHashtag(district string) string {
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(district)
+}
+
+// nostrNote builds the unsigned note announcing event, its title after the
+// emoji prefix, if any.
+func nostrNote(event Event, emoji, summary, shortLink string, now time.Time) *nostrEvent {
+	text := summary
+	if text == "" {
+		text = strings.TrimSpace(event.Description)
+		if utf8.RuneCountInString(text) > nostrMaxDescription {
+			text = string([]rune(text)[:nostrMaxDescription]) + " …"
+		}
+	}
+	hashtags := []string{"Berlin", "Polizei"}
+	if district := nostrHashtag(event.Location); district != "" && district != "Berlin" {
+		hashtags = append(hashtags, district)
+	}
+
+	var content strings.Builder
+	content.WriteString(emojiTitle(emoji, event.Title) + "\n\n")
+	if text != "" {
+		content.WriteString(text + "\n\n")
+	}
+	if link := cmp.Or(shortLink, event.Link); link != "" {
+		content.WriteString(link + "\n\n")
+	}
+	tags := [][]string{}
+	for i, hashtag := range hashtags {
+		if i > 0 {
+			content.WriteByte(' ')
+		}
+		content.WriteString("#" + hashtag)
+		tags = append(tags, []string{"t", strings.ToLower(hashtag)})
+	}
+	if event.Link != "" {
+		tags = append(tags, []string{"r", event.Link})
+	}
+	return &nostrEvent{
+		CreatedAt: now.Unix(),
+		Kind:      nostrKindTextNote,
+		Tags:      tags,
+		Content:   content.String(),
+	}
+}
+
+// nostrNotifier publishes every event as a signed note to a set of relays.
+type nostrNotifier struct {
+	secretKey *btcec.PrivateKey
+	relays    []string
+}
+
+func newNostrNotifier(secretKey string, relays []string) (*nostrNotifier, error) {
+	key, err := parseNostrKey(secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nostr key: %w", err)
+	}
+	for _, relay := range relays {
+		if !strings.HasPrefix(relay, "wss://") && !strings.HasPrefix(relay, "ws://") {
+			return nil, fmt.Errorf("invalid nostr relay %q", relay)
+		}
+	}
+	return &nostrNotifier{secretKey: key, relays: relays}, nil
+}
+
+func (n *nostrNotifier) Name() string { return "nostr" }
+
+// Notify publishes one note per event; digests are not bundled, as notes are
+// short anyway. Replayed events are dated to their publication, so that
+// clients sort them into the history instead of showing them as new. It
+// succeeds if every note reached at least one relay.
+func (n *nostrNotifier) Notify(ctx context.Context, notification Notification) error {
+	events := notification.Digest
+	if events == nil {
+		events = []Event{notification.Event}
+	}
+	for _, event := range events {
+		createdAt := time.Now()
+		if notification.Replay {
+			createdAt = time.Unix(event.DateTime, 0)
+		}
+		note := nostrNote(event, notification.Emoji[event.Hash], notification.Summaries[event.Hash], notification.ShortLinks[event.Hash], createdAt)
+		err := note.sign(n.secretKey)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, relay := range n.relays {
+			err := publishNostr(ctx, relay, note)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", relay, err))
+			}
+		}
+		if len(errs) == len(n.relays) {
+			return errors.Join(errs...)
+		}
+		if notification.receipt != nil {
+			notification.receipt(event.Hash, note.ID)
+		}
+	}
+	return nil
+}
+
+// Correct asks the relays to delete the note of an event that was taken
+// down. Notes can't be edited, so updates are left alone rather than
+// reposted as new notes.
+func (n *nostrNotifier) Correct(ctx context.Context, correction Correction) error {
+	if correction.Type != correctionDeleted || correction.MessageID == "" {
+		return nil
+	}
+	deletion := &nostrEvent{
+		CreatedAt: time.Now().Unix(),
+		Kind:      nostrKindDeletion,
+		Tags:      [][]string{{"e", correction.MessageID}},
+		Content:   "Meldung entfernt",
+	}
+	err := deletion.sign(n.secretKey)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, relay := range n.relays {
+		err := publishNostr(ctx, relay, deletion)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", relay, err))
+		}
+	}
+	if len(errs) == len(n.relays) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// publishNostr sends the event to a relay and waits for its OK.
+func publishNostr(ctx context.Context, relay string, event *nostrEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	config, err := websocket.NewConfig(relay, "http://localhost/")
+	if err != nil {
+		return err
+	}
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	err = websocket.JSON.Send(conn, []any{"EVENT", event})
+	if err != nil {
+		return err
+	}
+	for {
+		var message []json.RawMessage
+		err = websocket.JSON.Receive(conn, &message)
+		if err != nil {
+			return err
+		}
+		var kind, id string
+		if len(message) < 4 || json.Unmarshal(message[0], &kind) != nil || kind != "OK" ||
+			json.Unmarshal(message[1], &id) != nil || id != event.ID {
+			// Relays may send notices or other messages first.
+			continue
+		}
+		var accepted bool
+		var reason string
+		_ = json.Unmarshal(message[2], &accepted)
+		_ = json.Unmarshal(message[3], &reason)
+		if !accepted {
+			return fmt.Errorf("rejected: %s", reason)
+		}
+		return nil
+	}
+}
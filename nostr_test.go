@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"golang.org/x/net/websocket"
+)
+
+func TestParseNostrKey(t *testing.T) {
+	// Example from NIP-19.
+	key, err := parseNostrKey("nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(key.Serialize()); got != "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa" {
+		t.Fatalf("unexpected key %s", got)
+	}
+	if _, err := parseNostrKey("nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe6"); err == nil {
+		t.Fatal("expected a broken checksum to be rejected")
+	}
+	if _, err := parseNostrKey("67dea2ed"); err == nil {
+		t.Fatal("expected a short key to be rejected")
+	}
+	if _, err := parseNostrKey(strings.Repeat("00", 32)); err == nil {
+		t.Fatal("expected the zero key to be rejected")
+	}
+}
+
+func TestNostrNote(t *testing.T) {
+	event := Event{Title: "Raub", Description: "Ein \"Mann\"\nflüchtete.", Location: "Friedrichshain-Kreuzberg", Link: "https://example.com/1"}
+	note := nostrNote(event, "", "", "", time.Unix(1700000000, 0))
+	if note.Content != "Raub\n\nEin \"Mann\"\nflüchtete.\n\nhttps://example.com/1\n\n#Berlin #Polizei #FriedrichshainKreuzberg" {
+		t.Fatalf("unexpected content %q", note.Content)
+	}
+	want := `[0,"",1700000000,1,[["t","berlin"],["t","polizei"],["t","friedrichshainkreuzberg"],["r","https://example.com/1"]],"Raub\n\nEin \"Mann\"\nflüchtete.\n\nhttps://example.com/1\n\n#Berlin #Polizei #FriedrichshainKreuzberg"]`
+	if got := note.commitment(); got != want {
+		t.Fatalf("unexpected commitment\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestNostrNotifier_PublishesSignedNotes(t *testing.T) {
+	var received []nostrEvent
+	relay := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		var message []json.RawMessage
+		if err := websocket.JSON.Receive(conn, &message); err != nil {
+			return
+		}
+		var event nostrEvent
+		_ = json.Unmarshal(message[1], &event)
+		received = append(received, event)
+		id := sha256.Sum256([]byte(event.commitment()))
+		accepted := hex.EncodeToString(id[:]) == event.ID && verifyNostr(event)
+		_ = websocket.JSON.Send(conn, []any{"NOTICE", "hello"})
+		_ = websocket.JSON.Send(conn, []any{"OK", event.ID, accepted, "invalid: id"})
+	}))
+	defer relay.Close()
+	relayURL := "ws" + strings.TrimPrefix(relay.URL, "http")
+
+	notifier, err := newNostrNotifier("0000000000000000000000000000000000000000000000000000000000000003", []string{relayURL})
+	if err != nil {
+		t.Fatalf("newNostrNotifier failed: %v", err)
+	}
+	err = notifier.Notify(context.Background(), Notification{Digest: []Event{
+		{Hash: "a", Title: "Raub", Location: "Mitte"},
+		{Hash: "b", Title: "Brand", Location: "Pankow"},
+	}})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(received) != 2 || received[0].PubKey != "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9" {
+		t.Fatalf("unexpected notes %+v", received)
+	}
+	if time.Since(time.Unix(received[0].CreatedAt, 0)) > time.Minute {
+		t.Fatalf("expected a fresh note, got created_at %d", received[0].CreatedAt)
+	}
+
+	err = notifier.Notify(context.Background(), Notification{Event: Event{Hash: "c", Title: "Alt", DateTime: 1700000000}, Replay: true})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(received) != 3 || received[2].CreatedAt != 1700000000 {
+		t.Fatalf("expected the replayed note to be dated to its publication, got %+v", received[2:])
+	}
+
+	unreachable, _ := newNostrNotifier("0000000000000000000000000000000000000000000000000000000000000003", []string{"ws://127.0.0.1:1"})
+	if err := unreachable.Notify(context.Background(), Notification{Event: Event{Title: "x"}}); err == nil {
+		t.Fatal("expected an error when no relay accepts the note")
+	}
+}
+
+// verifyNostr checks the signature of event against its public key.
+func verifyNostr(event nostrEvent) bool {
+	publicKey, err := hex.DecodeString(event.PubKey)
+	if err != nil {
+		return false
+	}
+	key, err := schnorr.ParsePubKey(publicKey)
+	if err != nil {
+		return false
+	}
+	sig, err := hex.DecodeString(event.Sig)
+	if err != nil {
+		return false
+	}
+	signature, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return false
+	}
+	id, err := hex.DecodeString(event.ID)
+	return err == nil && signature.Verify(id, key)
+}
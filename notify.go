@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+)
+
+// Notifier delivers events to an external target.
+type Notifier interface {
+	// Name identifies the target in logs and when selecting targets.
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// selectiveNotifier is a target that only wants some of the events, e.g. those
+// of high severity.
+type selectiveNotifier interface {
+	selects(event *Event, classification Classification) bool
+}
+
+// selectEvents returns the events notifier wants.
+func (a *App) selectEvents(notifier Notifier, events []Event) []Event {
+	selective, ok := notifier.(selectiveNotifier)
+	if !ok {
+		return events
+	}
+	rules := a.rules()
+	var selected []Event
+	for _, event := range events {
+		if selective.selects(&event, classifyEvent(rules, &event)) {
+			selected = append(selected, event)
+		}
+	}
+	return selected
+}
+
+// Notification is a single event sent to a target. Replay marks events that
+// are re-sent from history rather than freshly scraped. If Digest is set, the
+// notification bundles these events into one message instead and Event is
+// unset.
+type Notification struct {
+	Event  Event
+	Replay bool
+	Digest []Event
+	// Summaries holds generated summaries of long events by hash.
+	Summaries map[string]string
+	// ShortLinks holds the short links of the events for this target by
+	// hash, if the short_links feature is enabled.
+	ShortLinks map[string]string
+	// Emoji holds the emoji prefixes of the events by hash for targets
+	// listed in NOTIFY_EMOJI. Targets sending text put them before the
+	// titles; the events themselves are left as they are.
+	Emoji map[string]string
+	// receipt, if set, records the ID the target gave the message about an
+	// event, so that a correction can refer to it later.
+	receipt func(hash, messageID string)
+	// posted, if set, returns the ID the target gave an earlier message
+	// about an event, "" if it was never sent one.
+	posted func(hash string) string
+}
+
+// digestSampleSize is the number of titles a digest summary names.
+const digestSampleSize = 3
+
+// digestSummary describes a digest in one line, naming the first few events
+// with their emoji prefixes, if any.
+func digestSummary(events []Event, emoji map[string]string) string {
+	titles := make([]string, 0, digestSampleSize)
+	for _, event := range events[:min(len(events), digestSampleSize)] {
+		titles = append(titles, emojiTitle(emoji[event.Hash], event.Title))
+	}
+	summary := fmt.Sprintf("%d neue Meldungen, darunter: %s", len(events), strings.Join(titles, "; "))
+	if len(events) > digestSampleSize {
+		summary += " …"
+	}
+	return summary
+}
+
+const notifyTimeout = 10 * time.Second
+
+// webhookNotifier POSTs each event as JSON to a URL.
+type webhookNotifier struct {
+	url    string
+	client HTTPDoer
+}
+
+func newWebhookNotifier(rawURL string) (*webhookNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", rawURL)
+	}
+	return &webhookNotifier{
+		url:    rawURL,
+		client: &http.Client{Timeout: notifyTimeout},
+	}, nil
+}
+
+func (n *webhookNotifier) Name() string {
+	u, _ := url.Parse(n.url)
+	return "webhook:" + u.Host
+}
+
+// webhookPayload is documented by eventSchema; see webhookSchemaVersion for
+// the compatibility rules.
+type webhookPayload = model.Payload
+
+func newWebhookPayload(notification Notification) webhookPayload {
+	payload := webhookPayload{
+		Schema: webhookSchemaVersion,
+		Replay: notification.Replay,
+	}
+	if notification.Digest == nil {
+		event := toAPIEvent(&notification.Event)
+		event.Summary = notification.Summaries[event.Hash]
+		payload.Type = model.PayloadCreated
+		payload.Event = &event
+		return payload
+	}
+	payload.Type = model.PayloadDigest
+	payload.Summary = digestSummary(notification.Digest, nil)
+	for i := range notification.Digest {
+		event := toAPIEvent(&notification.Digest[i])
+		event.Summary = notification.Summaries[event.Hash]
+		payload.Events = append(payload.Events, event)
+	}
+	return payload
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.post(ctx, newWebhookPayload(notification))
+}
+
+// Correct sends an event.updated or event.deleted payload.
+func (n *webhookNotifier) Correct(ctx context.Context, correction Correction) error {
+	payload := webhookPayload{Schema: webhookSchemaVersion, Type: correction.Type}
+	if correction.Type == correctionDeleted {
+		payload.Hash = correction.Event.Hash
+	} else {
+		event := toAPIEvent(&correction.Event)
+		payload.Event = &event
+		payload.Changes = correction.Changes
+	}
+	return n.post(ctx, payload)
+}
+
+func (n *webhookNotifier) post(ctx context.Context, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// buildNotifiers creates the configured notification targets, skipping (and
+// logging) invalid ones.
+func buildNotifiers(config Config) []Notifier {
+	var notifiers []Notifier
+	for _, rawURL := range config.WebhookURLs {
+		notifier, err := newWebhookNotifier(rawURL)
+		if err != nil {
+			log.Println("Skipping notifier:", err)
+			continue
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if config.NostrPrivateKey != "" && len(config.NostrRelays) > 0 {
+		notifier, err := newNostrNotifier(config.NostrPrivateKey, config.NostrRelays)
+		if err != nil {
+			log.Println("Skipping notifier:", err)
+		} else {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	for _, rawURL := range config.AppriseURLs {
+		notifier, err := newAppriseNotifier(rawURL, config.AppriseAPIURL)
+		if err != nil {
+			log.Println("Skipping notifier:", err)
+			continue
+		}
+		log.Println("Notifying", notifier.Name())
+		notifiers = append(notifiers, notifier)
+	}
+	if config.TelegramBotToken != "" || config.TelegramChannel != "" {
+		notifier, err := newTelegramChannelNotifier(config.TelegramBotToken, config.TelegramChannel)
+		if err != nil {
+			log.Println("Skipping notifier:", err)
+		} else {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	sms, err := buildSMSNotifiers(config)
+	if err != nil {
+		log.Println("Skipping notifier:", err)
+	}
+	notifiers = append(notifiers, sms...)
+	return notifiers
+}
+
+// notify sends the events to every given target. Failed deliveries are
+// retried and finally parked as dead letters. Retries can take minutes, so
+// each target is sent to from its own goroutine and on a context detached from
+// the caller's: a failing target never holds up the others, and the end of a
+// scrape run doesn't cut its deliveries short. notify waits for all targets,
+// see notifyInBackground for callers that must not.
+//
+// During a target's quiet hours, events below high severity are held back (see
+// holdForQuietHours). Scrapes bringing at least NotifyDigestThreshold events
+// are sent as a single digest per target. Replays are exempt from both, as
+// they are triggered on purpose and meant to seed targets with the individual
+// events. Targets only selecting some events (see selectiveNotifier) never get
+// the others.
+func (a *App) notify(ctx context.Context, notifiers []Notifier, events []Event, replay bool) (sent, failed int) {
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, notifier := range notifiers {
+		wg.Go(func() {
+			s, f := a.notifyTarget(ctx, notifier, events, replay, now)
+			mu.Lock()
+			sent += s
+			failed += f
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	return sent, failed
+}
+
+// notifyInBackground notifies the targets about new events without waiting
+// for the deliveries, for scrape runs which hold scrapeMu while storing.
+func (a *App) notifyInBackground(ctx context.Context, notifiers []Notifier, events []Event) {
+	a.deliveries.Go(func() {
+		a.notify(ctx, notifiers, events, false)
+	})
+}
+
+// notifyTarget sends the events to one target. Deliveries to the same target
+// take turns, so the events of overlapping scrape runs arrive in order.
+func (a *App) notifyTarget(ctx context.Context, notifier Notifier, events []Event, replay bool, now time.Time) (sent, failed int) {
+	lock, _ := a.targetLocks.LoadOrStore(notifier.Name(), new(sync.Mutex))
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	pending := a.selectEvents(notifier, events)
+	if !replay {
+		pending = a.holdForQuietHours(ctx, notifier, pending, now)
+		pending = a.breakingFirst(pending)
+	}
+
+	if threshold := a.config.NotifyDigestThreshold; !replay && threshold > 0 && len(pending) >= threshold {
+		// Breaking events are sent on their own, ahead of the digest.
+		var digest []Event
+		for _, event := range pending {
+			if !a.isBreaking(&event) {
+				digest = append(digest, event)
+				continue
+			}
+			s, f := a.send(ctx, notifier, Notification{Event: event})
+			sent += s
+			failed += f
+		}
+		if len(digest) > 0 {
+			s, f := a.send(ctx, notifier, Notification{Digest: digest})
+			sent += s
+			failed += f
+		}
+		return sent, failed
+	}
+	for _, event := range pending {
+		s, f := a.send(ctx, notifier, Notification{Event: event, Replay: replay})
+		sent += s
+		failed += f
+	}
+	return sent, failed
+}
+
+// send delivers a notification and counts the events it covers as sent or
+// failed. A failed digest is parked as one dead letter per event, so each of
+// them can be redelivered on its own.
+func (a *App) send(ctx context.Context, notifier Notifier, notification Notification) (sent, failed int) {
+	events := notification.Digest
+	if events == nil {
+		events = []Event{notification.Event}
+	}
+	notification.Summaries = a.summarize(ctx, events)
+	notification.ShortLinks = a.shortLinks(ctx, notifier, events)
+	notification.receipt = a.receipts(ctx, notifier)
+	notification.posted = a.posted(ctx, notifier)
+
+	// Only what is delivered is redacted; dead letters keep the stored event.
+	delivered := notification
+	delivered.Event = *a.redactions.event(&notification.Event)
+	delivered.Digest = a.redactions.events(notification.Digest)
+	delivered.Summaries = a.redactions.summaries(notification.Summaries)
+	delivered.Emoji = a.emoji(notifier, events)
+	attempts, err := a.deliver(ctx, notifier, delivered)
+	if err == nil {
+		return len(events), 0
+	}
+	log.Printf("Error notifying %s about %d events after %d attempts: %v", notifier.Name(), len(events), attempts, err)
+	for _, event := range events {
+		a.deadLetter(ctx, notifier, Notification{Event: event, Replay: notification.Replay}, attempts, err)
+	}
+	return 0, len(events)
+}
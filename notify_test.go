@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// recordingNotifier collects notifications in memory.
+type recordingNotifier struct {
+	name string
+	err  error
+
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (n *recordingNotifier) Name() string { return n.name }
+
+func (n *recordingNotifier) Notify(_ context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload failed: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	notifier, err := newWebhookNotifier(server.URL)
+	if err != nil {
+		t.Fatalf("newWebhookNotifier failed: %v", err)
+	}
+	err = notifier.Notify(context.Background(), Notification{Event: Event{Title: "Brand", Hash: "h1"}, Replay: true})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if payload.Schema != webhookSchemaVersion || payload.Type != "event.created" || !payload.Replay || payload.Event == nil || payload.Event.Hash != "h1" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier, err := newWebhookNotifier(server.URL)
+	if err != nil {
+		t.Fatalf("newWebhookNotifier failed: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), Notification{}); err == nil {
+		t.Fatalf("expected error for 502 response")
+	}
+}
+
+func TestNewWebhookNotifier_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"", "ftp://example.org", "example.org/hook"} {
+		if _, err := newWebhookNotifier(rawURL); err == nil {
+			t.Fatalf("expected error for %q", rawURL)
+		}
+	}
+}
+
+func TestNotify_DigestAboveThreshold(t *testing.T) {
+	app := newTestApp(t)
+	app.config.NotifyDigestThreshold = 3
+	target := &recordingNotifier{name: "webhook:a"}
+
+	events := []Event{{Title: "Brand", Hash: "1"}, {Title: "Raub", Hash: "2"}, {Title: "Unfall", Hash: "3"}, {Title: "Einbruch", Hash: "4"}}
+	sent, failed := app.notify(context.Background(), []Notifier{target}, events, false)
+	if sent != 4 || failed != 0 || len(target.sent) != 1 || len(target.sent[0].Digest) != 4 {
+		t.Fatalf("expected a single digest of 4 events, got %d sent, %d failed: %+v", sent, failed, target.sent)
+	}
+
+	payload := newWebhookPayload(target.sent[0])
+	if payload.Type != "event.digest" || payload.Event != nil || len(payload.Events) != 4 {
+		t.Fatalf("unexpected digest payload: %+v", payload)
+	}
+	if payload.Summary != "4 neue Meldungen, darunter: Brand; Raub; Unfall …" {
+		t.Fatalf("unexpected summary %q", payload.Summary)
+	}
+
+	target.sent = nil
+	app.notify(context.Background(), []Notifier{target}, events[:2], false)
+	app.notify(context.Background(), []Notifier{target}, events, true)
+	if len(target.sent) != 6 {
+		t.Fatalf("expected small batches and replays to be sent one by one, got %d notifications", len(target.sent))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxPageSize bounds the pages read from berlin.de. Real ones are well below
+// 1 MiB; anything larger is a misconfigured URL or an upstream anomaly and
+// isn't worth parsing, let alone holding in memory.
+const maxPageSize = 5 << 20
+
+// errUnexpectedPage is returned for responses that aren't HTML pages of a
+// sensible size.
+var errUnexpectedPage = errors.New("unexpected page")
+
+// checkPageHeaders rejects responses that aren't HTML or announce a body
+// larger than maxPageSize.
+func checkPageHeaders(header http.Header) error {
+	contentType := header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return fmt.Errorf("%w: content type %q is not HTML", errUnexpectedPage, contentType)
+	}
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && length > maxPageSize {
+		return fmt.Errorf("%w: %d bytes exceed the limit of %d", errUnexpectedPage, length, maxPageSize)
+	}
+	return nil
+}
+
+// checkPageSize rejects bodies larger than maxPageSize, for responses that
+// didn't announce their length.
+func checkPageSize(body []byte) error {
+	if len(body) > maxPageSize {
+		return fmt.Errorf("%w: more than %d bytes", errUnexpectedPage, maxPageSize)
+	}
+	return nil
+}
+
+// readPage parses the body of res as HTML, after checking that it is some
+// and not too large, and converting it to UTF-8.
+func readPage(res *http.Response) (*goquery.Document, error) {
+	err := checkPageHeaders(res.Header)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	err = checkPageSize(body)
+	if err != nil {
+		return nil, err
+	}
+	body, err = decodePage(body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	return goquery.NewDocumentFromReader(bytes.NewReader(body))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckPageHeaders(t *testing.T) {
+	for _, c := range []struct {
+		contentType, length string
+		ok                  bool
+	}{
+		{"text/html; charset=utf-8", "", true},
+		{"application/xhtml+xml", "1024", true},
+		{"TEXT/HTML", strconv.Itoa(maxPageSize), true},
+		{"text/html", strconv.Itoa(maxPageSize + 1), false},
+		{"application/json", "", false},
+		{"application/pdf", "", false},
+		{"", "", false},
+	} {
+		header := http.Header{}
+		header.Set("Content-Type", c.contentType)
+		if c.length != "" {
+			header.Set("Content-Length", c.length)
+		}
+		err := checkPageHeaders(header)
+		if (err == nil) != c.ok || (err != nil && !errors.Is(err, errUnexpectedPage)) {
+			t.Errorf("%q, %q: unexpected error %v", c.contentType, c.length, err)
+		}
+	}
+}
+
+func TestFetchDetailAttempt_RejectsUnexpectedPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"description": "nope"}`)
+		case "/huge":
+			// Streamed without a length, so only reading finds it too large.
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, "<html><body>")
+			chunk := strings.Repeat("x", 1<<16)
+			for written := 0; written <= maxPageSize; written += len(chunk) {
+				_, _ = io.WriteString(w, chunk)
+			}
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/json", "/huge"} {
+		page, status, err := fetchDetailAttempt(context.Background(), server.Client(), server.URL+path, defaultUserAgent)
+		if !errors.Is(err, errUnexpectedPage) || page != nil || status != 0 {
+			t.Errorf("%s: expected the page to be rejected, got %v %d %+v", path, err, status, page)
+		}
+	}
+}
+
+// contentTypeTransport serves the fixtures with a different content type.
+type contentTypeTransport struct {
+	fixtureTransport
+	contentType string
+}
+
+func (t contentTypeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.fixtureTransport.RoundTrip(req)
+	if err == nil {
+		res.Header.Set("Content-Type", t.contentType)
+	}
+	return res, err
+}
+
+func TestScrape_RejectsUnexpectedListPage(t *testing.T) {
+	app := newFixtureApp(t)
+	app.client = &http.Client{Transport: contentTypeTransport{fixtureTransport{dir: "testdata/fixtures"}, "application/octet-stream"}}
+	ctx := context.Background()
+
+	run := &scrapeRun{}
+	err := app.scrapeInto(ctx, run)
+	if !errors.Is(err, errUnexpectedPage) || run.Lists != 0 {
+		t.Fatalf("expected the list page to be rejected, got %v %+v", err, run)
+	}
+	events, err := app.store.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %d", len(events))
+	}
+}
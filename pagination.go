@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor names the last item of a page, which the next page continues
+// after. Lists are ordered newest first and items of the same time by ID, so
+// items added in the meantime sort before the cursor and never shift or
+// repeat the pages still to come. Clients only see it encoded, see String.
+type pageCursor struct {
+	// at is the time of the item: Event.DateTime for events, the Unix time
+	// of their creation for other items.
+	at int64
+	// id is the hash of events, the row ID of other items.
+	id string
+}
+
+// eventCursor returns the cursor continuing after event.
+func eventCursor(event *Event) pageCursor {
+	return pageCursor{at: event.DateTime, id: event.Hash}
+}
+
+func (c pageCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.at, 10) + ":" + c.id))
+}
+
+func parsePageCursor(value string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(data), ":")
+	seconds, err := strconv.ParseInt(at, 10, 64)
+	if !ok || err != nil || id == "" {
+		return pageCursor{}, errInvalidCursor
+	}
+	return pageCursor{at: seconds, id: id}, nil
+}
+
+// rowID returns the ID of the cursor of a list of rows.
+func (c pageCursor) rowID() (uint, error) {
+	id, err := strconv.ParseUint(c.id, 10, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	return uint(id), nil
+}
+
+// queryCursor reads the cursor of ?cursor=, nil for the first page.
+func queryCursor(query url.Values) (*pageCursor, error) {
+	value := query.Get("cursor")
+	if value == "" {
+		return nil, nil
+	}
+	cursor, err := parsePageCursor(value)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// setNextPage links the next page of a list response starting at cursor, as
+// the request with ?cursor= set to it, in a Link header (RFC 8288).
+func setNextPage(w http.ResponseWriter, r *http.Request, cursor pageCursor) {
+	query := r.URL.Query()
+	query.Set("cursor", cursor.String())
+	w.Header().Set("Link", "<"+r.URL.Path+"?"+query.Encode()+`>; rel="next"`)
+}
+
+// eventPage returns up to size events matching filter, newest first,
+// starting at cursor (the first page if empty), and the cursor of the next
+// page, empty on the last one.
+func (a *App) eventPage(ctx context.Context, filter EventFilter, size int, cursor string) ([]Event, string, error) {
+	if cursor != "" {
+		after, err := parsePageCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.After = &after
+	}
+	events, err := a.store.Recent(ctx, filter, size+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(events) <= size {
+		return events, "", nil
+	}
+	page := events[:size]
+	return page, eventCursor(&page[size-1]).String(), nil
+}
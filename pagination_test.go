@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// nextLink matches the Link header of a page, see setNextPage.
+var nextLink = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+func TestFacts_CursorPagesStayStable(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	ctx := context.Background()
+	// Two events share a second, which a page boundary splits.
+	for i, at := range []int64{50, 40, 30, 30, 20} {
+		if err := app.store.Create(ctx, &Event{Title: fmt.Sprint("Meldung ", i), Hash: fmt.Sprint("h", i), DateTime: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	var hashes []string
+	path := "/api/facts?limit=2"
+	for pages := 0; path != ""; pages++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer stats")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []apiEventFacts
+		err = json.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %d %v", path, res.StatusCode, err)
+		}
+		for _, event := range page {
+			hashes = append(hashes, event.Event.Hash)
+		}
+		path = ""
+		if match := nextLink.FindStringSubmatch(res.Header.Get("Link")); match != nil {
+			path = match[1]
+		}
+		if pages == 0 {
+			// New events published meanwhile, one of them in the second of
+			// the last event of the page, don't shift the later pages.
+			for _, event := range []Event{{Title: "Neu", Hash: "new", DateTime: 60}, {Title: "Gleichzeitig", Hash: "h0a", DateTime: 40}} {
+				if err := app.store.Create(ctx, &event); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if fmt.Sprint(hashes) != "[h0 h1 h2 h3 h4]" {
+		t.Fatalf("expected every event once, got %v", hashes)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/facts?cursor=kaputt", nil)
+	req.Header.Set("Authorization", "Bearer stats")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid cursor, got %d", res.StatusCode)
+	}
+}
+
+func TestAuditLog_CursorPages(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	ctx := context.Background()
+	for _, target := range []string{"a", "b", "c"} {
+		app.audit(ctx, "takedown", target, nil)
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	var targets []string
+	path := "/api/audit?limit=2"
+	for path != "" {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []apiAuditEntry
+		_ = json.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		for _, entry := range page {
+			targets = append(targets, entry.Target)
+		}
+		path = ""
+		if match := nextLink.FindStringSubmatch(res.Header.Get("Link")); match != nil {
+			path = match[1]
+		}
+	}
+	if fmt.Sprint(targets) != "[c b a]" {
+		t.Fatalf("expected the entries newest first, got %v", targets)
+	}
+}
+
+func TestDeadLetters_CursorPages(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	ctx := context.Background()
+	for _, hash := range []string{"a", "b", "c"} {
+		if err := app.store.SaveDeadLetter(ctx, &DeadLetter{Target: "webhook:down", EventHash: hash}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	var hashes []string
+	path := "/api/dead-letters?limit=2"
+	for path != "" {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []apiDeadLetter
+		_ = json.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		for _, letter := range page {
+			hashes = append(hashes, letter.EventHash)
+		}
+		path = ""
+		if match := nextLink.FindStringSubmatch(res.Header.Get("Link")); match != nil {
+			path = match[1]
+		}
+	}
+	if fmt.Sprint(hashes) != "[c b a]" {
+		t.Fatalf("expected the dead letters newest first, got %v", hashes)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/Luiggi33/berlin-police-feed/pkg/model
+
+go 1.25.0
@@ -0,0 +1,130 @@
+// Package model holds the types of the Berlin police feed's JSON API and
+// webhooks, so that the server, the Go client and webhook consumers share
+// them exactly. It is a module of its own and versioned independently of the
+// server; changes are additive within a major version, like the webhook
+// schema (see SchemaVersion).
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is a press release of the police as served by the API and sent to
+// webhooks.
+type Event struct {
+	Hash        string     `json:"hash"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	District    string     `json:"district"`
+	Link        string     `json:"link"`
+	PublishedAt time.Time  `json:"publishedAt"`
+	IncidentAt  *time.Time `json:"incidentAt,omitempty"`
+	// ParentHash is set for incidents split off a compilation report.
+	ParentHash string `json:"parentHash,omitempty"`
+	WaybackURL string `json:"waybackUrl,omitempty"`
+	// LinkDeadSince is set once the link was found to be gone.
+	LinkDeadSince *time.Time `json:"linkDeadSince,omitempty"`
+	Edits         []Edit     `json:"edits,omitempty"`
+	// Summary is only set in notifications.
+	Summary string `json:"summary,omitempty"`
+	// Mentions are only set on the permalink.
+	Mentions []Mention `json:"mentions,omitempty"`
+}
+
+// Edit is a manual correction of an event.
+type Edit struct {
+	At    time.Time `json:"at"`
+	Actor string    `json:"actor"`
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+	Note  string    `json:"note,omitempty"`
+}
+
+// Mention is an external page linking to an event, received as Webmention.
+type Mention struct {
+	Source     string    `json:"source"`
+	Title      string    `json:"title,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// Fact kinds.
+const (
+	FactWeapon  = "weapon"
+	FactVehicle = "vehicle"
+	FactAge     = "age"
+)
+
+// Fact is a detail extracted from the text of an event.
+type Fact struct {
+	Kind string `json:"kind"`
+	// Value is the normalized fact, e.g. "messer" or "17".
+	Value string `json:"value"`
+	// Role is "suspect" or "victim" for ages if the text makes it clear.
+	Role string `json:"role,omitempty"`
+}
+
+// EventFacts is an event with its facts, as listed by /api/facts.
+type EventFacts struct {
+	Event Event  `json:"event"`
+	Facts []Fact `json:"facts"`
+}
+
+// Category is a category of the default classification rules. Instances may
+// define their own.
+type Category string
+
+const (
+	CategoryViolence    Category = "gewalt"
+	CategoryFire        Category = "brand"
+	CategoryProperty    Category = "eigentum"
+	CategorySexualCrime Category = "sexualdelikt"
+	CategorySuicide     Category = "suizid"
+	CategoryMissing     Category = "vermisst"
+	CategoryTraffic     Category = "verkehr"
+	CategoryDrugs       Category = "drogen"
+)
+
+// Categories lists the categories of the default rules.
+var Categories = []Category{
+	CategoryViolence, CategoryFire, CategoryProperty, CategorySexualCrime,
+	CategorySuicide, CategoryMissing, CategoryTraffic, CategoryDrugs,
+}
+
+// MarshalJSON encodes the event canonically: all times in UTC with second
+// precision, so that the same event always encodes to the same bytes.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type plain Event
+	canonical := plain(e)
+	canonical.PublishedAt = canonicalTime(e.PublishedAt)
+	canonical.IncidentAt = canonicalTimePtr(e.IncidentAt)
+	canonical.LinkDeadSince = canonicalTimePtr(e.LinkDeadSince)
+	if e.Edits != nil {
+		canonical.Edits = make([]Edit, len(e.Edits))
+		for i, edit := range e.Edits {
+			edit.At = canonicalTime(edit.At)
+			canonical.Edits[i] = edit
+		}
+	}
+	if e.Mentions != nil {
+		canonical.Mentions = make([]Mention, len(e.Mentions))
+		for i, mention := range e.Mentions {
+			mention.ReceivedAt = canonicalTime(mention.ReceivedAt)
+			canonical.Mentions[i] = mention
+		}
+	}
+	return json.Marshal(canonical)
+}
+
+func canonicalTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+func canonicalTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	canonical := canonicalTime(*t)
+	return &canonical
+}
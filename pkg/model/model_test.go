@@ -0,0 +1,39 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEvent_CanonicalJSON(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	incident := time.Date(2024, 5, 1, 11, 30, 0, 0, berlin)
+	event := Event{
+		Hash:        "abc",
+		Title:       "Brand in Wohnhaus",
+		PublishedAt: time.Date(2024, 5, 1, 14, 0, 0, 123456789, berlin),
+		IncidentAt:  &incident,
+		Edits:       []Edit{{At: time.Date(2024, 5, 2, 9, 0, 0, 5, berlin), Field: "title"}},
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"hash":"abc","title":"Brand in Wohnhaus","description":"","district":"","link":"","publishedAt":"2024-05-01T12:00:00Z","incidentAt":"2024-05-01T09:30:00Z","edits":[{"at":"2024-05-02T07:00:00Z","actor":"","field":"title","old":"","new":""}]}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+	if !event.Edits[0].At.Equal(time.Date(2024, 5, 2, 9, 0, 0, 5, berlin)) || event.IncidentAt.Location() != berlin {
+		t.Error("marshaling changed the event")
+	}
+
+	// Pointers and payloads encode the same way.
+	payload, err := json.Marshal(Payload{Schema: SchemaVersion, Type: PayloadCreated, Event: &event})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"schema":"v1","type":"event.created","event":` + want + `}`; string(payload) != want {
+		t.Errorf("got  %s\nwant %s", payload, want)
+	}
+}
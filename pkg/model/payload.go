@@ -0,0 +1,38 @@
+package model
+
+import _ "embed"
+
+// SchemaVersion is sent as "schema" in every webhook payload. Changes within
+// a version are additive only: fields may be added, but never removed,
+// renamed or retyped, so consumers must ignore fields they don't know.
+// Anything else requires a new version.
+const SchemaVersion = "v1"
+
+// Payload types.
+const (
+	PayloadCreated = "event.created"
+	PayloadDigest  = "event.digest"
+	PayloadUpdated = "event.updated"
+	PayloadDeleted = "event.deleted"
+)
+
+// Payload is the body of a webhook.
+type Payload struct {
+	Schema string `json:"schema"`
+	Type   string `json:"type"`
+	Replay bool   `json:"replay,omitempty"`
+	// Event is set for event.created and event.updated, Events and Summary
+	// for event.digest.
+	Event   *Event  `json:"event,omitempty"`
+	Events  []Event `json:"events,omitempty"`
+	Summary string  `json:"summary,omitempty"`
+	// Changes is set for event.updated, Hash for event.deleted.
+	Changes []string `json:"changes,omitempty"`
+	Hash    string   `json:"hash,omitempty"`
+}
+
+// Schema documents Payload as JSON Schema. The server publishes it at
+// /api/schema/event.
+//
+//go:embed schema.json
+var Schema string
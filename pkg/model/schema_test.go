@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonSchema struct {
+	Required   []string              `json:"required"`
+	Properties map[string]jsonSchema `json:"properties"`
+	Items      *jsonSchema           `json:"items"`
+	Const      string                `json:"const"`
+	Enum       []string              `json:"enum"`
+	Defs       map[string]jsonSchema `json:"$defs"`
+}
+
+// assertCovered fails if a JSON field of typ is missing from schema.
+func assertCovered(t *testing.T, path string, typ reflect.Type, schema jsonSchema) {
+	t.Helper()
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("%s.%s is not documented in the event schema", path, name)
+		}
+	}
+}
+
+func TestSchema_CoversPayload(t *testing.T) {
+	var schema jsonSchema
+	if err := json.Unmarshal([]byte(Schema), &schema); err != nil {
+		t.Fatalf("event schema is not valid JSON: %v", err)
+	}
+	if schema.Properties["schema"].Const != SchemaVersion {
+		t.Fatalf("schema documents version %q, payloads send %q", schema.Properties["schema"].Const, SchemaVersion)
+	}
+	for _, typ := range []string{PayloadCreated, PayloadDigest, PayloadUpdated, PayloadDeleted} {
+		if !strings.Contains(strings.Join(schema.Properties["type"].Enum, ","), typ) {
+			t.Errorf("payload type %s is not documented in the event schema", typ)
+		}
+	}
+
+	assertCovered(t, "payload", reflect.TypeOf(Payload{}), schema)
+	event := schema.Defs["event"]
+	assertCovered(t, "event", reflect.TypeOf(Event{}), event)
+	assertCovered(t, "edit", reflect.TypeOf(Edit{}), *event.Properties["edits"].Items)
+	assertCovered(t, "mention", reflect.TypeOf(Mention{}), *event.Properties["mentions"].Items)
+}
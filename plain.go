@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPlainLimit = 50
+	maxPlainLimit     = 1000
+)
+
+// parsePlainQuery reads the usual from, to and district filters plus limit
+// and cursor.
+func parsePlainQuery(query url.Values) (EventFilter, int, error) {
+	filter, err := parseEventFilter(query)
+	if err != nil {
+		return filter, 0, err
+	}
+	filter.After, err = queryCursor(query)
+	if err != nil {
+		return filter, 0, err
+	}
+	limit := defaultPlainLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPlainLimit {
+			return filter, 0, fmt.Errorf("limit must be between 1 and %d", maxPlainLimit)
+		}
+	}
+	return filter, limit, nil
+}
+
+// writePlainEvent renders one event as a paragraph: title, district and time,
+// description and link, each on its own line.
+func writePlainEvent(w *bufio.Writer, event *Event) {
+	// Event times are Berlin wall clock times stored as UTC.
+	published := time.Unix(event.DateTime, 0).UTC().Format("02.01.2006, 15:04 Uhr")
+	fmt.Fprintf(w, "%s\n%s, %s\n", event.Title, event.Location, published)
+	if description := strings.TrimSpace(event.Description); description != "" {
+		fmt.Fprintf(w, "%s\n", description)
+	}
+	if event.Link != "" {
+		fmt.Fprintf(w, "%s\n", event.Link)
+	}
+	w.WriteString("\n")
+}
+
+// handlePlain lists the newest events as plain UTF-8 text, one paragraph per
+// event, for screen readers, e-ink devices and terminals.
+func (a *App) handlePlain(w http.ResponseWriter, r *http.Request) {
+	filter, limit, err := parsePlainQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := a.store.Recent(r.Context(), filter, limit+1)
+	if err != nil {
+		log.Println("Error loading events:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		setNextPage(w, r, eventCursor(&events[limit-1]))
+	}
+	events = a.redactions.events(events)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "Berliner Polizeimeldungen\n%s\n\n", newAPIMeta(a.config).notice())
+	if len(events) == 0 {
+		out.WriteString("Keine Meldungen gefunden.\n")
+	}
+	for i := range events {
+		writePlainEvent(out, &events[i])
+	}
+	err = out.Flush()
+	if err != nil {
+		log.Println("Error writing plain text:", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPlain_ListsFilteredEventsNewestFirst(t *testing.T) {
+	app := newTestApp(t)
+	seedExportEvents(t, app.store)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	get := func(query string) (int, string) {
+		res, err := http.Get(server.URL + "/plain" + query)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode == http.StatusOK && res.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Fatalf("unexpected content type %s", res.Header.Get("Content-Type"))
+		}
+		return res.StatusCode, string(body)
+	}
+
+	status, body := get("?district=Mitte")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	paragraphs := strings.Split(strings.TrimSpace(body), "\n\n")
+	if len(paragraphs) != 3 {
+		t.Fatalf("expected header and two events, got:\n%s", body)
+	}
+	if !strings.HasPrefix(paragraphs[1], "Unfall\nMitte, 10.03.2024, 12:00 Uhr") || !strings.HasPrefix(paragraphs[2], "Raub") {
+		t.Fatalf("expected Mitte events newest first, got:\n%s", body)
+	}
+	if strings.Contains(body, "Pankow") {
+		t.Fatalf("expected the district filter to apply:\n%s", body)
+	}
+
+	if _, body := get("?limit=1"); strings.Count(body, "\n\n") != 2 {
+		t.Fatalf("expected a single event:\n%s", body)
+	}
+	if status, _ := get("?limit=0"); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid limit, got %d", status)
+	}
+	if _, body := get("?district=Nirgendwo"); !strings.Contains(body, "Keine Meldungen") {
+		t.Fatalf("expected empty notice:\n%s", body)
+	}
+}
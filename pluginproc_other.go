@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// killProcessGroup leaves cmd as it is; only the shell is killed on
+// cancellation and WaitDelay bounds the wait for its children.
+func killProcessGroup(*exec.Cmd) {}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Plugins add sources without changing this program: every scrape runs their
+// command with sh -c, which prints one JSON object per line to stdout and
+// exits with status 0. Lines describe events,
+//
+//	{"title": "…", "description": "…", "link": "https://…", "location": "Mitte", "published": "2026-03-14T09:12:00+01:00"}
+//
+// of which title, link and published (RFC 3339) are required, or the source
+// itself, usually in the first line:
+//
+//	{"kind": "source", "label": "Polizei Potsdam", "title": "Polizeidirektion West", "url": "https://…", "overlaps": false}
+//
+// The plugin fetches on its own, so SOURCE_REQUEST_INTERVALS and
+// SOURCE_DOMAINS don't apply. It runs for at most SCRAPE_TIMEOUT, in a
+// process group that is killed as a whole afterwards, and sees only PATH,
+// HOME and SOURCE_NAME, set to its name, of our environment, which holds
+// database URLs, tokens and keys. At most maxPageSize of its output is read.
+// The start of anything written to stderr ends up in the error if the command
+// fails.
+
+// errInvalidPlugin is returned for SOURCE_PLUGINS entries that can't be used.
+var errInvalidPlugin = errors.New("invalid plugin")
+
+var errPluginOutput = errors.New("plugin output too large")
+
+const (
+	// pluginWaitDelay is how long a plugin may keep its output open after
+	// being killed.
+	pluginWaitDelay = 5 * time.Second
+	// maxPluginError bounds the stderr output quoted in errors.
+	maxPluginError = 1 << 10
+)
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest,
+// calling full, if set, once that happens.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	full     func()
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		if !b.overflow && b.full != nil {
+			b.full()
+		}
+		b.overflow = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// pluginLine is a line of the output of a plugin.
+type pluginLine struct {
+	Kind string `json:"kind"`
+
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Link        string    `json:"link"`
+	Location    string    `json:"location"`
+	Published   time.Time `json:"published"`
+
+	Label    string `json:"label"`
+	URL      string `json:"url"`
+	Overlaps bool   `json:"overlaps"`
+}
+
+// pluginSource is a source scraped by an external command.
+type pluginSource struct {
+	name    string
+	command string
+
+	mu sync.Mutex
+	// info is how the plugin last described itself.
+	info sourceInfo
+}
+
+// buildPlugins returns the sources of SOURCE_PLUGINS, name=command entries
+// whose names mustn't be taken by other sources.
+func buildPlugins(config Config) ([]Source, error) {
+	var sources []Source
+	taken := map[string]bool{sourceBerlin: true}
+	for name := range sourceConstructors {
+		taken[name] = true
+	}
+	for _, entry := range config.SourcePlugins {
+		name, command, ok := strings.Cut(entry, "=")
+		name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+		if !ok || name == "" || command == "" || strings.ContainsAny(name, " ,") {
+			return nil, fmt.Errorf("%w %q in SOURCE_PLUGINS, expected name=command", errInvalidPlugin, entry)
+		}
+		if taken[name] {
+			return nil, fmt.Errorf("%w %q in SOURCE_PLUGINS, the name is taken", errInvalidPlugin, name)
+		}
+		taken[name] = true
+		sources = append(sources, &pluginSource{
+			name:    name,
+			command: command,
+			info:    sourceInfo{Name: name, Label: name, Title: name},
+		})
+	}
+	return sources, nil
+}
+
+func (s *pluginSource) Info() sourceInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.info
+}
+
+// Scrape runs the command and reads the events it prints.
+func (s *pluginSource) Scrape(ctx context.Context, _ HTTPDoer) ([]Event, error) {
+	// Plugins printing too much are stopped right away.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stdout := &cappedBuffer{limit: maxPageSize, full: cancel}
+	stderr := &cappedBuffer{limit: maxPluginError}
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = pluginWaitDelay
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + os.Getenv("HOME"), "SOURCE_NAME=" + s.name}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if stdout.overflow {
+		return nil, fmt.Errorf("plugin %s: %w, the limit is %d bytes", s.name, errPluginOutput, maxPageSize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w: %s", s.name, err, strings.ToValidUTF8(strings.TrimSpace(stderr.buf.String()), "�"))
+	}
+
+	var events []Event
+	scanner := bufio.NewScanner(&stdout.buf)
+	scanner.Buffer(nil, maxPageSize)
+	for scanner.Scan() {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var line pluginLine
+		err := json.Unmarshal(text, &line)
+		if err != nil {
+			log.Printf("Skipping invalid line of plugin %s: %v", s.name, err)
+			continue
+		}
+		switch line.Kind {
+		case "source":
+			s.describe(line)
+		case "", "event":
+			if line.Title == "" || line.Link == "" || line.Published.IsZero() {
+				log.Printf("Skipping event of plugin %s without title, link or publication time", s.name)
+				continue
+			}
+			event := Event{
+				Title:       strings.TrimSpace(line.Title),
+				Description: strings.TrimSpace(line.Description),
+				Link:        strings.TrimSpace(line.Link),
+				Location:    strings.TrimSpace(line.Location),
+				DateTime:    wallClock(line.Published),
+			}
+			if event.Description == "" {
+				event.Description = defaultDescription
+			}
+			event.Hash = sourceHash(s.name, &event)
+			events = append(events, event)
+		default:
+			log.Printf("Skipping line of unknown kind %q of plugin %s", line.Kind, s.name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", s.name, err)
+	}
+	return events, nil
+}
+
+// describe takes over how the plugin describes itself, keeping the name.
+func (s *pluginSource) describe(line pluginLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = sourceInfo{Name: s.name, Label: line.Label, Title: line.Title, URL: line.URL, Overlaps: line.Overlaps}
+	if s.info.Label == "" {
+		s.info.Label = s.name
+	}
+	if s.info.Title == "" {
+		s.info.Title = s.info.Label
+	}
+}
+
+// Complete leaves the events as they are, as plugins print them whole.
+func (s *pluginSource) Complete(context.Context, HTTPDoer, *Event) error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPluginSource_Scrape(t *testing.T) {
+	app := newTestApp(t)
+	sources, err := buildSources(Config{SourcePlugins: []string{`potsdam=test "$SOURCE_NAME" = potsdam && cat testdata/plugins/potsdam.ndjson`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.scrapers = sources
+	ctx := context.Background()
+
+	if err := app.scrapeSource(ctx, sources[0]); err != nil {
+		t.Fatalf("scrapeSource failed: %v", err)
+	}
+	events, err := app.store.Recent(ctx, EventFilter{Sources: []string{"potsdam"}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the valid event only, got %+v", events)
+	}
+	published := time.Date(2026, 3, 14, 9, 12, 0, 0, time.UTC)
+	if event := events[0]; event.Title != "Fahrraddiebstahl am Hauptbahnhof Potsdam" || event.Location != "Potsdam" || event.DateTime != published.Unix() {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, httptest.NewRequest("GET", "/rss/all?source=potsdam", nil))
+	if !strings.Contains(rec.Body.String(), "[Polizei Potsdam] Fahrraddiebstahl") {
+		t.Fatalf("expected the label the plugin gave itself:\n%s", rec.Body.String())
+	}
+}
+
+func TestPluginSource_Errors(t *testing.T) {
+	sources, err := buildSources(Config{SourcePlugins: []string{"broken=echo kaputt >&2; exit 3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sources[0].Scrape(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "kaputt") {
+		t.Fatalf("expected the failure with stderr, got %v", err)
+	}
+
+	for _, entry := range []string{"berlin=cat", "bvg=cat", "ohne-befehl"} {
+		if _, err := buildSources(Config{SourcePlugins: []string{entry}}); !errors.Is(err, errInvalidPlugin) {
+			t.Fatalf("expected %q to be rejected, got %v", entry, err)
+		}
+	}
+}
+
+func TestPluginSource_Sandbox(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://secret")
+	sources, err := buildSources(Config{SourcePlugins: []string{
+		`env=test -z "$DATABASE_URL" || echo "$DATABASE_URL" >&2 && test -z "$DATABASE_URL"`,
+		"slow=sleep 6; echo hi",
+		"chatty=yes",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sources[0].Scrape(context.Background(), nil); err != nil {
+		t.Fatalf("expected the plugin not to see DATABASE_URL, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := sources[1].Scrape(ctx, nil); err == nil {
+		t.Fatal("expected the slow plugin to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("expected the plugin to be killed after the timeout, took %v", elapsed)
+	}
+
+	if _, err := sources[2].Scrape(context.Background(), nil); !errors.Is(err, errPluginOutput) {
+		t.Fatalf("expected endless output to be cut off, got %v", err)
+	}
+}
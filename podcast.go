@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// podcastCheckInterval is how often runPodcast checks whether yesterday's
+// episode still has to be recorded, which also retries failed recordings.
+const podcastCheckInterval = time.Hour
+
+var germanMonths = []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"}
+
+// Speaker turns text into MP3 audio.
+type Speaker interface {
+	// Model identifies the backend and voice the audio comes from.
+	Model() string
+	Speak(ctx context.Context, text string) ([]byte, error)
+}
+
+// openAISpeaker talks to an OpenAI compatible speech API.
+type openAISpeaker struct {
+	url, model, voice, apiKey string
+	client                    HTTPDoer
+}
+
+func (s *openAISpeaker) Model() string { return "openai:" + s.model + ":" + s.voice }
+
+func (s *openAISpeaker) Speak(ctx context.Context, text string) ([]byte, error) {
+	encoded, err := json.Marshal(map[string]any{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"response_format": "mp3",
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(s.url, "/") + "/audio/speech"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil, fmt.Errorf("%s responded with %s", url, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// commandSpeaker runs a shell command that reads the text on stdin and writes
+// MP3 audio to stdout, e.g. piper piped into ffmpeg.
+type commandSpeaker struct {
+	command string
+}
+
+func (s *commandSpeaker) Model() string { return "command" }
+
+func (s *commandSpeaker) Speak(ctx context.Context, text string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("command produced no audio")
+	}
+	return stdout.Bytes(), nil
+}
+
+// buildSpeaker creates the configured speaker, or nil if the podcast is
+// disabled (the default).
+func buildSpeaker(config Config) (Speaker, error) {
+	switch config.TTS {
+	case "":
+		return nil, nil
+	case "openai":
+		url := config.TTSURL
+		if url == "" {
+			url = "https://api.openai.com/v1"
+		}
+		model, voice := config.TTSModel, config.TTSVoice
+		if model == "" {
+			model = "tts-1"
+		}
+		if voice == "" {
+			voice = "alloy"
+		}
+		return &openAISpeaker{url: url, model: model, voice: voice, apiKey: config.TTSAPIKey, client: &http.Client{}}, nil
+	case "command":
+		if config.TTSCommand == "" {
+			return nil, errors.New("TTS_COMMAND is required")
+		}
+		return &commandSpeaker{command: config.TTSCommand}, nil
+	default:
+		return nil, fmt.Errorf("unknown tts backend %q (available: openai, command)", config.TTS)
+	}
+}
+
+// spokenDate renders a date the way it's read out, e.g. "15. Oktober 2026".
+func spokenDate(day time.Time) string {
+	return fmt.Sprintf("%d. %s %d", day.Day(), germanMonths[day.Month()-1], day.Year())
+}
+
+// podcastScript is the text of an episode: one short paragraph per report,
+// using the generated summary where there is one.
+func podcastScript(day time.Time, events []Event, summaries map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Berliner Polizeimeldungen vom %s.\n\n", spokenDate(day))
+	if len(events) == 1 {
+		b.WriteString("Es gab eine Meldung.\n\n")
+	} else {
+		fmt.Fprintf(&b, "Es gab %d Meldungen.\n\n", len(events))
+	}
+	for _, event := range events {
+		text := summaries[event.Hash]
+		if text == "" {
+			text = firstSentence(event.Description) + "."
+		}
+		fmt.Fprintf(&b, "%s: %s. %s\n\n", event.Location, strings.TrimSuffix(event.Title, "."), text)
+	}
+	b.WriteString("Das waren die Meldungen des Tages.\n")
+	return b.String()
+}
+
+// episodeEvents returns the reports published on day. Compilations that were
+// split into separate reports are left out, as their parts are read instead.
+func (a *App) episodeEvents(ctx context.Context, day time.Time) ([]Event, error) {
+	// Event times are Berlin wall clock times stored as UTC.
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	var events []Event
+	err := a.store.EachBatch(ctx, EventFilter{From: from, To: from.AddDate(0, 0, 1)}, exportBatchSize, func(batch []Event) error {
+		events = append(events, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	split := make(map[string]bool)
+	for _, event := range events {
+		if event.ParentHash != "" {
+			split[event.ParentHash] = true
+		}
+	}
+	return slices.DeleteFunc(events, func(event Event) bool { return split[event.Hash] }), nil
+}
+
+// recordEpisode writes the audio and script of the episode for day into
+// PodcastDir. Days without reports get no episode.
+func (a *App) recordEpisode(ctx context.Context, day time.Time) error {
+	events, err := a.episodeEvents(ctx, day)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	hashes := make([]string, len(events))
+	for i, event := range events {
+		hashes[i] = event.Hash
+	}
+	summaries, err := a.store.Summaries(ctx, hashes)
+	if err != nil {
+		return err
+	}
+
+	script := podcastScript(day, a.redactions.events(events), a.redactions.summaries(summaries))
+	audio, err := a.speaker.Speak(ctx, script)
+	if err != nil {
+		return fmt.Errorf("speaking with %s: %w", a.speaker.Model(), err)
+	}
+
+	err = os.MkdirAll(a.config.PodcastDir, 0o755)
+	if err != nil {
+		return err
+	}
+	base := filepath.Join(a.config.PodcastDir, day.Format(time.DateOnly))
+	err = os.WriteFile(base+".txt", []byte(script), 0o644)
+	if err != nil {
+		return err
+	}
+	// The audio goes last and is renamed into place, as its existence marks
+	// the episode as done.
+	err = os.WriteFile(base+".mp3.tmp", audio, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(base+".mp3.tmp", base+".mp3")
+}
+
+type podcastEpisode struct {
+	Day    time.Time
+	Size   int64
+	Script string
+}
+
+// podcastEpisodes returns the recorded episodes, newest first.
+func podcastEpisodes(dir string) ([]podcastEpisode, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.mp3"))
+	if err != nil {
+		return nil, err
+	}
+	var episodes []podcastEpisode
+	for _, path := range paths {
+		base := strings.TrimSuffix(path, ".mp3")
+		day, err := time.ParseInLocation(time.DateOnly, filepath.Base(base), berlin)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		script, _ := os.ReadFile(base + ".txt")
+		episodes = append(episodes, podcastEpisode{Day: day, Size: info.Size(), Script: string(script)})
+	}
+	slices.SortFunc(episodes, func(a, b podcastEpisode) int { return b.Day.Compare(a.Day) })
+	return episodes, nil
+}
+
+// prunePodcast removes all but the newest PodcastEpisodes episodes.
+func (a *App) prunePodcast() error {
+	episodes, err := podcastEpisodes(a.config.PodcastDir)
+	if err != nil || len(episodes) <= a.config.PodcastEpisodes {
+		return err
+	}
+	for _, episode := range episodes[a.config.PodcastEpisodes:] {
+		base := filepath.Join(a.config.PodcastDir, episode.Day.Format(time.DateOnly))
+		err = errors.Join(err, os.Remove(base+".mp3"))
+		if removeErr := os.Remove(base + ".txt"); !errors.Is(removeErr, os.ErrNotExist) {
+			err = errors.Join(err, removeErr)
+		}
+	}
+	return err
+}
+
+// runPodcast records the episode of the previous day once it is over, until
+// ctx is cancelled.
+func (a *App) runPodcast(ctx context.Context) {
+	if a.speaker == nil {
+		return
+	}
+	for {
+		yesterday := time.Now().In(berlin).AddDate(0, 0, -1)
+		path := filepath.Join(a.config.PodcastDir, yesterday.Format(time.DateOnly)+".mp3")
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			start := time.Now()
+			err := a.recordEpisode(ctx, yesterday)
+			if err != nil {
+				log.Println("Error recording podcast episode:", err)
+			} else {
+				log.Printf("Recorded podcast episode of %s in %s", yesterday.Format(time.DateOnly), time.Since(start).Round(time.Millisecond))
+			}
+			err = a.prunePodcast()
+			if err != nil {
+				log.Println("Error pruning podcast episodes:", err)
+			}
+		}
+		if sleepContext(ctx, podcastCheckInterval) != nil {
+			return
+		}
+	}
+}
+
+// handlePodcast serves the podcast feed, with the recorded episodes as
+// enclosures and their scripts as transcripts.
+func (a *App) handlePodcast(w http.ResponseWriter, r *http.Request) {
+	episodes, err := podcastEpisodes(a.config.PodcastDir)
+	if err != nil {
+		log.Println("Error listing podcast episodes:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	base := strings.TrimSuffix(a.config.BaseURL, "/")
+	if base == "" {
+		base = requestBaseURL(r)
+	}
+
+	feed := &feeds.Feed{
+		Title:       "Berliner Polizeimeldungen zum Hören",
+		Link:        &feeds.Link{Href: base + "/podcast"},
+		Description: "Die Berliner Polizeimeldungen jedes Tages, vorgelesen",
+		Author:      &feeds.Author{Name: "Aron", Email: "github@luiggi33.de"},
+		Copyright:   newAPIMeta(a.config).notice(),
+		Created:     time.Now(),
+	}
+	if image := a.config.FeedImage; image != "" {
+		if strings.HasPrefix(image, "/") {
+			image = base + image
+		}
+		feed.Image = &feeds.Image{Url: image, Title: feed.Title, Link: feed.Link.Href}
+	}
+	for _, episode := range episodes {
+		day := episode.Day.Format(time.DateOnly)
+		feed.Add(&feeds.Item{
+			Id:          base + "/podcast/" + day + ".mp3",
+			Title:       "Polizeimeldungen vom " + spokenDate(episode.Day),
+			Link:        &feeds.Link{Href: base + "/podcast/" + day + ".mp3"},
+			Description: episode.Script,
+			Created:     episode.Day,
+			Enclosure: &feeds.Enclosure{
+				Url:    base + "/podcast/" + day + ".mp3",
+				Length: strconv.FormatInt(episode.Size, 10),
+				Type:   "audio/mpeg",
+			},
+		})
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		log.Println("Error rendering podcast:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/rss+xml")
+	_, err = io.WriteString(w, rss)
+	if err != nil {
+		log.Println("Error writing podcast:", err)
+	}
+}
+
+// handlePodcastEpisode serves the audio of a single episode.
+func (a *App) handlePodcastEpisode(w http.ResponseWriter, r *http.Request) {
+	day, ok := strings.CutSuffix(r.PathValue("file"), ".mp3")
+	if _, err := time.Parse(time.DateOnly, day); !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/mpeg")
+	http.ServeFile(w, r, filepath.Join(a.config.PodcastDir, day+".mp3"))
+}
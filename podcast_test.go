@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingSpeaker struct {
+	texts []string
+}
+
+func (s *recordingSpeaker) Model() string { return "test" }
+
+func (s *recordingSpeaker) Speak(ctx context.Context, text string) ([]byte, error) {
+	s.texts = append(s.texts, text)
+	return []byte("ID3 audio"), nil
+}
+
+func TestPodcastScript(t *testing.T) {
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, berlin)
+	events := []Event{
+		{Hash: "a", Title: "Raub in Mitte.", Location: "Mitte", Description: "Ein Mann wurde beraubt. Er blieb unverletzt."},
+		{Hash: "b", Title: "Brand", Location: "Pankow", Description: "Lang und ausführlich."},
+	}
+	script := podcastScript(day, events, map[string]string{"b": "Ein Keller brannte."})
+	for _, want := range []string{
+		"Berliner Polizeimeldungen vom 15. Oktober 2026.",
+		"Es gab 2 Meldungen.",
+		"Mitte: Raub in Mitte. Ein Mann wurde beraubt.\n",
+		"Pankow: Brand. Ein Keller brannte.\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("script misses %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestPodcast_RecordsAndServesEpisodes(t *testing.T) {
+	app := newTestApp(t)
+	speaker := &recordingSpeaker{}
+	app.speaker = speaker
+	app.config.PodcastDir = t.TempDir()
+	app.config.PodcastEpisodes = 1
+	ctx := context.Background()
+
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, berlin)
+	at := func(hour int) int64 { return time.Date(2026, 10, 15, hour, 0, 0, 0, time.UTC).Unix() }
+	events := []Event{
+		{Hash: "parent", Title: "Tägliche Kurzmeldungen", Location: "Berlin", DateTime: at(9)},
+		{Hash: "parent-1", ParentHash: "parent", Title: "Einbruch", Location: "Spandau", DateTime: at(9)},
+		{Hash: "other", Title: "Unfall", Location: "Mitte", DateTime: at(23)},
+		{Hash: "next", Title: "Morgen", Location: "Mitte", DateTime: at(24)},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	if err := app.recordEpisode(ctx, day); err != nil {
+		t.Fatalf("recordEpisode failed: %v", err)
+	}
+	if len(speaker.texts) != 1 {
+		t.Fatalf("expected one recording, got %d", len(speaker.texts))
+	}
+	script := speaker.texts[0]
+	if !strings.Contains(script, "Spandau: Einbruch") || !strings.Contains(script, "Mitte: Unfall") {
+		t.Fatalf("script misses reports:\n%s", script)
+	}
+	if strings.Contains(script, "Kurzmeldungen") || strings.Contains(script, "Morgen") {
+		t.Fatalf("script contains split compilation or other day:\n%s", script)
+	}
+
+	// Days without reports get no episode.
+	if err := app.recordEpisode(ctx, day.AddDate(0, 0, -1)); err != nil {
+		t.Fatalf("recordEpisode failed: %v", err)
+	}
+	if len(speaker.texts) != 1 {
+		t.Fatal("expected no recording for an empty day")
+	}
+
+	// Only the newest episode is kept.
+	if err := os.WriteFile(filepath.Join(app.config.PodcastDir, "2026-10-01.mp3"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.prunePodcast(); err != nil {
+		t.Fatalf("prunePodcast failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(app.config.PodcastDir, "2026-10-01.mp3")); !os.IsNotExist(err) {
+		t.Fatal("expected the old episode to be pruned")
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/podcast")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	enclosure := `<enclosure url="` + server.URL + `/podcast/2026-10-15.mp3" length="9" type="audio/mpeg">`
+	if !strings.Contains(string(body), enclosure) {
+		t.Fatalf("podcast misses enclosure %s:\n%s", enclosure, body)
+	}
+
+	res, err = http.Get(server.URL + "/podcast/2026-10-15.mp3")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ = io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "ID3 audio" || res.Header.Get("Content-Type") != "audio/mpeg" {
+		t.Fatalf("unexpected episode response %d %q", res.StatusCode, body)
+	}
+
+	res, err = http.Get(server.URL + "/podcast/..%2fsecret.mp3")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for invalid episode, got %d", res.StatusCode)
+	}
+}
+
+func TestCommandSpeaker(t *testing.T) {
+	audio, err := (&commandSpeaker{command: "tr a-z A-Z"}).Speak(context.Background(), "hallo")
+	if err != nil || string(audio) != "HALLO" {
+		t.Fatalf("unexpected result %q, %v", audio, err)
+	}
+	if _, err := (&commandSpeaker{command: "echo broken >&2; exit 1"}).Speak(context.Background(), "x"); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected error with stderr, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+// gRPC API of the Berlin police feed, enabled by the feature flag "grpc" and
+// served on GRPC_ADDRESS (":9090" by default) without TLS. Calls need a stats
+// token as metadata "authorization: Bearer <token>". Server reflection and the
+// health service are enabled. The Go code in policefeedpb is generated from
+// this file, see "make proto".
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: policefeed.proto
+
+package policefeedpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PoliceFeed_ListEvents_FullMethodName   = "/policefeed.v1.PoliceFeed/ListEvents"
+	PoliceFeed_StreamEvents_FullMethodName = "/policefeed.v1.PoliceFeed/StreamEvents"
+	PoliceFeed_GetStats_FullMethodName     = "/policefeed.v1.PoliceFeed/GetStats"
+)
+
+// PoliceFeedClient is the client API for PoliceFeed service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PoliceFeedClient interface {
+	// ListEvents returns the matching events, newest first.
+	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	// StreamEvents sends new events as soon as they are scraped, until the
+	// client cancels the call.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	// GetStats counts events like /api/stats.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+}
+
+type policeFeedClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPoliceFeedClient(cc grpc.ClientConnInterface) PoliceFeedClient {
+	return &policeFeedClient{cc}
+}
+
+func (c *policeFeedClient) ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEventsResponse)
+	err := c.cc.Invoke(ctx, PoliceFeed_ListEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policeFeedClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PoliceFeed_ServiceDesc.Streams[0], PoliceFeed_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PoliceFeed_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *policeFeedClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, PoliceFeed_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PoliceFeedServer is the server API for PoliceFeed service.
+// All implementations must embed UnimplementedPoliceFeedServer
+// for forward compatibility.
+type PoliceFeedServer interface {
+	// ListEvents returns the matching events, newest first.
+	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	// StreamEvents sends new events as soon as they are scraped, until the
+	// client cancels the call.
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	// GetStats counts events like /api/stats.
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	mustEmbedUnimplementedPoliceFeedServer()
+}
+
+// UnimplementedPoliceFeedServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPoliceFeedServer struct{}
+
+func (UnimplementedPoliceFeedServer) ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEvents not implemented")
+}
+func (UnimplementedPoliceFeedServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedPoliceFeedServer) GetStats(context.Context, *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedPoliceFeedServer) mustEmbedUnimplementedPoliceFeedServer() {}
+func (UnimplementedPoliceFeedServer) testEmbeddedByValue()                    {}
+
+// UnsafePoliceFeedServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PoliceFeedServer will
+// result in compilation errors.
+type UnsafePoliceFeedServer interface {
+	mustEmbedUnimplementedPoliceFeedServer()
+}
+
+func RegisterPoliceFeedServer(s grpc.ServiceRegistrar, srv PoliceFeedServer) {
+	// If the following call pancis, it indicates UnimplementedPoliceFeedServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PoliceFeed_ServiceDesc, srv)
+}
+
+func _PoliceFeed_ListEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PoliceFeedServer).ListEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PoliceFeed_ListEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PoliceFeedServer).ListEvents(ctx, req.(*ListEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PoliceFeed_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PoliceFeedServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PoliceFeed_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+func _PoliceFeed_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PoliceFeedServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PoliceFeed_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PoliceFeedServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PoliceFeed_ServiceDesc is the grpc.ServiceDesc for PoliceFeed service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PoliceFeed_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "policefeed.v1.PoliceFeed",
+	HandlerType: (*PoliceFeedServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListEvents",
+			Handler:    _PoliceFeed_ListEvents_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _PoliceFeed_GetStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _PoliceFeed_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "policefeed.proto",
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// presseportal.de republishes the press releases of police departments in
+// its Blaulicht section, each office with an RSS feed of its own. The source
+// reads the one of the Bundespolizeidirektion Berlin, the federal police at
+// stations and airports, by default. Joint releases with the Polizei Berlin
+// also appear on berlin.de, so the source overlaps it (see
+// findBerlinDuplicate).
+
+const sourcePresseportal = "presseportal"
+
+var (
+	// presseportalPrefix matches the office code starting each title, e.g.
+	// "BPOLD-B: ".
+	presseportalPrefix = regexp.MustCompile(`^[A-ZÄÖÜ0-9]+(-[A-ZÄÖÜ0-9]+)*:\s*`)
+	// presseportalDateline matches the dateline starting each text, e.g.
+	// "Berlin (ots) - ".
+	presseportalDateline = regexp.MustCompile(`^[^()]{1,60} \(ots\)\s*[-–]\s*`)
+)
+
+type presseportalSource struct {
+	url       string
+	userAgent string
+}
+
+func newPresseportalSource(config Config) (Source, error) {
+	return &presseportalSource{url: config.PresseportalURL, userAgent: config.UserAgent}, nil
+}
+
+func (s *presseportalSource) Info() sourceInfo {
+	return sourceInfo{Name: sourcePresseportal, Label: "Bundespolizei", Title: "Bundespolizei Berlin (presseportal.de)", URL: s.url, Overlaps: true}
+}
+
+func (s *presseportalSource) Scrape(ctx context.Context, client HTTPDoer) ([]Event, error) {
+	feed, err := fetchRSS(ctx, client, s.url, s.userAgent)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, item := range feed.Items {
+		published, err := parseRSSTime(item.PubDate)
+		if err != nil || item.Link == "" {
+			log.Printf("Skipping presseportal.de item without link or date %q", item.PubDate)
+			continue
+		}
+		event := Event{
+			Title:       presseportalPrefix.ReplaceAllString(strings.TrimSpace(item.Title), ""),
+			Description: presseportalDateline.ReplaceAllString(htmlText(item.Description), ""),
+			Link:        strings.TrimSpace(item.Link),
+			DateTime:    wallClock(published),
+		}
+		event.Hash = sourceHash(sourcePresseportal, &event)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Complete leaves the events as they are, as the feed carries their text.
+func (s *presseportalSource) Complete(context.Context, HTTPDoer, *Event) error {
+	return nil
+}
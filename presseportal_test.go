@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPresseportalSource_SkipsBerlinDuplicates(t *testing.T) {
+	app := newFixtureApp(t)
+	app.client = &http.Client{Transport: contentTypeTransport{fixtureTransport{dir: "testdata/fixtures"}, "application/rss+xml; charset=utf-8"}}
+	source, _ := newPresseportalSource(Config{PresseportalURL: "https://www.presseportal.de/rss/dienststelle_70238.rss2", UserAgent: defaultUserAgent})
+	app.scrapers = []Source{source}
+	ctx := context.Background()
+
+	berlinReport := Event{
+		Title:       "Gesuchter Mann am Hauptbahnhof festgenommen",
+		Description: "Bundespolizisten haben gestern Abend am Hauptbahnhof einen mit Haftbefehl gesuchten Mann festgenommen. Die Polizei Berlin unterstützte.",
+		Location:    "Mitte",
+		Link:        eventLinkPrefix + "/polizei/polizeimeldungen/2026/pressemitteilung.2001.php",
+		Hash:        "berlin",
+		DateTime:    time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC).Unix(),
+		Source:      sourceBerlin,
+	}
+	if err := app.store.Create(ctx, &berlinReport); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.scrapeSource(ctx, source); err != nil {
+		t.Fatalf("scrapeSource failed: %v", err)
+	}
+	events, err := app.store.Recent(ctx, EventFilter{Sources: []string{sourcePresseportal}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected only the press release berlin.de doesn't have, got %+v", events)
+	}
+	event := events[0]
+	if event.Title != "Taschendieb in der S-Bahn gestellt" {
+		t.Fatalf("expected the title without office code, got %q", event.Title)
+	}
+	if event.Description != "Aufmerksame Reisende haben am Freitag in einer S-Bahn in Lichtenberg einen Taschendieb gestellt. Die Bundespolizei ermittelt." {
+		t.Fatalf("expected the text without dateline and markup, got %q", event.Description)
+	}
+	if event.Location != "Lichtenberg" {
+		t.Fatalf("expected the district from the text, got %q", event.Location)
+	}
+	if event.DateTime != time.Date(2026, 3, 14, 12, 5, 0, 0, time.UTC).Unix() {
+		t.Fatalf("unexpected publication time %d", event.DateTime)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	same := duplicateWords(&Event{Title: "Mann am Hauptbahnhof festgenommen", Description: "Bundespolizisten nahmen einen Mann fest."})
+	other := duplicateWords(&Event{Title: "Festnahme am Hauptbahnhof", Description: "Bundespolizisten nahmen einen gesuchten Mann fest."})
+	unrelated := duplicateWords(&Event{Title: "Brand in Kleingartenanlage", Description: "Eine Laube brannte nieder."})
+	if s := similarity(same, other); s < duplicateSimilarity {
+		t.Fatalf("expected reports of the same incident to be similar, got %.2f", s)
+	}
+	if s := similarity(same, unrelated); s >= duplicateSimilarity {
+		t.Fatalf("expected unrelated reports to differ, got %.2f", s)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Problem codes let API clients tell failures apart without parsing the
+// human readable detail.
+const (
+	codeInvalidRequest = "invalid_request"
+	codeInvalidFilter  = "invalid_filter"
+	codeUnauthorized   = "unauthorized"
+	codeForbidden      = "forbidden"
+	codeNotFound       = "not_found"
+	codeGone           = "gone"
+	codeConflict       = "conflict"
+	codeUnprocessable  = "unprocessable"
+	codeRateLimited    = "rate_limited"
+	codeUpstreamFailed = "upstream_failed"
+	codeUnavailable    = "unavailable"
+	codeInternal       = "internal"
+)
+
+// statusCodes are the problem codes of errors that don't need a more
+// specific one.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          codeInvalidRequest,
+	http.StatusUnauthorized:        codeUnauthorized,
+	http.StatusForbidden:           codeForbidden,
+	http.StatusNotFound:            codeNotFound,
+	http.StatusGone:                codeGone,
+	http.StatusConflict:            codeConflict,
+	http.StatusUnprocessableEntity: codeUnprocessable,
+	http.StatusTooManyRequests:     codeRateLimited,
+	http.StatusBadGateway:          codeUpstreamFailed,
+	http.StatusServiceUnavailable:  codeUnavailable,
+}
+
+// problem is an API error as RFC 7807 problem details. Error repeats the
+// detail for clients written against the earlier {"error": ...} responses.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+	Error  string `json:"error,omitempty"`
+}
+
+// writeProblem answers with an application/problem+json error.
+func writeProblem(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Error:  detail,
+	})
+	if err != nil {
+		log.Println("Error writing problem response:", err)
+	}
+}
+
+// writeJSONError answers with a problem whose code follows from status.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = codeInternal
+	}
+	writeProblem(w, status, code, message)
+}
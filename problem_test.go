@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIErrors_ProblemDetails(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats-token"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for _, c := range []struct {
+		path, token string
+		status      int
+		code        string
+	}{
+		{"/api/events/missing", "", http.StatusNotFound, codeNotFound},
+		{"/api/stats", "", http.StatusUnauthorized, codeUnauthorized},
+		{"/api/audit", "stats-token", http.StatusForbidden, codeForbidden},
+		{"/api/stats?from=gestern", "stats-token", http.StatusBadRequest, codeInvalidFilter},
+		{"/api/stats?groupBy=year", "stats-token", http.StatusBadRequest, codeInvalidRequest},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+c.path, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got problem
+		err = json.NewDecoder(res.Body).Decode(&got)
+		_ = res.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: decoding problem: %v", c.path, err)
+		}
+		if res.Header.Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%s: got content type %q", c.path, res.Header.Get("Content-Type"))
+		}
+		if res.StatusCode != c.status || got.Status != c.status || got.Code != c.code || got.Title != http.StatusText(c.status) || got.Detail == "" {
+			t.Errorf("%s: got %d %+v, want %d with code %s", c.path, res.StatusCode, got, c.status, c.code)
+		}
+	}
+}
+
+func TestWriteJSONError_Codes(t *testing.T) {
+	for status, want := range map[int]string{
+		http.StatusTooManyRequests:     codeRateLimited,
+		http.StatusServiceUnavailable:  codeUnavailable,
+		http.StatusInternalServerError: codeInternal,
+	} {
+		w := httptest.NewRecorder()
+		writeJSONError(w, status, "message")
+		var got problem
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Code != want || got.Error != "message" {
+			t.Errorf("status %d: got %+v, want code %s", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultAttribution credits the original publisher of the reports.
+const defaultAttribution = "Quelle: Polizei Berlin"
+
+// apiMeta describes where the data comes from and under which terms it may be
+// redistributed. It accompanies every feed, API response and export carrying
+// event data.
+type apiMeta struct {
+	License     string `json:"license,omitempty"`
+	LicenseURL  string `json:"licenseUrl,omitempty"`
+	Source      string `json:"source"`
+	Attribution string `json:"attribution,omitempty"`
+}
+
+func newAPIMeta(config Config) apiMeta {
+	return apiMeta{
+		License:     config.DataLicense,
+		LicenseURL:  config.DataLicenseURL,
+		Source:      config.PoliceURL,
+		Attribution: config.DataAttribution,
+	}
+}
+
+// notice renders the metadata as a single line for feed channels.
+func (m apiMeta) notice() string {
+	var parts []string
+	if m.Attribution != "" {
+		parts = append(parts, m.Attribution)
+	}
+	parts = append(parts, m.Source)
+	if m.License != "" {
+		license := "Lizenz: " + m.License
+		if m.LicenseURL != "" {
+			license += " (" + m.LicenseURL + ")"
+		}
+		parts = append(parts, license)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fields returns the metadata as key/value pairs for file formats that can
+// carry them, e.g. Parquet footers.
+func (m apiMeta) fields() map[string]string {
+	fields := map[string]string{"source": m.Source}
+	if m.Attribution != "" {
+		fields["attribution"] = m.Attribution
+	}
+	if m.License != "" {
+		fields["license"] = m.License
+	}
+	if m.LicenseURL != "" {
+		fields["license_url"] = m.LicenseURL
+	}
+	return fields
+}
+
+// setHeaders adds the metadata to responses whose bodies can't
+// carry it themselves, like arrays and export downloads.
+func (m apiMeta) setHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-Data-Source", m.Source)
+	if m.Attribution != "" {
+		w.Header().Set("X-Data-Attribution", m.Attribution)
+	}
+	if m.License != "" {
+		w.Header().Set("X-Data-License", m.License)
+	}
+	if m.LicenseURL != "" {
+		w.Header().Add("Link", "<"+m.LicenseURL+`>; rel="license"`)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProvenance_FeedAndAPI(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	app.config.DataLicense = "CC BY 4.0"
+	app.config.DataLicenseURL = "https://creativecommons.org/licenses/by/4.0/"
+	app.config.DataAttribution = defaultAttribution
+	app.feed.SetCopyright(newAPIMeta(app.config).notice())
+
+	if !strings.Contains(app.feed.RSS(), "<copyright>Quelle: Polizei Berlin, https://example.com/, Lizenz: CC BY 4.0") {
+		t.Fatalf("rss channel lacks copyright:\n%s", app.feed.RSS())
+	}
+	if !strings.Contains(app.feed.Atom(), "<rights>Quelle: Polizei Berlin") {
+		t.Fatal("atom feed lacks rights")
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer stats")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return res
+	}
+
+	res := get("/api/stats")
+	var stats statsResult
+	err := json.NewDecoder(res.Body).Decode(&stats)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding stats failed: %v", err)
+	}
+	if stats.Meta.License != "CC BY 4.0" || stats.Meta.Source != "https://example.com/" {
+		t.Fatalf("unexpected meta %+v", stats.Meta)
+	}
+
+	res = get("/api/facts")
+	_ = res.Body.Close()
+	if res.Header.Get("X-Data-License") != "CC BY 4.0" || !strings.Contains(res.Header.Get("Link"), `rel="license"`) {
+		t.Fatalf("facts response lacks license headers: %v", res.Header)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type schemeKey struct{}
+
+// parseTrustedProxies parses IP addresses and CIDR ranges, skipping invalid
+// entries with a warning.
+func parseTrustedProxies(values []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			log.Printf("Ignoring invalid trusted proxy %q", value)
+			continue
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
+
+func (a *App) trustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range a.config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded applies the X-Forwarded-For, -Proto and -Host headers of requests
+// coming from a trusted proxy, so that handlers see the client's address in
+// RemoteAddr and the public host in Host. Headers from anyone else are
+// ignored, as they are trivial to forge.
+func (a *App) forwarded(next http.Handler) http.Handler {
+	if len(a.config.TrustedProxies) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err != nil || !a.trustedProxy(peer.Addr()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		if client, ok := a.forwardedClient(r.Header.Values("X-Forwarded-For")); ok {
+			r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+		}
+		if host := lastHeaderValue(r.Header.Values("X-Forwarded-Host")); host != "" {
+			r.Host = host
+		}
+		if proto := lastHeaderValue(r.Header.Values("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			r = r.WithContext(context.WithValue(r.Context(), schemeKey{}, proto))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClient returns the client address from X-Forwarded-For: the
+// rightmost entry not belonging to a trusted proxy, as entries further left
+// were supplied by the client itself.
+func (a *App) forwardedClient(values []string) (netip.Addr, bool) {
+	var hops []string
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !a.trustedProxy(client) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+func lastHeaderValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	parts := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// requestBaseURL returns the scheme and host the client used to reach the
+// server, e.g. "https://feed.example.org", taking trusted proxies into account.
+func requestBaseURL(r *http.Request) string {
+	scheme, _ := r.Context().Value(schemeKey{}).(string)
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwarded_TrustedProxy(t *testing.T) {
+	a := &App{config: Config{TrustedProxies: parseTrustedProxies([]string{"10.0.0.0/8", "::1", "bogus"})}}
+	if len(a.config.TrustedProxies) != 2 {
+		t.Fatalf("expected invalid entries to be skipped, got %v", a.config.TrustedProxies)
+	}
+
+	var remote, base string
+	handler := a.forwarded(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, base = r.RemoteAddr, requestBaseURL(r)
+	}))
+	serve := func(peer string, headers map[string]string) {
+		req := httptest.NewRequest(http.MethodGet, "/rss", nil)
+		req.RemoteAddr = peer
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	headers := map[string]string{
+		"X-Forwarded-For":   "192.0.2.9, 203.0.113.5, 10.1.2.3",
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "feed.example.org",
+	}
+
+	serve("10.0.0.1:4711", headers)
+	if remote != "203.0.113.5:0" {
+		t.Fatalf("expected the rightmost untrusted client, got %s", remote)
+	}
+	if base != "https://feed.example.org" {
+		t.Fatalf("unexpected base URL %s", base)
+	}
+
+	serve("[::1]:4711", map[string]string{"X-Forwarded-For": "2001:db8::1"})
+	if remote != "[2001:db8::1]:0" || base != "http://example.com" {
+		t.Fatalf("unexpected %s %s", remote, base)
+	}
+
+	serve("198.51.100.7:4711", headers)
+	if remote != "198.51.100.7:4711" || base != "http://example.com" {
+		t.Fatalf("expected headers from untrusted peers to be ignored, got %s %s", remote, base)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// qualityCheck flags events that look like the result of a parsing problem.
+type qualityCheck struct {
+	Name        string
+	Description string
+	where       func(db *gorm.DB, now time.Time) (string, []any)
+}
+
+var qualityChecks = []qualityCheck{
+	{
+		Name:        "default_description",
+		Description: "No description could be extracted from the detail page",
+		where: func(db *gorm.DB, _ time.Time) (string, []any) {
+			return "description IN ? OR description = ''", []any{storedTexts(db, defaultDescription)}
+		},
+	},
+	{
+		Name:        "missing_district",
+		Description: "No district was found on the list page",
+		where: func(*gorm.DB, time.Time) (string, []any) {
+			return "location = ''", nil
+		},
+	},
+	{
+		Name:        "future_timestamp",
+		Description: "Publication time lies in the future",
+		where: func(_ *gorm.DB, now time.Time) (string, []any) {
+			return "date_time > ?", []any{now.Add(time.Hour).Unix()}
+		},
+	},
+	{
+		Name:        "empty_link",
+		Description: "Link to the detail page is missing",
+		where: func(*gorm.DB, time.Time) (string, []any) {
+			return "link = '' OR link = ?", []any{eventLinkPrefix}
+		},
+	},
+}
+
+// qualitySampleSize limits how many example hashes are reported per check.
+const qualitySampleSize = 10
+
+type QualityIssue struct {
+	Check       string   `json:"check"`
+	Description string   `json:"description"`
+	Count       int64    `json:"count"`
+	Ratio       float64  `json:"ratio"`
+	Samples     []string `json:"samples,omitempty"`
+}
+
+type QualityReport struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Since       time.Time      `json:"since"`
+	Checked     int64          `json:"checked"`
+	Issues      []QualityIssue `json:"issues"`
+}
+
+// QualityReport runs all quality checks against the events published since
+// the given time.
+func (s *gormStore) QualityReport(ctx context.Context, since, now time.Time) (*QualityReport, error) {
+	inWindow := func() *gorm.DB {
+		return s.db.WithContext(ctx).Model(&Event{}).Where("date_time >= ? AND taken_down_at IS NULL", since.Unix())
+	}
+
+	report := &QualityReport{GeneratedAt: now, Since: since}
+	err := inWindow().Count(&report.Checked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, check := range qualityChecks {
+		clause, args := check.where(s.db, now)
+		issue := QualityIssue{Check: check.Name, Description: check.Description}
+
+		err := inWindow().Where(clause, args...).Count(&issue.Count).Error
+		if err != nil {
+			return nil, err
+		}
+		if issue.Count > 0 {
+			err = inWindow().Where(clause, args...).
+				Order("date_time DESC").Limit(qualitySampleSize).Pluck("hash", &issue.Samples).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		if report.Checked > 0 {
+			issue.Ratio = float64(issue.Count) / float64(report.Checked)
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	return report, nil
+}
+
+// qualityState caches the latest report, so that metrics scrapes don't run the
+// checks every time.
+type qualityState struct {
+	mu     sync.Mutex
+	report *QualityReport
+}
+
+func (s *qualityState) set(report *QualityReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report = report
+}
+
+func (s *qualityState) get() *QualityReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report
+}
+
+func (a *App) checkQuality(ctx context.Context) (*QualityReport, error) {
+	now := time.Now()
+	report, err := a.store.QualityReport(ctx, now.Add(-a.config.QualityWindow), now)
+	if err != nil {
+		return nil, err
+	}
+	a.quality.set(report)
+	for _, issue := range report.Issues {
+		if issue.Count > 0 {
+			log.Printf("Data quality: %d of %d recent events fail %s", issue.Count, report.Checked, issue.Check)
+		}
+	}
+	return report, nil
+}
+
+func (a *App) handleQuality(w http.ResponseWriter, r *http.Request) {
+	report := a.quality.get()
+	if report == nil {
+		var err error
+		report, err = a.checkQuality(r.Context())
+		if err != nil {
+			log.Println("Error checking data quality:", err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (a *App) writeQualityMetrics(m *metricsWriter) {
+	report := a.quality.get()
+	if report == nil {
+		return
+	}
+	samples := make([]metricSample, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		samples = append(samples, metricSample{labels: map[string]string{"check": issue.Check}, value: float64(issue.Count)})
+	}
+	m.gauge("policefeed_quality_events_checked", "Number of recent events covered by the data quality checks.", float64(report.Checked))
+	m.write("policefeed_quality_issues", "gauge", "Number of recent events failing a data quality check.", samples...)
+}
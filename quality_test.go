@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQualityReport(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	now := time.Now()
+	events := []Event{
+		{Title: "ok", Description: "fine", Location: "Mitte", Link: eventLinkPrefix + "/a", Hash: "ok", DateTime: now.Add(-time.Hour).Unix()},
+		{Title: "nodesc", Description: defaultDescription, Location: "Mitte", Link: eventLinkPrefix + "/b", Hash: "nodesc", DateTime: now.Add(-time.Hour).Unix()},
+		{Title: "nodistrict", Description: "fine", Link: eventLinkPrefix, Hash: "nodistrict", DateTime: now.Add(-time.Hour).Unix()},
+		{Title: "future", Description: "fine", Location: "Mitte", Link: eventLinkPrefix + "/c", Hash: "future", DateTime: now.Add(48 * time.Hour).Unix()},
+		{Title: "old", Description: defaultDescription, Hash: "old", DateTime: now.AddDate(-1, 0, 0).Unix()},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	report, err := app.store.QualityReport(ctx, now.Add(-7*24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("QualityReport failed: %v", err)
+	}
+	if report.Checked != 4 {
+		t.Fatalf("expected 4 events in window, got %d", report.Checked)
+	}
+	want := map[string]int64{"default_description": 1, "missing_district": 1, "future_timestamp": 1, "empty_link": 1}
+	for _, issue := range report.Issues {
+		if issue.Count != want[issue.Check] {
+			t.Fatalf("check %s: expected %d, got %d", issue.Check, want[issue.Check], issue.Count)
+		}
+	}
+}
+
+func TestQuality_EndpointAndMetrics(t *testing.T) {
+	app := newTestApp(t)
+	app.config.QualityWindow = 24 * time.Hour
+	event := Event{Title: "x", Description: defaultDescription, Location: "Mitte", Link: eventLinkPrefix + "/x", Hash: "x", DateTime: time.Now().Unix()}
+	if err := app.store.Create(context.Background(), &event); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/quality")
+	if err != nil {
+		t.Fatalf("GET /api/quality failed: %v", err)
+	}
+	var report QualityReport
+	err = json.NewDecoder(res.Body).Decode(&report)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding report failed: %v", err)
+	}
+	if report.Checked != 1 || report.Issues[0].Count != 1 || report.Issues[0].Samples[0] != "x" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	res, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if !strings.Contains(string(body), `policefeed_quality_issues{check="default_description"} 1`) {
+		t.Fatalf("metrics missing quality issues:\n%s", body)
+	}
+}
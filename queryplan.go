@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// featureQueryPlans logs the plan of every distinct query, to find filters
+// lacking an index.
+const featureQueryPlans = "query_plans"
+
+// fullScan matches plan lines of scans reading a whole table: SQLite's "SCAN
+// events" without an index, and Postgres' "Seq Scan on events". Searching
+// only the deleted_at index of soft deletes reads nearly all rows as well.
+var fullScan = regexp.MustCompile(`^\s*(?:->\s*)?(?:SCAN (\w+)$|Seq Scan on (\w+)|SEARCH (\w+) USING (?:COVERING )?INDEX \w+ \(deleted_at=\?\)$)`)
+
+// logQueryPlans registers a callback logging the plan of each distinct query
+// once, with a warning if it filters a table by scanning all of it.
+func logQueryPlans(db *gorm.DB) error {
+	var logged sync.Map
+	return db.Callback().Query().After("gorm:query").Register("policefeed:query_plan", func(tx *gorm.DB) {
+		query := tx.Statement.SQL.String()
+		if (tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound)) || query == "" {
+			return
+		}
+		if _, seen := logged.LoadOrStore(query, true); seen {
+			return
+		}
+		plan, err := explainQuery(tx, query)
+		if err != nil {
+			log.Printf("Error explaining %s: %v", query, err)
+			return
+		}
+		log.Printf("Query plan of %s:\n%s", query, strings.Join(plan, "\n"))
+		if !strings.Contains(query, " WHERE ") {
+			return
+		}
+		for _, line := range plan {
+			if match := fullScan.FindStringSubmatch(line); match != nil {
+				log.Printf("Warning: query filters %s with a full scan, consider an index: %s", match[1]+match[2]+match[3], query)
+			}
+		}
+	})
+}
+
+// explainQuery returns the plan the database chose for query, one line per
+// step.
+func explainQuery(tx *gorm.DB, query string) ([]string, error) {
+	prefix := "EXPLAIN QUERY PLAN "
+	if isPostgres(tx) {
+		prefix = "EXPLAIN "
+	}
+	rows, err := tx.Statement.ConnPool.QueryContext(tx.Statement.Context, prefix+query, tx.Statement.Vars...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	// The description is the last column of SQLite's plan and the only one
+	// of Postgres'.
+	values := make([]any, len(columns))
+	for i := range values {
+		values[i] = new(any)
+	}
+	var plan []string
+	for rows.Next() {
+		err := rows.Scan(values...)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, fmt.Sprint(*values[len(values)-1].(*any)))
+	}
+	return plan, rows.Err()
+}
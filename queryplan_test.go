@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLogQueryPlans(t *testing.T) {
+	db := openTestDB(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	if err := logQueryPlans(db); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(io.Discard)
+
+	store := &gormStore{db: db}
+	ctx := context.Background()
+	_, _ = store.FindByHash(ctx, "a")
+	_, _ = store.FindByHash(ctx, "b")
+	if n := strings.Count(out.String(), "Query plan of"); n != 1 {
+		t.Fatalf("expected the plan to be logged once, got %d:\n%s", n, out.String())
+	}
+	if strings.Contains(out.String(), "Warning") {
+		t.Fatalf("expected the lookup by hash to use an index:\n%s", out.String())
+	}
+
+	var events []Event
+	db.Where("description = ?", "x").Find(&events)
+	if !strings.Contains(out.String(), "Warning: query filters events with a full scan") {
+		t.Fatalf("expected a full scan warning:\n%s", out.String())
+	}
+}
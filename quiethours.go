@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// quietHours is a daily window in Berlin time, given in minutes after
+// midnight. Windows with start after end span midnight, e.g. 23:00-07:00.
+type quietHours struct {
+	start, end int
+}
+
+func parseQuietHours(value string) (quietHours, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return quietHours{}, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", value)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return quietHours{}, fmt.Errorf("invalid quiet hours %q: %w", value, err)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return quietHours{}, fmt.Errorf("invalid quiet hours %q: %w", value, err)
+	}
+	return quietHours{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+	}, nil
+}
+
+func (q quietHours) contains(t time.Time) bool {
+	t = t.In(berlin)
+	minute := t.Hour()*60 + t.Minute()
+	if q.start <= q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// quietHoursEnv reads per-target quiet hours from a comma separated list of
+// target=HH:MM-HH:MM entries; the target * applies to all targets without an
+// entry of their own. Invalid entries are logged and skipped.
+func quietHoursEnv(name string) map[string]quietHours {
+	hours := make(map[string]quietHours)
+	for _, entry := range listEnv(name) {
+		target, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			log.Printf("Invalid %s entry %q, expected target=HH:MM-HH:MM", name, entry)
+			continue
+		}
+		q, err := parseQuietHours(value)
+		if err != nil {
+			log.Printf("Invalid %s entry %q: %v", name, entry, err)
+			continue
+		}
+		hours[strings.TrimSpace(target)] = q
+	}
+	return hours
+}
+
+func (c Config) quietHoursFor(target string) (quietHours, bool) {
+	if q, ok := c.QuietHours[target]; ok {
+		return q, true
+	}
+	q, ok := c.QuietHours["*"]
+	return q, ok
+}
+
+// QueuedNotification is an event held back during a target's quiet hours.
+type QueuedNotification struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Target    string `gorm:"index"`
+	EventHash string
+}
+
+func (s *gormStore) QueueNotification(ctx context.Context, queued *QueuedNotification) error {
+	return s.db.WithContext(ctx).Create(queued).Error
+}
+
+// QueuedNotifications returns the notifications held back for target, oldest
+// first.
+func (s *gormStore) QueuedNotifications(ctx context.Context, target string) ([]QueuedNotification, error) {
+	var queued []QueuedNotification
+	err := s.db.WithContext(ctx).Where("target = ?", target).Order("id").Find(&queued).Error
+	return queued, err
+}
+
+func (s *gormStore) DeleteQueuedNotifications(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Delete(&QueuedNotification{}, ids).Error
+}
+
+// holdForQuietHours queues the events that must not go to notifier right now
+// and returns the rest. High severity and breaking events are never held
+// back.
+func (a *App) holdForQuietHours(ctx context.Context, notifier Notifier, events []Event, now time.Time) []Event {
+	q, ok := a.config.quietHoursFor(notifier.Name())
+	if !ok || !q.contains(now) {
+		return events
+	}
+
+	var immediate []Event
+	for _, event := range events {
+		if classifyEvent(a.rules(), &event).Severity >= severityHigh || a.isBreaking(&event) {
+			immediate = append(immediate, event)
+			continue
+		}
+		err := a.store.QueueNotification(context.WithoutCancel(ctx), &QueuedNotification{
+			Target:    notifier.Name(),
+			EventHash: event.Hash,
+		})
+		if err != nil {
+			log.Printf("Error queueing notification for %s about %s, sending now: %v", notifier.Name(), event.Hash, err)
+			immediate = append(immediate, event)
+		}
+	}
+	return immediate
+}
+
+// flushQuietHours sends everything held back for targets whose quiet hours
+// are over as a single digest per target.
+func (a *App) flushQuietHours(ctx context.Context, now time.Time) {
+	for _, notifier := range a.notifiers {
+		q, ok := a.config.quietHoursFor(notifier.Name())
+		if ok && q.contains(now) {
+			continue
+		}
+		queued, err := a.store.QueuedNotifications(ctx, notifier.Name())
+		if err != nil {
+			log.Printf("Error loading queued notifications for %s: %v", notifier.Name(), err)
+			continue
+		}
+		if len(queued) == 0 {
+			continue
+		}
+
+		var events []Event
+		ids := make([]uint, 0, len(queued))
+		for _, entry := range queued {
+			event, err := a.store.FindByHash(ctx, entry.EventHash)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				// Keep it queued for the next attempt.
+				log.Printf("Error loading queued event %s: %v", entry.EventHash, err)
+				continue
+			}
+			ids = append(ids, entry.ID)
+			if event != nil {
+				events = append(events, *event)
+			}
+		}
+
+		switch len(events) {
+		case 0:
+		case 1:
+			a.send(ctx, notifier, Notification{Event: events[0]})
+		default:
+			a.send(ctx, notifier, Notification{Digest: events})
+		}
+		log.Printf("Sent %d notifications held back during quiet hours to %s", len(events), notifier.Name())
+
+		err = a.store.DeleteQueuedNotifications(context.WithoutCancel(ctx), ids)
+		if err != nil {
+			log.Printf("Error deleting queued notifications for %s: %v", notifier.Name(), err)
+		}
+	}
+}
+
+// runQuietHours periodically delivers the notifications held back during
+// quiet hours until ctx is cancelled.
+func (a *App) runQuietHours(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.flushQuietHours(ctx, now)
+		}
+	}
+}
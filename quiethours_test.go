@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQuietHours_Contains(t *testing.T) {
+	overnight, err := parseQuietHours("23:00-07:00")
+	if err != nil {
+		t.Fatalf("parseQuietHours failed: %v", err)
+	}
+	daytime, err := parseQuietHours("12:00-13:30")
+	if err != nil {
+		t.Fatalf("parseQuietHours failed: %v", err)
+	}
+
+	at := func(hour, minute int) time.Time { return time.Date(2026, 7, 1, hour, minute, 0, 0, berlin) }
+	tests := []struct {
+		q    quietHours
+		t    time.Time
+		want bool
+	}{
+		{overnight, at(23, 0), true},
+		{overnight, at(3, 0), true},
+		{overnight, at(7, 0), false},
+		{overnight, at(12, 0), false},
+		{daytime, at(13, 29), true},
+		{daytime, at(13, 30), false},
+		// 21:30 UTC is 23:30 in Berlin during summer time.
+		{overnight, time.Date(2026, 7, 1, 21, 30, 0, 0, time.UTC), true},
+	}
+	for _, test := range tests {
+		if got := test.q.contains(test.t); got != test.want {
+			t.Errorf("%+v contains %s = %v, want %v", test.q, test.t, got, test.want)
+		}
+	}
+
+	for _, invalid := range []string{"23:00", "25:00-07:00", "abends-morgens"} {
+		if _, err := parseQuietHours(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestNotify_QuietHours(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	now := time.Now()
+	start := now.In(berlin).Add(-time.Hour)
+	end := now.In(berlin).Add(time.Hour)
+	app.config.QuietHours = map[string]quietHours{"*": {
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+	}}
+	target := &recordingNotifier{name: "webhook:a"}
+	app.notifiers = []Notifier{target}
+
+	events := []Event{
+		{Title: "Fahrraddiebstahl", Hash: "low1"},
+		{Title: "Schüsse in Neukölln", Hash: "high"},
+		{Title: "Verkehrsunfall", Hash: "low2"},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	app.notify(ctx, app.notifiers, events, false)
+	if len(target.sent) != 1 || target.sent[0].Event.Hash != "high" {
+		t.Fatalf("expected only the high severity event during quiet hours, got %+v", target.sent)
+	}
+
+	app.flushQuietHours(ctx, now)
+	if len(target.sent) != 1 {
+		t.Fatalf("held back events were sent during quiet hours")
+	}
+
+	app.flushQuietHours(ctx, now.Add(2*time.Hour))
+	if len(target.sent) != 2 || len(target.sent[1].Digest) != 2 {
+		t.Fatalf("expected a digest of the 2 held back events, got %+v", target.sent)
+	}
+	queued, _ := app.store.QueuedNotifications(ctx, "webhook:a")
+	if len(queued) != 0 {
+		t.Fatalf("queue not emptied: %+v", queued)
+	}
+}
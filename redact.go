@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// defaultRedaction replaces matches of rules without a replacement.
+const defaultRedaction = "[entfernt]"
+
+// redactionRule blanks out matches of a regular expression, e.g. phone
+// numbers or names that slipped into a report.
+type redactionRule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+
+	re *regexp.Regexp
+}
+
+// redactor applies redaction rules when events are rendered. The stored
+// events keep their original text, so rules can be changed or dropped later.
+type redactor []redactionRule
+
+// loadRedactionRules reads the rules from a JSON file. Without a file there
+// are none.
+func loadRedactionRules(path string) (redactor, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRedactionRules(data, path)
+}
+
+// parseRedactionRules parses rules in JSON read from source.
+func parseRedactionRules(data []byte, source string) (redactor, error) {
+	var rules redactor
+	err := json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", source, err)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("%s: rule %d needs a name and a pattern", source, i)
+		}
+		rule.re, err = regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %s: %w", source, rule.Name, err)
+		}
+		if rule.re.MatchString("") {
+			return nil, fmt.Errorf("%s: rule %s matches empty text", source, rule.Name)
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = defaultRedaction
+		}
+	}
+	return rules, nil
+}
+
+func (r redactor) text(s string) string {
+	for _, rule := range r {
+		s = rule.re.ReplaceAllString(s, rule.Replacement)
+	}
+	return s
+}
+
+// event returns a redacted copy of event, including the values of its manual
+// corrections.
+func (r redactor) event(event *Event) *Event {
+	if len(r) == 0 {
+		return event
+	}
+	redacted := *event
+	redacted.Title = r.text(event.Title)
+	redacted.Description = r.text(event.Description)
+	redacted.Location = r.text(event.Location)
+	redacted.Edits = slices.Clone(event.Edits)
+	for i := range redacted.Edits {
+		redacted.Edits[i].Old = r.text(redacted.Edits[i].Old)
+		redacted.Edits[i].New = r.text(redacted.Edits[i].New)
+	}
+	return &redacted
+}
+
+// events returns redacted copies of events.
+func (r redactor) events(events []Event) []Event {
+	if len(r) == 0 || len(events) == 0 {
+		return events
+	}
+	redacted := make([]Event, len(events))
+	for i := range events {
+		redacted[i] = *r.event(&events[i])
+	}
+	return redacted
+}
+
+// summaries returns redacted copies of generated summaries by hash.
+func (r redactor) summaries(summaries map[string]string) map[string]string {
+	if len(r) == 0 {
+		return summaries
+	}
+	redacted := make(map[string]string, len(summaries))
+	for hash, summary := range summaries {
+		redacted[hash] = r.text(summary)
+	}
+	return redacted
+}
+
+// settingRedactionRules holds the rules the posts of targets were last
+// redacted with, see correctRedactions.
+const settingRedactionRules = "redaction_rules"
+
+func (s *gormStore) RedactionRules(ctx context.Context) ([]byte, error) {
+	var setting Setting
+	err := s.db.WithContext(ctx).First(&setting, "key = ?", settingRedactionRules).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return []byte(setting.Value), err
+}
+
+// SetRedactionRules stores the rules in effect, or removes them if data is
+// nil.
+func (s *gormStore) SetRedactionRules(ctx context.Context, data []byte) error {
+	if data == nil {
+		return s.db.WithContext(ctx).Delete(&Setting{Key: settingRedactionRules}).Error
+	}
+	return s.db.WithContext(ctx).Save(&Setting{Key: settingRedactionRules, Value: string(data)}).Error
+}
+
+// ReceiptHashes returns the hashes of the events any target has a receipt
+// for.
+func (s *gormStore) ReceiptHashes(ctx context.Context) ([]string, error) {
+	var hashes []string
+	err := s.db.WithContext(ctx).Model(&NotificationReceipt{}).Distinct("event_hash").Order("event_hash").Pluck("event_hash", &hashes).Error
+	return hashes, err
+}
+
+// correctRedactions edits what targets were sent once the redaction rules
+// changed since the last start: events whose redacted title, text or district
+// come out differently under the new rules are sent as corrections, so that
+// posts neither keep what is redacted now nor stay blanked out by dropped
+// rules.
+func (a *App) correctRedactions(ctx context.Context) error {
+	data, err := a.store.RedactionRules(ctx)
+	if err != nil {
+		return err
+	}
+	var previous redactor
+	if data != nil {
+		previous, err = parseRedactionRules(data, "stored redaction rules")
+		if err != nil {
+			return err
+		}
+	}
+	if slices.EqualFunc(previous, a.redactions, func(a, b redactionRule) bool {
+		return a.Name == b.Name && a.Pattern == b.Pattern && a.Replacement == b.Replacement
+	}) {
+		return nil
+	}
+
+	hashes, err := a.store.ReceiptHashes(ctx)
+	if err != nil {
+		return err
+	}
+	corrected := 0
+	for chunk := range slices.Chunk(hashes, exportBatchSize) {
+		events, err := a.store.EventsByHash(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			event := &events[i]
+			if event.TakenDownAt != nil {
+				continue
+			}
+			changes := redactionChanges(previous.event(event), a.redactions.event(event))
+			if len(changes) == 0 {
+				continue
+			}
+			a.correct(ctx, Correction{Type: correctionUpdated, Event: *event, Changes: changes})
+			corrected++
+		}
+	}
+	if corrected > 0 {
+		log.Printf("Redaction rules changed, correcting %d events sent before", corrected)
+	}
+
+	data = nil
+	if len(a.redactions) > 0 {
+		data, err = json.Marshal(a.redactions)
+		if err != nil {
+			return err
+		}
+	}
+	return a.store.SetRedactionRules(ctx, data)
+}
+
+// redactionChanges names the fields that differ between two redacted copies
+// of an event, like Correction.Changes.
+func redactionChanges(old, redacted *Event) []string {
+	var changes []string
+	if old.Title != redacted.Title {
+		changes = append(changes, "title")
+	}
+	if old.Location != redacted.Location {
+		changes = append(changes, "district")
+	}
+	if old.Description != redacted.Description {
+		changes = append(changes, "description")
+	}
+	return changes
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestRedaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redactions.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "telefon", "pattern": "\\b0\\d{2,4}[ /-]?\\d{4,8}\\b"},
+		{"name": "name", "pattern": "Max Mustermann", "replacement": "M."}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadRedactionRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp(t)
+	app.redactions = rules
+	app.feed.SetRedactor(rules)
+	ctx := context.Background()
+	event := Event{Title: "Zeugen gesucht", Description: "Max Mustermann bittet um Hinweise unter 030 46649123.", Hash: "redacted", DateTime: 1}
+	if err := app.store.Create(ctx, &event); err != nil {
+		t.Fatal(err)
+	}
+	app.feed.Add(event)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	for _, path := range []string{"/rss", "/api/events/redacted", "/event/redacted", "/plain"} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if strings.Contains(string(body), "46649123") || strings.Contains(string(body), "Mustermann") {
+			t.Errorf("GET %s leaks redacted text: %s", path, body)
+		}
+		if !strings.Contains(string(body), "M. bittet um Hinweise unter [entfernt]") {
+			t.Errorf("GET %s lacks the replacements: %s", path, body)
+		}
+	}
+
+	stored, err := app.store.FindByHash(ctx, "redacted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Description != event.Description {
+		t.Fatalf("the stored original was changed: %q", stored.Description)
+	}
+}
+
+func TestRedaction_ExportsAndPodcast(t *testing.T) {
+	app := newTestApp(t)
+	app.redactions = redactor{{Name: "name", Pattern: "Mustermann", Replacement: "M.", re: regexp.MustCompile("Mustermann")}}
+	app.speaker = &recordingSpeaker{}
+	app.config.PodcastDir = t.TempDir()
+	ctx := context.Background()
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, berlin)
+	event := Event{
+		Title:       "Zeugen gesucht",
+		Description: "Max Mustermann bittet um Hinweise.",
+		Location:    "Mitte, Mustermannstraße",
+		Hash:        "redacted",
+		DateTime:    time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC).Unix(),
+	}
+	if err := app.store.Create(ctx, &event); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	var csvExport bytes.Buffer
+	if _, err := writeEvents(ctx, app.store, EventFilter{}, app.redactions, exportFormatCSV, &csvExport); err != nil {
+		t.Fatal(err)
+	}
+	snapshotPath := filepath.Join(dir, "events.db")
+	if _, err := writeSQLiteSnapshot(ctx, app.store, EventFilter{}, app.redactions, apiMeta{}, snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeParquetExport(ctx, app.store, EventFilter{}, app.redactions, apiMeta{}, dir); err != nil {
+		t.Fatal(err)
+	}
+	parquetEvents, err := parquet.ReadFile[parquetEvent](filepath.Join(dir, "events.parquet"))
+	if err != nil || len(parquetEvents) != 1 {
+		t.Fatalf("unexpected Parquet export %v, %v", parquetEvents, err)
+	}
+	if err := app.recordEpisode(ctx, day); err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile(filepath.Join(app.config.PodcastDir, "2026-10-15.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, output := range map[string]string{
+		"csv":     csvExport.String(),
+		"sqlite":  string(snapshot),
+		"parquet": parquetEvents[0].Description + parquetEvents[0].District,
+		"podcast": string(script),
+	} {
+		if strings.Contains(output, "Mustermann") {
+			t.Errorf("%s export leaks redacted text: %s", name, output)
+		}
+		if !strings.Contains(output, "Max M.") || !strings.Contains(output, "Mitte, M.straße") {
+			t.Errorf("%s export lacks the replacements: %s", name, output)
+		}
+	}
+}
+
+func TestLoadRedactionRules_Invalid(t *testing.T) {
+	for _, rules := range []string{
+		`[{"name": "leer", "pattern": ".*"}]`,
+		`[{"name": "kaputt", "pattern": "("}]`,
+		`[{"pattern": "x"}]`,
+	} {
+		path := filepath.Join(t.TempDir(), "redactions.json")
+		if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadRedactionRules(path); err == nil {
+			t.Errorf("expected %s to be rejected", rules)
+		}
+	}
+}
+
+func TestCorrectRedactions(t *testing.T) {
+	app := newTestApp(t)
+	target := &correctingRecorder{recordingNotifier: recordingNotifier{name: "correcting"}}
+	app.notifiers = []Notifier{target}
+	ctx := context.Background()
+	for _, event := range []Event{
+		{Title: "Zeugen gesucht", Description: "Hinweise unter 030 46649123.", Hash: "sent", DateTime: 1},
+		{Title: "Ohne Nummer", Description: "Keine Hinweise.", Hash: "unchanged", DateTime: 1},
+		{Title: "Nie gesendet", Description: "Hinweise unter 030 46649123.", Hash: "unsent", DateTime: 1},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.notify(ctx, app.notifiers, []Event{{Hash: "sent"}, {Hash: "unchanged"}}, true)
+
+	rules, err := parseRedactionRules([]byte(`[{"name": "telefon", "pattern": "\\b0\\d{2,4}[ /-]?\\d{4,8}\\b"}]`), "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.redactions = rules
+	if err := app.correctRedactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	app.deliveries.Wait()
+	if len(target.corrections) != 1 {
+		t.Fatalf("expected one correction, got %+v", target.corrections)
+	}
+	if c := target.corrections[0]; c.Event.Hash != "sent" || c.Event.Description != "Hinweise unter [entfernt]." || c.MessageID != "msg-sent" || !slices.Equal(c.Changes, []string{"description"}) {
+		t.Fatalf("unexpected correction %+v", c)
+	}
+
+	// Unchanged rules correct nothing; dropping them restores the text.
+	if err := app.correctRedactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	app.redactions = nil
+	if err := app.correctRedactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	app.deliveries.Wait()
+	if len(target.corrections) != 2 || target.corrections[1].Event.Description != "Hinweise unter 030 46649123." {
+		t.Fatalf("expected the original text back, got %+v", target.corrections)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// maxReplayEvents bounds a single replay, which runs synchronously within the
+// request.
+const maxReplayEvents = 1000
+
+type replayResult struct {
+	Events  int      `json:"events"`
+	Targets []string `json:"targets"`
+	DryRun  bool     `json:"dryRun"`
+	Sent    int      `json:"sent"`
+	Failed  int      `json:"failed"`
+}
+
+// handleReplay re-sends stored events from a date range to the notification
+// targets, e.g. to seed a newly added target. Without "confirm": true it only
+// reports what would be sent.
+func (a *App) handleReplay(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		From     string   `json:"from"`
+		To       string   `json:"to"`
+		District string   `json:"district"`
+		Targets  []string `json:"targets"`
+		Confirm  bool     `json:"confirm"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if request.From == "" || request.To == "" {
+		writeJSONError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+	filter, err := parseEventFilter(url.Values{
+		"from":     {request.From},
+		"to":       {request.To},
+		"district": {request.District},
+	})
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, codeInvalidFilter, err.Error())
+		return
+	}
+
+	var notifiers []Notifier
+	for _, notifier := range a.notifiers {
+		if len(request.Targets) == 0 || slices.Contains(request.Targets, notifier.Name()) {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	if len(notifiers) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "no matching notification targets")
+		return
+	}
+
+	var events []Event
+	err = a.store.EachBatch(r.Context(), filter, exportBatchSize, func(batch []Event) error {
+		events = append(events, batch...)
+		if len(events) > maxReplayEvents {
+			return errTooManyReplayEvents
+		}
+		return nil
+	})
+	if errors.Is(err, errTooManyReplayEvents) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Println("Error loading events for replay:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	result := replayResult{Events: len(events), DryRun: !request.Confirm}
+	for _, notifier := range notifiers {
+		result.Targets = append(result.Targets, notifier.Name())
+	}
+	if request.Confirm {
+		result.Sent, result.Failed = a.notify(r.Context(), notifiers, events, true)
+		a.audit(r.Context(), auditActionReplay, "", map[string]any{
+			"filter":  filter,
+			"targets": result.Targets,
+			"events":  result.Events,
+			"failed":  result.Failed,
+		})
+		log.Printf("Replayed %d events to %v for %s (%d failed)", result.Events, result.Targets, actorFromContext(r.Context()), result.Failed)
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+var errTooManyReplayEvents = fmt.Errorf("range contains more than %d events, narrow it down", maxReplayEvents)
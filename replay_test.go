@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postJSON(t *testing.T, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("building request failed: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	return res
+}
+
+func TestReplay(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	seeded := &recordingNotifier{name: "webhook:new.example"}
+	other := &recordingNotifier{name: "webhook:old.example"}
+	app.notifiers = []Notifier{seeded, other}
+
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, berlin)
+	for i, at := range []time.Time{day, day.Add(2 * time.Hour), day.AddDate(0, 0, -3)} {
+		event := Event{Title: "event", Hash: string(rune('a' + i)), DateTime: at.Unix()}
+		if err := app.store.Create(context.Background(), &event); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	body := `{"from":"2026-03-01","to":"2026-03-02","targets":["webhook:new.example"]}`
+	res := postJSON(t, server.URL+"/api/replay", "secret", body)
+	var result replayResult
+	err := json.NewDecoder(res.Body).Decode(&result)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding result failed: %v", err)
+	}
+	if !result.DryRun || result.Events != 2 || len(seeded.sent) != 0 {
+		t.Fatalf("expected a dry run over 2 events, got %+v (%d sent)", result, len(seeded.sent))
+	}
+
+	body = `{"from":"2026-03-01","to":"2026-03-02","targets":["webhook:new.example"],"confirm":true}`
+	res = postJSON(t, server.URL+"/api/replay", "secret", body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if len(seeded.sent) != 2 || !seeded.sent[0].Replay {
+		t.Fatalf("expected 2 replayed notifications, got %+v", seeded.sent)
+	}
+	if len(other.sent) != 0 {
+		t.Fatalf("unselected target was notified")
+	}
+
+	entries, err := app.store.AuditLog(context.Background(), AuditFilter{Action: auditActionReplay, Limit: 10})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one audit entry, got %v (%v)", entries, err)
+	}
+}
+
+func TestReplay_Validation(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	app.notifiers = []Notifier{&recordingNotifier{name: "webhook:a", err: errors.New("down")}}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for _, body := range []string{
+		`{"confirm":true}`,
+		`{"from":"2026-03-01","to":"2026-03-02","targets":["unknown"]}`,
+		`{"from":"yesterday","to":"2026-03-02"}`,
+	} {
+		res := postJSON(t, server.URL+"/api/replay", "secret", body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, res.StatusCode)
+		}
+	}
+}
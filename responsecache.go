@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultResponseCacheSize bounds the bytes of cached response bodies,
+	// small enough for a Raspberry Pi.
+	defaultResponseCacheSize = 8 << 20
+	// maxCachedResponse is the largest body worth caching; bigger responses
+	// would evict too much else.
+	maxCachedResponse = 1 << 20
+)
+
+// cachedResponse is a rendered response, stored until it expires.
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    compressedBody
+	expires time.Time
+}
+
+// responseCache keeps rendered responses of hot API queries, evicting the
+// least recently used ones once their bodies exceed size bytes. New data
+// invalidates all of them, see invalidate.
+type responseCache struct {
+	mu    sync.Mutex
+	size  int
+	bytes int
+	order *list.List
+	items map[string]*list.Element
+	// generation counts invalidations, so that a response rendered from
+	// data that changed meanwhile isn't cached.
+	generation int
+
+	hits, misses int
+}
+
+func newResponseCache(size int) *responseCache {
+	return &responseCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the cached response for key, unless it has expired, and the
+// current generation to pass to put otherwise.
+func (c *responseCache) get(key string, now time.Time) (*cachedResponse, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.items[key]
+	if !ok || now.After(element.Value.(*cachedResponse).expires) {
+		if ok {
+			c.remove(element)
+		}
+		c.misses++
+		return nil, c.generation
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedResponse), c.generation
+}
+
+// put caches a response rendered in the given generation.
+func (c *responseCache) put(response *cachedResponse, generation int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation || response.size() > c.size {
+		return
+	}
+	if element, ok := c.items[response.key]; ok {
+		c.remove(element)
+	}
+	c.items[response.key] = c.order.PushFront(response)
+	c.bytes += response.size()
+	for c.bytes > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *responseCache) remove(element *list.Element) {
+	response := c.order.Remove(element).(*cachedResponse)
+	delete(c.items, response.key)
+	c.bytes -= response.size()
+}
+
+func (r *cachedResponse) size() int {
+	return r.body.size()
+}
+
+// invalidate drops all cached responses, as any of them may be affected by
+// new or changed events.
+func (c *responseCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.items)
+	c.bytes = 0
+	c.generation++
+}
+
+func (c *responseCache) writeMetrics(m *metricsWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m.write("policefeed_response_cache_requests_total", "counter", "Number of cacheable API requests by result.",
+		metricSample{labels: map[string]string{"result": "hit"}, value: float64(c.hits)},
+		metricSample{labels: map[string]string{"result": "miss"}, value: float64(c.misses)})
+	m.gauge("policefeed_response_cache_entries", "Number of cached API responses.", float64(c.order.Len()))
+	m.gauge("policefeed_response_cache_bytes", "Size of the cached API response bodies.", float64(c.bytes))
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.body.Len() <= maxCachedResponse {
+		r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// cached serves h's successful responses from the response cache for up to
+// API_CACHE_TTL, keyed by path and query. Cached bodies are compressed once,
+// so that hits cost neither the rendering nor the compression. Responses the
+// handler compressed itself are not cached. Handlers behind
+// authentication must be wrapped inside requireScope, so that only authorized
+// requests get to the cache.
+func (a *App) cached(h http.HandlerFunc) http.HandlerFunc {
+	if a.responses == nil || a.config.APICacheTTL <= 0 {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+		now := time.Now()
+		response, generation := a.responses.get(key, now)
+		w.Header().Add("Vary", "Accept-Encoding")
+		if response != nil {
+			for name, values := range response.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("X-Cache", "HIT")
+			body := response.body.negotiate(w, r)
+			w.WriteHeader(response.status)
+			_, _ = w.Write(body)
+			return
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		recorder := &responseRecorder{ResponseWriter: w}
+		h(recorder, r)
+		if recorder.status != http.StatusOK || recorder.body.Len() > maxCachedResponse || w.Header().Get("Content-Encoding") != "" {
+			return
+		}
+		header := w.Header().Clone()
+		header.Del("X-Cache")
+		a.responses.put(&cachedResponse{
+			key:     key,
+			status:  recorder.status,
+			header:  header,
+			body:    compressBody(recorder.body.Bytes()),
+			expires: now.Add(a.config.APICacheTTL),
+		}, generation)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCached_ServesAndInvalidates(t *testing.T) {
+	app := newTestApp(t)
+	app.config.APICacheTTL = time.Minute
+	app.responses = newResponseCache(defaultResponseCacheSize)
+	seedExportEvents(t, app.store)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	get := func(query string) (string, string) {
+		t.Helper()
+		res, err := http.Get(server.URL + "/plain" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.Header.Get("X-Cache"), string(body)
+	}
+
+	if cache, _ := get("?district=Mitte&limit=5"); cache != "MISS" {
+		t.Fatalf("expected a miss, got %q", cache)
+	}
+	// The same query in a different order is served from the cache.
+	cache, body := get("?limit=5&district=Mitte")
+	if cache != "HIT" || !strings.Contains(body, "Unfall") {
+		t.Fatalf("expected a hit, got %q:\n%s", cache, body)
+	}
+
+	event, _ := app.store.FindByHash(context.Background(), "e3")
+	event.Title = "Verkehrsunfall"
+	if _, err := app.store.Upsert(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	app.replaceEvent(*event)
+	cache, body = get("?limit=5&district=Mitte")
+	if cache != "MISS" || !strings.Contains(body, "Verkehrsunfall") {
+		t.Fatalf("expected an edit to invalidate the cache, got %q:\n%s", cache, body)
+	}
+
+	// Expired responses are rendered again.
+	app.config.APICacheTTL = -time.Second
+	get("?district=Pankow")
+	if cache, _ := get("?district=Pankow"); cache != "MISS" {
+		t.Fatalf("expected an expired response to miss, got %q", cache)
+	}
+}
+
+func TestCached_ZeroTTLDisablesCache(t *testing.T) {
+	t.Setenv("API_CACHE_TTL", "0")
+	config := loadConfig()
+	if config.APICacheTTL != 0 {
+		t.Fatalf("expected API_CACHE_TTL=0 to be kept, got %s", config.APICacheTTL)
+	}
+
+	app := newTestApp(t)
+	app.config.APICacheTTL = config.APICacheTTL
+	app.responses = newResponseCache(defaultResponseCacheSize)
+	seedExportEvents(t, app.store)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for range 2 {
+		res, err := http.Get(server.URL + "/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if cache := res.Header.Get("X-Cache"); cache != "" {
+			t.Fatalf("expected the cache to be bypassed, got %q", cache)
+		}
+	}
+}
+
+func TestResponseCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResponseCache(10)
+	expires := time.Now().Add(time.Minute)
+	put := func(key, body string) {
+		_, generation := cache.get(key, time.Now())
+		cache.put(&cachedResponse{key: key, status: http.StatusOK, body: compressedBody{body: []byte(body)}, expires: expires}, generation)
+	}
+	put("a", "1234")
+	put("b", "1234")
+	cache.get("a", time.Now())
+	put("c", "1234")
+	if response, _ := cache.get("b", time.Now()); response != nil {
+		t.Fatal("expected b to be evicted")
+	}
+	if response, _ := cache.get("a", time.Now()); response == nil {
+		t.Fatal("expected a to be kept")
+	}
+	put("d", "too large to cache")
+	if response, _ := cache.get("d", time.Now()); response != nil || cache.bytes != 8 {
+		t.Fatalf("expected d not to be cached, %d bytes cached", cache.bytes)
+	}
+
+	// Responses rendered before an invalidation are dropped.
+	_, generation := cache.get("e", time.Now())
+	cache.invalidate()
+	cache.put(&cachedResponse{key: "e", status: http.StatusOK, body: compressedBody{body: []byte("1")}, expires: expires}, generation)
+	if cache.order.Len() != 0 {
+		t.Fatal("expected a stale response not to be cached")
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventRevision is a change to one field of an event that a re-scrape picked
+// up from the police's page, e.g. when a report is amended.
+type EventRevision struct {
+	ID        uint   `gorm:"primaryKey"`
+	EventHash string `gorm:"index;not null"`
+	Field     string
+	Old       string `gorm:"serializer:encrypted"`
+	New       string `gorm:"serializer:encrypted"`
+	CreatedAt time.Time
+}
+
+func (s *gormStore) AddRevisions(ctx context.Context, revisions []EventRevision) error {
+	return s.db.WithContext(ctx).Create(&revisions).Error
+}
+
+func (s *gormStore) Revisions(ctx context.Context, hash string) ([]EventRevision, error) {
+	var revisions []EventRevision
+	err := s.db.WithContext(ctx).Where("event_hash = ?", hash).Order("id").Find(&revisions).Error
+	return revisions, err
+}
+
+// SetChangeSummary stores the summary of the latest revision without touching
+// UpdatedAt, which the re-scrape already set.
+func (s *gormStore) SetChangeSummary(ctx context.Context, hash, summary string) error {
+	return s.db.WithContext(ctx).Model(&Event{}).Where("hash = ?", hash).UpdateColumn("change_summary", summary).Error
+}
+
+// revisionFields are the fields compared between versions of an event, with
+// the names used in the change summary.
+var revisionFields = []struct {
+	name, label string
+	value       func(*Event) string
+}{
+	{"title", "Titel", func(e *Event) string { return e.Title }},
+	{"district", "Bezirk", func(e *Event) string { return e.Location }},
+	{"description", "Beschreibung", func(e *Event) string { return e.Description }},
+	{"link", "Link", func(e *Event) string { return e.Link }},
+}
+
+func diffRevisions(previous, current *Event) []EventRevision {
+	var revisions []EventRevision
+	for _, field := range revisionFields {
+		old, new := field.value(previous), field.value(current)
+		if old != new {
+			revisions = append(revisions, EventRevision{EventHash: current.Hash, Field: field.name, Old: old, New: new})
+		}
+	}
+	return revisions
+}
+
+// changeSummary lists the changed fields for the feed, e.g.
+// "Aktualisiert: Titel, Beschreibung".
+func changeSummary(revisions []EventRevision) string {
+	var labels []string
+	for _, field := range revisionFields {
+		for _, revision := range revisions {
+			if revision.Field == field.name {
+				labels = append(labels, field.label)
+				break
+			}
+		}
+	}
+	return "Aktualisiert: " + strings.Join(labels, ", ")
+}
+
+// recordRevisions stores what a re-scrape changed about an event and sets
+// its change summary, so that the re-published feed item says what changed.
+// Targets that support corrections are sent the amended event.
+func (a *App) recordRevisions(ctx context.Context, previous, current *Event) {
+	revisions := diffRevisions(previous, current)
+	if len(revisions) == 0 {
+		return
+	}
+	err := a.store.AddRevisions(ctx, revisions)
+	if err != nil {
+		log.Println("Error storing revisions:", err)
+		return
+	}
+	current.ChangeSummary = changeSummary(revisions)
+	err = a.store.SetChangeSummary(ctx, current.Hash, current.ChangeSummary)
+	if err != nil {
+		log.Println("Error storing change summary:", err)
+	}
+	changes := make([]string, 0, len(revisions))
+	for _, revision := range revisions {
+		changes = append(changes, revision.Field)
+	}
+	a.correct(ctx, Correction{Type: correctionUpdated, Event: *current, Changes: changes})
+}
+
+// diffOp is a run of words that is unchanged, deleted or inserted.
+type diffOp struct {
+	Kind string // " ", "-" or "+"
+	Text string
+}
+
+var diffTokens = regexp.MustCompile(`\s+|[^\s]+`)
+
+// maxDiffCells bounds the table diffWords builds, as event pages are public.
+// Beyond it, the changed middle is shown as deleted and inserted as a whole.
+const maxDiffCells = 1 << 20
+
+// diffWords compares old and new word by word, based on their longest common
+// subsequence. The common beginning and end are left out of the comparison.
+func diffWords(old, new string) []diffOp {
+	a, b := diffTokens.FindAllString(old, -1), diffTokens.FindAllString(new, -1)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	add := func(kind string, tokens ...string) {
+		for _, text := range tokens {
+			if n := len(ops); n > 0 && ops[n-1].Kind == kind {
+				ops[n-1].Text += text
+				continue
+			}
+			ops = append(ops, diffOp{Kind: kind, Text: text})
+		}
+	}
+	add(" ", a[:prefix]...)
+	oldMiddle, newMiddle := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(oldMiddle)+1)*(len(newMiddle)+1) > maxDiffCells {
+		add("-", oldMiddle...)
+		add("+", newMiddle...)
+	} else {
+		diffTokensLCS(oldMiddle, newMiddle, add)
+	}
+	add(" ", a[len(a)-suffix:]...)
+	return ops
+}
+
+// diffTokensLCS passes the tokens of a and b to add as unchanged, deleted or
+// inserted, based on their longest common subsequence.
+func diffTokensLCS(a, b []string, add func(kind string, tokens ...string)) {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(" ", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add("-", a[i])
+			i++
+		default:
+			add("+", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add("-", a[i])
+	}
+	for ; j < len(b); j++ {
+		add("+", b[j])
+	}
+}
+
+// eventChange is a revision or manual edit as shown on the event page.
+type eventChange struct {
+	time  time.Time
+	At    string
+	Label string
+	Note  string
+	Diff  []diffOp
+}
+
+func fieldLabel(name string) string {
+	for _, field := range revisionFields {
+		if field.name == name {
+			return field.label
+		}
+	}
+	return name
+}
+
+var eventPage = template.Must(template.New("event").Parse(`<!DOCTYPE html>
+<html lang="de">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Event.Title}}</title>
+{{with .Preview}}<meta name="description" content="{{.Description}}">
+<meta property="og:type" content="article">
+<meta property="og:locale" content="de_DE">
+<meta property="og:site_name" content="{{.SiteName}}">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+{{with .URL}}<meta property="og:url" content="{{.}}">
+<link rel="canonical" href="{{.}}">
+{{end}}{{with .Image}}<meta property="og:image" content="{{.}}">
+{{end}}<meta property="article:published_time" content="{{.Published}}">
+{{with .District}}<meta property="article:section" content="{{.}}">
+{{end}}<meta name="twitter:card" content="summary">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+{{end}}<link rel="alternate" type="application/json" href="/api/events/{{.Event.Hash}}">
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+.meta, footer { color: #555; font-size: .9em; }
+del { background: #fdd; } ins { background: #dfd; text-decoration: none; }
+.diff { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Event.Title}}</h1>
+<p class="meta">{{.Published}}{{with .Event.Location}} · {{.}}{{end}}</p>
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end}}
+{{if .Event.Link}}<p><a href="{{.Event.Link}}">Meldung bei der Polizei Berlin</a>{{with .Event.WaybackURL}} · <a href="{{.}}">Archivierte Fassung</a>{{end}}</p>
+{{end}}
+{{- if .Mentions}}
+<h2>Berichterstattung</h2>
+<ul>
+{{range .Mentions}}<li><a href="{{.Source}}">{{or .Title .Source}}</a></li>
+{{end}}</ul>
+{{- end}}
+{{- if .Changes}}
+<h2>Änderungen</h2>
+{{range .Changes}}<h3>{{.At}} – {{.Label}}</h3>
+{{with .Note}}<p class="meta">{{.}}</p>
+{{end}}<p class="diff">{{range .Diff}}{{if eq .Kind "-"}}<del>{{.Text}}</del>{{else if eq .Kind "+"}}<ins>{{.Text}}</ins>{{else}}{{.Text}}{{end}}{{end}}</p>
+{{end}}
+{{- end}}
+<footer><p>{{.Notice}}</p></footer>
+</body>
+</html>
+`))
+
+// previewLength is the most characters of a report used as description in
+// link previews. Messengers cut off longer ones anyway.
+const previewLength = 200
+
+// linkPreview holds the Open Graph and Twitter Card tags of an event page,
+// which Telegram, Discord, Mastodon and others show when a link to it is
+// posted.
+type linkPreview struct {
+	SiteName, Title, Description string
+	URL, Image                   string
+	Published, District          string
+}
+
+// newLinkPreview describes event by its summary if there is one, otherwise by
+// the beginning of its report, preceded by the district.
+func newLinkPreview(feed *FeedBuilder, event *Event, summary string) linkPreview {
+	text := summary
+	if text == "" && event.Description != defaultDescription {
+		text = excerpt(strings.Join(strings.Fields(event.Description), " "), previewLength)
+	}
+	description := event.Location
+	if description != "" && text != "" {
+		description += " – "
+	}
+	preview := linkPreview{
+		Title:       event.Title,
+		Description: description + text,
+		Published:   eventTime(event.DateTime).Format(time.RFC3339),
+		District:    event.Location,
+	}
+	preview.SiteName, preview.URL, preview.Image = feed.pageInfo(event.Hash)
+	return preview
+}
+
+// excerpt shortens text to at most n characters, cutting at a word boundary.
+func excerpt(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	cut := string(runes[:n-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:–-") + "…"
+}
+
+// handleEventPage renders the permalink page of an event, including what the
+// police amended since it was first published, any manual corrections and
+// the coverage received as Webmentions.
+func (a *App) handleEventPage(w http.ResponseWriter, r *http.Request) {
+	event, err := a.store.FindByHash(r.Context(), r.PathValue("hash"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err == nil && event.TakenDownAt != nil {
+		http.Error(w, "Diese Meldung wurde entfernt.", http.StatusGone)
+		return
+	}
+	var revisions []EventRevision
+	if err == nil {
+		revisions, err = a.store.Revisions(r.Context(), event.Hash)
+	}
+	var summaries map[string]string
+	if err == nil {
+		summaries, err = a.store.Summaries(r.Context(), []string{event.Hash})
+	}
+	var mentions []Mention
+	if err == nil && slices.Contains(a.config.Features, featureWebmention) {
+		mentions, err = a.store.Mentions(r.Context(), event.Hash)
+	}
+	if err != nil {
+		log.Println("Error loading event:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	event = a.redactions.event(event)
+	var changes []eventChange
+	for _, revision := range revisions {
+		changes = append(changes, eventChange{
+			time:  revision.CreatedAt,
+			At:    revision.CreatedAt.In(berlin).Format("02.01.2006 15:04"),
+			Label: fieldLabel(revision.Field),
+			Diff:  diffWords(a.redactions.text(revision.Old), a.redactions.text(revision.New)),
+		})
+	}
+	for _, edit := range event.Edits {
+		changes = append(changes, eventChange{
+			time:  edit.At,
+			At:    edit.At.In(berlin).Format("02.01.2006 15:04"),
+			Label: fieldLabel(edit.Field) + " (Korrektur)",
+			Note:  edit.Note,
+			Diff:  diffWords(edit.Old, edit.New),
+		})
+	}
+	slices.SortStableFunc(changes, func(a, b eventChange) int { return a.time.Compare(b.time) })
+
+	var paragraphs []string
+	for _, paragraph := range strings.Split(event.Description, "\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = eventPage.Execute(w, map[string]any{
+		"Event":      event,
+		"Published":  eventTime(event.DateTime).Format("02.01.2006 15:04"),
+		"Paragraphs": paragraphs,
+		"Mentions":   mentions,
+		"Changes":    changes,
+		"Preview":    newLinkPreview(a.feed, event, a.redactions.text(summaries[event.Hash])),
+		"Notice":     newAPIMeta(a.config).notice(),
+	})
+	if err != nil {
+		log.Println("Error rendering event page:", err)
+	}
+}
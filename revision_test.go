@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffWords(t *testing.T) {
+	ops := diffWords("Ein Mann wurde verletzt.", "Ein Mann wurde gestern schwer verletzt.")
+	var old, new, inserted strings.Builder
+	for _, op := range ops {
+		if op.Kind != "+" {
+			old.WriteString(op.Text)
+		}
+		if op.Kind != "-" {
+			new.WriteString(op.Text)
+		}
+		switch op.Kind {
+		case "+":
+			inserted.WriteString(op.Text)
+		case "-":
+			t.Fatalf("unexpected deletion %q", op.Text)
+		}
+	}
+	if old.String() != "Ein Mann wurde verletzt." || new.String() != "Ein Mann wurde gestern schwer verletzt." {
+		t.Fatalf("diff doesn't reproduce the texts: %+v", ops)
+	}
+	if strings.TrimSpace(inserted.String()) != "gestern schwer" || len(ops) != 3 {
+		t.Fatalf("expected a single insertion, got %+v", ops)
+	}
+
+	ops = diffWords("Brand in Mitte", "Brand in Pankow")
+	if len(ops) != 3 || ops[1] != (diffOp{Kind: "-", Text: "Mitte"}) || ops[2] != (diffOp{Kind: "+", Text: "Pankow"}) {
+		t.Fatalf("unexpected diff %+v", ops)
+	}
+
+	// Texts too long to compare word by word are replaced as a whole,
+	// between their common beginning and end.
+	long := strings.Repeat("Wort ", 2000)
+	ops = diffWords("Anfang "+long+"Ende", "Anfang "+strings.ReplaceAll(long, "Wort", "Satz")+"Ende")
+	if len(ops) != 4 || ops[0].Text != "Anfang " || ops[1].Kind != "-" || ops[2].Kind != "+" || ops[3].Text != " Ende" {
+		t.Fatalf("unexpected diff of long texts: %d operations", len(ops))
+	}
+}
+
+func TestEventPage_ShowsChanges(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	previous := Event{Title: "Raub", Hash: "a", Location: "Mitte", Description: "Ein Mann wurde verletzt.", Link: "https://example.com/a"}
+	if err := app.store.Create(ctx, &previous); err != nil {
+		t.Fatal(err)
+	}
+	current := previous
+	current.Description = "Ein Mann wurde schwer verletzt."
+	if err := app.store.Update(ctx, &current); err != nil {
+		t.Fatal(err)
+	}
+	app.recordRevisions(ctx, &previous, &current)
+	if current.ChangeSummary != "Aktualisiert: Beschreibung" {
+		t.Fatalf("unexpected change summary %q", current.ChangeSummary)
+	}
+	// Unchanged events leave no revision.
+	app.recordRevisions(ctx, &current, &current)
+
+	edited := current
+	edited.Edits = []EventEdit{{At: time.Now(), Field: "district", Old: "Mitte", New: "Tiergarten", Note: "Tatort <laut> Nachtrag"}}
+	if err := app.store.Update(ctx, &edited); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/event/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	page := string(body)
+	for _, want := range []string{
+		"<h1>Raub</h1>",
+		"<h2>Änderungen</h2>",
+		"– Beschreibung</h3>",
+		"<ins>schwer </ins>",
+		"– Bezirk (Korrektur)</h3>",
+		"Tatort &lt;laut&gt; Nachtrag",
+		"<del>Mitte</del><ins>Tiergarten</ins>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("page is missing %s:\n%s", want, page)
+		}
+	}
+	if strings.Index(page, "– Beschreibung") > strings.Index(page, "– Bezirk (Korrektur)") {
+		t.Fatalf("expected changes in chronological order:\n%s", page)
+	}
+
+	res, err = http.Get(server.URL + "/event/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", res.StatusCode)
+	}
+}
+
+func TestEventPage_LinkPreview(t *testing.T) {
+	app := newTestApp(t)
+	app.feed.SetBaseURL("https://feed.example.org/")
+	ctx := context.Background()
+	event := Event{Title: "Raub", Hash: "a", Location: "Mitte", DateTime: 1700000000, Description: strings.Repeat("Ein Mann wurde <schwer> verletzt. ", 20)}
+	if err := app.store.Create(ctx, &event); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	page := func() string {
+		res, err := http.Get(server.URL + "/event/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		return string(body)
+	}
+	body := page()
+	for _, want := range []string{
+		`<meta property="og:title" content="Raub">`,
+		`<meta property="og:url" content="https://feed.example.org/event/a">`,
+		`<meta property="article:published_time" content="2023-11-14T22:13:20&#43;01:00">`,
+		`<meta property="article:section" content="Mitte">`,
+		`<meta name="twitter:card" content="summary">`,
+		`<meta property="og:description" content="Mitte – Ein Mann wurde &lt;schwer&gt; verletzt.`,
+		`&lt;schwer&gt;…">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("page is missing %s:\n%s", want, body)
+		}
+	}
+
+	// A summary describes the event better than the start of its report.
+	err := app.store.SaveSummary(ctx, &EventSummary{EventHash: "a", Model: "test", Summary: "Ein Mann wurde bei einem Raub verletzt."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := page(); !strings.Contains(body, `<meta property="og:description" content="Mitte – Ein Mann wurde bei einem Raub verletzt.">`) {
+		t.Fatalf("expected the summary as description:\n%s", body)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got := excerpt("kurz", 10); got != "kurz" {
+		t.Fatalf("unexpected excerpt %q", got)
+	}
+	if got := excerpt("Brand in Mitte, Pankow und Spandau", 20); got != "Brand in Mitte…" {
+		t.Fatalf("unexpected excerpt %q", got)
+	}
+}
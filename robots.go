@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// robotsTxt returns the content of /robots.txt: ROBOTS_FILE if set, otherwise
+// a policy that lets crawlers read the feeds and event pages but keeps them
+// out of /admin/ and the paths in ROBOTS_DISALLOW. With BASE_URL it points to
+// the sitemap.
+func robotsTxt(config Config) ([]byte, error) {
+	if config.RobotsFile != "" {
+		data, err := os.ReadFile(config.RobotsFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading robots.txt: %w", err)
+		}
+		return data, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	b.WriteString("Disallow: /admin/\n")
+	for _, path := range config.RobotsDisallow {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("ROBOTS_DISALLOW: %q is not a path", path)
+		}
+		b.WriteString("Disallow: " + path + "\n")
+	}
+	b.WriteString("Allow: /\n")
+	if config.BaseURL != "" {
+		b.WriteString("\nSitemap: " + strings.TrimSuffix(config.BaseURL, "/") + "/sitemap.xml\n")
+	}
+	return []byte(b.String()), nil
+}
+
+func (a *App) handleRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(a.robots)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRobotsTxt(t *testing.T) {
+	robots, err := robotsTxt(Config{BaseURL: "https://feed.example.org/", RobotsDisallow: []string{"/api/", "/export/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "User-agent: *\nDisallow: /admin/\nDisallow: /api/\nDisallow: /export/\nAllow: /\n\nSitemap: https://feed.example.org/sitemap.xml\n"
+	if string(robots) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", robots, want)
+	}
+
+	if _, err := robotsTxt(Config{RobotsDisallow: []string{"api"}}); err == nil {
+		t.Error("expected a relative path to be rejected")
+	}
+
+	path := filepath.Join(t.TempDir(), "robots.txt")
+	if err := os.WriteFile(path, []byte("User-agent: *\nDisallow: /\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	robots, err = robotsTxt(Config{RobotsFile: path, RobotsDisallow: []string{"/api/"}})
+	if err != nil || string(robots) != "User-agent: *\nDisallow: /\n" {
+		t.Errorf("expected ROBOTS_FILE to be served as is, got %q, %v", robots, err)
+	}
+}
+
+func TestRoutes_RobotsHeaders(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/robots.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if !strings.Contains(string(body), "Disallow: /admin/") || res.Header.Get("X-Robots-Tag") != "" {
+		t.Fatalf("unexpected robots.txt %q with X-Robots-Tag %q", body, res.Header.Get("X-Robots-Tag"))
+	}
+
+	res, err = http.Get(server.URL + "/api/audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if got := res.Header.Get("X-Robots-Tag"); got != "noindex, nofollow" {
+		t.Fatalf("expected X-Robots-Tag on admin endpoints, got %q", got)
+	}
+}
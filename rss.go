@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// Sources publishing an RSS feed are read with fetchRSS.
+
+// rssFeedTypes are the content types RSS feeds are served with.
+var rssFeedTypes = []string{"application/rss+xml", "application/xml", "text/xml"}
+
+// rssDocument is the part of an RSS feed sources read.
+type rssDocument struct {
+	Items []rssDocumentItem `xml:"channel>item"`
+}
+
+type rssDocumentItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// fetchRSS fetches the RSS feed at feedURL, with the same size limit as pages
+// (see maxPageSize).
+func fetchRSS(ctx context.Context, client HTTPDoer, feedURL, userAgent string) (*rssDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", strings.Join(rssFeedTypes, ", "))
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainBody(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", feedURL, res.Status)
+	}
+	contentType := res.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !strings.HasSuffix(mediaType, "xml") {
+		return nil, fmt.Errorf("%w: content type %q is not XML", errUnexpectedPage, contentType)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	err = checkPageSize(body)
+	if err != nil {
+		return nil, err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	var document rssDocument
+	err = decoder.Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnexpectedPage, err)
+	}
+	return &document, nil
+}
+
+// parseRSSTime parses the pubDate of an RSS item.
+func parseRSSTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	t, err := time.Parse(time.RFC1123Z, value)
+	if err != nil {
+		return time.Parse(time.RFC1123, value)
+	}
+	return t, nil
+}
+
+// htmlText returns the text of an HTML fragment, with line breaks and runs of
+// white space collapsed to single spaces.
+func htmlText(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return strings.Join(strings.Fields(fragment), " ")
+	}
+	doc.Find("br").ReplaceWithHtml(" ")
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var errScrapeInProgress = errors.New("previous scrape run is still in progress")
+
+// watchdogGrace is how long a timed out run may take to unwind before the
+// watchdog stops waiting for it.
+const watchdogGrace = 30 * time.Second
+
+// ScrapeStatus describes the outcome of the most recent scrape runs.
+type ScrapeStatus struct {
+	LastRun             time.Time `json:"lastRun"`
+	LastSuccess         time.Time `json:"lastSuccess"`
+	LastError           string    `json:"lastError,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	NextRun             time.Time `json:"nextRun"`
+	RetryPending        bool      `json:"retryPending"`
+	// PriorityUntil is set while breaking events shorten the interval, see
+	// startPriorityLane.
+	PriorityUntil *time.Time `json:"priorityUntil,omitempty"`
+}
+
+type scrapeState struct {
+	mu     sync.Mutex
+	status ScrapeStatus
+}
+
+func (s *scrapeState) record(started time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastRun = started
+	if err != nil {
+		s.status.LastError = err.Error()
+		s.status.ConsecutiveFailures++
+		return
+	}
+	s.status.LastSuccess = started
+	s.status.LastError = ""
+	s.status.ConsecutiveFailures = 0
+}
+
+// restore seeds the state with a successful run from a previous process.
+func (s *scrapeState) restore(lastSuccess time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastRun = lastSuccess
+	s.status.LastSuccess = lastSuccess
+}
+
+func (s *scrapeState) scheduled(next time.Time, retry bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.NextRun = next
+	s.status.RetryPending = retry
+}
+
+// prioritize extends the priority lane until the given time.
+func (s *scrapeState) prioritize(until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.PriorityUntil == nil || s.status.PriorityUntil.Before(until) {
+		s.status.PriorityUntil = &until
+	}
+}
+
+func (s *scrapeState) snapshot() ScrapeStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// runScrape performs one scrape run bounded by the configured timeout and
+// records its outcome.
+func (a *App) runScrape(ctx context.Context) error {
+	if !a.scrapeMu.TryLock() {
+		return errScrapeInProgress
+	}
+
+	started := time.Now()
+	a.lifecycle(ctx, lifecyclePayload{Type: lifecycleStarted})
+	// The run is only read once the scrape returned; after a watchdog
+	// timeout it may still be written to.
+	run := &scrapeRun{}
+	err := runWithWatchdog(ctx, a.config.ScrapeTimeout, watchdogGrace, func(ctx context.Context) error {
+		defer a.scrapeMu.Unlock()
+		return a.scrapeInto(ctx, run)
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("scrape run exceeded %s: %w", a.config.ScrapeTimeout, err)
+	}
+	seconds := time.Since(started).Seconds()
+	if errors.Is(err, errMaintenance) {
+		log.Println("Scrape run aborted:", err)
+		a.lifecycle(ctx, lifecyclePayload{Type: lifecycleMaintenance, Seconds: seconds, Error: err.Error()})
+	} else if err != nil {
+		a.lifecycle(ctx, lifecyclePayload{Type: lifecycleFailed, Seconds: seconds, Error: err.Error()})
+	} else {
+		finished := *run
+		if outcome := finished.outcome(); outcome != lifecycleSucceeded {
+			log.Printf("Scrape run found %d lists with %d items, %d unparsed: %s", finished.Lists, finished.Items, finished.Unparsed, outcome)
+		}
+		a.lifecycle(ctx, lifecyclePayload{Type: finished.outcome(), Seconds: seconds, Run: &finished})
+		if finished.outcome() == lifecycleSucceeded {
+			a.ping(ctx, sourceBerlin)
+		}
+	}
+
+	a.scrapeState.record(started, err)
+	if err == nil {
+		err := a.store.SetLastScrape(ctx, started)
+		if err != nil {
+			log.Println("Error persisting last scrape time:", err)
+		}
+		_, err = a.checkQuality(ctx)
+		if err != nil {
+			log.Println("Error checking data quality:", err)
+		}
+	}
+	return err
+}
+
+// runWithWatchdog runs fn with a context that expires after timeout. Should fn
+// not return within grace of its deadline (e.g. because something blocks
+// without honouring the context), the watchdog stops waiting for it and
+// reports the deadline as the error.
+func runWithWatchdog(ctx context.Context, timeout, grace time.Duration, fn func(context.Context) error) error {
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		err := fn(runCtx)
+		if err == nil {
+			err = runCtx.Err()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-runCtx.Done():
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(grace):
+		return runCtx.Err()
+	}
+}
+
+// retryDelay picks the backoff for the given number of consecutive failures,
+// sticking with the last configured delay once they are exhausted. A retry is
+// never scheduled later than the next regular run would be.
+func retryDelay(delays []time.Duration, failures int, interval time.Duration) time.Duration {
+	if len(delays) == 0 || failures < 1 {
+		return interval
+	}
+	delay := delays[min(failures, len(delays))-1]
+	return min(delay, interval)
+}
+
+// startupDelay returns how long to wait before the first scrape run. If the
+// last successful run is younger than freshness, the startup scrape is skipped
+// and the first run happens when the regular interval would have been due.
+func startupDelay(lastScrape, now time.Time, freshness, interval time.Duration) time.Duration {
+	if lastScrape.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(lastScrape)
+	if elapsed < 0 || elapsed >= freshness {
+		return 0
+	}
+	return max(interval-elapsed, 0)
+}
+
+// schedule scrapes immediately (unless a recent run makes that unnecessary)
+// and then every ScrapeInterval until ctx is cancelled. Failed runs are
+// retried with an increasing delay taken from ScrapeRetryDelays instead of
+// waiting for the next regular run, and breaking events shorten the interval
+// for a while.
+func (a *App) schedule(ctx context.Context) {
+	lastScrape, err := a.store.LastScrape(ctx)
+	if err != nil {
+		log.Println("Error loading last scrape time:", err)
+	}
+
+	delay := startupDelay(lastScrape, time.Now(), a.config.ScrapeFreshness, a.config.ScrapeInterval)
+	if delay > 0 {
+		log.Printf("Last scrape ran at %s, skipping startup scrape", lastScrape.Format(time.RFC3339))
+		a.scrapeState.restore(lastScrape)
+		a.scrapeState.scheduled(time.Now().Add(delay), false)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		next := a.config.ScrapeInterval
+		err := a.runScrape(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Println("Error scraping:", err)
+			failures := a.scrapeState.snapshot().ConsecutiveFailures
+			next = retryDelay(a.config.ScrapeRetryDelays, failures, a.config.ScrapeInterval)
+			log.Printf("Retrying scrape in %s (attempt %d)", next, failures+1)
+		}
+		next = a.nextScrape(next, time.Now())
+
+		a.scrapeState.scheduled(time.Now().Add(next), err != nil)
+		timer.Reset(next)
+	}
+}
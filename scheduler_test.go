@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunWithWatchdog_Success(t *testing.T) {
+	err := runWithWatchdog(context.Background(), time.Second, time.Second, func(ctx context.Context) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRunWithWatchdog_CancelsOnTimeout(t *testing.T) {
+	err := runWithWatchdog(context.Background(), 10*time.Millisecond, time.Second, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRunWithWatchdog_AbandonsHungRun(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	err := runWithWatchdog(context.Background(), 10*time.Millisecond, 10*time.Millisecond, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("watchdog waited too long for hung run")
+	}
+}
+
+func TestScrapeState_Record(t *testing.T) {
+	var s scrapeState
+	now := time.Now()
+
+	s.record(now, errors.New("boom"))
+	s.record(now, errors.New("boom"))
+	status := s.snapshot()
+	if status.ConsecutiveFailures != 2 || status.LastError != "boom" {
+		t.Fatalf("unexpected status after failures: %+v", status)
+	}
+
+	s.record(now, nil)
+	status = s.snapshot()
+	if status.ConsecutiveFailures != 0 || status.LastError != "" || !status.LastSuccess.Equal(now) {
+		t.Fatalf("unexpected status after success: %+v", status)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	delays := []time.Duration{5 * time.Minute, 15 * time.Minute, 30 * time.Minute}
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, time.Hour},
+		{1, 5 * time.Minute},
+		{2, 15 * time.Minute},
+		{3, 30 * time.Minute},
+		{7, 30 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := retryDelay(delays, c.failures, time.Hour); got != c.want {
+			t.Fatalf("retryDelay(%d) = %s, want %s", c.failures, got, c.want)
+		}
+	}
+	if got := retryDelay(delays, 3, 10*time.Minute); got != 10*time.Minute {
+		t.Fatalf("expected retry to be capped at interval, got %s", got)
+	}
+}
+
+func TestStartupDelay(t *testing.T) {
+	now := time.Now()
+	if got := startupDelay(time.Time{}, now, 15*time.Minute, time.Hour); got != 0 {
+		t.Fatalf("expected immediate scrape without previous run, got %s", got)
+	}
+	if got := startupDelay(now.Add(-20*time.Minute), now, 15*time.Minute, time.Hour); got != 0 {
+		t.Fatalf("expected immediate scrape for stale run, got %s", got)
+	}
+	if got := startupDelay(now.Add(-5*time.Minute), now, 15*time.Minute, time.Hour); got != 55*time.Minute {
+		t.Fatalf("expected startup scrape to be skipped, got %s", got)
+	}
+}
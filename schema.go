@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigration marks a migration as applied.
+type SchemaMigration struct {
+	ID        string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+// migration is a schema change that AutoMigrate can't express safely. Every
+// step has to be idempotent: on Postgres migrations run online and outside a
+// transaction (CREATE INDEX CONCURRENTLY can't run inside one), so a failed
+// migration is completed by simply running it again. On SQLite the whole
+// migration runs in a transaction instead.
+type migration struct {
+	ID          string
+	Description string
+	// Flag names the feature flag that enables the migration. Migrations
+	// without a flag are always applied.
+	Flag string
+	Up   func(db *gorm.DB) error
+}
+
+const featureEventMetadata = "event_metadata"
+
+var migrations = []migration{
+	{
+		ID:          "0001_events_hidden",
+		Description: "add events.hidden for hiding events from all outputs",
+		Flag:        featureEventMetadata,
+		Up: func(db *gorm.DB) error {
+			return addColumn(db, "events", "hidden", "boolean NOT NULL DEFAULT false")
+		},
+	},
+	{
+		ID:          "0002_events_severity",
+		Description: "add events.severity for keyword based severity levels",
+		Flag:        featureEventMetadata,
+		Up: func(db *gorm.DB) error {
+			return addColumn(db, "events", "severity", "integer NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		ID:          "0003_events_category",
+		Description: "add indexed events.category for keyword based categories",
+		Flag:        featureEventMetadata,
+		Up: func(db *gorm.DB) error {
+			err := addColumn(db, "events", "category", "text NOT NULL DEFAULT ''")
+			if err != nil {
+				return err
+			}
+			return createIndex(db, "idx_events_category", "events", "category")
+		},
+	},
+	{
+		ID:          "0004_events_hashes",
+		Description: "compute identity and content hashes of existing events",
+		Up:          backfillEventHashes,
+	},
+	{
+		ID:          "0005_event_changes",
+		Description: "record existing events as created for /api/sync",
+		Up:          backfillEventChanges,
+	},
+}
+
+// addColumn adds a column unless it already exists. Constant defaults keep
+// this a metadata-only change on Postgres 11+, so it doesn't rewrite the table.
+func addColumn(db *gorm.DB, table, column, definition string) error {
+	if db.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)).Error
+}
+
+// createIndex builds an index without blocking writes on Postgres.
+func createIndex(db *gorm.DB, name, table, column string) error {
+	concurrently := ""
+	if isPostgres(db) {
+		concurrently = "CONCURRENTLY "
+	}
+	return db.Exec(fmt.Sprintf("CREATE INDEX %sIF NOT EXISTS %s ON %s (%s)", concurrently, name, table, column)).Error
+}
+
+type migrationStatus struct {
+	migration
+	Applied bool
+	Enabled bool
+}
+
+func migrationStatuses(db *gorm.DB, features []string) ([]migrationStatus, error) {
+	var applied []string
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		err := db.Model(&SchemaMigration{}).Pluck("id", &applied).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]migrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		statuses = append(statuses, migrationStatus{
+			migration: m,
+			Applied:   slices.Contains(applied, m.ID),
+			Enabled:   m.Flag == "" || slices.Contains(features, m.Flag),
+		})
+	}
+	return statuses, nil
+}
+
+// applyMigrations runs all pending migrations whose feature flag is enabled.
+func applyMigrations(db *gorm.DB, features []string) error {
+	statuses, err := migrationStatuses(db, features)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		if status.Applied || !status.Enabled {
+			continue
+		}
+		log.Printf("Applying migration %s: %s", status.ID, status.Description)
+
+		run := func(tx *gorm.DB) error {
+			err := status.Up(tx)
+			if err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: status.ID, AppliedAt: time.Now()}).Error
+		}
+		if isPostgres(db) {
+			err = run(db)
+		} else {
+			err = db.Transaction(run)
+		}
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", status.ID, err)
+		}
+	}
+	return nil
+}
+
+var errPendingMigrations = errors.New("there are pending migrations")
+
+// runMigrate implements the migrate command. It applies pending migrations,
+// or with -check only reports them and fails if any enabled one is pending.
+func runMigrate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	check := flags.Bool("check", false, "only report pending migrations")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	db, err := openConfiguredDatabase(config)
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+
+	if !*check {
+		_, err = NewGormStore(db)
+		if err != nil {
+			return err
+		}
+		return applyMigrations(db, config.Features)
+	}
+
+	statuses, err := migrationStatuses(db, config.Features)
+	if err != nil {
+		return err
+	}
+
+	pending := false
+	for _, status := range statuses {
+		state := "applied"
+		switch {
+		case status.Applied:
+		case status.Enabled:
+			state = "pending"
+			pending = true
+		default:
+			state = "disabled (feature " + status.Flag + ")"
+		}
+		fmt.Printf("%-24s %-32s %s\n", status.ID, state, status.Description)
+	}
+	if pending {
+		return errPendingMigrations
+	}
+	return nil
+}
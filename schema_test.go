@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestApplyMigrations_RespectsFeatureFlags(t *testing.T) {
+	_, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	if err := applyMigrations(db, nil); err != nil {
+		t.Fatalf("applyMigrations without features failed: %v", err)
+	}
+	if db.Migrator().HasColumn("events", "hidden") {
+		t.Fatalf("flagged migration applied without its feature flag")
+	}
+
+	statuses, err := migrationStatuses(db, nil)
+	if err != nil {
+		t.Fatalf("migrationStatuses failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Flag == "" {
+			if !status.Applied {
+				t.Fatalf("expected unflagged %s to be applied", status.ID)
+			}
+			continue
+		}
+		if status.Applied || status.Enabled {
+			t.Fatalf("expected %s to be disabled and pending, got %+v", status.ID, status)
+		}
+	}
+
+	features := []string{featureEventMetadata}
+	if err := applyMigrations(db, features); err != nil {
+		t.Fatalf("applyMigrations failed: %v", err)
+	}
+	for _, column := range []string{"hidden", "severity", "category"} {
+		if !db.Migrator().HasColumn("events", column) {
+			t.Fatalf("expected column %s to exist", column)
+		}
+	}
+	if !db.Migrator().HasIndex("events", "idx_events_category") {
+		t.Fatalf("expected category index to exist")
+	}
+
+	// Running again must be a no-op.
+	if err := applyMigrations(db, features); err != nil {
+		t.Fatalf("second applyMigrations failed: %v", err)
+	}
+	statuses, err = migrationStatuses(db, features)
+	if err != nil {
+		t.Fatalf("migrationStatuses failed: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Fatalf("expected %s to be applied", status.ID)
+		}
+	}
+
+	// Events can still be written with the extra columns present.
+	store, err := NewGormStore(db)
+	if err != nil {
+		t.Fatalf("NewGormStore failed: %v", err)
+	}
+	if err := store.Create(t.Context(), &Event{Hash: "after-migration"}); err != nil {
+		t.Fatalf("create after migration failed: %v", err)
+	}
+}
+
+func TestBackfillEventHashes(t *testing.T) {
+	_, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	event := Event{Title: "t", Description: "d", Link: "https://www.berlin.de/x", Hash: "old"}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	// Simulate a row written before the hashes existed.
+	if err := db.Model(&event).UpdateColumns(map[string]any{"identity_hash": "", "content_hash": ""}).Error; err != nil {
+		t.Fatalf("clearing hashes failed: %v", err)
+	}
+
+	if err := backfillEventHashes(db); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+	var stored Event
+	if err := db.First(&stored, event.ID).Error; err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if stored.IdentityHash != identityHash(&event) || stored.ContentHash != contentHash(&event) {
+		t.Fatalf("hashes not backfilled: %+v", stored)
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	defaultDescription = "Keine Beschreibung gefunden"
+	eventLinkPrefix    = "https://www.berlin.de"
+
+	// defaultUserAgent identifies the scraper honestly, so that berlin.de can
+	// tell who is fetching and whom to contact.
+	defaultUserAgent = "berlin-police-feed/1.2 (+https://github.com/Luiggi33/berlin-police-feed; github@luiggi33.de)"
+	// featureStealth rotates browser user agents instead, for when honest
+	// requests get blocked.
+	featureStealth = "stealth"
+
+	// detailFetchTimeout bounds a shared detail fetch, which no longer ends
+	// with the run that started it (see fetchDetail). It leaves room for all
+	// retries, including their waits after 429 responses.
+	detailFetchTimeout = 5 * time.Minute
+)
+
+// stealthUserAgents are rotated through with the stealth feature flag.
+var stealthUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
+}
+
+// userAgents returns the user agents to send to berlin.de, rotated through
+// by attempt.
+func (a *App) userAgents() []string {
+	if slices.Contains(a.config.Features, featureStealth) {
+		return stealthUserAgents
+	}
+	return []string{a.config.UserAgent}
+}
+
+type MetaTag struct {
+	Name    string
+	Content string
+}
+
+// detailPage is what the scraper uses from the page of a single report.
+type detailPage struct {
+	MetaTags []MetaTag
+	// Sections are the headed parts of the report text, see reportSections.
+	Sections []reportSection
+	// HasText is false if the page came without the report text, e.g.
+	// because a consent banner hides it, leaving only the meta tags.
+	HasText bool
+}
+
+func extractMetaTags(ctx context.Context, client HTTPDoer, url string, userAgents []string) ([]MetaTag, error) {
+	page, err := fetchDetailPage(ctx, client, url, userAgents)
+	if err != nil {
+		return nil, err
+	}
+	return page.MetaTags, nil
+}
+
+func fetchDetailPage(ctx context.Context, client HTTPDoer, url string, userAgents []string) (*detailPage, error) {
+	maxRetries := 3
+	var lastErr error
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<uint(attempt)) * time.Second
+			jitter := time.Duration(rand.Float64() * float64(backoff))
+			if err := sleepContext(ctx, backoff+jitter); err != nil {
+				return nil, err
+			}
+		}
+
+		page, status, err := fetchDetailAttempt(ctx, client, url, userAgents[attempt%len(userAgents)])
+		if err == nil {
+			return page, nil
+		}
+		lastErr = err
+		if status == 0 {
+			log.Printf("Attempt %d failed: %v\n", attempt+1, err)
+			continue
+		}
+		log.Printf("Attempt %d failed with status %d\n", attempt+1, status)
+		// 429 (Too Many Requests)
+		if status == 429 {
+			if err := sleepContext(ctx, time.Duration(30+rand.Intn(30))*time.Second); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("failed after %d attempts, last error: %v", maxRetries, lastErr)
+}
+
+// fetchDetailAttempt makes a single request for a detail page. It returns the
+// status of unsuccessful responses, or 0 if there was none. The body is
+// drained before the attempt returns, so the connection is reused by the
+// next request instead of setting up a new one.
+func fetchDetailAttempt(ctx context.Context, client HTTPDoer, url, userAgent string) (*detailPage, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer drainBody(res.Body)
+
+	if res.StatusCode != 200 {
+		return nil, res.StatusCode, errors.New(res.Status)
+	}
+
+	doc, err := readPage(res)
+	if err != nil {
+		return nil, 0, err
+	}
+	if marker := maintenanceMarker(doc.Selection, "div.textile"); marker != "" {
+		return nil, 0, maintenanceError(marker)
+	}
+
+	var metaTags []MetaTag
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		metaTag := MetaTag{}
+		if name, exists := s.Attr("name"); exists {
+			metaTag.Name = name
+			metaTag.Content = s.AttrOr("content", "")
+		} else if property, exists := s.Attr("property"); exists {
+			metaTag.Name = property
+			metaTag.Content = s.AttrOr("content", "")
+		}
+		metaTags = append(metaTags, metaTag)
+	})
+
+	return &detailPage{MetaTags: metaTags, Sections: reportSections(doc), HasText: doc.Find("div.textile").Length() > 0}, 0, nil
+}
+
+// fetchDetail fetches a detail page once for all callers asking for it at the
+// same time, e.g. a scrape run and one abandoned by the watchdog that is
+// still going. The page is shared between them and must not be modified.
+//
+// The fetch runs on its own context, as the caller who started it may be the
+// abandoned run whose context is already cancelled. Each caller stops waiting
+// once its own context is done.
+func (a *App) fetchDetail(ctx context.Context, link string) (*detailPage, error) {
+	fetched := a.detailFetches.DoChan(link, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), detailFetchTimeout)
+		defer cancel()
+		return fetchDetailPage(fetchCtx, a.client, link, a.userAgents())
+	})
+	select {
+	case result := <-fetched:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*detailPage), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// sleepContext waits for d to elapse, returning early with the context's
+// error if it is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newCollector builds a collector for a single scrape run. A fresh collector
+// is needed per run, as colly refuses to revisit a URL it has already seen.
+func (a *App) newCollector(ctx context.Context, run *scrapeRun) *colly.Collector {
+	c := colly.NewCollector(
+		colly.AllowedDomains(a.allowedDomains(a.berlinSource())...),
+		colly.StdlibContext(ctx),
+	)
+	// Share the app's transport, so list pages count towards the same rate
+	// limit as detail pages.
+	c.WithTransport(a.transport())
+	if jar := a.cookieJar(); jar != nil {
+		c.SetCookieJar(jar)
+	}
+
+	userAgents := a.userAgents()
+	visits := 0
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("User-Agent", userAgents[visits%len(userAgents)])
+		visits++
+		log.Println("Visiting:", r.URL)
+	})
+
+	c.OnError(func(_ *colly.Response, err error) {
+		log.Println("Something went wrong:", err)
+	})
+
+	var newEvents []Event
+	// known holds the hashes in newEvents that are already stored, i.e. that
+	// are refreshed rather than created.
+	known := make(map[string]bool)
+	// journaled holds the hashes journaled by this run, see JournalEntry.
+	journaled := make(map[string]bool)
+
+	// One more byte than allowed, to tell pages at the limit from larger ones.
+	c.MaxBodySize = maxPageSize + 1
+	c.OnResponseHeaders(func(r *colly.Response) {
+		if err := checkPageHeaders(*r.Headers); err != nil {
+			run.aborted = err
+			r.Request.Abort()
+		}
+	})
+	c.OnResponse(func(r *colly.Response) {
+		if err := checkPageSize(r.Body); err != nil {
+			run.aborted = err
+			return
+		}
+		// colly only converts pages whose Content-Type names a charset,
+		// those that declare theirs in a <meta> tag are left to decodePage.
+		if strings.Contains(strings.ToLower(r.Headers.Get("Content-Type")), "charset") {
+			return
+		}
+		body, err := decodePage(r.Body, "text/html")
+		if err != nil {
+			run.aborted = err
+			return
+		}
+		r.Body = body
+	})
+	// Registered first, so it runs before the callbacks reading the page.
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		if run.aborted != nil {
+			return
+		}
+		if marker := maintenanceMarker(e.DOM, "ul.list--tablelist"); marker != "" {
+			log.Printf("%s is a maintenance page, skipping it", e.Request.URL)
+			run.aborted = maintenanceError(marker)
+		}
+	})
+	c.OnHTML("ul.list--tablelist", func(*colly.HTMLElement) {
+		if run.aborted != nil {
+			return
+		}
+		run.Lists++
+	})
+	c.OnHTML("ul.list--tablelist > li", func(e *colly.HTMLElement) {
+		if run.aborted != nil {
+			return
+		}
+		event := Event{}
+		run.Items++
+
+		t, err := time.Parse("02.01.2006 15:04 Uhr", e.ChildText("div.cell.nowrap.date"))
+		if err != nil {
+			log.Println("Error parsing date:", err)
+			run.Unparsed++
+			return
+		}
+		event.DateTime = t.Unix()
+		event.Title = e.ChildText("a")
+		event.Link = eventLinkPrefix + e.ChildAttr("a", "href")
+		event.Location = strings.TrimPrefix(e.ChildText("span.category"), "Ereignisort: ")
+		event.Description = defaultDescription
+		event.Source = sourceBerlin
+
+		event.Hash = eventHash("", &event)
+
+		exists, _ := checkDuplicate(ctx, &event, a.store, a.seen)
+		if exists {
+			stored, err := a.store.FindByIdentity(ctx, identityHash(&event))
+			if err != nil || len(stored.Edits) > 0 || stored.TakenDownAt != nil {
+				// Manual corrections and takedowns take precedence over the
+				// upstream page.
+				return
+			}
+			// Keep the public ID, even if the title was amended.
+			event.Hash = stored.Hash
+			if stored.Description != defaultDescription && stored.Title == event.Title {
+				// Only a missing description or an amended title ("Nachtrag")
+				// is worth fetching the detail page again for; the rest is
+				// refreshed from the list page.
+				event.Description = stored.Description
+				event.ParentHash = stored.ParentHash
+				setIncidentTime(&event)
+				known[event.Hash] = true
+				newEvents = append(newEvents, event)
+				return
+			}
+		}
+
+		// Journal the report first, so it isn't lost if the run crashes or the
+		// detail page can't be fetched.
+		journaled[event.Hash] = true
+		err = a.store.SaveJournalEntry(ctx, &event)
+		if err != nil {
+			log.Println("Error journaling event:", err)
+		}
+		events, err := a.enrichEvent(ctx, event)
+		if err != nil {
+			log.Println("Error extracting meta tags:", err)
+			return
+		}
+		for _, e := range events {
+			known[e.Hash], _ = checkDuplicate(ctx, &e, a.store, a.seen)
+			newEvents = append(newEvents, e)
+		}
+	})
+
+	c.OnScraped(func(r *colly.Response) {
+		if run.aborted != nil {
+			return
+		}
+		log.Printf("%s scraped, collected %d events!", r.Request.URL, len(newEvents))
+		for _, e := range a.resumeJournal(ctx, journaled) {
+			known[e.Hash], _ = checkDuplicate(ctx, &e, a.store, a.seen)
+			newEvents = append(newEvents, e)
+		}
+
+		stored, created, updated := a.storeEvents(ctx, newEvents, known)
+		err := a.store.DeleteJournalEntries(ctx, stored)
+		if err != nil {
+			log.Println("Error cleaning up scrape journal:", err)
+		}
+		a.startPriorityLane(created, time.Now())
+		run.Created += len(created)
+		run.Updated += len(updated)
+
+		newEvents = nil
+		clear(known)
+		clear(journaled)
+	})
+
+	return c
+}
+
+// storeEvents writes scraped events, of which known tells those stored
+// already, and publishes them: new events go to the feed and the notifiers,
+// changed ones replace their feed items. It returns the hashes written and
+// the events created and updated.
+func (a *App) storeEvents(ctx context.Context, events []Event, known map[string]bool) (stored []string, created, updated []Event) {
+	for _, event := range events {
+		var previous *Event
+		if known[event.Hash] {
+			previous, _ = a.store.FindByHash(ctx, event.Hash)
+		}
+		written, err := a.store.Upsert(ctx, &event)
+		if err != nil {
+			log.Println("Error storing event:", err)
+			continue
+		}
+		stored = append(stored, event.Hash)
+		if !known[event.Hash] {
+			created = append(created, event)
+			continue
+		}
+		if !written {
+			continue
+		}
+		// Reload to get the complete row, including UpdatedAt.
+		reloaded, err := a.store.FindByHash(ctx, event.Hash)
+		if err != nil {
+			log.Println("Error reloading updated event:", err)
+			continue
+		}
+		if previous != nil {
+			a.recordRevisions(ctx, previous, reloaded)
+		}
+		updated = append(updated, *reloaded)
+	}
+	a.updateFacts(ctx, append(created, updated...)...)
+
+	for _, event := range created {
+		a.seen.Add(event.IdentityHash)
+	}
+
+	if len(created) > 0 {
+		log.Printf("Added %d new events", len(created))
+	}
+	// Only reports of berlin.de make up the feed, so only those reach its
+	// subscribers; the other sources are served by their own endpoints.
+	if berlin := slices.DeleteFunc(slices.Clone(created), func(e Event) bool { return !e.fromBerlin() }); len(berlin) > 0 {
+		a.feed.Add(berlin...)
+		a.notifyInBackground(ctx, a.notifiers, berlin)
+		a.live.publish(a.redactions.events(berlin))
+	}
+	for _, event := range updated {
+		a.feed.Replace(event)
+	}
+	if len(updated) > 0 {
+		log.Printf("Refreshed %d changed events", len(updated))
+	}
+	if len(created) > 0 || len(updated) > 0 {
+		a.responses.invalidate()
+	}
+	return stored, created, updated
+}
+
+// enrichEvent completes a report read from the list page with its detail
+// page. It returns the report followed by the incidents split off it.
+func (a *App) enrichEvent(ctx context.Context, event Event) ([]Event, error) {
+	page, err := a.fetchDetail(ctx, event.Link)
+	if err != nil {
+		return nil, err
+	}
+	if !page.HasText {
+		log.Printf("%s has no report text, check SCRAPE_COOKIES and SCRAPE_SESSION_URLS", event.Link)
+	}
+	descriptionIdx := slices.IndexFunc(page.MetaTags, func(tag MetaTag) bool { return tag.Name == "description" })
+	if descriptionIdx != -1 {
+		event.Description = page.MetaTags[descriptionIdx].Content
+	}
+	setIncidentTime(&event)
+	return append([]Event{event}, splitReport(&event, page.Sections, a.districtMap())...), nil
+}
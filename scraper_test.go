@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExtractMetaTags_Success(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		fmt.Fprintln(w, `<!doctype html><html><head>
+            <meta name="description" content="desc">
+            <meta property="og:title" content="otitle">
+            </head><body>ok</body></html>`)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	t.Log("calling extractMetaTags on", server.URL)
+	tags, err := extractMetaTags(context.Background(), server.Client(), server.URL, []string{defaultUserAgent})
+	if err != nil {
+		t.Fatalf("extractMetaTags error: %v", err)
+	}
+	if len(tags) < 2 {
+		t.Fatalf("expected at least 2 meta tags, got %d", len(tags))
+	}
+	foundDesc := false
+	foundOG := false
+	for _, mt := range tags {
+		if mt.Name == "description" && mt.Content == "desc" {
+			foundDesc = true
+		}
+		if mt.Name == "og:title" && mt.Content == "otitle" {
+			foundOG = true
+		}
+	}
+	if !foundDesc {
+		t.Fatalf("description meta not found or incorrect")
+	}
+	if !foundOG {
+		t.Fatalf("og:title meta not found or incorrect")
+	}
+}
+
+func TestExtractMetaTags_RetryThenSuccess(t *testing.T) {
+	var calls int
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(500)
+			fmt.Fprintln(w, "error")
+			return
+		}
+		w.WriteHeader(200)
+		fmt.Fprintln(w, `<!doctype html><html><head>
+            <meta name="description" content="afterretry">
+            </head><body>ok</body></html>`)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	tags, err := extractMetaTags(context.Background(), server.Client(), server.URL, []string{defaultUserAgent})
+	if err != nil {
+		t.Fatalf("extractMetaTags expected success after retry, got error: %v", err)
+	}
+	if len(tags) == 0 {
+		t.Fatalf("expected tags after retry, got none")
+	}
+	found := false
+	for _, mt := range tags {
+		if mt.Name == "description" && mt.Content == "afterretry" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected description 'afterretry', not found")
+	}
+}
+
+func TestExtractMetaTags_CancelledContext(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := extractMetaTags(ctx, server.Client(), server.URL, []string{defaultUserAgent})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestFetchDetailPage_UserAgent(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.UserAgent())
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, `<html><head><meta name="description" content="desc"></head></html>`)
+	}))
+	defer server.Close()
+
+	app := newTestApp(t)
+	app.config.UserAgent = defaultUserAgent
+	if _, err := fetchDetailPage(context.Background(), server.Client(), server.URL, app.userAgents()); err != nil {
+		t.Fatalf("fetchDetailPage failed: %v", err)
+	}
+	if len(received) != 2 || received[0] != defaultUserAgent || received[1] != defaultUserAgent {
+		t.Fatalf("expected the configured user agent on every attempt, got %q", received)
+	}
+
+	app.config.Features = []string{featureStealth}
+	if agents := app.userAgents(); len(agents) != len(stealthUserAgents) || agents[0] == defaultUserAgent {
+		t.Fatalf("expected browser user agents in stealth mode, got %q", agents)
+	}
+}
+
+func TestFetchDetail_CollapsesConcurrentFetches(t *testing.T) {
+	var requests atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(arrived)
+		}
+		<-release
+		fmt.Fprintln(w, `<html><head><meta name="description" content="desc"></head></html>`)
+	}))
+	defer server.Close()
+
+	app := newTestApp(t)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	pages := make([]*detailPage, 2)
+	for i := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pages[i], _ = app.fetchDetail(ctx, server.URL)
+		}()
+		if i == 0 {
+			<-arrived
+		}
+	}
+	// Give the second fetch time to join the first before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if requests.Load() != 1 {
+		t.Fatalf("expected one request, got %d", requests.Load())
+	}
+	if pages[0] == nil || pages[0] != pages[1] {
+		t.Fatalf("expected both callers to get the page, got %v", pages)
+	}
+}
+
+func TestFetchDetail_SurvivesFirstCallerGivingUp(t *testing.T) {
+	arrived, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-release
+		fmt.Fprintln(w, `<html><head><meta name="description" content="desc"></head></html>`)
+	}))
+	defer server.Close()
+
+	app := newTestApp(t)
+	abandoned, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := app.fetchDetail(abandoned, server.URL)
+		first <- err
+	}()
+	<-arrived
+
+	second := make(chan *detailPage, 1)
+	go func() {
+		page, _ := app.fetchDetail(context.Background(), server.URL)
+		second <- page
+	}()
+	// Give the second fetch time to join the first before it is abandoned.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the abandoned caller to stop waiting, got %v", err)
+	}
+	close(release)
+	if page := <-second; page == nil {
+		t.Fatalf("expected the second caller to get the page")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+)
+
+func (a *App) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/atom", a.cached(a.handleFeed("atom", "application/atom+xml")))
+	mux.HandleFunc("/rss", a.cached(a.handleFeed("rss", "application/atom+xml")))
+	mux.HandleFunc("/json", a.cached(a.handleFeed("json", "application/json")))
+	mux.HandleFunc("GET /rss/all", a.cached(a.handleAllSources))
+	mux.HandleFunc("GET /f/{file}", a.cached(a.handleVariant))
+	mux.HandleFunc("GET /plain", a.cached(a.handlePlain))
+	mux.HandleFunc("GET /event/{hash}", a.handleEventPage)
+	mux.HandleFunc("GET /api/events/{hash}", a.handleEvent)
+	mux.HandleFunc("GET /api/sync", a.handleSync)
+	mux.HandleFunc("PATCH /api/events/{hash}", a.requireScope(scopeAdmin, a.handleEventPatch))
+	mux.HandleFunc("POST /api/events/{hash}/takedown", a.requireScope(scopeAdmin, a.handleTakedown))
+	mux.HandleFunc("POST /api/events/{hash}/hide", a.requireScope(scopeAdmin, a.handleHide))
+	mux.HandleFunc("POST /api/exports", a.requireScope(scopeStats, a.shed(a.handleExportCreate)))
+	mux.HandleFunc("GET /api/exports/{id}", a.requireScope(scopeStats, a.handleExportStatus))
+	mux.HandleFunc("GET /api/exports/{id}/download", a.requireScope(scopeStats, a.handleExportDownload))
+	mux.HandleFunc("GET /export/sqlite", a.requireScope(scopeStats, a.shed(a.handleSQLiteExport)))
+	mux.HandleFunc("POST /api/replay", a.requireScope(scopeAdmin, a.handleReplay))
+	mux.HandleFunc("GET /api/dead-letters", a.requireScope(scopeAdmin, a.handleDeadLetters))
+	mux.HandleFunc("POST /api/dead-letters/{id}/redeliver", a.requireScope(scopeAdmin, a.handleRedeliver))
+	mux.HandleFunc("POST /admin/severity/test", a.requireScope(scopeAdmin, a.handleRulesTest))
+	mux.HandleFunc("POST /admin/severity/reload", a.requireScope(scopeAdmin, a.handleRulesReload))
+	mux.HandleFunc("GET /admin/bundle", a.requireScope(scopeAdmin, a.handleBundleExport))
+	mux.HandleFunc("POST /admin/bundle", a.requireScope(scopeAdmin, a.handleBundleImport))
+	mux.HandleFunc("DELETE /admin/bundle", a.requireScope(scopeAdmin, a.handleBundleDelete))
+	mux.HandleFunc("GET /api/stats", a.requireScope(scopeStats, a.cached(a.shed(a.handleStats))))
+	mux.HandleFunc("GET /api/facts", a.requireScope(scopeStats, a.cached(a.shed(a.handleFacts))))
+	mux.HandleFunc("POST /api/query", a.requireScope(scopeStats, a.shed(a.handleBulkQuery)))
+	mux.HandleFunc("/graphql", a.requireScope(scopeStats, a.shed(a.handleGraphQL())))
+	mux.HandleFunc("GET /grafana/{$}", a.requireScope(scopeStats, func(w http.ResponseWriter, r *http.Request) {}))
+	mux.HandleFunc("POST /grafana/search", a.requireScope(scopeStats, a.handleGrafanaSearch))
+	mux.HandleFunc("POST /grafana/query", a.requireScope(scopeStats, a.shed(a.handleGrafanaQuery)))
+	mux.HandleFunc("GET /api/audit", a.requireScope(scopeAdmin, a.handleAuditLog))
+	mux.HandleFunc("GET /api/analytics", a.requireScope(scopeAdmin, a.shed(a.handleUsage)))
+	mux.HandleFunc("GET /api/quality", a.handleQuality)
+	mux.HandleFunc("GET /api/ha", a.cached(a.handleHomeAssistant))
+	mux.HandleFunc("GET /api/ha/package.yaml", a.handleHomeAssistantPackage)
+	mux.HandleFunc("GET /api/schema/event", handleEventSchema)
+	mux.HandleFunc("GET /api/schema/graphql", a.handleGraphQLSchema)
+	mux.HandleFunc("GET /robots.txt", a.handleRobots)
+	if slices.Contains(a.config.Features, featureShortLinks) {
+		mux.HandleFunc("GET /e/{code}", a.handleShortLink)
+		mux.HandleFunc("GET /api/short-links", a.requireScope(scopeAdmin, a.handleShortLinkStats))
+	}
+	if a.config.StaticDir != "" {
+		mux.Handle("GET /static/", http.StripPrefix("/static/", precompressedFileServer(os.DirFS(a.config.StaticDir))))
+	}
+	if slices.Contains(a.config.Features, featureGRPC) {
+		mux.HandleFunc("GET /api/schema/policefeed.proto", handleGRPCProto)
+	}
+	if a.config.BaseURL != "" {
+		mux.HandleFunc("GET /sitemap.xml", a.cached(a.handleSitemap))
+	}
+	if a.activityPub != nil {
+		mux.HandleFunc("GET /.well-known/webfinger", a.handleWebFinger)
+		mux.HandleFunc("GET /ap/actor", a.handleActor)
+		mux.HandleFunc("GET /ap/outbox", a.handleOutbox)
+		mux.HandleFunc("GET /ap/followers", a.handleFollowers)
+		mux.HandleFunc("GET /ap/notes/{hash}", a.handleNote)
+		mux.HandleFunc("POST /ap/inbox", a.handleInbox)
+	}
+	if slices.Contains(a.config.Features, featureWebmention) {
+		mux.HandleFunc("POST /webmention", a.handleWebmention)
+	}
+	if a.renderer != nil {
+		mux.HandleFunc("GET /archive/{file}", a.handleArchive)
+	}
+	if a.speaker != nil {
+		mux.HandleFunc("GET /podcast", a.handlePodcast)
+		mux.HandleFunc("GET /podcast/{file}", a.handlePodcastEpisode)
+	}
+	mux.HandleFunc("/status", a.handleStatus)
+	mux.HandleFunc("/metrics", a.handleMetrics)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/rss", http.StatusSeeOther)
+	})
+	return a.forwarded(a.trackUsage(mux))
+}
+
+func (a *App) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(struct {
+		Scrape ScrapeStatus `json:"scrape"`
+	}{
+		Scrape: a.scrapeState.snapshot(),
+	})
+	if err != nil {
+		log.Println("Error writing status:", err)
+	}
+}
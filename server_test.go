@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	store, db := openTestStore(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+
+	app, err := NewApp(context.Background(), Config{PoliceURL: "https://example.com/"}, http.DefaultClient, store, NewFeedBuilder("https://example.com/"))
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	// Runs before the store is closed, as cleanups run last in, first out.
+	t.Cleanup(app.deliveries.Wait)
+	return app
+}
+
+func TestRoutes_ServeFeeds(t *testing.T) {
+	app := newTestApp(t)
+	app.feed.Add(Event{Title: "ServedTitle", Hash: "served", DateTime: 1})
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	for _, path := range []string{"/rss", "/atom", "/json"} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, res.StatusCode)
+		}
+		if !strings.Contains(string(body), "ServedTitle") {
+			t.Fatalf("GET %s: body missing event title", path)
+		}
+	}
+}
+
+func TestRoutes_StatusAndMetrics(t *testing.T) {
+	app := newTestApp(t)
+	app.scrapeState.record(time.Now(), errors.New("upstream down"))
+	app.scrapeState.scheduled(time.Now().Add(5*time.Minute), true)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatalf("GET /status failed: %v", err)
+	}
+	var status struct {
+		Scrape ScrapeStatus `json:"scrape"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&status)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding status failed: %v", err)
+	}
+	if !status.Scrape.RetryPending || status.Scrape.ConsecutiveFailures != 1 || status.Scrape.LastError != "upstream down" {
+		t.Fatalf("unexpected status: %+v", status.Scrape)
+	}
+
+	res, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if !strings.Contains(string(body), "policefeed_scrape_retry_pending 1\n") {
+		t.Fatalf("metrics missing retry state:\n%s", body)
+	}
+}
+
+func TestRoutes_ServeStatic(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StaticDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(app.config.StaticDir, "logo.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/static/logo.png")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "png" {
+		t.Fatalf("unexpected response %d: %q", res.StatusCode, body)
+	}
+}
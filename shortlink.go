@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const featureShortLinks = "short_links"
+
+// shortLinkPrefix is the path short links live under, see handleShortLink.
+const shortLinkPrefix = "/e/"
+
+// ShortLink is a short address of an event's page handed to one notification
+// target, so that posts with a character limit stay short and clicks can be
+// counted by target. Its code is the ID in base 36.
+type ShortLink struct {
+	ID        uint   `gorm:"primaryKey"`
+	EventHash string `gorm:"uniqueIndex:idx_short_link;not null"`
+	Channel   string `gorm:"uniqueIndex:idx_short_link;not null"`
+	Clicks    int64
+	CreatedAt time.Time
+}
+
+// ShortLinkClicks sums up the short links of one target.
+type ShortLinkClicks struct {
+	Channel string `json:"channel"`
+	Links   int64  `json:"links"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// ShortLink returns the short link of the event for the target, creating it
+// on first use.
+func (s *gormStore) ShortLink(ctx context.Context, hash, channel string) (*ShortLink, error) {
+	link := ShortLink{EventHash: hash, Channel: channel}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).First(&link, "event_hash = ? AND channel = ?", hash, channel).Error
+	return &link, err
+}
+
+// FollowShortLink counts a click on the short link and returns it.
+func (s *gormStore) FollowShortLink(ctx context.Context, id uint) (*ShortLink, error) {
+	result := s.db.WithContext(ctx).Model(&ShortLink{}).Where("id = ?", id).Update("clicks", gorm.Expr("clicks + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var link ShortLink
+	err := s.db.WithContext(ctx).First(&link, id).Error
+	return &link, err
+}
+
+// ShortLinkClicks returns the links and clicks of every target.
+func (s *gormStore) ShortLinkClicks(ctx context.Context) ([]ShortLinkClicks, error) {
+	var counts []ShortLinkClicks
+	err := s.db.WithContext(ctx).Model(&ShortLink{}).
+		Select("channel, COUNT(*) AS links, COALESCE(SUM(clicks), 0) AS clicks").
+		Group("channel").Order("channel").Scan(&counts).Error
+	return counts, err
+}
+
+// TopShortLinks returns the most clicked short links.
+func (s *gormStore) TopShortLinks(ctx context.Context, limit int) ([]ShortLink, error) {
+	var links []ShortLink
+	err := s.db.WithContext(ctx).Where("clicks > 0").Order("clicks DESC, id").Limit(limit).Find(&links).Error
+	return links, err
+}
+
+// shortLinks returns the short links of the events for the notification
+// target, by hash. Without the short_links feature it returns nothing and
+// targets link the police's page as before. Failures only mean an event goes
+// out without a short link.
+func (a *App) shortLinks(ctx context.Context, notifier Notifier, events []Event) map[string]string {
+	if !slices.Contains(a.config.Features, featureShortLinks) {
+		return nil
+	}
+	links := make(map[string]string, len(events))
+	for _, event := range events {
+		link, err := a.store.ShortLink(ctx, event.Hash, notifier.Name())
+		if err != nil {
+			log.Printf("Error creating short link for %s: %v", event.Hash, err)
+			continue
+		}
+		links[event.Hash] = a.feed.absoluteURL(shortLinkPrefix + strconv.FormatUint(uint64(link.ID), 36))
+	}
+	return links
+}
+
+// handleShortLink counts a click and redirects to the event's page.
+func (a *App) handleShortLink(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("code"), 36, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	link, err := a.store.FollowShortLink(r.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Println("Error following short link:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/event/"+link.EventHash, http.StatusFound)
+}
+
+type shortLinkReport struct {
+	Channels []ShortLinkClicks `json:"channels"`
+	Top      []shortLinkEntry  `json:"top"`
+}
+
+type shortLinkEntry struct {
+	EventHash string `json:"eventHash"`
+	Channel   string `json:"channel"`
+	Clicks    int64  `json:"clicks"`
+}
+
+// handleShortLinkStats reports how often the short links of each target were
+// followed, and the most clicked ones.
+func (a *App) handleShortLinkStats(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 100 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+	}
+	channels, err := a.store.ShortLinkClicks(r.Context())
+	var top []ShortLink
+	if err == nil {
+		top, err = a.store.TopShortLinks(r.Context(), limit)
+	}
+	if err != nil {
+		log.Println("Error loading short links:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	report := shortLinkReport{Channels: channels, Top: []shortLinkEntry{}}
+	if report.Channels == nil {
+		report.Channels = []ShortLinkClicks{}
+	}
+	for _, link := range top {
+		report.Top = append(report.Top, shortLinkEntry{EventHash: link.EventHash, Channel: link.Channel, Clicks: link.Clicks})
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShortLinks(t *testing.T) {
+	app := newTestApp(t)
+	app.config.Features = []string{featureShortLinks}
+	app.config.AdminTokens = []string{"secret"}
+	app.feed.SetBaseURL("https://feed.example.org")
+	ctx := context.Background()
+	event := Event{Title: "Raub", Hash: "a", Link: "https://example.com/a"}
+	if err := app.store.Create(ctx, &event); err != nil {
+		t.Fatal(err)
+	}
+
+	nostr := &recordingNotifier{name: "nostr"}
+	sent, failed := app.notify(ctx, []Notifier{nostr, &recordingNotifier{name: "sms"}}, []Event{event}, false)
+	if sent != 2 || failed != 0 {
+		t.Fatalf("unexpected result %d sent, %d failed", sent, failed)
+	}
+	link := nostr.sent[0].ShortLinks["a"]
+	if !strings.HasPrefix(link, "https://feed.example.org/e/") {
+		t.Fatalf("unexpected short link %q", link)
+	}
+	// The same event keeps its link for the same target.
+	if again := app.shortLinks(ctx, nostr, []Event{event}); again["a"] != link {
+		t.Fatalf("expected the link to be reused, got %q and %q", link, again["a"])
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for range 2 {
+		res, err := client.Get(server.URL + strings.TrimPrefix(link, "https://feed.example.org"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusFound || res.Header.Get("Location") != "/event/a" {
+			t.Fatalf("unexpected redirect %d to %q", res.StatusCode, res.Header.Get("Location"))
+		}
+	}
+	res, err := client.Get(server.URL + "/e/zzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown link, got %d", res.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/short-links", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report shortLinkReport
+	err = json.NewDecoder(res.Body).Decode(&report)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ShortLinkClicks{{Channel: "nostr", Links: 1, Clicks: 2}, {Channel: "sms", Links: 1, Clicks: 0}}
+	if len(report.Channels) != 2 || report.Channels[0] != want[0] || report.Channels[1] != want[1] {
+		t.Fatalf("unexpected channels %+v", report.Channels)
+	}
+	if len(report.Top) != 1 || report.Top[0] != (shortLinkEntry{EventHash: "a", Channel: "nostr", Clicks: 2}) {
+		t.Fatalf("unexpected top links %+v", report.Top)
+	}
+}
+
+func TestNostrNote_ShortLink(t *testing.T) {
+	note := nostrNote(Event{Title: "Raub", Link: "https://example.com/a"}, "", "", "https://feed.example.org/e/1", time.Unix(1700000000, 0))
+	if !strings.Contains(note.Content, "https://feed.example.org/e/1") || strings.Contains(note.Content, "https://example.com/a") {
+		t.Fatalf("expected the short link in the note:\n%s", note.Content)
+	}
+}
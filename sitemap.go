@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxSitemapURLs is the most URLs a sitemap may list according to the
+// protocol. Older events are left out beyond it.
+const maxSitemapURLs = 50000
+
+// SitemapEntry is the part of an event the sitemap needs.
+type SitemapEntry struct {
+	Hash      string
+	UpdatedAt time.Time
+}
+
+// SitemapEntries returns the newest events by publication time, without those
+// taken down.
+func (s *gormStore) SitemapEntries(ctx context.Context, limit int) ([]SitemapEntry, error) {
+	var entries []SitemapEntry
+	err := s.db.WithContext(ctx).Model(&Event{}).Select("hash", "updated_at").
+		Where("taken_down_at IS NULL").Order("date_time DESC").Order("hash").Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// handleSitemap lists the event pages for search engines, so that the archive
+// can be found when searching for an incident. It needs BASE_URL, as
+// sitemaps only take absolute URLs.
+func (a *App) handleSitemap(w http.ResponseWriter, r *http.Request) {
+	entries, err := a.store.SitemapEntries(r.Context(), maxSitemapURLs)
+	if err != nil {
+		log.Println("Error loading sitemap:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	sitemap := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: make([]sitemapURL, 0, len(entries))}
+	for _, entry := range entries {
+		url := sitemapURL{Loc: a.feed.absoluteURL("/event/" + entry.Hash)}
+		if !entry.UpdatedAt.IsZero() {
+			url.LastMod = entry.UpdatedAt.UTC().Format(time.RFC3339)
+		}
+		sitemap.URLs = append(sitemap.URLs, url)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	err = xml.NewEncoder(w).Encode(sitemap)
+	if err != nil {
+		log.Println("Error writing sitemap:", err)
+	}
+}
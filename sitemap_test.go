@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemap(t *testing.T) {
+	app := newTestApp(t)
+	app.config.BaseURL = "https://feed.example.org"
+	app.feed.SetBaseURL(app.config.BaseURL)
+	ctx := context.Background()
+	for _, event := range []Event{
+		{Title: "Alt", Hash: "old", DateTime: 1},
+		{Title: "Neu", Hash: "new", DateTime: 2},
+		{Title: "Entfernt", Hash: "gone", DateTime: 3},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := app.store.TakeDown(ctx, "gone", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	sitemap := string(body)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	newer := strings.Index(sitemap, "<loc>https://feed.example.org/event/new</loc>")
+	older := strings.Index(sitemap, "<loc>https://feed.example.org/event/old</loc>")
+	if newer < 0 || older < newer {
+		t.Fatalf("expected both pages, newest first:\n%s", sitemap)
+	}
+	if strings.Contains(sitemap, "gone") || !strings.Contains(sitemap, "<lastmod>") {
+		t.Fatalf("expected lastmod and no taken down events:\n%s", sitemap)
+	}
+}
+
+func TestSitemap_NeedsBaseURL(t *testing.T) {
+	app := newTestApp(t)
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if strings.Contains(string(body), "<urlset") {
+		t.Fatalf("expected no sitemap without BASE_URL:\n%s", body)
+	}
+}
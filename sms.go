@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// smsMaxLength is the length of a single SMS. Longer texts are split and
+	// billed per part, so messages are cut to fit.
+	smsMaxLength = 160
+	// twilioURL is the default SMS_GATEWAY_URL with SMS_TWILIO_ACCOUNT.
+	twilioURL = "https://api.twilio.com"
+)
+
+// smsCharacters replaces punctuation outside the GSM 7-bit alphabet. A single
+// such character makes gateways send the whole message as UCS-2, which fits
+// only 70 characters into an SMS.
+var smsCharacters = strings.NewReplacer(
+	"…", "...", "–", "-", "—", "-",
+	"„", `"`, "“", `"`, "”", `"`, "‚", "'", "‘", "'", "’", "'",
+)
+
+// smsGateway sends a text message to a phone number.
+type smsGateway interface {
+	send(ctx context.Context, to, text string) error
+}
+
+// twilioGateway talks to the Twilio Messages API or a compatible one.
+type twilioGateway struct {
+	url, account, token, from string
+	client                    HTTPDoer
+}
+
+func (g *twilioGateway) send(ctx context.Context, to, text string) error {
+	form := url.Values{"To": {to}, "From": {g.from}, "Body": {text}}
+	endpoint := strings.TrimSuffix(g.url, "/") + "/2010-04-01/Accounts/" + url.PathEscape(g.account) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(g.account, g.token)
+	return doSMSRequest(g.client, req)
+}
+
+// httpGateway POSTs messages as JSON ({"to", "from", "text"}) to a URL, for
+// self-hosted gateways like an Android phone running an SMS gateway app.
+type httpGateway struct {
+	url, token, from string
+	client           HTTPDoer
+}
+
+func (g *httpGateway) send(ctx context.Context, to, text string) error {
+	body, err := json.Marshal(map[string]string{"to": to, "from": g.from, "text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	return doSMSRequest(g.client, req)
+}
+
+func doSMSRequest(client HTTPDoer, req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("sms gateway responded with %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// smsNotifier texts one phone number about the events it selects. Each
+// recipient is a target of its own, so that a failed delivery is only
+// retried for the recipient it failed for.
+type smsNotifier struct {
+	gateway smsGateway
+	to      string
+	// name identifies the target by a hash of the number, see smsTargetName.
+	name string
+	// minSeverity and districts restrict the events sent, as every message
+	// reaches a phone directly and may cost money.
+	minSeverity int
+	districts   []string
+}
+
+func (n *smsNotifier) Name() string { return n.name }
+
+// smsTargetName names the target of a phone number without revealing it in
+// logs, metrics and the admin API: "sms:" and the beginning of the number's
+// SHA-256 hash, which stays the same when the recipients are reordered.
+func smsTargetName(to string) string {
+	sum := sha256.Sum256([]byte(to))
+	return "sms:" + hex.EncodeToString(sum[:4])
+}
+
+func (n *smsNotifier) selects(event *Event, classification Classification) bool {
+	if classification.Severity < n.minSeverity {
+		return false
+	}
+	return len(n.districts) == 0 || slices.ContainsFunc(n.districts, func(district string) bool {
+		return strings.EqualFold(district, event.Location)
+	})
+}
+
+func (n *smsNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.gateway.send(ctx, n.to, smsText(notification))
+}
+
+// smsExcerpt is excerpt restricted to the GSM 7-bit alphabet.
+func smsExcerpt(text string, n int) string {
+	text = smsCharacters.Replace(text)
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	// Leave room for "..." in place of "…".
+	return smsCharacters.Replace(excerpt(text, n-2))
+}
+
+// smsText writes a notification as a single SMS: district and title, cut to
+// leave room for the link, which is the short link if there is one.
+func smsText(notification Notification) string {
+	if notification.Digest != nil {
+		return smsExcerpt(digestSummary(notification.Digest, nil), smsMaxLength)
+	}
+	event := notification.Event
+	prefix := "Polizei Berlin: "
+	if event.Location != "" {
+		prefix = "Polizei Berlin, " + smsCharacters.Replace(event.Location) + ": "
+	}
+	link := cmp.Or(notification.ShortLinks[event.Hash], event.Link)
+	room := smsMaxLength - utf8.RuneCountInString(prefix)
+	if link != "" {
+		room -= utf8.RuneCountInString(link) + 1
+	}
+	text := prefix + smsExcerpt(event.Title, max(room, 20))
+	if link != "" {
+		text += " " + link
+	}
+	return text
+}
+
+// buildSMSNotifiers creates a target for each of SMS_RECIPIENTS, sending
+// through Twilio if SMS_TWILIO_ACCOUNT is set and to SMS_GATEWAY_URL
+// otherwise.
+func buildSMSNotifiers(config Config) ([]Notifier, error) {
+	if len(config.SMSRecipients) == 0 {
+		return nil, nil
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	var gateway smsGateway
+	switch {
+	case config.SMSTwilioAccount != "":
+		gateway = &twilioGateway{
+			url:     cmp.Or(config.SMSGatewayURL, twilioURL),
+			account: config.SMSTwilioAccount,
+			token:   config.SMSGatewayToken,
+			from:    config.SMSFrom,
+			client:  client,
+		}
+	case config.SMSGatewayURL != "":
+		gateway = &httpGateway{url: config.SMSGatewayURL, token: config.SMSGatewayToken, from: config.SMSFrom, client: client}
+	default:
+		return nil, errors.New("SMS_RECIPIENTS requires SMS_GATEWAY_URL or SMS_TWILIO_ACCOUNT")
+	}
+	var notifiers []Notifier
+	for i, to := range config.SMSRecipients {
+		if !strings.HasPrefix(to, "+") {
+			log.Printf("Skipping SMS recipient %d: numbers need the country code, e.g. +49", i+1)
+			continue
+		}
+		name := smsTargetName(to)
+		log.Printf("SMS recipient %d is target %s", i+1, name)
+		notifiers = append(notifiers, &smsNotifier{
+			gateway:     gateway,
+			to:          to,
+			name:        name,
+			minSeverity: config.SMSMinSeverity,
+			districts:   config.SMSDistricts,
+		})
+	}
+	return notifiers, nil
+}
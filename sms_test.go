@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type recordingGateway struct {
+	texts []string
+}
+
+func (g *recordingGateway) send(_ context.Context, to, text string) error {
+	g.texts = append(g.texts, to+" "+text)
+	return nil
+}
+
+func TestSMSNotifier_SelectsEvents(t *testing.T) {
+	app := newTestApp(t)
+	gateway := &recordingGateway{}
+	sms := &smsNotifier{gateway: gateway, to: "+4930123", name: smsTargetName("+4930123"), minSeverity: severityHigh, districts: []string{"mitte"}}
+	events := []Event{
+		{Hash: "a", Title: "Schüsse am Alexanderplatz", Location: "Mitte", Link: "https://example.com/a"},
+		{Hash: "b", Title: "Fahrraddiebstahl", Location: "Mitte"},
+		{Hash: "c", Title: "Schüsse in Spandau", Location: "Spandau"},
+	}
+	sent, failed := app.notify(context.Background(), []Notifier{sms}, events, false)
+	if sent != 1 || failed != 0 {
+		t.Fatalf("unexpected result %d sent, %d failed", sent, failed)
+	}
+	if len(gateway.texts) != 1 || gateway.texts[0] != "+4930123 Polizei Berlin, Mitte: Schüsse am Alexanderplatz https://example.com/a" {
+		t.Fatalf("unexpected messages %q", gateway.texts)
+	}
+}
+
+func TestSMSText_FitsOneMessage(t *testing.T) {
+	event := Event{Hash: "a", Title: strings.Repeat("Sehr lange Überschrift ", 20), Location: "Charlottenburg-Wilmersdorf", Link: "https://www.berlin.de/polizei/polizeimeldungen/2025/pressemitteilung.1234567.php"}
+	text := smsText(Notification{Event: event, ShortLinks: map[string]string{"a": "https://feed.example.org/e/1a"}})
+	if utf8.RuneCountInString(text) > smsMaxLength || !strings.HasSuffix(text, "... https://feed.example.org/e/1a") {
+		t.Fatalf("unexpected text (%d characters): %s", utf8.RuneCountInString(text), text)
+	}
+
+	// Punctuation outside the GSM 7-bit alphabet would turn the message
+	// into UCS-2.
+	event = Event{Hash: "b", Title: "Raub – Täter flüchtet „mit Beute“…", Location: "Mitte"}
+	if text := smsText(Notification{Event: event}); text != `Polizei Berlin, Mitte: Raub - Täter flüchtet "mit Beute"...` {
+		t.Fatalf("unexpected text %s", text)
+	}
+	digest := make([]Event, digestSampleSize+1)
+	if text := smsText(Notification{Digest: digest}); strings.ContainsAny(text, "…–") {
+		t.Fatalf("unexpected digest text %s", text)
+	}
+}
+
+func TestSMSGateways(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2010-04-01/Accounts/AC1/Messages.json":
+			user, password, _ := r.BasicAuth()
+			_ = r.ParseForm()
+			got = append(got, user+":"+password+" "+r.PostForm.Get("From")+" "+r.PostForm.Get("To")+" "+r.PostForm.Get("Body"))
+			w.WriteHeader(http.StatusCreated)
+		case "/send":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			got = append(got, r.Header.Get("Authorization")+" "+body["from"]+" "+body["to"]+" "+body["text"])
+		default:
+			http.Error(w, "unknown account", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	for _, config := range []Config{
+		{SMSRecipients: []string{"+4930123"}, SMSFrom: "+4930999", SMSTwilioAccount: "AC1", SMSGatewayToken: "token", SMSGatewayURL: server.URL},
+		{SMSRecipients: []string{"+4930123", "030456"}, SMSFrom: "Polizei", SMSGatewayToken: "token", SMSGatewayURL: server.URL + "/send"},
+	} {
+		notifiers, err := buildSMSNotifiers(config)
+		if err != nil || len(notifiers) != 1 || notifiers[0].Name() != smsTargetName("+4930123") {
+			t.Fatalf("unexpected notifiers %v (%v)", notifiers, err)
+		}
+		if name := notifiers[0].Name(); strings.Contains(name, "30123") || len(name) != len("sms:")+8 {
+			t.Fatalf("target name %s reveals the number", name)
+		}
+		if err := notifiers[0].Notify(context.Background(), Notification{Event: Event{Title: "Raub"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"AC1:token +4930999 +4930123 Polizei Berlin: Raub", "Bearer token Polizei +4930123 Polizei Berlin: Raub"}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unexpected requests %q", got)
+	}
+
+	notifiers, _ := buildSMSNotifiers(Config{SMSRecipients: []string{"+4930123"}, SMSTwilioAccount: "AC2", SMSGatewayURL: server.URL})
+	err := notifiers[0].Notify(context.Background(), Notification{Event: Event{Title: "Raub"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown account") {
+		t.Fatalf("expected the gateway's error, got %v", err)
+	}
+	if _, err := buildSMSNotifiers(Config{SMSRecipients: []string{"+4930123"}}); err == nil {
+		t.Fatal("expected an error without a gateway")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// maxConcurrentSnapshots bounds how many SQLite snapshots are built at once,
+// as each one reads the whole (filtered) events table.
+const maxConcurrentSnapshots = 2
+
+// snapshotEvent is the row layout of SQLite snapshots. It has the same
+// columns as CSV exports and leaves out everything internal.
+type snapshotEvent struct {
+	Hash        string `gorm:"primaryKey"`
+	Title       string
+	Description string
+	District    string `gorm:"index"`
+	Link        string
+	PublishedAt time.Time `gorm:"index"`
+	IncidentAt  *time.Time
+	ParentHash  string
+}
+
+func (snapshotEvent) TableName() string { return "events" }
+
+// snapshotMetadata holds the provenance of a snapshot (see apiMeta.fields).
+type snapshotMetadata struct {
+	Key   string `gorm:"primaryKey"`
+	Value string
+}
+
+func (snapshotMetadata) TableName() string { return "metadata" }
+
+// writeSQLiteSnapshot writes the matching events, redacted, and meta into a
+// new SQLite database at path and returns the number of rows written.
+func writeSQLiteSnapshot(ctx context.Context, store EventStore, filter EventFilter, redactions redactor, meta apiMeta, path string) (int, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return 0, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+	defer sqlDB.Close()
+	db = db.WithContext(ctx)
+
+	err = db.AutoMigrate(&snapshotEvent{}, &snapshotMetadata{})
+	if err != nil {
+		return 0, err
+	}
+	for key, value := range meta.fields() {
+		err = db.Create(&snapshotMetadata{Key: key, Value: value}).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	rows := 0
+	err = store.EachBatch(ctx, filter, exportBatchSize, func(events []Event) error {
+		batch := make([]snapshotEvent, 0, len(events))
+		for _, event := range redactions.events(events) {
+			row := snapshotEvent{
+				Hash:        event.Hash,
+				Title:       event.Title,
+				Description: event.Description,
+				District:    event.Location,
+				Link:        event.Link,
+				PublishedAt: time.Unix(event.DateTime, 0).UTC(),
+				ParentHash:  event.ParentHash,
+			}
+			if event.IncidentTime != nil {
+				incident := time.Unix(*event.IncidentTime, 0).UTC()
+				row.IncidentAt = &incident
+			}
+			batch = append(batch, row)
+		}
+		rows += len(batch)
+		return db.CreateInBatches(batch, 100).Error
+	})
+	if err != nil {
+		return rows, err
+	}
+	// Leave a single self-contained file behind.
+	return rows, db.Exec("VACUUM").Error
+}
+
+// handleSQLiteExport sends the matching events as a SQLite database, built on
+// the fly. It takes the same from, to and district filters as the API.
+func (a *App) handleSQLiteExport(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEventFilter(r.URL.Query())
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, codeInvalidFilter, err.Error())
+		return
+	}
+
+	select {
+	case a.snapshots <- struct{}{}:
+		defer func() { <-a.snapshots }()
+	default:
+		w.Header().Set("Retry-After", strconv.Itoa(60))
+		writeJSONError(w, http.StatusTooManyRequests, "too many snapshots in progress")
+		return
+	}
+
+	if a.config.ExportDir != "" {
+		err = os.MkdirAll(a.config.ExportDir, 0o755)
+		if err != nil {
+			log.Println("Error creating export directory:", err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+	file, err := os.CreateTemp(a.config.ExportDir, "snapshot-*.db")
+	if err != nil {
+		log.Println("Error creating snapshot:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	path := file.Name()
+	_ = file.Close()
+	defer os.Remove(path)
+
+	meta := newAPIMeta(a.config)
+	rows, err := writeSQLiteSnapshot(r.Context(), a.store, filter, a.redactions, meta, path)
+	if err != nil {
+		log.Println("Error writing snapshot:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	file, err = os.Open(path)
+	if err != nil {
+		log.Println("Error opening snapshot:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Println("Error reading snapshot:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="polizeimeldungen-%s.db"`, time.Now().Format("20060102")))
+	w.Header().Set("X-Export-Rows", strconv.Itoa(rows))
+	meta.setHeaders(w)
+	_, err = io.Copy(w, file)
+	if err != nil {
+		log.Println("Error sending snapshot:", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSQLiteExport(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	seedExportEvents(t, app.store)
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/export/sqlite?district=Mitte", nil)
+	req.Header.Set("Authorization", "Bearer stats")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "application/vnd.sqlite3" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(file, res.Body); err != nil {
+		t.Fatalf("reading snapshot failed: %v", err)
+	}
+	_ = file.Close()
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening snapshot failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+	var rows []snapshotEvent
+	if err := db.Order("published_at").Find(&rows).Error; err != nil {
+		t.Fatalf("querying snapshot failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Hash != "e1" || rows[1].Hash != "e3" || rows[0].District != "Mitte" {
+		t.Fatalf("unexpected snapshot rows: %+v", rows)
+	}
+}
+
+func TestSQLiteExport_RequiresToken(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/export/sqlite")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Sources differ in how often they change and how much traffic their portal
+// tolerates, so each can be given its own scrape interval, rate limit and
+// hosts to fetch from. berlin.de keeps SCRAPE_INTERVAL and
+// SCRAPE_REQUEST_INTERVAL, which its scheduler is built around, but its
+// hosts can be set like any other's.
+
+// errDomainNotAllowed is returned for requests of a source to hosts outside
+// its allowed domains.
+var errDomainNotAllowed = errors.New("domain not allowed")
+
+// sourceDurationsEnv reads a comma separated list of source=duration entries,
+// e.g. "bvg=5m,brandenburg=2h". Invalid entries and entries for berlin are
+// logged and skipped.
+func sourceDurationsEnv(name string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for _, entry := range listEnv(name) {
+		source, value, _ := strings.Cut(entry, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d <= 0 || source == sourceBerlin {
+			log.Printf("Invalid %s entry %q, expected source=duration for a source other than berlin", name, entry)
+			continue
+		}
+		durations[strings.TrimSpace(source)] = d
+	}
+	return durations
+}
+
+// sourceDomainsEnv reads a comma separated list of source=hosts entries, the
+// hosts separated by "|", e.g. "berlin=www.berlin.de|service.berlin.de".
+// Invalid entries are logged and skipped.
+func sourceDomainsEnv(name string) map[string][]string {
+	domains := make(map[string][]string)
+	for _, entry := range listEnv(name) {
+		source, value, ok := strings.Cut(entry, "=")
+		hosts := splitCategories(value, "|")
+		if !ok || len(hosts) == 0 {
+			log.Printf("Invalid %s entry %q, expected source=host|host", name, entry)
+			continue
+		}
+		domains[strings.TrimSpace(source)] = hosts
+	}
+	return domains
+}
+
+// sourceInterval returns how often the source name is scraped.
+func (a *App) sourceInterval(name string) time.Duration {
+	if d, ok := a.config.SourceIntervals[name]; ok {
+		return d
+	}
+	return a.config.ScrapeInterval
+}
+
+// allowedDomains returns the hosts source may fetch from, by default the
+// host of its URL.
+func (a *App) allowedDomains(source sourceInfo) []string {
+	if hosts, ok := a.config.SourceDomains[source.Name]; ok {
+		return hosts
+	}
+	u, err := url.Parse(source.URL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	return []string{u.Hostname()}
+}
+
+// limitSources gives every source a rate limiter of its own for its hosts,
+// so that a portal asking for long pauses doesn't slow down the others.
+// Requests to other hosts, berlin.de among them, keep the shared limit of
+// ScrapeRequestInterval.
+func (a *App) limitSources() {
+	transport, ok := a.transport().(*politeTransport)
+	if !ok {
+		return
+	}
+	shared := a.allowedDomains(a.berlinSource())
+	for _, source := range a.scrapers {
+		info := source.Info()
+		interval, ok := a.config.SourceRequestIntervals[info.Name]
+		if !ok {
+			interval = a.config.ScrapeRequestInterval
+		}
+		limit := rate.Inf
+		if interval > 0 {
+			limit = rate.Every(interval)
+		}
+		hosts := slices.DeleteFunc(slices.Clone(a.allowedDomains(info)), func(host string) bool {
+			return slices.Contains(shared, host)
+		})
+		transport.limitHosts(hosts, rate.NewLimiter(limit, 1))
+	}
+}
+
+// sourceClient returns the client a source scrapes with, refusing requests
+// to hosts outside its allowed domains.
+func (a *App) sourceClient(source sourceInfo) HTTPDoer {
+	return domainGuard{next: a.client, domains: a.allowedDomains(source)}
+}
+
+// domainGuard passes on requests to the given hosts only, like colly's
+// AllowedDomains does for berlin.de.
+type domainGuard struct {
+	next    HTTPDoer
+	domains []string
+}
+
+func (g domainGuard) Do(req *http.Request) (*http.Response, error) {
+	if !slices.Contains(g.domains, req.URL.Hostname()) {
+		return nil, fmt.Errorf("%w: %s", errDomainNotAllowed, req.URL.Hostname())
+	}
+	return g.next.Do(req)
+}
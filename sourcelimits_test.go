@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSourceLimitsEnv(t *testing.T) {
+	t.Setenv("SOURCE_INTERVALS", "bvg=5m, brandenburg=2h,berlin=1m,sbahn=bald")
+	intervals := sourceDurationsEnv("SOURCE_INTERVALS")
+	if len(intervals) != 2 || intervals["bvg"] != 5*time.Minute || intervals["brandenburg"] != 2*time.Hour {
+		t.Fatalf("unexpected intervals %v", intervals)
+	}
+	t.Setenv("SOURCE_DOMAINS", "berlin=www.berlin.de|service.berlin.de,bvg=,sbahn")
+	domains := sourceDomainsEnv("SOURCE_DOMAINS")
+	if len(domains) != 1 || !slices.Equal(domains[sourceBerlin], []string{"www.berlin.de", "service.berlin.de"}) {
+		t.Fatalf("unexpected domains %v", domains)
+	}
+
+	app := &App{config: Config{ScrapeInterval: time.Hour, SourceIntervals: intervals}}
+	if app.sourceInterval("bvg") != 5*time.Minute || app.sourceInterval("sbahn") != time.Hour {
+		t.Fatal("expected sources without an interval of their own to use SCRAPE_INTERVAL")
+	}
+}
+
+func TestLimitSources_SeparateLimiters(t *testing.T) {
+	transport := newPoliteTransport(fixtureTransport{dir: "testdata/fixtures"}, rate.Every(time.Hour), 1)
+	config := Config{
+		PoliceURL:              "https://www.berlin.de/polizei/polizeimeldungen/",
+		Sources:                []string{sourceBrandenburg},
+		BrandenburgURL:         "https://polizei.brandenburg.de/pressemeldungen/",
+		SourceRequestIntervals: map[string]time.Duration{sourceBrandenburg: time.Millisecond},
+	}
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+	app, err := NewApp(context.Background(), config, &http.Client{Transport: transport}, store, NewFeedBuilder("https://example.com/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := app.sourceClient(app.scrapers[0].Info())
+
+	// berlin.de uses up the shared limit, which would block for an hour.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, page := range []string{config.PoliceURL, config.BrandenburgURL, config.BrandenburgURL} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, page, nil)
+		res, err := app.client.Do(req)
+		if err != nil {
+			t.Fatalf("expected %s not to wait for berlin.de: %v", page, err)
+		}
+		drainBody(res.Body)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, config.PoliceURL, nil)
+	if _, err := client.Do(req); !errors.Is(err, errDomainNotAllowed) {
+		t.Fatalf("expected the source to be kept to its domain, got %v", err)
+	}
+}
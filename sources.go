@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Events can come from several sources, named by Event.Source. The police
+// reports of berlin.de are the original one, and the source of all events
+// stored before there were others. They are scraped by the App itself (see
+// newCollector), the portals listed in SOURCES by their Source.
+
+// sourceInfo describes a source to readers.
+type sourceInfo struct {
+	// Name is stored in Event.Source and selects the source in queries.
+	Name string
+	// Label prefixes item titles in feeds combining sources, e.g. "Polizei".
+	Label string
+	// Title and URL name the source in the <source> element of RSS items.
+	Title string
+	URL   string
+	// Overlaps marks sources that republish some reports of berlin.de, e.g.
+	// joint press releases. Their new events are dropped if berlin.de has
+	// the same report, see findBerlinDuplicate.
+	Overlaps bool
+}
+
+// Source is a portal scraped besides berlin.de, or a plugin scraping one (see
+// pluginSource).
+type Source interface {
+	Info() sourceInfo
+	// Scrape returns the events the portal lists right now, with their
+	// hash, title, link, location and publication time.
+	Scrape(ctx context.Context, client HTTPDoer) ([]Event, error)
+	// Complete fills in the rest of a new event, e.g. the description from
+	// its detail page. Events stored before aren't completed again.
+	Complete(ctx context.Context, client HTTPDoer, event *Event) error
+}
+
+var errUnknownSource = errors.New("unknown source")
+
+// sourceConstructors build the sources that can be enabled in SOURCES.
+var sourceConstructors = map[string]func(Config) (Source, error){
+	sourceBrandenburg:   newBrandenburgSource,
+	sourcePresseportal:  newPresseportalSource,
+	sourceBundespolizei: newBundespolizeiSource,
+	sourceBVG:           newBVGSource,
+	sourceSBahn:         newSBahnSource,
+}
+
+// errMissingSourceURL is returned for sources enabled without the URL they
+// are read from, which is configured in the variable name.
+func errMissingSourceURL(name string) error {
+	return fmt.Errorf("%s must be set to read this source", name)
+}
+
+// buildSources returns the sources enabled in SOURCES, followed by those of
+// SOURCE_PLUGINS.
+func buildSources(config Config) ([]Source, error) {
+	var sources []Source
+	for _, name := range config.Sources {
+		constructor, ok := sourceConstructors[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q in SOURCES", errUnknownSource, name)
+		}
+		source, err := constructor(config)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", name, err)
+		}
+		sources = append(sources, source)
+	}
+	plugins, err := buildPlugins(config)
+	if err != nil {
+		return nil, err
+	}
+	return append(sources, plugins...), nil
+}
+
+// berlinSource describes the police reports of berlin.de.
+func (a *App) berlinSource() sourceInfo {
+	return sourceInfo{Name: sourceBerlin, Label: "Polizei", Title: "Polizei Berlin", URL: a.config.PoliceURL}
+}
+
+// sources returns the sources of this instance.
+func (a *App) sources() []sourceInfo {
+	sources := []sourceInfo{a.berlinSource()}
+	for _, source := range a.scrapers {
+		sources = append(sources, source.Info())
+	}
+	return sources
+}
+
+// sourceOf returns the source of event. Events of sources no longer
+// configured keep their name as label.
+func (a *App) sourceOf(event *Event) sourceInfo {
+	name := event.Source
+	if name == "" {
+		name = sourceBerlin
+	}
+	for _, source := range a.sources() {
+		if source.Name == name {
+			return source
+		}
+	}
+	return sourceInfo{Name: name, Label: name, Title: name}
+}
+
+// selectedSources returns the names of the sources chosen by ?source= and
+// ?exclude_source=, each a comma separated list. Without either, all
+// sources are chosen.
+func (a *App) selectedSources(query url.Values) ([]string, error) {
+	include := splitCategories(query.Get("source"), ",")
+	exclude := splitCategories(query.Get("exclude_source"), ",")
+	sources := a.sources()
+	for _, name := range slices.Concat(include, exclude) {
+		if !slices.ContainsFunc(sources, func(source sourceInfo) bool { return source.Name == name }) {
+			return nil, fmt.Errorf("%w %q", errUnknownSource, name)
+		}
+	}
+	var selected []string
+	for _, source := range sources {
+		if (len(include) == 0 || slices.Contains(include, source.Name)) && !slices.Contains(exclude, source.Name) {
+			selected = append(selected, source.Name)
+		}
+	}
+	return selected, nil
+}
+
+// handleAllSources serves /rss/all, the newest events of all sources merged
+// by publication time, each labelled with its source.
+func (a *App) handleAllSources(w http.ResponseWriter, r *http.Request) {
+	sources, err := a.selectedSources(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, err := parseSince(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var events []Event
+	if len(sources) > 0 {
+		events, err = a.store.Recent(r.Context(), EventFilter{Sources: sources}, defaultVariantLimit)
+		if err != nil {
+			log.Println("Error loading events of all sources:", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+	events = slices.DeleteFunc(events, func(event Event) bool { return event.DateTime <= since })
+	body, err := a.feed.Sourced("/rss/all", events, a.sourceOf)
+	if err != nil {
+		log.Println("Error rendering feed of all sources:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", feedFormats["rss"])
+	_, err = io.WriteString(w, body)
+	if err != nil {
+		log.Println("Error writing feed of all sources:", err)
+	}
+}
+
+// fromBerlin reports whether event is a police report of berlin.de, the
+// only source of the built-in feeds and variants.
+func (e *Event) fromBerlin() bool {
+	return e.Source == "" || e.Source == sourceBerlin
+}
+
+// eventHash derives the public hash of an event from prefix, its title and
+// its time. Events of berlin.de have an empty prefix, which keeps the hashes
+// of newCollector and importEvents stable.
+func eventHash(prefix string, event *Event) string {
+	return fmt.Sprintf("%x", adler32.Checksum([]byte(prefix+event.Title+strconv.FormatInt(event.DateTime, 10))))
+}
+
+// sourceHash derives the public hash of an event of a source like
+// newCollector does for berlin.de, but including the name of the source, so
+// that two sources publishing the same title at the same time don't collide.
+func sourceHash(source string, event *Event) string {
+	return eventHash(source+"\x00", event)
+}
+
+// wallClock returns t the way Event.DateTime stores it: the Berlin wall clock
+// time read as UTC, like the dates scraped from berlin.de.
+func wallClock(t time.Time) int64 {
+	t = t.In(berlin)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC).Unix()
+}
+
+// eventTime is the inverse of wallClock: it returns the time in Berlin that
+// Event.DateTime stores.
+func eventTime(dateTime int64) time.Time {
+	t := time.Unix(dateTime, 0).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, berlin)
+}
+
+// fetchPage fetches and parses a page of a source, checked like the pages of
+// berlin.de (see readPage).
+func fetchPage(ctx context.Context, client HTTPDoer, pageURL, userAgent string) (*goquery.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainBody(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", pageURL, res.Status)
+	}
+	return readPage(res)
+}
+
+// scrapeSource stores the events source lists right now. As on berlin.de,
+// events are recognised by their link and keep their hash, and manual
+// corrections and takedowns take precedence over the portal.
+func (a *App) scrapeSource(ctx context.Context, source Source) error {
+	client := a.sourceClient(source.Info())
+	listed, err := source.Scrape(ctx, client)
+	if err != nil {
+		return err
+	}
+	// Plugins describe themselves while scraping.
+	info := source.Info()
+	var events []Event
+	known := make(map[string]bool)
+	for _, event := range listed {
+		event.Source = info.Name
+		exists, _ := checkDuplicate(ctx, &event, a.store, a.seen)
+		if exists {
+			stored, err := a.store.FindByIdentity(ctx, identityHash(&event))
+			if err != nil || len(stored.Edits) > 0 || stored.TakenDownAt != nil {
+				continue
+			}
+			event.Hash = stored.Hash
+			if event.Description == "" {
+				event.Description = stored.Description
+			}
+			known[event.Hash] = true
+		} else {
+			err := source.Complete(ctx, client, &event)
+			if err != nil {
+				log.Printf("Error completing %s: %v", event.Link, err)
+				continue
+			}
+			if info.Overlaps {
+				duplicate, err := a.findBerlinDuplicate(ctx, &event)
+				if err != nil {
+					log.Println("Error looking for duplicates:", err)
+				} else if duplicate != nil {
+					log.Printf("Skipping %s, berlin.de published it as %s", event.Link, duplicate.Hash)
+					continue
+				}
+			}
+		}
+		if event.Location == "" {
+			event.Location = a.districtMap().find(event.Title + "\n" + event.Description)
+		}
+		setIncidentTime(&event)
+		events = append(events, event)
+	}
+	_, created, updated := a.storeEvents(ctx, events, known)
+	log.Printf("%s scraped, %d new and %d changed events", info.Title, len(created), len(updated))
+	return nil
+}
+
+// scheduleSource scrapes source right away and then at its interval (see
+// sourceInterval) until ctx is cancelled.
+func (a *App) scheduleSource(ctx context.Context, source Source) {
+	name := source.Info().Name
+	ticker := time.NewTicker(a.sourceInterval(name))
+	defer ticker.Stop()
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, a.config.ScrapeTimeout)
+		err := a.scrapeSource(runCtx, source)
+		cancel()
+		if err != nil {
+			log.Printf("Error scraping %s: %v", name, err)
+		} else {
+			a.ping(ctx, name)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
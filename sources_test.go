@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAllSources_LabelsAndFilters(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	events := []Event{
+		{Title: "Raub in Mitte", Link: eventLinkPrefix + "/a", Hash: "a", DateTime: 1},
+		{Title: "Brand in Pankow", Link: eventLinkPrefix + "/b", Hash: "b", DateTime: 3, Source: sourceBerlin},
+		{Title: "Unfall in Potsdam", Link: "https://example.org/c", Hash: "c", DateTime: 2, Source: "elsewhere"},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	get := func(query string) (int, string) {
+		res, err := http.Get(server.URL + "/rss/all" + query)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	status, body := get("")
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	brand, raub := strings.Index(body, "[Polizei] Brand in Pankow"), strings.Index(body, "[Polizei] Raub in Mitte")
+	if brand == -1 || raub == -1 || brand > raub {
+		t.Fatalf("expected labelled events newest first:\n%s", body)
+	}
+	if !strings.Contains(body, `<source url="https://example.com/">Polizei Berlin</source>`) {
+		t.Fatalf("expected the source element:\n%s", body)
+	}
+	if strings.Contains(body, "Potsdam") {
+		t.Fatalf("expected events of unknown sources to be left out:\n%s", body)
+	}
+
+	if _, body := get("?exclude_source=berlin"); strings.Contains(body, "<item>") {
+		t.Fatalf("expected no items without sources:\n%s", body)
+	}
+	if _, body := get("?source=Berlin"); !strings.Contains(body, "Raub in Mitte") {
+		t.Fatalf("expected the chosen source:\n%s", body)
+	}
+	if status, _ := get("?source=nirgendwo"); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown source, got %d", status)
+	}
+
+	app.feed.Add(events[0])
+	if strings.Contains(app.feed.RSS(), "<source") || strings.Contains(app.feed.RSS(), "[Polizei]") {
+		t.Fatalf("expected the built-in feed to stay unlabelled:\n%s", app.feed.RSS())
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Some reports collect several unrelated incidents ("Tägliche Kurzmeldungen"),
+// each under its own heading. These are split into sub-events, so that every
+// incident gets its own feed item, district and notification. The report
+// itself is kept as well, and the sub-events link back to it.
+
+// reportSection is a headed part of a report's text.
+type reportSection struct {
+	Heading string
+	Text    string
+}
+
+var (
+	compilationTitle = regexp.MustCompile(`(?i)kurzmeldung|sammelmeldung|meldungen vom`)
+	berlinDistricts  = []string{
+		"Charlottenburg-Wilmersdorf", "Friedrichshain-Kreuzberg", "Lichtenberg", "Marzahn-Hellersdorf",
+		"Mitte", "Neukölln", "Pankow", "Reinickendorf", "Spandau", "Steglitz-Zehlendorf",
+		"Tempelhof-Schöneberg", "Treptow-Köpenick",
+	}
+)
+
+const maxSubEventTitleLength = 100
+
+// reportSections splits the report text into sections. Headings are h2-h4
+// elements or paragraphs consisting of bold text only; text before the first
+// heading is ignored.
+func reportSections(doc *goquery.Document) []reportSection {
+	var sections []reportSection
+	doc.Find("div.textile").Children().Each(func(_ int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+		bold := strings.Join(strings.Fields(s.Find("strong, b").Text()), " ")
+		if goquery.NodeName(s) == "h2" || goquery.NodeName(s) == "h3" || goquery.NodeName(s) == "h4" || bold == text {
+			sections = append(sections, reportSection{Heading: text})
+			return
+		}
+		if len(sections) == 0 {
+			return
+		}
+		last := &sections[len(sections)-1]
+		last.Text = strings.TrimSpace(last.Text + "\n\n" + text)
+	})
+	return sections
+}
+
+// districtMap finds the Berlin district named in a text, by its name, a part
+// of a double name like "Kreuzberg", or an alias such as the locality
+// "Wedding". Names match as whole words, so that "Mitteilung" isn't taken for
+// "Mitte".
+type districtMap struct {
+	// names maps each name and alias to its district.
+	names   map[string]string
+	aliases map[string][]string
+	pattern *regexp.Regexp
+}
+
+var defaultDistricts, _ = newDistrictMap(nil)
+
+// newDistrictMap builds a map of the districts with the given further names
+// per district.
+func newDistrictMap(aliases map[string][]string) (*districtMap, error) {
+	names := make(map[string]string)
+	for _, district := range berlinDistricts {
+		names[district] = district
+		for _, part := range strings.Split(district, "-") {
+			names[part] = district
+		}
+	}
+	for district, list := range aliases {
+		if !slices.Contains(berlinDistricts, district) {
+			return nil, fmt.Errorf("unknown district %q", district)
+		}
+		for _, alias := range list {
+			alias = strings.TrimSpace(alias)
+			if other, ok := names[alias]; alias == "" || ok && other != district {
+				return nil, fmt.Errorf("district %s: invalid alias %q", district, alias)
+			}
+			names[alias] = district
+		}
+	}
+
+	// Longer names first, so that a double name beats its parts.
+	keys := slices.SortedFunc(maps.Keys(names), func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	for i, key := range keys {
+		keys[i] = regexp.QuoteMeta(key)
+	}
+	return &districtMap{
+		names:   names,
+		aliases: aliases,
+		pattern: regexp.MustCompile(`\b(` + strings.Join(keys, "|") + `)\b`),
+	}, nil
+}
+
+// find returns the first district named in text.
+func (m *districtMap) find(text string) string {
+	return m.names[m.pattern.FindString(text)]
+}
+
+// isCompilation reports whether a report with these sections covers several
+// incidents: it needs at least two sections and either a title saying so or
+// only district names as headings.
+func isCompilation(title string, sections []reportSection, districts *districtMap) bool {
+	if len(sections) < 2 {
+		return false
+	}
+	if compilationTitle.MatchString(title) {
+		return true
+	}
+	for _, section := range sections {
+		if districts.find(section.Heading) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitReport returns the sub-events of a compilation report, or nothing if
+// the report covers a single incident.
+func splitReport(parent *Event, sections []reportSection, districts *districtMap) []Event {
+	if !isCompilation(parent.Title, sections, districts) {
+		return nil
+	}
+
+	var events []Event
+	for i, section := range sections {
+		if section.Text == "" {
+			continue
+		}
+		n := strconv.Itoa(i + 1)
+		event := Event{
+			Title:       section.Heading,
+			Description: section.Text,
+			Location:    districts.find(section.Heading),
+			Link:        parent.Link + "#" + n,
+			DateTime:    parent.DateTime,
+			Hash:        parent.Hash + "-" + n,
+			ParentHash:  parent.Hash,
+		}
+		if event.Location != "" {
+			// The heading only names the district, so use the first
+			// sentence as title instead.
+			event.Title = firstSentence(section.Text)
+		} else if event.Location = districts.find(section.Text); event.Location == "" {
+			event.Location = parent.Location
+		}
+		setIncidentTime(&event)
+		events = append(events, event)
+	}
+	return events
+}
+
+func firstSentence(text string) string {
+	if end := strings.IndexAny(text, ".!?"); end != -1 {
+		text = text[:end]
+	}
+	if len(text) > maxSubEventTitleLength {
+		cut := strings.LastIndex(text[:maxSubEventTitleLength], " ")
+		if cut <= 0 {
+			cut = maxSubEventTitleLength
+		}
+		text = text[:cut] + " …"
+	}
+	return text
+}
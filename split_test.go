@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestReportSections(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="textile">
+		<p>Einleitung</p>
+		<h3>Mitte</h3>
+		<p>Erster Absatz.</p>
+		<p>Zweiter Absatz.</p>
+		<p><strong>Lichtenberg</strong></p>
+		<p>Ein Text mit <strong>Hervorhebung</strong>.</p>
+	</div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := reportSections(doc)
+	want := []reportSection{
+		{Heading: "Mitte", Text: "Erster Absatz.\n\nZweiter Absatz."},
+		{Heading: "Lichtenberg", Text: "Ein Text mit Hervorhebung."},
+	}
+	if len(sections) != len(want) {
+		t.Fatalf("expected %d sections, got %+v", len(want), sections)
+	}
+	for i := range want {
+		if sections[i] != want[i] {
+			t.Fatalf("section %d: expected %+v, got %+v", i, want[i], sections[i])
+		}
+	}
+}
+
+func TestSplitReport(t *testing.T) {
+	parent := &Event{
+		Title:    "Tägliche Kurzmeldungen",
+		Link:     "https://www.berlin.de/polizei/polizeimeldungen/2026/pressemitteilung.1.php",
+		Hash:     "abc",
+		Location: "berlinweit",
+		DateTime: 1000,
+	}
+	events := splitReport(parent, []reportSection{
+		{Heading: "Kreuzberg", Text: "Ein Auto brannte. Die Feuerwehr löschte."},
+		{Heading: "Einbruch in Kita", Text: "In Steglitz brachen Unbekannte ein."},
+		{Heading: "Ohne Ort", Text: "Laut Mitteilung wurde ein Fahrrad gestohlen."},
+	}, defaultDistricts)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 sub-events, got %d", len(events))
+	}
+	cases := []struct{ title, location string }{
+		{"Ein Auto brannte", "Friedrichshain-Kreuzberg"},
+		{"Einbruch in Kita", "Steglitz-Zehlendorf"},
+		{"Ohne Ort", "berlinweit"},
+	}
+	for i, c := range cases {
+		event := events[i]
+		if event.Title != c.title || event.Location != c.location {
+			t.Fatalf("sub-event %d: got title %q, location %q", i, event.Title, event.Location)
+		}
+		if event.ParentHash != "abc" || event.DateTime != 1000 || !strings.HasPrefix(event.Hash, "abc-") || !strings.HasPrefix(event.Link, parent.Link+"#") {
+			t.Fatalf("sub-event %d not linked to parent: %+v", i, event)
+		}
+	}
+	if events[0].Hash == events[1].Hash {
+		t.Fatal("sub-events share a hash")
+	}
+}
+
+func TestSplitReport_SingleIncident(t *testing.T) {
+	sections := []reportSection{
+		{Heading: "Festnahme", Text: "Ein Mann wurde festgenommen."},
+		{Heading: "Hinweise", Text: "Zeugen werden gebeten, sich zu melden."},
+	}
+	if events := splitReport(&Event{Title: "Raub in Späti"}, sections, defaultDistricts); events != nil {
+		t.Fatalf("expected no split, got %+v", events)
+	}
+	if events := splitReport(&Event{Title: "Tägliche Kurzmeldungen"}, sections[:1], defaultDistricts); events != nil {
+		t.Fatalf("expected no split for a single section, got %+v", events)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// statsGroupings turn a wall clock time into the key of its bucket.
+var statsGroupings = map[string]func(time.Time) string{
+	"hour":    func(t time.Time) string { return strconv.Itoa(t.Hour()) },
+	"weekday": func(t time.Time) string { return strconv.Itoa(int(t.Weekday())) },
+	"day":     func(t time.Time) string { return t.Format(time.DateOnly) },
+	"month":   func(t time.Time) string { return t.Format("2006-01") },
+}
+
+type statsBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type statsResult struct {
+	GroupBy string        `json:"groupBy"`
+	Time    string        `json:"time"`
+	Buckets []statsBucket `json:"buckets"`
+	// Unknown counts events without an incident time when grouping by it.
+	Unknown int     `json:"unknown"`
+	Meta    apiMeta `json:"meta"`
+}
+
+// handleStats counts events per hour, weekday (0 is Sunday), day or month,
+// either by publication or by incident time. The from, to and district
+// filters always apply to the publication time.
+func (a *App) handleStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseEventFilter(query)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, codeInvalidFilter, err.Error())
+		return
+	}
+	result, err := a.stats(r.Context(), filter, query.Get("groupBy"), query.Get("time"))
+	var invalid errInvalidStats
+	if errors.As(err, &invalid) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Println("Error computing stats:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// errInvalidStats rejects an unknown grouping or time.
+type errInvalidStats string
+
+func (e errInvalidStats) Error() string {
+	return string(e)
+}
+
+// stats counts the events matching filter, grouped by groupBy ("day" if
+// empty) of their publication or incident time ("published" if empty).
+func (a *App) stats(ctx context.Context, filter EventFilter, groupBy, timeBasis string) (statsResult, error) {
+	result := statsResult{GroupBy: groupBy, Time: timeBasis, Meta: newAPIMeta(a.config)}
+	if result.GroupBy == "" {
+		result.GroupBy = "day"
+	}
+	if result.Time == "" {
+		result.Time = "published"
+	}
+	group, ok := statsGroupings[result.GroupBy]
+	if !ok {
+		return result, errInvalidStats("groupBy must be hour, weekday, day or month")
+	}
+	if result.Time != "published" && result.Time != "incident" {
+		return result, errInvalidStats("time must be published or incident")
+	}
+
+	counts := make(map[string]int)
+	err := a.store.EachBatch(ctx, filter, exportBatchSize, func(events []Event) error {
+		for _, event := range events {
+			unix := event.DateTime
+			if result.Time == "incident" {
+				if event.IncidentTime == nil {
+					result.Unknown++
+					continue
+				}
+				unix = *event.IncidentTime
+			}
+			counts[group(time.Unix(unix, 0).UTC())]++
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Buckets = make([]statsBucket, 0, len(counts))
+	for key, count := range counts {
+		result.Buckets = append(result.Buckets, statsBucket{Key: key, Count: count})
+	}
+	slices.SortFunc(result.Buckets, func(a, b statsBucket) int {
+		// Hours and weekdays sort numerically, dates and months as text.
+		x, errX := strconv.Atoi(a.Key)
+		y, errY := strconv.Atoi(b.Key)
+		if errX == nil && errY == nil {
+			return x - y
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return result, nil
+}
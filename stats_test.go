@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStats_ByPublishedAndIncidentTime(t *testing.T) {
+	app := newTestApp(t)
+	app.config.StatsTokens = []string{"stats"}
+	ctx := context.Background()
+	published := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Title: "a", Description: "Dienstagabend gegen 22:30 Uhr wurde ein Mann angegriffen.", Hash: "a", DateTime: published.Unix()},
+		{Title: "b", Description: "In der Nacht zu Mittwoch gegen 23 Uhr brannte ein Auto.", Hash: "b", DateTime: published.Unix()},
+		{Title: "c", Description: "Die Polizei sucht Zeugen.", Hash: "c", DateTime: published.Add(time.Hour).Unix()},
+	}
+	for i := range events {
+		setIncidentTime(&events[i])
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	get := func(query string) statsResult {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/stats?"+query, nil)
+		req.Header.Set("Authorization", "Bearer stats")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/stats failed: %v", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET /api/stats?%s: unexpected status %d", query, res.StatusCode)
+		}
+		var result statsResult
+		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+			t.Fatalf("decoding stats failed: %v", err)
+		}
+		return result
+	}
+
+	byPublished := get("groupBy=hour")
+	if len(byPublished.Buckets) != 2 || byPublished.Buckets[0] != (statsBucket{Key: "9", Count: 2}) {
+		t.Fatalf("unexpected stats by publication: %+v", byPublished)
+	}
+
+	byIncident := get("groupBy=hour&time=incident")
+	want := []statsBucket{{Key: "22", Count: 1}, {Key: "23", Count: 1}}
+	if byIncident.Unknown != 1 || len(byIncident.Buckets) != 2 || byIncident.Buckets[0] != want[0] || byIncident.Buckets[1] != want[1] {
+		t.Fatalf("unexpected stats by incident time: %+v", byIncident)
+	}
+}
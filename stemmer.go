@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The stemmer is a deliberately small take on the Snowball German stemmer:
+// it folds umlauts and strips common inflection suffixes, so "Raubes",
+// "Schüssen" or "verletzte" reduce to the same stem as the keyword. Past
+// participles are handled by also trying the word without its "ge" prefix
+// ("geraubt" -> "raub"). It trades some precision for having no dependencies;
+// compounds ("Raubüberfall") are still covered by plain substring matching.
+
+var umlautReplacer = strings.NewReplacer("ä", "a", "ö", "o", "ü", "u", "ß", "ss")
+
+// stemSuffixes are tried longest first.
+var stemSuffixes = []string{"erinnen", "erin", "ern", "em", "en", "er", "es", "e", "s"}
+
+const minStemLength = 3
+
+// germanStem reduces a single lower case word to its stem.
+func germanStem(word string) string {
+	word = umlautReplacer.Replace(word)
+	for _, suffix := range stemSuffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= minStemLength {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+	for _, suffix := range []string{"et", "t"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= minStemLength {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// stemVariants returns the stems a word might stand for.
+func stemVariants(word string) []string {
+	variants := []string{germanStem(word)}
+	if rest, ok := strings.CutPrefix(word, "ge"); ok && len(rest) > minStemLength &&
+		(strings.HasSuffix(word, "t") || strings.HasSuffix(word, "en")) {
+		variants = append(variants, germanStem(rest))
+	}
+	return variants
+}
+
+// tokenize splits lower case text into words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// stemmedText is a text prepared for keyword matching.
+type stemmedText struct {
+	plain string
+	words [][]string
+}
+
+func newStemmedText(text string) stemmedText {
+	text = strings.ToLower(text)
+	prepared := stemmedText{plain: umlautReplacer.Replace(text)}
+	for _, word := range tokenize(text) {
+		prepared.words = append(prepared.words, stemVariants(word))
+	}
+	return prepared
+}
+
+// contains reports whether the keyword occurs in the text, either literally
+// (which also covers compounds) or as a sequence of words with matching stems.
+func (t stemmedText) contains(keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	return strings.Contains(t.plain, umlautReplacer.Replace(keyword)) || t.containsWords(keyword)
+}
+
+// containsWords is the stricter variant of contains that only matches whole
+// words, so "bus" doesn't match "Busch".
+func (t stemmedText) containsWords(keyword string) bool {
+	var stems []string
+	for _, word := range tokenize(strings.ToLower(keyword)) {
+		stems = append(stems, germanStem(word))
+	}
+	if len(stems) == 0 {
+		return false
+	}
+outer:
+	for i := 0; i+len(stems) <= len(t.words); i++ {
+		for j, stem := range stems {
+			if !slices.Contains(t.words[i+j], stem) {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
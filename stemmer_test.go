@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestGermanStem(t *testing.T) {
+	tests := map[string]string{
+		"raub":      "raub",
+		"raubes":    "raub",
+		"schüsse":   "schuss",
+		"schüssen":  "schuss",
+		"messern":   "mess",
+		"messer":    "mess",
+		"verletzt":  "verletz",
+		"verletzte": "verletz",
+		"brände":    "brand",
+		"vermisst":  "vermiss",
+		"ist":       "ist",
+	}
+	for word, want := range tests {
+		if got := germanStem(word); got != want {
+			t.Errorf("germanStem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestStemmedText_Contains(t *testing.T) {
+	tests := []struct {
+		text, keyword string
+		want          bool
+	}{
+		{"Wegen des Raubes wird ermittelt", "raub", true},
+		{"Zwei Männer haben einen Kiosk geraubt", "raub", true},
+		{"Raubüberfall in Moabit", "raub", true},
+		{"Überfall auf eine Tankstelle", "überfallen", true},
+		{"Sie wurden bestohlen", "gestohlen", false},
+		{"Mann mit Schüssen verletzt", "schüsse", true},
+		{"Eine Frau wurde schwer verletzte", "schwer verletzt", true},
+		{"Schwer zu sagen, ob jemand verletzt wurde", "schwer verletzt", false},
+		{"Jugendlicher vermisst", "vermisste", true},
+		{"Verkehrskontrolle in Spandau", "raub", false},
+	}
+	for _, test := range tests {
+		if got := newStemmedText(test.text).contains(test.keyword); got != test.want {
+			t.Errorf("%q contains %q = %v, want %v", test.text, test.keyword, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Event struct {
+	gorm.Model
+	Title       string `gorm:"serializer:encrypted"`
+	Description string `gorm:"serializer:encrypted"`
+	Location    string
+	Link        string
+	DateTime    int64
+	Hash        string `gorm:"unique"`
+	// Source names where the event comes from, see sourceInfo.
+	Source string `gorm:"index;default:berlin"`
+	// IdentityHash and ContentHash are maintained by BeforeSave, see
+	// identityHash and contentHash.
+	IdentityHash string `gorm:"index"`
+	ContentHash  string
+	// IncidentTime is when the incident happened according to the text, if
+	// it says so (see parseIncidentTime). Like DateTime it is a Unix time.
+	IncidentTime *int64
+	// ParentHash links an incident split off a compilation report to that
+	// report (see splitReport).
+	ParentHash string `gorm:"index"`
+	// ArchiveSize is the size of the PDF copy of the detail page, zero if
+	// there is none (see archiveEvents).
+	ArchiveSize int64
+	// WaybackURL is the Wayback Machine capture of the detail page, if one
+	// was made (see archiveToWayback).
+	WaybackURL string
+	// LinkCheckedAt is when the link was last checked, LinkDeadSince when it
+	// was first found dead (see checkLinks).
+	LinkCheckedAt *time.Time
+	LinkDeadSince *time.Time
+	// ChangeSummary names the fields the latest re-scrape changed, see
+	// recordRevisions.
+	ChangeSummary string
+	// TakenDownAt is set once the content of the event was removed for good,
+	// see TakeDown.
+	TakenDownAt *time.Time
+
+	// Edits records every manual correction made through the API.
+	Edits []EventEdit `gorm:"serializer:encryptedjson"`
+}
+
+// EventEdit is a single manual change to one field of an event.
+type EventEdit = model.Edit
+
+// Setting is a small key/value record for state that has to survive restarts.
+type Setting struct {
+	Key       string `gorm:"primaryKey"`
+	Value     string
+	UpdatedAt time.Time
+}
+
+const settingLastScrape = "last_successful_scrape"
+
+// EventFilter restricts which events a query returns. Zero values match
+// everything.
+type EventFilter struct {
+	From     time.Time
+	To       time.Time
+	District string
+	// Sources restricts the events to those of the named sources.
+	Sources []string
+	// After leaves out the events up to the cursor, in the order of Recent.
+	After *pageCursor
+}
+
+func (f EventFilter) apply(query *gorm.DB) *gorm.DB {
+	query = query.Where("taken_down_at IS NULL")
+	if !f.From.IsZero() {
+		query = query.Where("date_time >= ?", f.From.Unix())
+	}
+	if !f.To.IsZero() {
+		query = query.Where("date_time < ?", f.To.Unix())
+	}
+	if f.District != "" {
+		query = query.Where("location = ?", f.District)
+	}
+	if len(f.Sources) > 0 {
+		query = query.Where("source IN ?", f.Sources)
+	}
+	if f.After != nil {
+		query = query.Where("date_time < ? OR (date_time = ? AND hash > ?)", f.After.at, f.After.at, f.After.id)
+	}
+	return query
+}
+
+// EventStore persists scraped events.
+type EventStore interface {
+	FindByHash(ctx context.Context, hash string) (*Event, error)
+	FindByIdentity(ctx context.Context, identity string) (*Event, error)
+	Create(ctx context.Context, event *Event) error
+	Upsert(ctx context.Context, event *Event) (bool, error)
+	Update(ctx context.Context, event *Event) error
+	All(ctx context.Context) ([]Event, error)
+	Recent(ctx context.Context, filter EventFilter, limit int) ([]Event, error)
+	EachBatch(ctx context.Context, filter EventFilter, batchSize int, fn func([]Event) error) error
+	// Prune deletes events older than five years, but always keeps the newest
+	// keep events.
+	Prune(ctx context.Context, keep int) error
+
+	LastScrape(ctx context.Context) (time.Time, error)
+	SetLastScrape(ctx context.Context, t time.Time) error
+	ConfigBundle(ctx context.Context) ([]byte, error)
+	SetConfigBundle(ctx context.Context, data []byte) error
+
+	RecordAudit(ctx context.Context, entry *AuditEntry) error
+	AuditLog(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+
+	SaveExportJob(ctx context.Context, job *ExportJob) error
+	FindExportJob(ctx context.Context, id string) (*ExportJob, error)
+	ExportJobs(ctx context.Context, statuses ...string) ([]ExportJob, error)
+
+	QualityReport(ctx context.Context, since, now time.Time) (*QualityReport, error)
+
+	SaveDeadLetter(ctx context.Context, letter *DeadLetter) error
+	FindDeadLetter(ctx context.Context, id uint) (*DeadLetter, error)
+	DeadLetters(ctx context.Context, filter DeadLetterFilter) ([]DeadLetter, error)
+
+	QueueNotification(ctx context.Context, queued *QueuedNotification) error
+	QueuedNotifications(ctx context.Context, target string) ([]QueuedNotification, error)
+	DeleteQueuedNotifications(ctx context.Context, ids []uint) error
+
+	SaveSummary(ctx context.Context, summary *EventSummary) error
+	Summaries(ctx context.Context, hashes []string) (map[string]string, error)
+
+	ReplaceFacts(ctx context.Context, hash string, facts []EventFact) error
+	EventsWithFacts(ctx context.Context, query FactQuery) ([]Event, map[string][]EventFact, error)
+	Facts(ctx context.Context, hashes []string) (map[string][]EventFact, error)
+
+	AddUsage(ctx context.Context, counts []UsageCount) error
+	Usage(ctx context.Context, first, last string) ([]UsageCount, error)
+
+	SaveFollower(ctx context.Context, follower *Follower) error
+	DeleteFollower(ctx context.Context, actorID string) error
+	Followers(ctx context.Context) ([]Follower, error)
+
+	SaveMention(ctx context.Context, mention *Mention) error
+	DeleteMention(ctx context.Context, hash, source string) error
+	Mentions(ctx context.Context, hash string) ([]Mention, error)
+
+	MissingArchives(ctx context.Context, since time.Time, limit int) ([]Event, error)
+	SetArchiveSize(ctx context.Context, hash string, size int64) error
+
+	MissingWayback(ctx context.Context, since time.Time, limit int) ([]Event, error)
+	SetWaybackURL(ctx context.Context, hash, waybackURL string) error
+
+	LinksToCheck(ctx context.Context, before time.Time, limit int) ([]Event, error)
+	SetLinkStatus(ctx context.Context, hash string, checkedAt time.Time, deadSince *time.Time) error
+	LinkRot(ctx context.Context) ([]LinkRotCount, error)
+
+	AddRevisions(ctx context.Context, revisions []EventRevision) error
+	Revisions(ctx context.Context, hash string) ([]EventRevision, error)
+	SetChangeSummary(ctx context.Context, hash, summary string) error
+
+	ShortLink(ctx context.Context, hash, channel string) (*ShortLink, error)
+	FollowShortLink(ctx context.Context, id uint) (*ShortLink, error)
+	ShortLinkClicks(ctx context.Context) ([]ShortLinkClicks, error)
+	TopShortLinks(ctx context.Context, limit int) ([]ShortLink, error)
+
+	SaveJournalEntry(ctx context.Context, event *Event) error
+	JournalEntries(ctx context.Context) ([]JournalEntry, error)
+	DeleteJournalEntries(ctx context.Context, hashes []string) error
+
+	TakeDown(ctx context.Context, hash string, at time.Time) ([]string, error)
+	Hide(ctx context.Context, hash string) ([]string, error)
+
+	SaveReceipt(ctx context.Context, receipt *NotificationReceipt) error
+	Receipt(ctx context.Context, target, hash string) (*NotificationReceipt, error)
+	ReceiptHashes(ctx context.Context) ([]string, error)
+	RedactionRules(ctx context.Context) ([]byte, error)
+	SetRedactionRules(ctx context.Context, data []byte) error
+
+	SitemapEntries(ctx context.Context, limit int) ([]SitemapEntry, error)
+
+	Changes(ctx context.Context, since uint64, limit int) ([]EventChange, error)
+	EventsByHash(ctx context.Context, hashes []string) ([]Event, error)
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) (EventStore, error) {
+	err := db.AutoMigrate(&Event{}, &Setting{}, &AuditEntry{}, &ExportJob{}, &SchemaMigration{}, &DeadLetter{}, &QueuedNotification{}, &EventSummary{}, &EventFact{}, &UsageCount{}, &Follower{}, &Mention{}, &EventRevision{}, &ShortLink{}, &JournalEntry{}, &NotificationReceipt{}, &EventChange{})
+	if err != nil {
+		return nil, err
+	}
+	return &gormStore{db: db}, nil
+}
+
+func (s *gormStore) FindByHash(ctx context.Context, hash string) (*Event, error) {
+	var event Event
+	err := s.db.WithContext(ctx).First(&event, &Event{Hash: hash}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindByIdentity returns the event with the given identity hash. Should older
+// data contain the same report twice, the first one stored wins.
+func (s *gormStore) FindByIdentity(ctx context.Context, identity string) (*Event, error) {
+	var event Event
+	err := s.db.WithContext(ctx).Where("identity_hash = ?", identity).Order("id").First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (s *gormStore) Create(ctx context.Context, event *Event) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(event).Error
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changeCreated, event.Hash)
+	})
+}
+
+// scrapedColumns are the columns a re-scrape may change. The publication time
+// never changes, and the hash stays the public ID even if the title does.
+var scrapedColumns = []string{"title", "description", "location", "link", "incident_time", "parent_hash", "identity_hash", "content_hash", "updated_at"}
+
+// Upsert creates the event, or refreshes the scraped columns of the stored
+// event with the same hash if its content changed. It reports whether a row
+// was written.
+func (s *gormStore) Upsert(ctx context.Context, event *Event) (bool, error) {
+	var written bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		err := tx.Model(&Event{}).Where("hash = ?", event.Hash).Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.AssignmentColumns(scrapedColumns),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "events.content_hash <> excluded.content_hash OR events.link <> excluded.link"},
+			}},
+		}).Create(event)
+		written = result.RowsAffected > 0
+		if result.Error != nil || !written {
+			return result.Error
+		}
+		kind := changeCreated
+		if existing > 0 {
+			kind = changeUpdated
+		}
+		return recordChanges(tx, kind, event.Hash)
+	})
+	return written, err
+}
+
+func (s *gormStore) Update(ctx context.Context, event *Event) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(event).Error
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changeUpdated, event.Hash)
+	})
+}
+
+func (s *gormStore) All(ctx context.Context) ([]Event, error) {
+	var events []Event
+	err := s.db.WithContext(ctx).Where("taken_down_at IS NULL").Find(&events).Error
+	return events, err
+}
+
+// Recent returns the newest matching events by publication time, newest
+// first. Ties are broken by hash to keep the result stable.
+func (s *gormStore) Recent(ctx context.Context, filter EventFilter, limit int) ([]Event, error) {
+	var events []Event
+	err := filter.apply(s.db.WithContext(ctx)).Order("date_time DESC").Order("hash").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// EachBatch calls fn with consecutive batches of the matching events, so that
+// large result sets never have to be held in memory at once.
+func (s *gormStore) EachBatch(ctx context.Context, filter EventFilter, batchSize int, fn func([]Event) error) error {
+	var batch []Event
+	return filter.apply(s.db.WithContext(ctx).Model(&Event{})).
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+func (s *gormStore) Prune(ctx context.Context, keep int) error {
+	return pruneEvents(ctx, s.db, keep)
+}
+
+// LastScrape returns the start time of the last successful scrape run, or the
+// zero time if there has not been one yet.
+func (s *gormStore) LastScrape(ctx context.Context) (time.Time, error) {
+	var setting Setting
+	err := s.db.WithContext(ctx).First(&setting, "key = ?", settingLastScrape).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(setting.Value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
+
+func (s *gormStore) SetLastScrape(ctx context.Context, t time.Time) error {
+	return s.db.WithContext(ctx).Save(&Setting{Key: settingLastScrape, Value: strconv.FormatInt(t.Unix(), 10)}).Error
+}
+
+// checkDuplicate reports whether a report was stored before, by its identity
+// hash.
+func checkDuplicate(ctx context.Context, event *Event, store EventStore, seen *hashCache) (bool, error) {
+	identity := identityHash(event)
+	if seen.Contains(identity) {
+		return true, nil
+	}
+	_, err := store.FindByIdentity(ctx, identity)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// pruneEvents deletes events older than five years, except for the newest
+// keep events that weren't taken down, so that a feed that has been quiet
+// for long, e.g. after a long downtime, never runs empty.
+func pruneEvents(ctx context.Context, db *gorm.DB, keep int) error {
+	lastTime := time.Now().AddDate(-5, 0, 0).Unix()
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := func(db *gorm.DB) *gorm.DB {
+			db = db.Where("date_time < ?", lastTime)
+			if keep > 0 {
+				newest := tx.Model(&Event{}).Select("id").Where("taken_down_at IS NULL").Order("date_time DESC").Order("id DESC").Limit(keep)
+				db = db.Where("id NOT IN (?)", newest)
+			}
+			return db
+		}
+		var hashes []string
+		err := tx.Model(&Event{}).Scopes(expired).Pluck("hash", &hashes).Error
+		if err != nil || len(hashes) == 0 {
+			return err
+		}
+		err = tx.Scopes(expired).Delete(&Event{}).Error
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changeDeleted, hashes...)
+	})
+}
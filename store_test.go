@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckDuplicate_InCache(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	ev := &Event{Hash: "h1", Link: "https://www.berlin.de/h1"}
+	seen := newHashCache(10)
+	seen.Add(identityHash(ev))
+
+	got, err := checkDuplicate(context.Background(), ev, store, seen)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got {
+		t.Fatalf("expected duplicate in cache, got false")
+	}
+}
+
+func TestCheckDuplicate_InDB(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	db.Create(&Event{Hash: "h2", Title: "t"})
+
+	ev := &Event{Hash: "h2"}
+
+	got, err := checkDuplicate(context.Background(), ev, store, newHashCache(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got {
+		t.Fatalf("expected duplicate in db, got false")
+	}
+}
+
+func TestCheckDuplicate_NotDuplicate(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	ev := &Event{Hash: "h3"}
+
+	got, err := checkDuplicate(context.Background(), ev, store, newHashCache(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got {
+		t.Fatalf("expected not duplicate, got true")
+	}
+}
+
+func TestPruneEvents(t *testing.T) {
+	db := openTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	old := Event{
+		Title:    "old",
+		DateTime: time.Now().AddDate(-6, 0, 0).Unix(),
+		Hash:     "oldhash",
+	}
+	newE := Event{
+		Title:    "new",
+		DateTime: time.Now().Unix(),
+		Hash:     "newhash",
+	}
+
+	if err := db.Create(&old).Error; err != nil {
+		t.Fatalf("create old event failed: %v", err)
+	}
+	if err := db.Create(&newE).Error; err != nil {
+		t.Fatalf("create new event failed: %v", err)
+	}
+
+	if err := pruneEvents(context.Background(), db, 0); err != nil {
+		t.Fatalf("pruneEvents returned error: %v", err)
+	}
+
+	var remaining []Event
+	if err := db.Find(&remaining).Error; err != nil {
+		t.Fatalf("find remaining failed: %v", err)
+	}
+
+	if len(remaining) != 1 {
+		t.Fatalf("expected 1 remaining event, got %d", len(remaining))
+	}
+	if remaining[0].Hash != "newhash" {
+		t.Fatalf("expected newhash remaining, got %s", remaining[0].Hash)
+	}
+}
+
+func TestPruneEvents_KeepsNewest(t *testing.T) {
+	db := openTestDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+
+	takenDown := time.Now()
+	events := []Event{
+		{Title: "new", DateTime: time.Now().Unix(), Hash: "new"},
+		{Title: "tombstone", DateTime: time.Now().AddDate(-6, 0, 0).Unix(), Hash: "tombstone", TakenDownAt: &takenDown},
+		{Title: "old", DateTime: time.Now().AddDate(-7, 0, 0).Unix(), Hash: "old"},
+		{Title: "older", DateTime: time.Now().AddDate(-8, 0, 0).Unix(), Hash: "older"},
+	}
+	if err := db.Create(&events).Error; err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	// The tombstone doesn't count towards the events kept.
+	if err := pruneEvents(context.Background(), db, 2); err != nil {
+		t.Fatalf("pruneEvents returned error: %v", err)
+	}
+	var remaining []string
+	if err := db.Model(&Event{}).Order("date_time DESC").Pluck("hash", &remaining).Error; err != nil {
+		t.Fatalf("find remaining failed: %v", err)
+	}
+	if !slices.Equal(remaining, []string{"new", "old"}) {
+		t.Fatalf("expected the two newest events to be kept, got %v", remaining)
+	}
+	var deleted []string
+	if err := db.Model(&EventChange{}).Where("kind = ?", changeDeleted).Order("event_hash").Pluck("event_hash", &deleted).Error; err != nil {
+		t.Fatalf("find changes failed: %v", err)
+	}
+	if !slices.Equal(deleted, []string{"older", "tombstone"}) {
+		t.Fatalf("expected the pruned events to be recorded as deleted, got %v", deleted)
+	}
+}
+
+func TestLastScrape_RoundTrip(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+	ctx := context.Background()
+
+	last, err := store.LastScrape(ctx)
+	if err != nil {
+		t.Fatalf("LastScrape returned error: %v", err)
+	}
+	if !last.IsZero() {
+		t.Fatalf("expected zero time before first scrape, got %v", last)
+	}
+
+	now := time.Unix(time.Now().Unix(), 0)
+	if err := store.SetLastScrape(ctx, now); err != nil {
+		t.Fatalf("SetLastScrape returned error: %v", err)
+	}
+	if err := store.SetLastScrape(ctx, now); err != nil {
+		t.Fatalf("SetLastScrape overwrite returned error: %v", err)
+	}
+	last, err = store.LastScrape(ctx)
+	if err != nil {
+		t.Fatalf("LastScrape returned error: %v", err)
+	}
+	if !last.Equal(now) {
+		t.Fatalf("expected %v, got %v", now, last)
+	}
+}
+
+func TestUpsert_RefreshesChangedEvents(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+	ctx := context.Background()
+
+	event := Event{Title: "t", Description: defaultDescription, Location: "Mitte", Hash: "up", DateTime: 1}
+	written, err := store.Upsert(ctx, &event)
+	if err != nil || !written {
+		t.Fatalf("expected insert, got written=%v err=%v", written, err)
+	}
+
+	same := Event{Title: "t", Description: defaultDescription, Location: "Mitte", Hash: "up", DateTime: 1}
+	written, err = store.Upsert(ctx, &same)
+	if err != nil || written {
+		t.Fatalf("expected unchanged event not to be written, got written=%v err=%v", written, err)
+	}
+
+	improved := Event{Title: "t", Description: "Ausführlich", Location: "Pankow", Hash: "up", DateTime: 1}
+	written, err = store.Upsert(ctx, &improved)
+	if err != nil || !written {
+		t.Fatalf("expected update, got written=%v err=%v", written, err)
+	}
+	stored, err := store.FindByHash(ctx, "up")
+	if err != nil {
+		t.Fatalf("FindByHash failed: %v", err)
+	}
+	if stored.Description != "Ausführlich" || stored.Location != "Pankow" || stored.ID != event.ID {
+		t.Fatalf("event not refreshed in place: %+v", stored)
+	}
+	if !stored.UpdatedAt.After(stored.CreatedAt) {
+		t.Fatalf("expected UpdatedAt after CreatedAt, got %v and %v", stored.UpdatedAt, stored.CreatedAt)
+	}
+}
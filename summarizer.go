@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Summarizer condenses the text of a long report into one or two sentences.
+type Summarizer interface {
+	// Model identifies the backend and model the summaries come from.
+	Model() string
+	Summarize(ctx context.Context, text string) (string, error)
+}
+
+const summaryPrompt = "Fasse die folgende Polizeimeldung in ein bis zwei sachlichen Sätzen auf Deutsch zusammen. Antworte nur mit der Zusammenfassung."
+
+// EventSummary is a generated summary, kept apart from the scraped event data.
+// It doubles as cache: every event is summarized at most once.
+type EventSummary struct {
+	EventHash string `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Model     string
+	Summary   string `gorm:"serializer:encrypted"`
+}
+
+func (s *gormStore) SaveSummary(ctx context.Context, summary *EventSummary) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(summary).Error
+}
+
+// Summaries returns the stored summaries of the given events by hash.
+func (s *gormStore) Summaries(ctx context.Context, hashes []string) (map[string]string, error) {
+	var summaries []EventSummary
+	err := s.db.WithContext(ctx).Where("event_hash IN ?", hashes).Find(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]string, len(summaries))
+	for _, summary := range summaries {
+		byHash[summary.EventHash] = summary.Summary
+	}
+	return byHash, nil
+}
+
+// openAISummarizer talks to an OpenAI compatible chat completions API.
+type openAISummarizer struct {
+	url, model, apiKey string
+	client             HTTPDoer
+}
+
+func (s *openAISummarizer) Model() string { return "openai:" + s.model }
+
+func (s *openAISummarizer) Summarize(ctx context.Context, text string) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	var response struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+	}
+	err := postJSONRequest(ctx, s.client, strings.TrimSuffix(s.url, "/")+"/chat/completions", s.apiKey, map[string]any{
+		"model": s.model,
+		"messages": []message{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: text},
+		},
+		"max_tokens": 150,
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", errors.New("no choices in response")
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// ollamaSummarizer talks to a local Ollama server.
+type ollamaSummarizer struct {
+	url, model string
+	client     HTTPDoer
+}
+
+func (s *ollamaSummarizer) Model() string { return "ollama:" + s.model }
+
+func (s *ollamaSummarizer) Summarize(ctx context.Context, text string) (string, error) {
+	var response struct {
+		Response string `json:"response"`
+	}
+	err := postJSONRequest(ctx, s.client, strings.TrimSuffix(s.url, "/")+"/api/generate", "", map[string]any{
+		"model":  s.model,
+		"prompt": summaryPrompt + "\n\n" + text,
+		"stream": false,
+	}, &response)
+	return response.Response, err
+}
+
+// postJSONRequest POSTs body as JSON and decodes the JSON response into v.
+func postJSONRequest(ctx context.Context, client HTTPDoer, url, token string, body, v any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return fmt.Errorf("%s responded with %s", url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// buildSummarizer creates the configured summarizer, or nil if summaries are
+// disabled (the default).
+func buildSummarizer(config Config) (Summarizer, error) {
+	if config.Summarizer == "" {
+		return nil, nil
+	}
+	if config.SummarizerModel == "" {
+		return nil, errors.New("SUMMARIZER_MODEL is required")
+	}
+	client := &http.Client{}
+	url := config.SummarizerURL
+	switch config.Summarizer {
+	case "openai":
+		if url == "" {
+			url = "https://api.openai.com/v1"
+		}
+		return &openAISummarizer{url: url, model: config.SummarizerModel, apiKey: config.SummarizerAPIKey, client: client}, nil
+	case "ollama":
+		if url == "" {
+			url = "http://localhost:11434"
+		}
+		return &ollamaSummarizer{url: url, model: config.SummarizerModel, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown summarizer %q (available: openai, ollama)", config.Summarizer)
+	}
+}
+
+// summarize returns summaries for the events whose description is at least
+// SummarizerMinLength long, generating the missing ones. Each generation is
+// bounded by SummarizerTimeout; failures only mean an event goes out without
+// a summary.
+func (a *App) summarize(ctx context.Context, events []Event) map[string]string {
+	if a.summarizer == nil {
+		return nil
+	}
+	var hashes []string
+	for _, event := range events {
+		if len(event.Description) >= a.config.SummarizerMinLength {
+			hashes = append(hashes, event.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	summaries, err := a.store.Summaries(ctx, hashes)
+	if err != nil {
+		log.Println("Error loading summaries:", err)
+		summaries = make(map[string]string)
+	}
+	for _, event := range events {
+		if _, done := summaries[event.Hash]; done || len(event.Description) < a.config.SummarizerMinLength {
+			continue
+		}
+
+		summarizeCtx, cancel := context.WithTimeout(ctx, a.config.SummarizerTimeout)
+		summary, err := a.summarizer.Summarize(summarizeCtx, event.Title+"\n\n"+event.Description)
+		cancel()
+		summary = strings.TrimSpace(summary)
+		if err != nil || summary == "" {
+			log.Printf("Error summarizing %s with %s: %v", event.Hash, a.summarizer.Model(), err)
+			continue
+		}
+
+		summaries[event.Hash] = summary
+		err = a.store.SaveSummary(context.WithoutCancel(ctx), &EventSummary{
+			EventHash: event.Hash,
+			Model:     a.summarizer.Model(),
+			Summary:   summary,
+		})
+		if err != nil {
+			log.Printf("Error saving summary of %s: %v", event.Hash, err)
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingSummarizer struct {
+	calls int
+	err   error
+}
+
+func (s *countingSummarizer) Model() string { return "test" }
+
+func (s *countingSummarizer) Summarize(ctx context.Context, text string) (string, error) {
+	s.calls++
+	if s.err != nil {
+		return "", s.err
+	}
+	return "Kurz: " + strings.SplitN(text, "\n", 2)[0], nil
+}
+
+func TestSummarize_CachesAndSkipsShortEvents(t *testing.T) {
+	app := newTestApp(t)
+	app.config.SummarizerMinLength = 20
+	app.config.SummarizerTimeout = time.Second
+	summarizer := &countingSummarizer{}
+	app.summarizer = summarizer
+
+	events := []Event{
+		{Title: "Lang", Description: strings.Repeat("Text ", 10), Hash: "long"},
+		{Title: "Kurz", Description: "Text", Hash: "short"},
+	}
+	summaries := app.summarize(context.Background(), events)
+	if summaries["long"] != "Kurz: Lang" || summaries["short"] != "" || summarizer.calls != 1 {
+		t.Fatalf("unexpected summaries %v after %d calls", summaries, summarizer.calls)
+	}
+
+	summaries = app.summarize(context.Background(), events)
+	if summaries["long"] != "Kurz: Lang" || summarizer.calls != 1 {
+		t.Fatalf("expected the cached summary, got %v after %d calls", summaries, summarizer.calls)
+	}
+
+	payload := newWebhookPayload(Notification{Event: events[0], Summaries: summaries})
+	if payload.Event.Summary != "Kurz: Lang" {
+		t.Fatalf("summary missing from payload: %+v", payload.Event)
+	}
+}
+
+func TestSummarize_FailureLeavesEventUnsummarized(t *testing.T) {
+	app := newTestApp(t)
+	app.config.SummarizerTimeout = time.Second
+	app.summarizer = &countingSummarizer{err: errors.New("timeout")}
+
+	summaries := app.summarize(context.Background(), []Event{{Description: "lang genug", Hash: "x"}})
+	if summaries["x"] != "" {
+		t.Fatalf("expected no summary, got %q", summaries["x"])
+	}
+}
+
+func TestSummarizerBackends(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "m" {
+			t.Errorf("unexpected model in %v", body)
+		}
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			if r.Header.Get("Authorization") != "Bearer key" {
+				t.Errorf("missing api key")
+			}
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"openai summary"}}]}`))
+		case "/api/generate":
+			_, _ = w.Write([]byte(`{"response":"ollama summary"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for provider, want := range map[string]string{"openai": "openai summary", "ollama": "ollama summary"} {
+		url := server.URL
+		if provider == "openai" {
+			url += "/v1"
+		}
+		summarizer, err := buildSummarizer(Config{Summarizer: provider, SummarizerURL: url, SummarizerModel: "m", SummarizerAPIKey: "key"})
+		if err != nil {
+			t.Fatalf("buildSummarizer(%s) failed: %v", provider, err)
+		}
+		got, err := summarizer.Summarize(context.Background(), "text")
+		if err != nil || got != want {
+			t.Fatalf("%s: got %q (%v), want %q", provider, got, err, want)
+		}
+	}
+
+	if s, err := buildSummarizer(Config{}); s != nil || err != nil {
+		t.Fatalf("expected summaries to be disabled by default")
+	}
+	if _, err := buildSummarizer(Config{Summarizer: "gpt"}); err == nil {
+		t.Fatalf("expected error for unknown summarizer")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Every write to an event is recorded as an EventChange, whose ID serves as
+// a version counter: a client that synced up to version n asks for the
+// changes after n and applies them, including deletions, which plain event
+// listings cannot express.
+
+// Kinds of event changes.
+const (
+	changeCreated = "created"
+	changeUpdated = "updated"
+	changeDeleted = "deleted"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 5000
+)
+
+// EventChange records that an event was created, updated or deleted (taken
+// down or pruned). IDs increase monotonically, and in the order the changes
+// are committed, see recordChanges.
+type EventChange struct {
+	ID        uint64 `gorm:"primaryKey"`
+	EventHash string `gorm:"index;not null"`
+	Kind      string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// syncLockKey identifies the Postgres advisory lock taken by recordChanges.
+const syncLockKey = 7380
+
+// recordChanges adds a change of the given kind for each hash, as part of
+// the write in tx.
+//
+// A client that synced up to version n never asks for lower versions again,
+// so IDs must become visible in order. On Postgres, concurrent transactions
+// would draw IDs in one order and commit in another, so they take turns: the
+// lock is held until tx ends, and the next transaction draws its IDs only
+// after that. SQLite writes one transaction at a time anyway.
+func recordChanges(tx *gorm.DB, kind string, hashes ...string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	if isPostgres(tx) {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", syncLockKey).Error
+		if err != nil {
+			return err
+		}
+	}
+	changes := make([]EventChange, len(hashes))
+	for i, hash := range hashes {
+		changes[i] = EventChange{EventHash: hash, Kind: kind}
+	}
+	return tx.Create(&changes).Error
+}
+
+// Changes returns up to limit changes after the version since, oldest
+// first.
+func (s *gormStore) Changes(ctx context.Context, since uint64, limit int) ([]EventChange, error) {
+	var changes []EventChange
+	err := s.db.WithContext(ctx).Where("id > ?", since).Order("id").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// EventsByHash returns the stored events with the given hashes.
+func (s *gormStore) EventsByHash(ctx context.Context, hashes []string) ([]Event, error) {
+	var events []Event
+	err := s.db.WithContext(ctx).Where("hash IN ?", hashes).Find(&events).Error
+	return events, err
+}
+
+// backfillEventChanges records existing events as created, in publication
+// order, so that the first sync of a client returns them.
+func backfillEventChanges(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO event_changes (event_hash, kind, created_at)
+		SELECT hash, ?, ? FROM events
+		WHERE deleted_at IS NULL AND taken_down_at IS NULL AND hash NOT IN (SELECT event_hash FROM event_changes)
+		ORDER BY date_time, id`, changeCreated, time.Now()).Error
+}
+
+// syncResponse lists the events changed since the requested version. Events
+// changed several times appear once, in the list of their latest change; an
+// event created and updated since then counts as created.
+type syncResponse struct {
+	// Version is the version to ask for next.
+	Version uint64     `json:"version"`
+	HasMore bool       `json:"hasMore"`
+	Created []apiEvent `json:"created"`
+	Updated []apiEvent `json:"updated"`
+	Deleted []string   `json:"deleted"`
+}
+
+// handleSync returns the event changes after ?since_version=, 0 for all
+// events, in batches of ?limit=. Clients repeat the request with the
+// returned version until hasMore is false.
+func (a *App) handleSync(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var since uint64
+	var err error
+	if value := query.Get("since_version"); value != "" {
+		since, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, codeInvalidRequest, "since_version must be a non-negative number")
+			return
+		}
+	}
+	limit := defaultSyncLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSyncLimit {
+			writeProblem(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxSyncLimit))
+			return
+		}
+	}
+
+	changes, err := a.store.Changes(r.Context(), since, limit+1)
+	if err != nil {
+		log.Println("Error loading changes:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	response := syncResponse{Version: since, Created: []apiEvent{}, Updated: []apiEvent{}, Deleted: []string{}}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		response.HasMore = true
+	}
+
+	// The kind of the latest change wins, except that created sticks.
+	kinds := make(map[string]string)
+	var order []string
+	for _, change := range changes {
+		response.Version = change.ID
+		previous, seen := kinds[change.EventHash]
+		if !seen {
+			order = append(order, change.EventHash)
+		}
+		if previous != changeCreated || change.Kind == changeDeleted {
+			kinds[change.EventHash] = change.Kind
+		}
+	}
+	var live []string
+	for _, hash := range order {
+		if kinds[hash] != changeDeleted {
+			live = append(live, hash)
+		}
+	}
+	events := map[string]Event{}
+	if len(live) > 0 {
+		found, err := a.store.EventsByHash(r.Context(), live)
+		if err != nil {
+			log.Println("Error loading changed events:", err)
+			writeJSONError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		for _, event := range a.redactions.events(found) {
+			events[event.Hash] = event
+		}
+	}
+	for _, hash := range order {
+		event, ok := events[hash]
+		switch {
+		case kinds[hash] == changeDeleted || !ok || event.TakenDownAt != nil:
+			response.Deleted = append(response.Deleted, hash)
+		case kinds[hash] == changeCreated:
+			response.Created = append(response.Created, toAPIEvent(&event))
+		default:
+			response.Updated = append(response.Updated, toAPIEvent(&event))
+		}
+	}
+	newAPIMeta(a.config).setHeaders(w)
+	writeJSON(w, http.StatusOK, response)
+}
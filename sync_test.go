@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func getSync(t *testing.T, server *httptest.Server, since uint64, limit int) syncResponse {
+	t.Helper()
+	res, err := http.Get(server.URL + "/api/sync?since_version=" + strconv.FormatUint(since, 10) + "&limit=" + strconv.Itoa(limit))
+	if err != nil {
+		t.Fatalf("GET /api/sync failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", res.StatusCode)
+	}
+	var response syncResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("decoding response failed: %v", err)
+	}
+	return response
+}
+
+func hashesOf(events []apiEvent) []string {
+	hashes := []string{}
+	for _, event := range events {
+		hashes = append(hashes, event.Hash)
+	}
+	return hashes
+}
+
+func TestSync(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	now := time.Now().Unix()
+	for _, event := range []Event{
+		{Title: "Eins", Hash: "a", Link: "https://example.com/a", DateTime: now},
+		{Title: "Zwei", Hash: "b", Link: "https://example.com/b", DateTime: now},
+		{Title: "Drei", Hash: "c", Link: "https://example.com/c", DateTime: now},
+	} {
+		if err := app.store.Create(ctx, &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	// A first sync in pages returns everything as created.
+	first := getSync(t, server, 0, 2)
+	if !first.HasMore || !slices.Equal(hashesOf(first.Created), []string{"a", "b"}) {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	second := getSync(t, server, first.Version, 2)
+	if second.HasMore || !slices.Equal(hashesOf(second.Created), []string{"c"}) {
+		t.Fatalf("unexpected second page %+v", second)
+	}
+	if again := getSync(t, server, second.Version, 2); again.Version != second.Version || len(again.Created)+len(again.Updated)+len(again.Deleted) != 0 {
+		t.Fatalf("expected no changes, got %+v", again)
+	}
+
+	// Re-scrapes, edits and takedowns show up after the last version.
+	changed := Event{Title: "Eins, ergänzt", Hash: "a", Link: "https://example.com/a", DateTime: now}
+	if written, err := app.store.Upsert(ctx, &changed); err != nil || !written {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	unchanged := Event{Title: "Zwei", Hash: "b", Link: "https://example.com/b", DateTime: now}
+	if written, err := app.store.Upsert(ctx, &unchanged); err != nil || written {
+		t.Fatalf("expected an unchanged upsert to write nothing: %v", err)
+	}
+	added := Event{Title: "Vier", Hash: "d", Link: "https://example.com/d", DateTime: now}
+	if _, err := app.store.Upsert(ctx, &added); err != nil {
+		t.Fatal(err)
+	}
+	edited, _ := app.store.FindByHash(ctx, "d")
+	edited.Title = "Vier, korrigiert"
+	if err := app.store.Update(ctx, edited); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.store.TakeDown(ctx, "c", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	delta := getSync(t, server, second.Version, 100)
+	if !slices.Equal(hashesOf(delta.Updated), []string{"a"}) || delta.Updated[0].Title != "Eins, ergänzt" {
+		t.Fatalf("expected a as updated, got %+v", delta.Updated)
+	}
+	if !slices.Equal(hashesOf(delta.Created), []string{"d"}) || delta.Created[0].Title != "Vier, korrigiert" {
+		t.Fatalf("expected d as created in its latest version, got %+v", delta.Created)
+	}
+	if !slices.Equal(delta.Deleted, []string{"c"}) {
+		t.Fatalf("expected c as deleted, got %v", delta.Deleted)
+	}
+
+	for _, query := range []string{"since_version=-1", "limit=0", "limit=100000"} {
+		res, err := http.Get(server.URL + "/api/sync?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", query, res.StatusCode)
+		}
+	}
+}
+
+func TestBackfillEventChanges(t *testing.T) {
+	store, db := openTestStore(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}()
+	ctx := context.Background()
+	if err := store.Create(ctx, &Event{Title: "Neu", Hash: "new", DateTime: 300}); err != nil {
+		t.Fatal(err)
+	}
+	// Events stored before changes were recorded.
+	for _, event := range []Event{{Title: "Alt", Hash: "old", DateTime: 200}, {Title: "Älter", Hash: "older", DateTime: 100}} {
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range 2 {
+		if err := backfillEventChanges(db); err != nil {
+			t.Fatalf("backfill failed: %v", err)
+		}
+	}
+	changes, err := store.Changes(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []string
+	for _, change := range changes {
+		hashes = append(hashes, change.EventHash+":"+change.Kind)
+	}
+	if !slices.Equal(hashes, []string{"new:created", "older:created", "old:created"}) {
+		t.Fatalf("unexpected changes %v", hashes)
+	}
+}
+
+// openSyncTestDB opens the Postgres database in TEST_POSTGRES_URL if set, as
+// that is where concurrent writers could commit out of order, else a SQLite
+// file with the connection pools of the server.
+func openSyncTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	var db *gorm.DB
+	var err error
+	if url := os.Getenv("TEST_POSTGRES_URL"); url != "" {
+		db, err = openDatabase(url)
+	} else {
+		db, err = openServerDatabase(Config{DatabaseURL: "sqlite:" + filepath.Join(t.TempDir(), "events.db"), DatabaseReadConns: 4, DatabaseWriteConns: 1})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	return db
+}
+
+func TestChanges_ConcurrentWritersAreNotSkipped(t *testing.T) {
+	store, err := NewGormStore(openSyncTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	var since uint64
+	if changes, err := store.Changes(ctx, 0, maxSyncLimit); err == nil && len(changes) > 0 {
+		since = changes[len(changes)-1].ID
+	}
+
+	const writers, perWriter = 8, 25
+	prefix := fmt.Sprintf("concurrent-%d-", time.Now().UnixNano())
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Go(func() {
+			for i := range perWriter {
+				hash := fmt.Sprintf("%s%d-%d", prefix, w, i)
+				if err := store.Create(ctx, &Event{Title: hash, Hash: hash, DateTime: int64(i)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Sync like a client while the writers are busy; a change committed after
+	// a higher version was handed out would never be seen.
+	seen := 0
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		changes, err := store.Changes(ctx, since, maxSyncLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, change := range changes {
+			if strings.HasPrefix(change.EventHash, prefix) {
+				seen++
+			}
+			since = change.ID
+		}
+	}
+	if seen != writers*perWriter {
+		t.Fatalf("expected %d changes, a syncing client saw %d", writers*perWriter, seen)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const auditActionTakedown = "event.takedown"
+
+// takedownColumns are cleared when an event is taken down. The hash, the
+// identity hash and the publication time remain as a tombstone, so that the
+// hash keeps answering 410 Gone and re-scrapes don't bring the report back.
+var takedownColumns = []string{
+	"title", "description", "location", "link", "content_hash", "incident_time",
+	"archive_size", "wayback_url", "link_checked_at", "link_dead_since",
+	"change_summary", "edits", "taken_down_at", "updated_at",
+}
+
+// TakeDown permanently removes the content of the event and of the incidents
+// split off it, along with everything derived from them. It returns the
+// hashes taken down.
+func (s *gormStore) TakeDown(ctx context.Context, hash string, at time.Time) ([]string, error) {
+	var hashes []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Event{}).Where("hash = ? OR parent_hash = ?", hash, hash).Pluck("hash", &hashes).Error
+		if err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// UpdateColumns skips BeforeSave, which would derive a new identity
+		// from the cleared link.
+		err = tx.Model(&Event{}).Where("hash IN ?", hashes).Select(takedownColumns).
+			UpdateColumns(&Event{Edits: []EventEdit{}, TakenDownAt: &at, Model: gorm.Model{UpdatedAt: at}}).Error
+		if err != nil {
+			return err
+		}
+		for _, model := range []any{&EventRevision{}, &EventSummary{}, &EventFact{}, &Mention{}, &QueuedNotification{}, &DeadLetter{}} {
+			err = tx.Where("event_hash IN ?", hashes).Delete(model).Error
+			if err != nil {
+				return err
+			}
+		}
+		// Edits record the old and new values of the fields; the entries
+		// stay, but without them.
+		err = tx.Model(&AuditEntry{}).Where("action = ? AND target IN ?", auditActionEventEdit, hashes).Update("payload", "").Error
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changeDeleted, hashes...)
+	})
+	return hashes, err
+}
+
+// tombstone is what remains of an event that was taken down.
+type tombstone struct {
+	Hash        string    `json:"hash"`
+	TakenDownAt time.Time `json:"takenDownAt"`
+}
+
+// writeTombstone answers requests for an event that was taken down.
+func writeTombstone(w http.ResponseWriter, event *Event) {
+	writeJSON(w, http.StatusGone, tombstone{Hash: event.Hash, TakenDownAt: event.TakenDownAt.UTC()})
+}
+
+// handleTakedown permanently removes an event's content, e.g. if the police
+// retract a report: from the database and audit log, the feeds, the response
+// cache, the PDF copies, finished exports and the files of removeDerivedFiles.
+// Targets that support corrections are asked to delete what they were sent.
+// Only the tombstone remains.
+func (a *App) handleTakedown(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(request.Reason) == "" {
+		writeJSONError(w, http.StatusBadRequest, "reason must not be empty")
+		return
+	}
+
+	event, err := a.store.FindByHash(r.Context(), r.PathValue("hash"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "event not found")
+		return
+	}
+	if err != nil {
+		log.Println("Error loading event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if event.TakenDownAt != nil {
+		writeTombstone(w, event)
+		return
+	}
+
+	now := time.Now().UTC()
+	hashes, err := a.store.TakeDown(r.Context(), event.Hash, now)
+	if err != nil {
+		log.Println("Error taking down event:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	for _, hash := range hashes {
+		a.feed.Remove(hash)
+		err := os.Remove(archivePath(a.config.ArchiveDir, hash))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error removing archive copy of %s: %v", hash, err)
+		}
+		a.correct(r.Context(), Correction{Type: correctionDeleted, Event: Event{Hash: hash}})
+	}
+	a.responses.invalidate()
+	expired := a.expireExportsSince(r.Context(), event.CreatedAt)
+	a.removeDerivedFiles(event)
+
+	a.audit(r.Context(), auditActionTakedown, event.Hash, map[string]any{
+		"reason":  request.Reason,
+		"events":  hashes,
+		"exports": expired,
+	})
+	log.Printf("Event %s taken down by %s (%d events, %d exports)", event.Hash, actorFromContext(r.Context()), len(hashes), expired)
+	writeJSON(w, http.StatusOK, tombstone{Hash: event.Hash, TakenDownAt: now})
+}
+
+// removeDerivedFiles deletes the files besides exports that may hold the
+// content of event: the podcast episode of its day, the Parquet analytics
+// exports and SQLite snapshots. The analytics exports are rewritten on their
+// next run and yesterday's episode is recorded again; older episodes stay
+// gone. Snapshots still being sent fail.
+func (a *App) removeDerivedFiles(event *Event) {
+	var paths []string
+	if a.config.PodcastDir != "" {
+		// Event times are Berlin wall clock times stored as UTC.
+		base := filepath.Join(a.config.PodcastDir, time.Unix(event.DateTime, 0).UTC().Format(time.DateOnly))
+		paths = append(paths, base+".txt", base+".mp3")
+	}
+	if a.config.AnalyticsExportDir != "" {
+		paths = append(paths,
+			filepath.Join(a.config.AnalyticsExportDir, "events.parquet"),
+			filepath.Join(a.config.AnalyticsExportDir, "facts.parquet"))
+	}
+	snapshots, _ := filepath.Glob(filepath.Join(cmp.Or(a.config.ExportDir, os.TempDir()), "snapshot-*.db*"))
+	paths = append(paths, snapshots...)
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error removing %s: %v", path, err)
+		}
+	}
+}
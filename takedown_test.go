@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTakedown(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"secret"}
+	app.config.ArchiveDir = t.TempDir()
+	app.config.ExportDir = t.TempDir()
+	app.config.PodcastDir = t.TempDir()
+	app.config.AnalyticsExportDir = t.TempDir()
+	ctx := context.Background()
+
+	report := Event{Title: "Zurückgezogen", Description: "Heikle Details", Location: "Mitte", Link: "https://www.berlin.de/polizei/1.php", Hash: "retracted", DateTime: 1, ArchiveSize: 10}
+	incident := Event{Title: "Vorfall daraus", Description: "Mehr Details", Hash: "retracted-1", ParentHash: "retracted", DateTime: 1}
+	other := Event{Title: "Bleibt", Link: "https://www.berlin.de/polizei/2.php", Hash: "kept", DateTime: 2}
+	for _, event := range []*Event{&report, &incident, &other} {
+		if err := app.store.Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.feed.Add(report, incident, other)
+	if err := app.store.SaveSummary(ctx, &EventSummary{EventHash: "retracted", Summary: "Heikle Zusammenfassung"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archivePath(app.config.ArchiveDir, "retracted"), []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	finished := time.Now()
+	job := ExportJob{ID: "export", Format: "csv", Status: exportDone, FinishedAt: &finished}
+	if err := app.store.SaveExportJob(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(app.exportPath(&job), []byte("Heikle Details"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	derived := []string{
+		filepath.Join(app.config.PodcastDir, "1970-01-01.txt"),
+		filepath.Join(app.config.PodcastDir, "1970-01-01.mp3"),
+		filepath.Join(app.config.AnalyticsExportDir, "events.parquet"),
+		filepath.Join(app.config.ExportDir, "snapshot-123.db"),
+	}
+	for _, path := range derived {
+		if err := os.WriteFile(path, []byte("Heikle Details"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edit := AuditEntry{Action: auditActionEventEdit, Target: "retracted", Payload: `[{"field":"description","old":"Heikle Details"}]`}
+	if err := app.store.RecordAudit(ctx, &edit); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	res := postJSON(t, server.URL+"/api/events/retracted/takedown", "secret", `{}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a reason to be required, got %d", res.StatusCode)
+	}
+	res = postJSON(t, server.URL+"/api/events/retracted/takedown", "secret", `{"reason": "Von der Polizei zurückgezogen"}`)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("takedown failed with %d", res.StatusCode)
+	}
+
+	for _, hash := range []string{"retracted", "retracted-1"} {
+		stored, err := app.store.FindByHash(ctx, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.TakenDownAt == nil || stored.Title != "" || stored.Description != "" || stored.Link != "" || stored.IdentityHash == "" {
+			t.Fatalf("unexpected tombstone %+v", stored)
+		}
+	}
+	if summaries, _ := app.store.Summaries(ctx, []string{"retracted"}); len(summaries) != 0 {
+		t.Fatalf("summary survived the takedown: %v", summaries)
+	}
+	if _, err := os.Stat(archivePath(app.config.ArchiveDir, "retracted")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("archive copy survived the takedown: %v", err)
+	}
+	if _, err := os.Stat(app.exportPath(&job)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("export survived the takedown: %v", err)
+	}
+	for _, path := range derived {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s survived the takedown: %v", filepath.Base(path), err)
+		}
+	}
+	edits, err := app.store.AuditLog(ctx, AuditFilter{Action: auditActionEventEdit, Limit: 10})
+	if err != nil || len(edits) != 1 || edits[0].Payload != "" {
+		t.Fatalf("edit payload survived the takedown: %+v, %v", edits, err)
+	}
+	if feed := app.feed.RSS(); strings.Contains(feed, "Zurückgezogen") || strings.Contains(feed, "Vorfall daraus") || !strings.Contains(feed, "Bleibt") {
+		t.Fatalf("unexpected feed after the takedown: %s", feed)
+	}
+	if recent, _ := app.store.Recent(ctx, EventFilter{}, 10); len(recent) != 1 || recent[0].Hash != "kept" {
+		t.Fatalf("tombstones are listed: %+v", recent)
+	}
+	entries, err := app.store.AuditLog(ctx, AuditFilter{Action: auditActionTakedown, Limit: 10})
+	if err != nil || len(entries) != 1 || entries[0].Target != "retracted" {
+		t.Fatalf("unexpected audit log %+v, %v", entries, err)
+	}
+
+	for _, path := range []string{"/api/events/retracted", "/event/retracted"} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusGone {
+			t.Errorf("GET %s: expected 410, got %d", path, res.StatusCode)
+		}
+	}
+}
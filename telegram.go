@@ -0,0 +1,199 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramMaxMessage is the most characters Telegram accepts in a message.
+const telegramMaxMessage = 4096
+
+// telegramChannelNotifier keeps a public Telegram channel as a mirror of the
+// feed: every event is posted on its own, edited when it changes and deleted
+// when it is taken down. The message IDs are kept as notification receipts.
+// Unlike a tgram:// Apprise target, which only sends new events to a chat,
+// it never bundles events into digests.
+type telegramChannelNotifier struct {
+	token   string
+	channel string
+	client  HTTPDoer
+	// endpoint is the Bot API server, replaced in tests.
+	endpoint string
+}
+
+func newTelegramChannelNotifier(token, channel string) (*telegramChannelNotifier, error) {
+	if token == "" || channel == "" {
+		return nil, errors.New("telegram channel mirror needs TELEGRAM_BOT_TOKEN and TELEGRAM_CHANNEL")
+	}
+	return &telegramChannelNotifier{
+		token:    token,
+		channel:  channel,
+		client:   &http.Client{Timeout: notifyTimeout},
+		endpoint: "https://api.telegram.org",
+	}, nil
+}
+
+func (n *telegramChannelNotifier) Name() string { return "telegram:" + n.channel }
+
+// Notify posts one message per event. Events with a receipt were posted
+// before, e.g. by a digest that failed halfway and is retried, or by a run
+// before a restart, and are not posted again.
+func (n *telegramChannelNotifier) Notify(ctx context.Context, notification Notification) error {
+	events := notification.Digest
+	if events == nil {
+		events = []Event{notification.Event}
+	}
+	for _, event := range events {
+		if notification.posted != nil && notification.posted(event.Hash) != "" {
+			continue
+		}
+		var message telegramMessage
+		err := n.call(ctx, "sendMessage", map[string]any{
+			"chat_id":    n.channel,
+			"text":       telegramText(event, notification.Emoji[event.Hash], notification.Summaries[event.Hash], notification.ShortLinks[event.Hash]),
+			"parse_mode": "HTML",
+		}, &message)
+		if err != nil {
+			return err
+		}
+		if notification.receipt != nil {
+			notification.receipt(event.Hash, strconv.FormatInt(message.MessageID, 10))
+		}
+	}
+	return nil
+}
+
+// Correct edits the message of an updated event and deletes that of one
+// taken down. Telegram refuses to delete messages older than 48 hours, which
+// are emptied instead.
+func (n *telegramChannelNotifier) Correct(ctx context.Context, correction Correction) error {
+	if correction.MessageID == "" {
+		return nil
+	}
+	switch correction.Type {
+	case correctionUpdated:
+		return n.edit(ctx, correction.MessageID, telegramText(correction.Event, correction.Emoji, "", ""))
+	case correctionDeleted:
+		err := n.call(ctx, "deleteMessage", map[string]any{"chat_id": n.channel, "message_id": correction.MessageID}, nil)
+		var apiErr *telegramError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest {
+			return n.edit(ctx, correction.MessageID, "<i>Meldung entfernt</i>")
+		}
+		return err
+	}
+	return nil
+}
+
+// edit replaces the text of a message. Edits not changing anything are
+// refused by Telegram, but leave the message as wanted.
+func (n *telegramChannelNotifier) edit(ctx context.Context, messageID, text string) error {
+	err := n.call(ctx, "editMessageText", map[string]any{
+		"chat_id":    n.channel,
+		"message_id": messageID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}, nil)
+	var apiErr *telegramError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Description, "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+// telegramText writes an event as a message: the title in bold after its
+// emoji prefix, if any, followed by district, summary or text, and link. Long
+// texts are shortened to fit the limit of a message, which Telegram applies
+// after parsing the markup.
+func telegramText(event Event, emoji, summary, shortLink string) string {
+	title := emojiTitle(emoji, event.Title)
+	parts := []string{"<b>" + html.EscapeString(title) + "</b>"}
+	if event.Location != "" {
+		parts = append(parts, html.EscapeString(event.Location))
+	}
+	text := summary
+	if text == "" && event.Description != defaultDescription {
+		text = event.Description
+	}
+	link := cmp.Or(shortLink, event.Link)
+	room := telegramMaxMessage - len([]rune(title+event.Location+link)) - 8
+	if text != "" && room > 1 {
+		parts = append(parts, html.EscapeString(excerpt(text, room)))
+	}
+	if link != "" {
+		parts = append(parts, html.EscapeString(link))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// telegramMessage is the part of a sent message needed here.
+type telegramMessage struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// telegramError is an error reported by the Bot API.
+type telegramError struct {
+	Code        int
+	Description string
+}
+
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+// call invokes a Bot API method and decodes its result into result, if given.
+// When Telegram asks to slow down, the call is repeated after the wait it
+// names, as long as ctx allows.
+func (n *telegramChannelNotifier) call(ctx context.Context, method string, params map[string]any, result any) error {
+	for {
+		req, err := jsonRequest(ctx, n.endpoint+"/bot"+n.token+"/"+method, params)
+		if err != nil {
+			return err
+		}
+		res, err := n.client.Do(req)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(io.LimitReader(res.Body, maxPageSize))
+		_ = res.Body.Close()
+		if err != nil {
+			return err
+		}
+		var response struct {
+			OK          bool            `json:"ok"`
+			Result      json.RawMessage `json:"result"`
+			ErrorCode   int             `json:"error_code"`
+			Description string          `json:"description"`
+			Parameters  struct {
+				RetryAfter int `json:"retry_after"`
+			} `json:"parameters"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return fmt.Errorf("telegram responded with %s", res.Status)
+		}
+		if response.OK {
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(response.Result, result)
+		}
+		wait := time.Duration(response.Parameters.RetryAfter) * time.Second
+		deadline, hasDeadline := ctx.Deadline()
+		if response.ErrorCode != http.StatusTooManyRequests || wait <= 0 || (hasDeadline && time.Now().Add(wait).After(deadline)) {
+			return &telegramError{Code: response.ErrorCode, Description: response.Description}
+		}
+		err = sleepContext(ctx, wait)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTelegramChannelNotifier_Mirrors(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+		texts []string
+	)
+	nextID := 41
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var params map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		method := strings.TrimPrefix(r.URL.Path, "/bottoken/")
+		calls = append(calls, method)
+		if params["chat_id"] != "@berlin" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"})
+			return
+		}
+		switch method {
+		case "sendMessage":
+			nextID++
+			texts = append(texts, params["text"].(string))
+			writeJSON(w, http.StatusOK, map[string]any{"ok": true, "result": map[string]any{"message_id": nextID}})
+		case "editMessageText":
+			texts = append(texts, params["text"].(string))
+			writeJSON(w, http.StatusOK, map[string]any{"ok": true, "result": map[string]any{"message_id": params["message_id"]}})
+		case "deleteMessage":
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error_code": 400, "description": "Bad Request: message can't be deleted"})
+		}
+	}))
+	defer api.Close()
+
+	app := newTestApp(t)
+	notifier, err := newTelegramChannelNotifier("token", "@berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.endpoint = api.URL
+	app.notifiers = []Notifier{notifier}
+	app.config.NotifyDigestThreshold = 2
+	ctx := context.Background()
+
+	events := []Event{
+		{Title: "Raub <Kiosk>", Location: "Mitte", Description: "Zwei Männer & eine Frau.", Link: "https://example.com/1", Hash: "h1", DateTime: 1},
+		{Title: "Brand", Link: "https://example.com/2", Hash: "h2", DateTime: 2},
+	}
+	if sent, failed := app.notify(ctx, app.notifiers, events, false); sent != 2 || failed != 0 {
+		t.Fatalf("expected both events posted, got %d sent, %d failed", sent, failed)
+	}
+	if len(texts) != 2 || texts[0] != "<b>Raub &lt;Kiosk&gt;</b>\n\nMitte\n\nZwei Männer &amp; eine Frau.\n\nhttps://example.com/1" {
+		t.Fatalf("expected one escaped message per event despite the digest, got %q", texts)
+	}
+	receipt, err := app.store.Receipt(ctx, notifier.Name(), "h1")
+	if err != nil {
+		t.Fatalf("expected the message ID to be kept: %v", err)
+	}
+
+	// After a restart, the receipts keep the events from being posted again.
+	restarted, err := newTelegramChannelNotifier("token", "@berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.endpoint = api.URL
+	app.notifiers = []Notifier{restarted}
+	if sent, failed := app.notify(ctx, app.notifiers, events, true); sent != 2 || failed != 0 || len(calls) != 2 {
+		t.Fatalf("expected the events to count as sent without posting them again, got %d sent, %d failed, calls %v", sent, failed, calls)
+	}
+
+	updated := events[0]
+	updated.Title = "Raub in Mitte"
+	app.correct(ctx, Correction{Type: correctionUpdated, Event: updated, Changes: []string{"title"}})
+	app.deliveries.Wait()
+	app.correct(ctx, Correction{Type: correctionDeleted, Event: Event{Hash: "h2"}})
+	app.deliveries.Wait()
+	if got := strings.Join(calls, ","); got != "sendMessage,sendMessage,editMessageText,deleteMessage,editMessageText" {
+		t.Fatalf("unexpected calls %s", got)
+	}
+	if !strings.HasPrefix(texts[2], "<b>Raub in Mitte</b>") || texts[3] != "<i>Meldung entfernt</i>" {
+		t.Fatalf("unexpected edits %q", texts[2:])
+	}
+	if receipt.MessageID != "42" {
+		t.Fatalf("unexpected message ID %q", receipt.MessageID)
+	}
+}
+
+func TestTelegramText_FitsMessage(t *testing.T) {
+	text := telegramText(Event{Title: "Lang", Description: strings.Repeat("ab ", 5000), Link: "https://example.com/1"}, "🔥", "", "")
+	// Telegram counts the text without markup.
+	text = strings.NewReplacer("<b>", "", "</b>", "").Replace(text)
+	if !strings.HasPrefix(text, "🔥 Lang\n") {
+		t.Fatalf("expected the emoji before the title, got %.20q", text)
+	}
+	if n := len([]rune(text)); n > telegramMaxMessage || !strings.HasSuffix(text, "…\n\nhttps://example.com/1") {
+		t.Fatalf("expected at most %d characters, got %d", telegramMaxMessage, n)
+	}
+}
@@ -0,0 +1,14 @@
+<!DOCTYPE html>
+<html lang="de">
+<head>
+    <meta charset="utf-8">
+    <meta name="description" content="Ein Radfahrer ist gestern Abend in Charlottenburg bei einem Zusammenstoß mit einem Auto schwer verletzt worden.">
+    <meta property="og:title" content="Radfahrer bei Unfall schwer verletzt">
+    <title>Radfahrer bei Unfall schwer verletzt - Berlin.de</title>
+</head>
+<body>
+<div class="textile">
+    <p>Ein Radfahrer ist gestern Abend in Charlottenburg bei einem Zusammenstoß mit einem Auto schwer verletzt worden.</p>
+</div>
+</body>
+</html>
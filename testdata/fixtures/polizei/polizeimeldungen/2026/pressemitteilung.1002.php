@@ -0,0 +1,14 @@
+<!DOCTYPE html>
+<html lang="de">
+<head>
+    <meta charset="utf-8">
+    <meta name="description" content="Nach einem Raub in einem Spätkauf in Neukölln haben Einsatzkräfte einen Tatverdächtigen festgenommen.">
+    <meta property="og:title" content="Festnahme nach Raub in Späti">
+    <title>Festnahme nach Raub in Späti - Berlin.de</title>
+</head>
+<body>
+<div class="textile">
+    <p>Nach einem Raub in einem Spätkauf in Neukölln haben Einsatzkräfte einen Tatverdächtigen festgenommen.</p>
+</div>
+</body>
+</html>
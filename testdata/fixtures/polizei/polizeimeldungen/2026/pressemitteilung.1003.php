@@ -0,0 +1,14 @@
+<!DOCTYPE html>
+<html lang="de">
+<head>
+    <meta charset="utf-8">
+    <meta name="description" content="In einem Mehrfamilienhaus in Pankow brannte gestern Nachmittag ein Kellerverschlag.">
+    <meta property="og:title" content="Brand in Kellerverschlag">
+    <title>Brand in Kellerverschlag - Berlin.de</title>
+</head>
+<body>
+<div class="textile">
+    <p>In einem Mehrfamilienhaus in Pankow brannte gestern Nachmittag ein Kellerverschlag.</p>
+</div>
+</body>
+</html>
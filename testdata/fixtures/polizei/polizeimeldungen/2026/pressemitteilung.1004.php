@@ -0,0 +1,18 @@
+<!DOCTYPE html>
+<html lang="de">
+<head>
+    <meta charset="utf-8">
+    <meta name="description" content="Tägliche Kurzmeldungen der Polizei Berlin vom 12. Oktober 2026.">
+    <meta property="og:title" content="Tägliche Kurzmeldungen">
+    <title>Tägliche Kurzmeldungen - Berlin.de</title>
+</head>
+<body>
+<div class="textile">
+    <p>Die Polizei Berlin meldet:</p>
+    <p><strong>Friedrichshain-Kreuzberg</strong></p>
+    <p>Unbekannte beschädigten gestern Abend gegen 22 Uhr mehrere Fahrzeuge in der Wrangelstraße. Die Ermittlungen dauern an.</p>
+    <p><strong>Spandau</strong></p>
+    <p>Ein 34-Jähriger wurde am Sonntagnachmittag beim Diebstahl in einem Baumarkt festgenommen.</p>
+</div>
+</body>
+</html>
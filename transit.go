@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// The disruption notices of BVG (underground, trams and buses) and S-Bahn
+// Berlin make the feed a complete picture of what's happening in the city
+// right now. Both are read from an RSS feed, e.g. one of the VBB; notices
+// that are updated keep their link and so replace the earlier version.
+
+const (
+	sourceBVG   = "bvg"
+	sourceSBahn = "sbahn"
+
+	// categoryDisruption is the category of all disruption notices.
+	categoryDisruption = "stoerung"
+)
+
+// sourceCategories are categories events get for their source alone, on top
+// of those the rules find.
+var sourceCategories = map[string]string{
+	sourceBVG:   categoryDisruption,
+	sourceSBahn: categoryDisruption,
+}
+
+// disruptionSource reads the disruption notices of a transport operator.
+type disruptionSource struct {
+	info      sourceInfo
+	userAgent string
+}
+
+func newBVGSource(config Config) (Source, error) {
+	if config.BVGURL == "" {
+		return nil, errMissingSourceURL("BVG_URL")
+	}
+	info := sourceInfo{Name: sourceBVG, Label: "BVG", Title: "BVG Störungsmeldungen", URL: config.BVGURL}
+	return &disruptionSource{info: info, userAgent: config.UserAgent}, nil
+}
+
+func newSBahnSource(config Config) (Source, error) {
+	if config.SBahnURL == "" {
+		return nil, errMissingSourceURL("SBAHN_URL")
+	}
+	info := sourceInfo{Name: sourceSBahn, Label: "S-Bahn", Title: "S-Bahn Berlin Störungsmeldungen", URL: config.SBahnURL}
+	return &disruptionSource{info: info, userAgent: config.UserAgent}, nil
+}
+
+func (s *disruptionSource) Info() sourceInfo {
+	return s.info
+}
+
+func (s *disruptionSource) Scrape(ctx context.Context, client HTTPDoer) ([]Event, error) {
+	feed, err := fetchRSS(ctx, client, s.info.URL, s.userAgent)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, item := range feed.Items {
+		published, err := parseRSSTime(item.PubDate)
+		if err != nil || item.Link == "" {
+			log.Printf("Skipping %s notice without link or date %q", s.info.Label, item.PubDate)
+			continue
+		}
+		event := Event{
+			Title:       strings.TrimSpace(item.Title),
+			Description: htmlText(item.Description),
+			Link:        strings.TrimSpace(item.Link),
+			DateTime:    wallClock(published),
+		}
+		event.Hash = sourceHash(s.info.Name, &event)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Complete leaves the events as they are, as the feed carries their text.
+func (s *disruptionSource) Complete(context.Context, HTTPDoer, *Event) error {
+	return nil
+}
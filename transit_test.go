@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDisruptionSource_Categorized(t *testing.T) {
+	app := newFixtureApp(t)
+	app.client = &http.Client{Transport: contentTypeTransport{fixtureTransport{dir: "testdata/fixtures"}, "application/rss+xml"}}
+	source, err := newBVGSource(Config{BVGURL: "https://www.bvg.de/rss/bvg.xml", UserAgent: defaultUserAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.scrapers = []Source{source}
+	ctx := context.Background()
+
+	if err := app.scrapeSource(ctx, source); err != nil {
+		t.Fatalf("scrapeSource failed: %v", err)
+	}
+	events, err := app.store.Recent(ctx, EventFilter{Sources: []string{sourceBVG}}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the dated notice only, got %+v", events)
+	}
+	event := events[0]
+	if event.Description != "Wegen eines Polizeieinsatzes fahren die Züge nur im Pendelverkehr. Bitte planen Sie mehr Zeit ein." {
+		t.Fatalf("unexpected description %q", event.Description)
+	}
+	categories := classifyEvent(app.rules(), &event).Categories
+	if len(categories) == 0 || categories[0] != categoryDisruption {
+		t.Fatalf("expected the disruption category first, got %v", categories)
+	}
+	if slices.Contains(classifyEvent(app.rules(), &Event{Title: "U8 gesperrt", Source: sourceBerlin}).Categories, categoryDisruption) {
+		t.Fatal("expected police reports to keep their categories")
+	}
+
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, httptest.NewRequest("GET", "/rss/all?source=bvg", nil))
+	if !strings.Contains(rec.Body.String(), "[BVG] U8: Pendelverkehr") || !strings.Contains(rec.Body.String(), "<author>BVG Störungsmeldungen</author>") {
+		t.Fatalf("expected the labelled notice in /rss/all:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// featureReaderAnalytics enables counting requests, see usageTracker.
+const featureReaderAnalytics = "reader_analytics"
+
+// Kinds of usage counts.
+const (
+	usageEndpoint = "endpoint"
+	usageDistrict = "district"
+	usageReaders  = "readers"
+)
+
+const usageFlushInterval = time.Minute
+
+// feedEndpoints are the routes whose clients count as readers.
+var feedEndpoints = []string{"/rss", "/atom", "/json"}
+
+// usageOther is the key districts outside berlinDistricts are counted under.
+const usageOther = "other"
+
+// UsageCount is the number of requests of one kind on one (Berlin) day, e.g.
+// to an endpoint or for a district. Nothing about individual clients is
+// stored.
+type UsageCount struct {
+	ID    uint   `gorm:"primaryKey"`
+	Day   string `gorm:"uniqueIndex:idx_usage_key;not null"`
+	Kind  string `gorm:"uniqueIndex:idx_usage_key;not null"`
+	Key   string `gorm:"uniqueIndex:idx_usage_key;not null"`
+	Count int64
+}
+
+// AddUsage adds the counts to the stored ones.
+func (s *gormStore) AddUsage(ctx context.Context, counts []UsageCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}, {Name: "kind"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{"count": clause.Expr{SQL: "usage_counts.count + excluded.count"}}),
+	}).Create(&counts).Error
+}
+
+// Usage returns the counts of the days from first to last, both inclusive.
+func (s *gormStore) Usage(ctx context.Context, first, last string) ([]UsageCount, error) {
+	var counts []UsageCount
+	err := s.db.WithContext(ctx).Where("day >= ? AND day <= ?", first, last).Order("day").Find(&counts).Error
+	return counts, err
+}
+
+type usageKey struct {
+	kind, key string
+}
+
+// usageTracker counts requests in memory until they are flushed to the store.
+// Readers are told apart by a hash of their address and user agent with a
+// random salt that changes daily and is never stored, so they can't be
+// recognized across days or identified from the data.
+type usageTracker struct {
+	mu      sync.Mutex
+	day     string
+	salt    []byte
+	readers map[uint64]struct{}
+	// flushedReaders is how many of readers are already stored.
+	flushedReaders int
+	counts         map[usageKey]int64
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{}
+}
+
+// rotate starts a new day if needed. It must be called with mu held and
+// returns the counts of the previous day that haven't been flushed yet.
+func (u *usageTracker) rotate(day string) []UsageCount {
+	if u.day == day {
+		return nil
+	}
+	pending := u.pending()
+	u.day = day
+	u.salt = make([]byte, 16)
+	_, _ = rand.Read(u.salt)
+	u.readers = make(map[uint64]struct{})
+	u.flushedReaders = 0
+	u.counts = make(map[usageKey]int64)
+	return pending
+}
+
+// pending returns and resets the counts since the last flush. It must be
+// called with mu held.
+func (u *usageTracker) pending() []UsageCount {
+	var counts []UsageCount
+	for key, count := range u.counts {
+		counts = append(counts, UsageCount{Day: u.day, Kind: key.kind, Key: key.key, Count: count})
+	}
+	if n := len(u.readers) - u.flushedReaders; n > 0 {
+		counts = append(counts, UsageCount{Day: u.day, Kind: usageReaders, Key: "feeds", Count: int64(n)})
+		u.flushedReaders = len(u.readers)
+	}
+	clear(u.counts)
+	return counts
+}
+
+// record counts a served request. It returns counts of a finished day that
+// should be flushed right away. Keys come from a fixed set, the route
+// patterns and berlinDistricts, so that clients can't grow the counts with
+// made-up paths or districts.
+func (u *usageTracker) record(r *http.Request, now time.Time) []UsageCount {
+	pattern := r.Pattern
+	if pattern == "" {
+		pattern = "unmatched"
+	}
+	district := r.URL.Query().Get("district")
+	if district != "" && !slices.Contains(berlinDistricts, district) {
+		district = usageOther
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	finished := u.rotate(now.In(berlin).Format(time.DateOnly))
+	u.counts[usageKey{usageEndpoint, pattern}]++
+	if district != "" {
+		u.counts[usageKey{usageDistrict, district}]++
+	}
+	if slices.Contains(feedEndpoints, pattern) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		sum := sha256.Sum256(append(append(slices.Clone(u.salt), host+"\x00"...), r.UserAgent()...))
+		u.readers[binary.BigEndian.Uint64(sum[:8])] = struct{}{}
+	}
+	return finished
+}
+
+func (u *usageTracker) flush() []UsageCount {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.pending()
+}
+
+// trackUsage counts the requests handled by next if reader analytics are
+// enabled.
+func (a *App) trackUsage(next http.Handler) http.Handler {
+	if a.usage == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		// The mux has set the matched pattern on r by now.
+		finished := a.usage.record(r, time.Now())
+		if len(finished) > 0 {
+			go a.saveUsage(context.Background(), finished)
+		}
+	})
+}
+
+func (a *App) saveUsage(ctx context.Context, counts []UsageCount) {
+	err := a.store.AddUsage(ctx, counts)
+	if err != nil {
+		log.Println("Error saving usage counts:", err)
+	}
+}
+
+// runUsageFlush periodically writes the counted usage to the store until ctx
+// is cancelled, and once more on the way out.
+func (a *App) runUsageFlush(ctx context.Context) {
+	if a.usage == nil {
+		return
+	}
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.saveUsage(context.WithoutCancel(ctx), a.usage.flush())
+			return
+		case <-ticker.C:
+			a.saveUsage(ctx, a.usage.flush())
+		}
+	}
+}
+
+type usageEntry struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+type usageDay struct {
+	Day     string `json:"day"`
+	Readers int64  `json:"readers"`
+}
+
+type usageReport struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Endpoints []usageEntry `json:"endpoints"`
+	Districts []usageEntry `json:"districts"`
+	// Readers estimates unique feed readers per day. Readers can't be told
+	// apart across days, so these don't add up to a total.
+	Readers []usageDay `json:"readers"`
+}
+
+// handleUsage reports the request counts of the last days (default 30).
+func (a *App) handleUsage(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > 366 {
+			writeJSONError(w, http.StatusBadRequest, "days must be between 1 and 366")
+			return
+		}
+	}
+	// Include what hasn't been flushed yet.
+	if a.usage != nil {
+		a.saveUsage(r.Context(), a.usage.flush())
+	}
+
+	today := time.Now().In(berlin)
+	report := usageReport{
+		From:      today.AddDate(0, 0, 1-days).Format(time.DateOnly),
+		To:        today.Format(time.DateOnly),
+		Endpoints: []usageEntry{},
+		Districts: []usageEntry{},
+		Readers:   []usageDay{},
+	}
+	counts, err := a.store.Usage(r.Context(), report.From, report.To)
+	if err != nil {
+		log.Println("Error loading usage counts:", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	totals := map[string]map[string]int64{usageEndpoint: {}, usageDistrict: {}}
+	for _, count := range counts {
+		if count.Kind == usageReaders {
+			report.Readers = append(report.Readers, usageDay{Day: count.Day, Readers: count.Count})
+			continue
+		}
+		if totals[count.Kind] != nil {
+			totals[count.Kind][count.Key] += count.Count
+		}
+	}
+	sorted := func(totals map[string]int64) []usageEntry {
+		entries := make([]usageEntry, 0, len(totals))
+		for key, count := range totals {
+			entries = append(entries, usageEntry{Key: key, Count: count})
+		}
+		slices.SortFunc(entries, func(a, b usageEntry) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+		})
+		return entries
+	}
+	report.Endpoints = sorted(totals[usageEndpoint])
+	report.Districts = sorted(totals[usageDistrict])
+	writeJSON(w, http.StatusOK, report)
+}
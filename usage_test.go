@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUsage_CountsRequestsAndReaders(t *testing.T) {
+	app := newTestApp(t)
+	app.config.AdminTokens = []string{"admin"}
+	app.usage = newUsageTracker()
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	request := func(path, userAgent, token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("User-Agent", userAgent)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return res
+	}
+	for _, userAgent := range []string{"reader-a", "reader-a", "reader-b"} {
+		_ = request("/rss", userAgent, "").Body.Close()
+	}
+	_ = request("/atom?district=Mitte", "reader-a", "").Body.Close()
+	_ = request("/atom?district=Gotham", "reader-a", "").Body.Close()
+
+	res := request("/api/analytics", "admin", "admin")
+	defer res.Body.Close()
+	var report usageReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		t.Fatalf("decoding report failed: %v", err)
+	}
+
+	counts := map[string]int64{}
+	for _, entry := range report.Endpoints {
+		counts[entry.Key] = entry.Count
+	}
+	if counts["/rss"] != 3 || counts["/atom"] != 2 {
+		t.Fatalf("unexpected endpoint counts %+v", report.Endpoints)
+	}
+	if len(report.Districts) != 2 || report.Districts[0] != (usageEntry{Key: "Mitte", Count: 1}) || report.Districts[1] != (usageEntry{Key: usageOther, Count: 1}) {
+		t.Fatalf("unexpected district counts %+v", report.Districts)
+	}
+	if len(report.Readers) != 1 || report.Readers[0].Readers != 2 {
+		t.Fatalf("expected 2 readers, got %+v", report.Readers)
+	}
+}
+
+func TestUsageTracker_RotatesDaily(t *testing.T) {
+	tracker := newUsageTracker()
+	req := httptest.NewRequest(http.MethodGet, "/rss", nil)
+	req.Pattern = "/rss"
+
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, berlin)
+	if finished := tracker.record(req, day); finished != nil {
+		t.Fatalf("expected nothing to flush on the first request, got %+v", finished)
+	}
+	salt := tracker.salt
+
+	finished := tracker.record(req, day.AddDate(0, 0, 1))
+	if len(finished) != 2 || finished[0].Day != "2026-03-01" {
+		t.Fatalf("expected the previous day to be flushed, got %+v", finished)
+	}
+	if string(tracker.salt) == string(salt) {
+		t.Fatal("expected a new salt for the new day")
+	}
+	if len(tracker.readers) != 1 {
+		t.Fatalf("expected the reader to be counted anew, got %d", len(tracker.readers))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// waybackCheckInterval is how often new events are checked for missing
+	// Wayback Machine captures.
+	waybackCheckInterval = time.Minute
+	// waybackRetryWindow is how long after it was stored a failed capture of
+	// an event is retried.
+	waybackRetryWindow = 24 * time.Hour
+	waybackBatchSize   = 50
+	// waybackPollInterval and waybackPollAttempts bound how long a capture
+	// is waited for.
+	waybackPollInterval = 5 * time.Second
+	waybackPollAttempts = 24
+)
+
+// waybackClient submits pages to the Internet Archive's Save Page Now API
+// (SPN2) and waits for the captures.
+type waybackClient struct {
+	endpoint  string
+	accessKey string
+	secretKey string
+	// submit is rate limited, as the API only allows a few captures per
+	// minute; polling the capture status is not.
+	submit       HTTPDoer
+	poll         HTTPDoer
+	pollInterval time.Duration
+}
+
+// newWaybackClient returns nil if archiving is disabled, i.e. no keys are
+// configured.
+func newWaybackClient(config Config) *waybackClient {
+	if config.WaybackAccessKey == "" || config.WaybackSecretKey == "" {
+		return nil
+	}
+	return &waybackClient{
+		endpoint:     "https://web.archive.org",
+		accessKey:    config.WaybackAccessKey,
+		secretKey:    config.WaybackSecretKey,
+		submit:       NewRateLimitedClient(1/config.WaybackInterval.Seconds(), 1),
+		poll:         &http.Client{Timeout: notifyTimeout},
+		pollInterval: waybackPollInterval,
+	}
+}
+
+// waybackStatus is the status of a capture job, or the response to
+// submitting one.
+type waybackStatus struct {
+	JobID       string `json:"job_id"`
+	Status      string `json:"status"`
+	Timestamp   string `json:"timestamp"`
+	OriginalURL string `json:"original_url"`
+	Message     string `json:"message"`
+}
+
+func (c *waybackClient) do(client HTTPDoer, req *http.Request) (*waybackStatus, error) {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "LOW "+c.accessKey+":"+c.secretKey)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	var status waybackStatus
+	err = json.NewDecoder(res.Body).Decode(&status)
+	return &status, err
+}
+
+// Save captures page and returns the address of the capture.
+func (c *waybackClient) Save(ctx context.Context, page string) (string, error) {
+	form := url.Values{"url": {page}, "skip_first_archive": {"1"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/save", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	status, err := c.do(c.submit, req)
+	if err != nil {
+		return "", err
+	}
+	if status.JobID == "" {
+		return "", fmt.Errorf("capture not started: %s", status.Message)
+	}
+
+	for range waybackPollAttempts {
+		err = sleepContext(ctx, c.pollInterval)
+		if err != nil {
+			return "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/save/status/"+url.PathEscape(status.JobID), nil)
+		if err != nil {
+			return "", err
+		}
+		job, err := c.do(c.poll, req)
+		if err != nil {
+			return "", err
+		}
+		switch job.Status {
+		case "success":
+			original := job.OriginalURL
+			if original == "" {
+				original = page
+			}
+			return c.endpoint + "/web/" + job.Timestamp + "/" + original, nil
+		case "error":
+			return "", fmt.Errorf("capture failed: %s", job.Message)
+		}
+	}
+	return "", errors.New("capture timed out")
+}
+
+// MissingWayback returns events stored since the given time whose detail
+// page hasn't been captured yet. Split off incidents share their report's
+// page and are skipped.
+func (s *gormStore) MissingWayback(ctx context.Context, since time.Time, limit int) ([]Event, error) {
+	var events []Event
+	err := s.db.WithContext(ctx).
+		Where("wayback_url = '' AND link <> '' AND parent_hash = '' AND created_at >= ?", since).
+		Order("created_at").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// SetWaybackURL records a capture without touching UpdatedAt.
+func (s *gormStore) SetWaybackURL(ctx context.Context, hash, waybackURL string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Event{}).Where("hash = ?", hash).UpdateColumn("wayback_url", waybackURL).Error
+		if err != nil {
+			return err
+		}
+		return recordChanges(tx, changeUpdated, hash)
+	})
+}
+
+// archiveToWayback captures the detail pages of new events one after the
+// other, so that they stay referenceable after berlin.de removes them.
+func (a *App) archiveToWayback(ctx context.Context) error {
+	events, err := a.store.MissingWayback(ctx, time.Now().Add(-waybackRetryWindow), waybackBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		capture, err := a.wayback.Save(ctx, event.Link)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("Error archiving %s in the Wayback Machine: %v", event.Link, err)
+			continue
+		}
+		err = a.store.SetWaybackURL(ctx, event.Hash, capture)
+		if err != nil {
+			return err
+		}
+		log.Printf("Archived %s as %s", event.Link, capture)
+	}
+	return nil
+}
+
+func (a *App) runWayback(ctx context.Context) {
+	if a.wayback == nil {
+		return
+	}
+	for {
+		err := a.archiveToWayback(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Println("Error archiving to the Wayback Machine:", err)
+		}
+		if sleepContext(ctx, waybackCheckInterval) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestArchiveToWayback(t *testing.T) {
+	var submitted []string
+	polls := 0
+	archive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "LOW access:secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/save":
+			page := r.PostFormValue("url")
+			submitted = append(submitted, page)
+			if page == "https://example.com/broken" {
+				writeJSON(w, http.StatusOK, map[string]string{"status": "error", "message": "You have already reached the limit"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"url": page, "job_id": "spn2-1"})
+		case r.URL.Path == "/save/status/spn2-1":
+			polls++
+			if polls == 1 {
+				writeJSON(w, http.StatusOK, map[string]string{"status": "pending"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"status": "success", "timestamp": "20240110120000", "original_url": "https://example.com/a"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer archive.Close()
+
+	app := newTestApp(t)
+	app.wayback = newWaybackClient(Config{WaybackAccessKey: "access", WaybackSecretKey: "secret", WaybackInterval: time.Millisecond})
+	app.wayback.endpoint = archive.URL
+	app.wayback.pollInterval = time.Millisecond
+	ctx := context.Background()
+	events := []Event{
+		{Title: "Raub", Hash: "a", Link: "https://example.com/a"},
+		{Title: "Kurzmeldung", Hash: "a-1", Link: "https://example.com/a", ParentHash: "a"},
+		{Title: "Brand", Hash: "b", Link: "https://example.com/broken"},
+	}
+	for i := range events {
+		if err := app.store.Create(ctx, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := app.archiveToWayback(ctx); err != nil {
+		t.Fatalf("archiveToWayback failed: %v", err)
+	}
+	if len(submitted) != 2 || polls != 2 {
+		t.Fatalf("unexpected submissions %v after %d polls", submitted, polls)
+	}
+	want := archive.URL + "/web/20240110120000/https://example.com/a"
+	event, _ := app.store.FindByHash(ctx, "a")
+	if event.WaybackURL != want || event.UpdatedAt.After(event.CreatedAt) {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	// The failed capture is retried on the next run.
+	if missing, _ := app.store.MissingWayback(ctx, time.Now().Add(-time.Hour), 10); len(missing) != 1 || missing[0].Hash != "b" {
+		t.Fatalf("unexpected missing captures %+v", missing)
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/api/events/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var permalink apiEvent
+	_ = json.NewDecoder(res.Body).Decode(&permalink)
+	if permalink.WaybackURL != want {
+		t.Fatalf("unexpected permalink %+v", permalink)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Luiggi33/berlin-police-feed/pkg/model"
+	"github.com/PuerkitoBio/goquery"
+	"gorm.io/gorm/clause"
+)
+
+const featureWebmention = "webmention"
+
+// webmentionMaxBody limits how much of a page is read when verifying or
+// discovering Webmentions.
+const webmentionMaxBody = 1 << 20
+
+// Mention is a Webmention received for an event: an external page, e.g. a
+// news article, linking to the event's permalink.
+type Mention struct {
+	ID        uint   `gorm:"primaryKey"`
+	EventHash string `gorm:"uniqueIndex:idx_mention_source;not null"`
+	Source    string `gorm:"uniqueIndex:idx_mention_source;not null"`
+	Title     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (s *gormStore) SaveMention(ctx context.Context, mention *Mention) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_hash"}, {Name: "source"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "updated_at"}),
+	}).Create(mention).Error
+}
+
+func (s *gormStore) DeleteMention(ctx context.Context, hash, source string) error {
+	return s.db.WithContext(ctx).Delete(&Mention{}, "event_hash = ? AND source = ?", hash, source).Error
+}
+
+func (s *gormStore) Mentions(ctx context.Context, hash string) ([]Mention, error) {
+	var mentions []Mention
+	err := s.db.WithContext(ctx).Where("event_hash = ?", hash).Order("created_at").Find(&mentions).Error
+	return mentions, err
+}
+
+type apiMention = model.Mention
+
+func toAPIMentions(mentions []Mention) []apiMention {
+	var result []apiMention
+	for _, mention := range mentions {
+		result = append(result, apiMention{Source: mention.Source, Title: mention.Title, ReceivedAt: mention.CreatedAt.UTC()})
+	}
+	return result
+}
+
+// webmentionEndpoint returns the URL of the receiving endpoint, which
+// permalinks advertise in their Link header.
+func (a *App) webmentionEndpoint() string {
+	return strings.TrimSuffix(a.config.BaseURL, "/") + "/webmention"
+}
+
+// handleWebmention receives a Webmention (https://www.w3.org/TR/webmention/)
+// for an event permalink. The source is verified right away: if it links to
+// the target the mention is stored, if it is gone or no longer links there
+// a previously stored mention is removed.
+func (a *App) handleWebmention(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<16)
+	source, target := r.PostFormValue("source"), r.PostFormValue("target")
+	sourceURL, err := url.Parse(source)
+	if err != nil || (sourceURL.Scheme != "http" && sourceURL.Scheme != "https") || sourceURL.Host == "" {
+		http.Error(w, "invalid source", http.StatusBadRequest)
+		return
+	}
+	if source == target {
+		http.Error(w, "source and target must differ", http.StatusBadRequest)
+		return
+	}
+	if len(a.config.WebmentionSources) > 0 && !slices.ContainsFunc(a.config.WebmentionSources, func(prefix string) bool {
+		return strings.HasPrefix(source, prefix)
+	}) {
+		http.Error(w, "source not accepted", http.StatusBadRequest)
+		return
+	}
+	hash, ok := strings.CutPrefix(target, a.feed.permalink(""))
+	if !ok || hash == "" || strings.Contains(hash, "/") {
+		http.Error(w, "target is not an event permalink", http.StatusBadRequest)
+		return
+	}
+	_, err = a.store.FindByHash(r.Context(), hash)
+	if err != nil {
+		http.Error(w, "target is not an event permalink", http.StatusBadRequest)
+		return
+	}
+
+	title, err := verifyMentionSource(r.Context(), a.mentionClient, source, target)
+	if errors.Is(err, errMentionGone) || errors.Is(err, errMentionNoLink) {
+		deleteErr := a.store.DeleteMention(r.Context(), hash, source)
+		if deleteErr != nil {
+			log.Println("Error deleting mention:", deleteErr)
+		}
+	}
+	if errors.Is(err, errMentionGone) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		http.Error(w, "verifying source failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = a.store.SaveMention(r.Context(), &Mention{EventHash: hash, Source: source, Title: title})
+	if err != nil {
+		log.Println("Error saving mention:", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Received webmention for %s from %s", hash, source)
+	w.WriteHeader(http.StatusOK)
+}
+
+var (
+	errMentionGone   = errors.New("source is gone")
+	errMentionNoLink = errors.New("source does not link to target")
+)
+
+// verifyMentionSource fetches source and checks that it links to target. It
+// returns the page title for HTML sources.
+func verifyMentionSource(ctx context.Context, client HTTPDoer, source, target string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+		return "", errMentionGone
+	}
+	if res.StatusCode/100 != 2 {
+		return "", fmt.Errorf("unexpected status %s", res.Status)
+	}
+	body := io.LimitReader(res.Body, webmentionMaxBody)
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(string(data), target) {
+			return "", errMentionNoLink
+		}
+		return "", nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return "", err
+	}
+	links := doc.Find("a[href], link[href], area[href]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		ref, err := res.Request.URL.Parse(strings.TrimSpace(href))
+		return err == nil && ref.String() == target
+	})
+	if links.Length() == 0 {
+		return "", errMentionNoLink
+	}
+	return strings.TrimSpace(doc.Find("title").First().Text()), nil
+}
+
+// webmentionNotifier sends a Webmention from the permalink of each new event
+// to the page the event links to, for sites that accept them.
+type webmentionNotifier struct {
+	feed   *FeedBuilder
+	client HTTPDoer
+}
+
+func (n *webmentionNotifier) Name() string { return "webmention" }
+
+func (n *webmentionNotifier) Notify(ctx context.Context, notification Notification) error {
+	events := notification.Digest
+	if events == nil {
+		events = []Event{notification.Event}
+	}
+	var errs []error
+	for _, event := range events {
+		if event.Link == "" {
+			continue
+		}
+		err := sendWebmention(ctx, n.client, n.feed.permalink(event.Hash), event.Link)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", event.Link, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendWebmention discovers the endpoint of target and notifies it about
+// source. Targets without an endpoint are skipped silently.
+func sendWebmention(ctx context.Context, client HTTPDoer, source, target string) error {
+	endpoint, err := discoverWebmentionEndpoint(ctx, client, target)
+	if err != nil || endpoint == "" {
+		return err
+	}
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, webmentionMaxBody))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("endpoint %s responded with %s", endpoint, res.Status)
+	}
+	return nil
+}
+
+// discoverWebmentionEndpoint looks for the endpoint in the Link header of
+// target, then in its HTML. It returns "" if target accepts no Webmentions.
+func discoverWebmentionEndpoint(ctx context.Context, client HTTPDoer, target string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return "", fmt.Errorf("unexpected status %s", res.Status)
+	}
+	base := res.Request.URL
+
+	for _, header := range res.Header.Values("Link") {
+		if endpoint, ok := webmentionLink(header); ok {
+			ref, err := base.Parse(endpoint)
+			if err != nil {
+				return "", err
+			}
+			return ref.String(), nil
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" {
+		return "", nil
+	}
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(res.Body, webmentionMaxBody))
+	if err != nil {
+		return "", err
+	}
+	var endpoint string
+	doc.Find("link[href][rel], a[href][rel]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		rel, _ := s.Attr("rel")
+		if !slices.Contains(strings.Fields(rel), "webmention") {
+			return true
+		}
+		href, _ := s.Attr("href")
+		ref, err := base.Parse(href)
+		if err == nil {
+			endpoint = ref.String()
+		}
+		return false
+	})
+	return endpoint, nil
+}
+
+// webmentionLink returns the URL of the rel="webmention" entry in a Link
+// header value, if there is one.
+func webmentionLink(header string) (string, bool) {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "rel") && slices.Contains(strings.Fields(strings.Trim(value, `"`)), "webmention") {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">"), true
+			}
+		}
+	}
+	return "", false
+}
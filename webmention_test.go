@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestWebmention_Receive(t *testing.T) {
+	app := newTestApp(t)
+	app.config.Features = []string{featureWebmention}
+	app.config.BaseURL = "https://feed.example.org"
+	app.feed.SetBaseURL(app.config.BaseURL)
+	// The source server of the test runs on loopback.
+	app.mentionClient = http.DefaultClient
+	seedExportEvents(t, app.store)
+	target := "https://feed.example.org/api/events/e1"
+
+	linking := true
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			body := `<html><head><title>Überfall in Mitte</title></head><body><p>Laut Polizei …</p>`
+			if linking {
+				body += `<a href="` + target + `">Polizeimeldung</a>`
+			}
+			_, _ = w.Write([]byte(body + `</body></html>`))
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer source.Close()
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	send := func(source, target string) int {
+		res, err := http.PostForm(server.URL+"/webmention", url.Values{"source": {source}, "target": {target}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+	permalink := func() apiEvent {
+		res, err := http.Get(server.URL + "/api/events/e1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if link := res.Header.Get("Link"); link != `<https://feed.example.org/webmention>; rel="webmention"` {
+			t.Fatalf("unexpected Link header %q", link)
+		}
+		var event apiEvent
+		_ = json.NewDecoder(res.Body).Decode(&event)
+		return event
+	}
+
+	if status := send(source.URL+"/article", "https://feed.example.org/api/events/missing"); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown target, got %d", status)
+	}
+	if status := send("ftp://example.com/", target); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid source, got %d", status)
+	}
+	if status := send(source.URL+"/article", target); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	// Repeated pings update the mention instead of duplicating it.
+	if status := send(source.URL+"/article", target); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	mentions := permalink().Mentions
+	if len(mentions) != 1 || mentions[0].Source != source.URL+"/article" || mentions[0].Title != "Überfall in Mitte" {
+		t.Fatalf("unexpected mentions %+v", mentions)
+	}
+
+	linking = false
+	if status := send(source.URL+"/article", target); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 once the link is gone, got %d", status)
+	}
+	if mentions := permalink().Mentions; len(mentions) != 0 {
+		t.Fatalf("expected mention to be removed, got %+v", mentions)
+	}
+	if status := send(source.URL+"/gone", target); status != http.StatusOK {
+		t.Fatalf("expected 200 for a deleted source, got %d", status)
+	}
+
+	app.config.WebmentionSources = []string{"https://www.rbb24.de/"}
+	linking = true
+	if status := send(source.URL+"/article", target); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a source not on the list, got %d", status)
+	}
+}
+
+func TestWebmentionNotifier_DiscoversEndpoints(t *testing.T) {
+	var received []url.Values
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/header":
+			w.Header().Add("Link", `<https://example.com/other>; rel="alternate", </endpoint>; rel="webmention other"`)
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><head><link rel="stylesheet" href="/style.css"><link rel="webmention" href="endpoint"></head></html>`))
+		case "/none":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html></html>`))
+		case "/endpoint":
+			_ = r.ParseForm()
+			received = append(received, r.PostForm)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer target.Close()
+
+	feed := NewFeedBuilder("https://example.com/")
+	feed.SetBaseURL("https://feed.example.org")
+	notifier := &webmentionNotifier{feed: feed, client: http.DefaultClient}
+	err := notifier.Notify(context.Background(), Notification{Digest: []Event{
+		{Hash: "a", Link: target.URL + "/header"},
+		{Hash: "b", Link: target.URL + "/html"},
+		{Hash: "c", Link: target.URL + "/none"},
+		{Hash: "d"},
+	}})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("expected 2 webmentions, got %+v", received)
+	}
+	if received[0].Get("source") != "https://feed.example.org/api/events/a" || received[0].Get("target") != target.URL+"/header" {
+		t.Fatalf("unexpected webmention %v", received[0])
+	}
+	if !strings.HasSuffix(received[1].Get("target"), "/html") {
+		t.Fatalf("unexpected webmention %v", received[1])
+	}
+}
+
+func TestWebmention_PingsUseTheAppTransport(t *testing.T) {
+	store, db := openTestStore(t)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	transport := newPoliteTransport(http.DefaultTransport, rate.Inf, 1)
+	config := Config{PoliceURL: "https://example.com/", BaseURL: "https://feed.example.org", Features: []string{featureWebmention}}
+	app, err := NewApp(context.Background(), config, &http.Client{Transport: transport}, store, NewFeedBuilder("https://example.com/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, notifier := range app.notifiers {
+		if mention, ok := notifier.(*webmentionNotifier); ok {
+			if client := mention.client.(*http.Client); client.Transport != transport {
+				t.Fatalf("expected the pings to go through the polite transport, got %T", client.Transport)
+			}
+			return
+		}
+	}
+	t.Fatal("no webmention notifier")
+}